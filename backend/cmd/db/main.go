@@ -8,40 +8,89 @@ import (
 
 	"cvwo/cmd/db/operations"
 	"cvwo/cmd/db/seed"
+	"cvwo/internal/config"
 	"cvwo/internal/database"
-
-	"github.com/joho/godotenv"
 )
 
+const usage = `Usage: go run main.go --action=<reset|seed|migrate|set-role|render-content> [--env-file=<path>] [--config=<path>] [arguments]
+
+Migrate commands:
+  up              Apply all pending migrations
+  down [steps]    Revert the last applied migration(s), default 1
+  status          List migrations and whether they are applied
+  create <name>   Create an empty up/down migration pair in --migrations-dir
+
+Set role arguments:
+  <username> <admin|moderator|member>
+
+Render content re-renders the Markdown of every post and comment`
+
 func main() {
 	var (
-		action  = flag.String("action", "", "Action: reset, seed")
-		envFile = flag.String("env-file", "", "Path to .env file (e.g. ./backend/.env)")
+		action        = flag.String("action", "", "Action: reset, seed, migrate, set-role, render-content")
+		envFile       = flag.String("env-file", "", "Path to .env file (e.g. ./backend/.env)")
+		configFile    = flag.String("config", "", "Settings file (.yaml, .yml or .toml), overridden by .env and environment variables")
+		migrationsDir = flag.String("migrations-dir", "embed/sql/migrations", "Source directory for new migrations (migrate create)")
 	)
 	flag.Parse()
 
 	if *action == "" {
-		fmt.Println("Usage: go run main.go --action=<reset|seed> [--env-file=<path>]")
+		fmt.Println(usage)
+		os.Exit(1)
+	}
+
+	// migrate subcommand and its arguments, e.g. --action=migrate down 2
+	args := flag.Args()
+	if (*action == "migrate" && len(args) == 0) || (*action == "set-role" && len(args) != 2) {
+		fmt.Println(usage)
 		os.Exit(1)
 	}
 
+	// Creating a migration only writes files
+	if *action == "migrate" && args[0] == "create" {
+		if len(args) < 2 {
+			log.Fatal("Migration name is required")
+		}
+		operations.CreateMigration(*migrationsDir, args[1])
+		return
+	}
+
 	if *envFile == "" {
 		*envFile = "../.env"
 	}
 
-	if err := godotenv.Load(*envFile); err != nil {
-		log.Println("No .env file found")
+	cfg, err := config.Load(*configFile, *envFile)
+	if err != nil {
+		log.Fatal("Could not load configuration: ", err)
+	}
+	// Only the database settings are needed here
+	if err := cfg.Database.Validate(); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
 	}
 
-	database.Connect()
+	if _, err := database.Connect(cfg.Database); err != nil {
+		log.Fatal(err)
+	}
 	defer database.DB.Close()
 
 	switch *action {
 	case "reset":
-		operations.ResetDatabase()
+		if err := operations.ResetDatabase(); err != nil {
+			log.Fatal(err)
+		}
 	case "seed":
-		operations.ResetDatabase()
-		seed.SeedDatabase()
+		if err := operations.ResetDatabase(); err != nil {
+			log.Fatal(err)
+		}
+		if err := seed.SeedDatabase(); err != nil {
+			log.Fatal(err)
+		}
+	case "migrate":
+		operations.MigrateDatabase(args[0], args[1:])
+	case "set-role":
+		operations.SetUserRole(args[0], args[1])
+	case "render-content":
+		operations.RenderContent()
 	default:
 		log.Fatalf("Unknown action: %s", *action)
 	}
@@ -1,19 +1,25 @@
 package operations
 
 import (
+	"context"
+	"cvwo/internal/constants"
+	"cvwo/internal/dataaccess"
 	"cvwo/internal/database"
+	"cvwo/internal/migrations"
 	"cvwo/cmd/db/utils"
 	"fmt"
 	"log"
+	"strconv"
 )
 
-func ResetDatabase() {
+// ResetDatabase drops every table and recreates them with the migrations
+func ResetDatabase() error {
 	fmt.Println("Resetting database...")
 
 	// Drop existing tables
 	dropSQL, err := utils.ReadSqlFile("drop.sql")
 	if err != nil {
-		log.Fatal("Failed to read drop.sql:", err)
+		return fmt.Errorf("failed to read drop.sql: %w", err)
 	}
 
 	if _, err := database.DB.Exec(string(dropSQL)); err != nil {
@@ -21,14 +27,114 @@ func ResetDatabase() {
 	}
 
 	// Recreate tables
-	schemaSQL, err := utils.ReadSqlFile("schema.sql")
+	if _, err := migrations.Up(database.DB); err != nil {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+
+	fmt.Println("Database reset completed!")
+	return nil
+}
+
+// MigrateDatabase runs a migrate subcommand: up, down [steps], status
+func MigrateDatabase(command string, args []string) {
+	switch command {
+	case "up":
+		applied, err := migrations.Up(database.DB)
+		for _, migration := range applied {
+			fmt.Printf("Applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal("Failed to apply migrations: ", err)
+		}
+		fmt.Printf("Applied %d migration(s)\n", len(applied))
+
+	case "down":
+		steps := 1
+		if len(args) > 0 {
+			var err error
+			steps, err = strconv.Atoi(args[0])
+			if err != nil || steps <= 0 {
+				log.Fatalf("Invalid number of steps: %s", args[0])
+			}
+		}
+
+		reverted, err := migrations.Down(database.DB, steps)
+		for _, migration := range reverted {
+			fmt.Printf("Reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal("Failed to revert migrations: ", err)
+		}
+		fmt.Printf("Reverted %d migration(s)\n", len(reverted))
+
+	case "status":
+		all, err := migrations.Status(database.DB)
+		if err != nil {
+			log.Fatal("Failed to read migration status: ", err)
+		}
+
+		pending := 0
+		for _, migration := range all {
+			status := "pending"
+			if migration.AppliedAt != nil {
+				status = "applied " + migration.AppliedAt.Format("2006-01-02 15:04:05")
+			} else {
+				pending++
+			}
+			fmt.Printf("%04d_%-40s %s\n", migration.Version, migration.Name, status)
+		}
+		fmt.Printf("%d pending migration(s)\n", pending)
+
+	default:
+		log.Fatalf("Unknown migrate command: %s", command)
+	}
+}
+
+// CreateMigration writes a new empty migration pair, does not need a database connection
+func CreateMigration(dir, name string) {
+	upPath, downPath, err := migrations.Create(dir, name)
+	if err != nil {
+		log.Fatal("Failed to create migration: ", err)
+	}
+
+	fmt.Printf("Created %s\n", upPath)
+	fmt.Printf("Created %s\n", downPath)
+}
+
+// SetUserRole changes a user's role, e.g. to appoint the first admin of a fresh database
+func SetUserRole(username, role string) {
+	switch role {
+	case constants.ROLE_ADMIN, constants.ROLE_MODERATOR, constants.ROLE_MEMBER:
+	default:
+		log.Fatalf("Unknown role: %s", role)
+	}
+
+	ctx := context.Background()
+	users := dataaccess.NewPostgresStore(database.DB)
+	user, err := users.GetUserByUsername(ctx, username)
 	if err != nil {
-		log.Fatal("Failed to read schema.sql:", err)
+		log.Fatalf("User %s not found: %v", username, err)
 	}
 
-	if _, err := database.DB.Exec(string(schemaSQL)); err != nil {
-		log.Fatal("Failed to execute schema:", err)
+	if err := users.UpdateUserRole(ctx, user.ID, role); err != nil {
+		log.Fatal("Failed to update role: ", err)
 	}
 
-	fmt.Println("Database reset completed!")
+	fmt.Printf("Set role of %s to %s\n", username, role)
+}
+
+// RenderContent re-renders the Markdown of every post and comment, after the rendering pipeline changed
+func RenderContent() {
+	store := dataaccess.NewPostgresStore(database.DB)
+	posts, err := store.RerenderPosts(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to render posts after %d: %v", posts, err)
+	}
+	fmt.Printf("Rendered %d post(s)\n", posts)
+
+	comments, err := store.RerenderComments(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to render comments after %d: %v", comments, err)
+	}
+	fmt.Printf("Rendered %d comment(s)\n", comments)
 }
@@ -1,45 +1,50 @@
 package seed
 
 import (
+	"context"
 	dbUtils "cvwo/cmd/db/utils"
 	"cvwo/internal/dataaccess"
 	"cvwo/internal/database"
 	"cvwo/internal/models"
 	"cvwo/internal/utils"
 	"fmt"
-	"log"
 )
 
-func SeedDatabase() {
+// SeedDatabase fills the database with the topics, users, posts, comments and votes in the JSON files
+func SeedDatabase() error {
 	fmt.Println("Seeding database from JSON files...")
+	store := dataaccess.NewPostgresStore(database.DB)
+	ctx := context.Background()
 
 	// Load topics
 	var topics []models.Topic
 	if err := dbUtils.ReadJSONFile("topics.json", &topics); err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	for _, topic := range topics {
-		database.DB.Exec("INSERT INTO topics (name, description) VALUES ($1, $2)", topic.Name, topic.Description)
+		if _, err := store.CreateTopic(ctx, topic); err != nil {
+			return fmt.Errorf("failed to create topic %s: %w", topic.Name, err)
+		}
 	}
 	fmt.Printf("Inserted %d topics\n", len(topics))
 
 	// Load users
 	var seedUsers []models.User
 	if err := dbUtils.ReadJSONFile("users.json", &seedUsers); err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	// Register users
 	for _, user := range seedUsers {
 		hashedPassword, err := utils.HashPassword(user.Password)
 		if err != nil {
-			log.Fatalf("Could not hash password for user %s: %v", user.Email, err)
+			return fmt.Errorf("could not hash password for user %s: %w", user.Email, err)
 		}
 		user.Password = string(hashedPassword)
-		err = dataaccess.CreateUser(user)
+		err = store.CreateUser(ctx, user)
 		if err != nil {
-			log.Fatalf("Failed to register user %s: %v", user.Email, err)
+			return fmt.Errorf("failed to register user %s: %w", user.Email, err)
 		}
 	}
 
@@ -48,14 +53,14 @@ func SeedDatabase() {
 	// Load posts
 	var seedPosts []models.Post
 	if err := dbUtils.ReadJSONFile("posts.json", &seedPosts); err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	// Create posts
 	for _, post := range seedPosts {
-		_, err := dataaccess.CreatePost(post)
+		_, err := store.CreatePost(ctx, post)
 		if err != nil {
-			log.Fatalf("Failed to create post titled '%s': %v", post.Title, err)
+			return fmt.Errorf("failed to create post titled '%s': %w", post.Title, err)
 		}
 	}
 
@@ -64,14 +69,14 @@ func SeedDatabase() {
 	// Load comments
 	var seedComments []models.Comment
 	if err := dbUtils.ReadJSONFile("comments.json", &seedComments); err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	// Create comments
 	for _, comment := range seedComments {
-		_, err := dataaccess.CreateComment(comment)
+		_, err := store.CreateComment(ctx, comment)
 		if err != nil {
-			log.Fatalf("Failed to create comment on post %d: %v", comment.PostID, err)
+			return fmt.Errorf("failed to create comment on post %d: %w", comment.PostID, err)
 		}
 	}
 
@@ -80,14 +85,14 @@ func SeedDatabase() {
 	// Load users' followed topics
 	var userTopics []UserTopicSeed
 	if err := dbUtils.ReadJSONFile("user_topics.json", &userTopics); err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	// Follow topics
 	for _, ut := range userTopics {
-		err := dataaccess.FollowTopic(ut.UserID, ut.TopicName)
+		err := store.FollowTopic(ctx, ut.UserID, ut.TopicName)
 		if err != nil {
-			log.Fatalf("Failed to follow topic %s for user %d: %v", ut.TopicName, ut.UserID, err)
+			return fmt.Errorf("failed to follow topic %s for user %d: %w", ut.TopicName, ut.UserID, err)
 		}
 	}
 
@@ -96,14 +101,14 @@ func SeedDatabase() {
 	// Load post votes
 	var postVotes []models.PostVote
 	if err := dbUtils.ReadJSONFile("post_votes.json", &postVotes); err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	// Vote on posts
 	for _, vote := range postVotes {
-		err := dataaccess.VotePost(vote)
+		err := store.VotePost(ctx, vote)
 		if err != nil {
-			log.Fatalf("Failed to record vote for post %d by user %d: %v", vote.PostID, vote.UserID, err)
+			return fmt.Errorf("failed to record vote for post %d by user %d: %w", vote.PostID, vote.UserID, err)
 		}
 	}
 
@@ -112,18 +117,19 @@ func SeedDatabase() {
 	// Load comment votes
 	var commentVotes []models.CommentVote
 	if err := dbUtils.ReadJSONFile("comment_votes.json", &commentVotes); err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	// Vote on comments
 	for _, vote := range commentVotes {
-		err := dataaccess.VoteComment(vote)
+		err := store.VoteComment(ctx, vote)
 		if err != nil {
-			log.Fatalf("Failed to record vote for comment %d by user %d: %v", vote.CommentID, vote.UserID, err)
+			return fmt.Errorf("failed to record vote for comment %d by user %d: %w", vote.CommentID, vote.UserID, err)
 		}
 	}
 
 	fmt.Printf("Inserted %d comment votes\n", len(commentVotes))
 
 	fmt.Println("Database seeded successfully!")
+	return nil
 }
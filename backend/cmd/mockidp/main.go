@@ -0,0 +1,34 @@
+// Mock OpenID Connect identity provider for trying single sign-on locally
+// Anyone can log in as anyone: the login page asks for the claims to put in the ID token
+//
+//	go run ./cmd/mockidp --addr=:9000 --client-id=cvwo --client-secret=secret
+//
+// then start the server with OIDC_ISSUER_URL=http://localhost:9000, OIDC_CLIENT_ID=cvwo and OIDC_CLIENT_SECRET=secret
+// The provider itself is in internal/oidc/oidctest, which the tests run against
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"cvwo/internal/oidc/oidctest"
+)
+
+func main() {
+	var (
+		addr         = flag.String("addr", ":9000", "Address to listen on")
+		issuer       = flag.String("issuer", "http://localhost:9000", "Issuer URL, must be the URL the server reaches this provider at")
+		clientID     = flag.String("client-id", "cvwo", "Client ID the server is registered with")
+		clientSecret = flag.String("client-secret", "secret", "Client secret the server is registered with")
+	)
+	flag.Parse()
+
+	idp, err := oidctest.New(*issuer, *clientID, *clientSecret)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Mock identity provider %s listening on %s", *issuer, *addr)
+	log.Fatal(http.ListenAndServe(*addr, idp))
+}
@@ -1,42 +1,173 @@
 package main
 
 import (
+	"context"
+	"cvwo/internal/config"
+	"cvwo/internal/constants"
+	"cvwo/internal/dataaccess"
 	"cvwo/internal/database"
+	"cvwo/internal/events"
+	"cvwo/internal/handlers"
+	"cvwo/internal/mail"
+	"cvwo/internal/migrations"
+	"cvwo/internal/oidc"
+	"cvwo/internal/ratelimit"
 	"cvwo/internal/routes"
+	"cvwo/internal/telemetry"
 	seedUtil "cvwo/cmd/db/seed"
 	"cvwo/cmd/db/operations"
 	"flag"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/cors"
-	"github.com/joho/godotenv"
 )
 
 func main() {
 	var (
-		seed  = flag.Bool("seed", false, "Seed the database with initial data")
+		seed            = flag.Bool("seed", false, "Seed the database with initial data")
+		migrate         = flag.Bool("migrate", false, "Apply pending migrations before starting")
+		checkMigrations = flag.Bool("check-migrations", true, "Refuse to start if there are pending migrations")
+		addr            = flag.String("addr", "", "Address the server listens on, overrides the configured address")
+		configFile      = flag.String("config", "", "Settings file (.yaml, .yml or .toml), overridden by .env and environment variables")
 	)
 
 	flag.Parse()
 
-	if err := godotenv.Load("../.env"); err != nil {
-		log.Println("No .env file found")
+	cfg, err := config.Load(*configFile, "../.env")
+	if err != nil {
+		log.Fatal("Could not load configuration: ", err)
+	}
+	if *addr != "" {
+		cfg.Server.Addr = *addr
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+
+	if err := run(cfg, *seed, *migrate, *checkMigrations); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Runs the server until it is stopped, returning why it could not start or failed
+// Returning, rather than exiting, lets the deferred cleanup close the database, the brokers and the tracer
+func run(cfg *config.Config, seed, migrate, checkMigrations bool) error {
+	// Tracing is enabled by setting OTEL_EXPORTER_OTLP_ENDPOINT, spans are dropped otherwise
+	// Deferred first so the spans of the last requests are flushed after everything else has stopped
+	if cfg.Telemetry.OTLPEndpoint != "" {
+		stopTracing, err := telemetry.StartTracing(context.Background(), cfg.Telemetry.OTLPEndpoint, cfg.Telemetry.ServiceName)
+		if err != nil {
+			return fmt.Errorf("could not start tracing: %w", err)
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), constants.TRACE_FLUSH_TIMEOUT)
+			defer cancel()
+			if err := stopTracing(ctx); err != nil {
+				log.Printf("Could not flush traces: %v", err)
+			}
+		}()
+	}
+
+	connStr, err := database.Connect(cfg.Database)
+	if err != nil {
+		return err
+	}
+	// Deferred before the rest so it runs after everything that uses the database has stopped
+	defer database.DB.Close()
+	telemetry.RegisterDB(database.DB, cfg.Database.Name)
+
+	if seed {
+		if err := operations.ResetDatabase(); err != nil {
+			return err
+		}
+		if err := seedUtil.SeedDatabase(); err != nil {
+			return err
+		}
+	}
+
+	if migrate {
+		applied, err := migrations.Up(database.DB)
+		if err != nil {
+			return fmt.Errorf("could not apply migrations: %w", err)
+		}
+		log.Printf("Applied %d migration(s)", len(applied))
+	}
+
+	if checkMigrations {
+		pending, err := migrations.Pending(database.DB)
+		if err != nil {
+			return fmt.Errorf("could not check migrations: %w", err)
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("database schema is behind by %d migration(s), run with --migrate or cmd/db --action=migrate up", len(pending))
+		}
+	}
+
+	// Already checked by Validate
+	trustedProxies, _ := cfg.Server.TrustedProxyPrefixes()
+	opts := handlers.Options{
+		JWTSecret:      cfg.Auth.JWTSecret,
+		Limits:         cfg.Limits,
+		AppBaseURL:     cfg.Server.AppBaseURL,
+		TrustedProxies: trustedProxies,
+	}
+
+	// Validate only allows the known brokers and stores
+	switch cfg.Events.Broker {
+	case "memory":
+		opts.Broker = events.NewMemoryBroker()
+	case "postgres":
+		broker, err := events.NewPostgresBroker(database.DB, connStr)
+		if err != nil {
+			return fmt.Errorf("could not listen for events: %w", err)
+		}
+		defer broker.Close()
+		opts.Broker = broker
+	}
+
+	switch cfg.RateLimit.Store {
+	case "memory":
+		opts.RateLimits = ratelimit.NewMemoryStore()
+	case "postgres":
+		store := ratelimit.NewPostgresStore(database.DB)
+		defer store.Close()
+		opts.RateLimits = store
 	}
 
-	database.Connect()
+	opts.Mailer, err = mail.New(cfg.Mail)
+	if err != nil {
+		return fmt.Errorf("could not configure the mailer: %w", err)
+	}
+	if cfg.Mail.Mailer != "smtp" {
+		log.Printf("Emails are not sent, MAILER=%s is only for local development", cfg.Mail.Mailer)
+	}
 
-	if *seed {
-		operations.ResetDatabase()
-		seedUtil.SeedDatabase()
+	// Single sign-on is enabled by setting OIDC_ISSUER_URL
+	if cfg.OIDC.IssuerURL != "" {
+		provider, err := oidc.NewProvider(context.Background(), oidc.Config{
+			IssuerURL:    cfg.OIDC.IssuerURL,
+			ClientID:     cfg.OIDC.ClientID,
+			ClientSecret: cfg.OIDC.ClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL(),
+			DisplayName:  cfg.OIDC.DisplayName,
+		})
+		if err != nil {
+			return fmt.Errorf("could not configure single sign-on: %w", err)
+		}
+		opts.SSO = provider
 	}
 
 	r := chi.NewRouter()
 
 	// Enable CORS
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:3000", "http://localhost:80", "http://localhost"},
+		AllowedOrigins:   cfg.Server.AllowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		ExposedHeaders:   []string{"Link"},
@@ -44,12 +175,66 @@ func main() {
 		MaxAge:           300,
 	}))
 
+	// Handlers read and write through the PostgreSQL stores
+	server := handlers.NewServer(dataaccess.NewPostgresStores(database.DB), opts)
+	server.AddReadinessCheck("database", database.DB.PingContext)
+	server.AddReadinessCheck("migrations", func(ctx context.Context) error {
+		pending, err := migrations.Pending(database.DB)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d pending migration(s)", len(pending))
+		}
+		return nil
+	})
+
+	// Probes and metrics are outside /api, so the reverse proxy does not expose them
+	r.Get("/healthz", handlers.Health)
+	r.Get("/readyz", server.Ready)
+	r.Handle("/metrics", telemetry.Handler())
+
 	// Add /api prefix
-	r.Route("/api", routes.GetRoutes())
+	r.Route("/api", routes.GetRoutes(server, cfg.Server))
 
-	log.Println("Starting server on :8000...")
-	err := http.ListenAndServe(":8000", r)
-	if err != nil {
-		log.Fatal(err)
+	httpServer := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           r,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	// Docker stops containers with SIGTERM, Ctrl+C sends SIGINT
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Printf("Starting server on %s...", cfg.Server.Addr)
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+	// A second signal stops the server at once
+	stop()
+
+	// Stop accepting connections and let in-flight requests finish
+	log.Println("Shutting down...")
+	server.BeginShutdown()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Could not finish in-flight requests: %v", err)
+	}
+	if err := server.WaitForMail(shutdownCtx); err != nil {
+		log.Printf("Could not finish sending emails: %v", err)
 	}
+	log.Println("Server stopped")
+	return nil
 }
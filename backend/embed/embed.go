@@ -13,3 +13,6 @@ var SqlFiles embed.FS
 
 //go:embed seed_data/*.json
 var JsonFiles embed.FS
+
+//go:embed sql/migrations/*.sql
+var MigrationFiles embed.FS
@@ -1,10 +1,33 @@
--- Drop all tables and extensions in correct order (respecting foreign key constraints)
+-- Drop all tables, functions and extensions in correct order (respecting foreign key constraints)
+DROP TABLE IF EXISTS schema_migrations CASCADE;
+DROP TABLE IF EXISTS sessions CASCADE;
+DROP TABLE IF EXISTS saved_comments CASCADE;
+DROP TABLE IF EXISTS saved_posts CASCADE;
+DROP TABLE IF EXISTS api_tokens CASCADE;
+DROP TABLE IF EXISTS user_identities CASCADE;
+DROP TABLE IF EXISTS login_challenges CASCADE;
+DROP TABLE IF EXISTS recovery_codes CASCADE;
+DROP TABLE IF EXISTS user_totp CASCADE;
+DROP TABLE IF EXISTS email_tokens CASCADE;
+DROP TABLE IF EXISTS login_attempts CASCADE;
+DROP TABLE IF EXISTS rate_limit_buckets CASCADE;
+DROP TABLE IF EXISTS moderation_log CASCADE;
+DROP TABLE IF EXISTS reports CASCADE;
+DROP TABLE IF EXISTS comment_revisions CASCADE;
+DROP TABLE IF EXISTS post_revisions CASCADE;
+DROP TABLE IF EXISTS notification_mutes CASCADE;
+DROP TABLE IF EXISTS notifications CASCADE;
 DROP TABLE IF EXISTS comment_votes CASCADE;
 DROP TABLE IF EXISTS post_votes CASCADE;
 DROP TABLE IF EXISTS comments CASCADE;
 DROP TABLE IF EXISTS posts CASCADE;
+DROP TABLE IF EXISTS topic_moderators CASCADE;
+DROP TABLE IF EXISTS topic_slug_redirects CASCADE;
 DROP TABLE IF EXISTS user_topics CASCADE;
 DROP TABLE IF EXISTS topics CASCADE;
 DROP TABLE IF EXISTS users CASCADE;
+DROP FUNCTION IF EXISTS controversy_score(DOUBLE PRECISION, DOUBLE PRECISION);
+DROP FUNCTION IF EXISTS wilson_lower_bound(DOUBLE PRECISION, DOUBLE PRECISION);
+DROP FUNCTION IF EXISTS hot_score(DOUBLE PRECISION, DOUBLE PRECISION, TIMESTAMP);
 DROP EXTENSION IF EXISTS ltree CASCADE;
 DROP EXTENSION IF EXISTS pg_trgm CASCADE;
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS topic_slug_redirects CASCADE;
+
+DROP INDEX IF EXISTS idx_topics_slug;
+ALTER TABLE topics DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE topics DROP COLUMN IF EXISTS is_archived;
+ALTER TABLE topics DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE topics DROP COLUMN IF EXISTS created_at;
+ALTER TABLE topics DROP COLUMN IF EXISTS slug;
+
+ALTER TABLE users DROP COLUMN IF EXISTS is_admin;
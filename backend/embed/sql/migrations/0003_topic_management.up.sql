@@ -0,0 +1,24 @@
+-- Admin flag for managing topics
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin BOOLEAN DEFAULT FALSE;
+
+-- Topics are looked up by slug instead of deslugifying the URL back into a name
+-- Slug format matches the frontend: lowercase, spaces replaced by hyphens
+ALTER TABLE topics ADD COLUMN IF NOT EXISTS slug VARCHAR(100);
+ALTER TABLE topics ADD COLUMN IF NOT EXISTS created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE topics ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE topics ADD COLUMN IF NOT EXISTS is_archived BOOLEAN DEFAULT FALSE;
+ALTER TABLE topics ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP;
+
+UPDATE topics SET slug = lower(replace(name, ' ', '-')) WHERE slug IS NULL;
+ALTER TABLE topics ALTER COLUMN slug SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_topics_slug ON topics (slug);
+
+-- Old slugs of renamed topics, so existing links keep resolving
+CREATE TABLE IF NOT EXISTS topic_slug_redirects (
+    slug VARCHAR(100) PRIMARY KEY,
+    topic_id INTEGER REFERENCES topics(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_topic_slug_redirects_topic_id ON topic_slug_redirects (topic_id);
@@ -0,0 +1,8 @@
+ALTER TABLE posts DROP COLUMN IF EXISTS is_pinned;
+ALTER TABLE posts DROP COLUMN IF EXISTS is_locked;
+
+DROP TABLE IF EXISTS topic_moderators CASCADE;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin BOOLEAN DEFAULT FALSE;
+UPDATE users SET is_admin = true WHERE role = 'admin';
+ALTER TABLE users DROP COLUMN IF EXISTS role;
@@ -0,0 +1,23 @@
+-- User roles: admin, moderator (all topics) or member
+-- Topic moderators are members with rows in topic_moderators
+ALTER TABLE users ADD COLUMN IF NOT EXISTS
+    role VARCHAR(20) NOT NULL DEFAULT 'member' CHECK (role IN ('admin', 'moderator', 'member'));
+
+UPDATE users SET role = 'admin' WHERE is_admin = true;
+ALTER TABLE users DROP COLUMN IF EXISTS is_admin;
+
+-- Topic moderators table
+CREATE TABLE IF NOT EXISTS topic_moderators (
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    topic_id INTEGER REFERENCES topics(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, topic_id)
+);
+
+-- user_id is the first column in the primary key, so it is already indexed
+CREATE INDEX IF NOT EXISTS idx_topic_moderators_topic_id ON topic_moderators (topic_id);
+
+-- Moderation state of posts
+-- Locked posts do not accept new comments, pinned posts are listed first within their topic
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS is_locked BOOLEAN DEFAULT FALSE;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS is_pinned BOOLEAN DEFAULT FALSE;
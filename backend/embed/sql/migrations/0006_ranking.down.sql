@@ -0,0 +1,24 @@
+DROP INDEX IF EXISTS idx_comments_controversy_rank;
+DROP INDEX IF EXISTS idx_comments_best_rank;
+DROP INDEX IF EXISTS idx_comments_hot_rank;
+
+DROP INDEX IF EXISTS idx_posts_controversy_rank;
+DROP INDEX IF EXISTS idx_posts_best_rank;
+DROP INDEX IF EXISTS idx_posts_hot_rank;
+
+ALTER TABLE comments DROP COLUMN IF EXISTS controversy_rank;
+ALTER TABLE comments DROP COLUMN IF EXISTS best_rank;
+ALTER TABLE comments DROP COLUMN IF EXISTS hot_rank;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS controversy_rank;
+ALTER TABLE posts DROP COLUMN IF EXISTS best_rank;
+ALTER TABLE posts DROP COLUMN IF EXISTS hot_rank;
+
+DROP FUNCTION IF EXISTS controversy_score(DOUBLE PRECISION, DOUBLE PRECISION);
+DROP FUNCTION IF EXISTS wilson_lower_bound(DOUBLE PRECISION, DOUBLE PRECISION);
+DROP FUNCTION IF EXISTS hot_score(DOUBLE PRECISION, DOUBLE PRECISION, TIMESTAMP);
+
+ALTER TABLE comments DROP COLUMN IF EXISTS downvotes;
+ALTER TABLE comments DROP COLUMN IF EXISTS upvotes;
+ALTER TABLE posts DROP COLUMN IF EXISTS downvotes;
+ALTER TABLE posts DROP COLUMN IF EXISTS upvotes;
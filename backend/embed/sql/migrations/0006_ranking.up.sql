@@ -0,0 +1,63 @@
+-- Upvotes and downvotes are kept separately from the summed score,
+-- since the best and controversial rankings need both
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS upvotes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS downvotes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS upvotes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS downvotes INTEGER NOT NULL DEFAULT 0;
+
+UPDATE posts SET
+    upvotes = (SELECT COUNT(*) FROM post_votes v WHERE v.post_id = posts.id AND v.vote_value > 0),
+    downvotes = (SELECT COUNT(*) FROM post_votes v WHERE v.post_id = posts.id AND v.vote_value < 0);
+
+UPDATE comments SET
+    upvotes = (SELECT COUNT(*) FROM comment_votes v WHERE v.comment_id = comments.id AND v.vote_value > 0),
+    downvotes = (SELECT COUNT(*) FROM comment_votes v WHERE v.comment_id = comments.id AND v.vote_value < 0);
+
+-- Time-decayed ranking: every 10x in net votes is worth 12.5 hours of recency
+-- Only depends on votes and creation time, so it can be stored and indexed instead of recomputed per read
+CREATE OR REPLACE FUNCTION hot_score(up DOUBLE PRECISION, down DOUBLE PRECISION, created_at TIMESTAMP)
+RETURNS DOUBLE PRECISION AS $$
+    SELECT sign(up - down) * log(greatest(abs(up - down), 1))
+        + (EXTRACT(EPOCH FROM created_at)::DOUBLE PRECISION - 1704067200) / 45000
+$$ LANGUAGE SQL IMMUTABLE PARALLEL SAFE;
+
+-- Lower bound of the Wilson score interval (95% confidence) of the upvote ratio
+-- Ranks a few unanimous votes below many mostly positive ones
+CREATE OR REPLACE FUNCTION wilson_lower_bound(up DOUBLE PRECISION, down DOUBLE PRECISION)
+RETURNS DOUBLE PRECISION AS $$
+    SELECT CASE WHEN up + down = 0 THEN 0 ELSE
+        ((up + 1.9208) / (up + down)
+            - 1.96 * sqrt((up * down) / (up + down) + 0.9604) / (up + down))
+        / (1 + 3.8416 / (up + down))
+    END
+$$ LANGUAGE SQL IMMUTABLE PARALLEL SAFE;
+
+-- Many votes split evenly between up and down rank highest
+CREATE OR REPLACE FUNCTION controversy_score(up DOUBLE PRECISION, down DOUBLE PRECISION)
+RETURNS DOUBLE PRECISION AS $$
+    SELECT CASE WHEN up <= 0 OR down <= 0 THEN 0 ELSE
+        power(up + down, least(up, down) / greatest(up, down))
+    END
+$$ LANGUAGE SQL IMMUTABLE PARALLEL SAFE;
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS hot_rank DOUBLE PRECISION
+    GENERATED ALWAYS AS (hot_score(upvotes, downvotes, created_at)) STORED;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS best_rank DOUBLE PRECISION
+    GENERATED ALWAYS AS (wilson_lower_bound(upvotes, downvotes)) STORED;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS controversy_rank DOUBLE PRECISION
+    GENERATED ALWAYS AS (controversy_score(upvotes, downvotes)) STORED;
+
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS hot_rank DOUBLE PRECISION
+    GENERATED ALWAYS AS (hot_score(upvotes, downvotes, created_at)) STORED;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS best_rank DOUBLE PRECISION
+    GENERATED ALWAYS AS (wilson_lower_bound(upvotes, downvotes)) STORED;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS controversy_rank DOUBLE PRECISION
+    GENERATED ALWAYS AS (controversy_score(upvotes, downvotes)) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_posts_hot_rank ON posts (hot_rank);
+CREATE INDEX IF NOT EXISTS idx_posts_best_rank ON posts (best_rank);
+CREATE INDEX IF NOT EXISTS idx_posts_controversy_rank ON posts (controversy_rank);
+
+CREATE INDEX IF NOT EXISTS idx_comments_hot_rank ON comments (hot_rank);
+CREATE INDEX IF NOT EXISTS idx_comments_best_rank ON comments (best_rank);
+CREATE INDEX IF NOT EXISTS idx_comments_controversy_rank ON comments (controversy_rank);
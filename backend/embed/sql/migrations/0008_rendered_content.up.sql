@@ -0,0 +1,13 @@
+-- Markdown content rendered to sanitized HTML, stored next to the source so it is rendered once per write
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS content_html TEXT NOT NULL DEFAULT '';
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS content_html TEXT NOT NULL DEFAULT '';
+
+-- Existing content is shown as escaped text with its line breaks until it is rendered as Markdown
+-- with go run cmd/db/main.go -action=render-content
+UPDATE posts SET
+    content_html = '<p>' || replace(replace(replace(replace(content, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), E'\n', '<br>') || '</p>'
+WHERE content <> '';
+
+UPDATE comments SET
+    content_html = '<p>' || replace(replace(replace(replace(content, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), E'\n', '<br>') || '</p>'
+WHERE content <> '';
@@ -0,0 +1,33 @@
+-- Every version of a post or comment, numbered from 1 per post or comment
+-- The editor is whoever saved the version: the author, or a moderator editing or restoring it
+CREATE TABLE IF NOT EXISTS post_revisions (
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL DEFAULT '',
+    editor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    restored_from INTEGER,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (post_id, revision)
+);
+
+CREATE TABLE IF NOT EXISTS comment_revisions (
+    comment_id INTEGER NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    editor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    restored_from INTEGER,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (comment_id, revision)
+);
+
+-- History starts at the current version of existing posts and comments
+INSERT INTO post_revisions (post_id, revision, title, content, editor_id, created_at)
+SELECT id, 1, title, COALESCE(content, ''), user_id, COALESCE(updated_at, created_at, CURRENT_TIMESTAMP)
+FROM posts
+ON CONFLICT DO NOTHING;
+
+INSERT INTO comment_revisions (comment_id, revision, content, editor_id, created_at)
+SELECT id, 1, content, user_id, COALESCE(updated_at, created_at, CURRENT_TIMESTAMP)
+FROM comments
+ON CONFLICT DO NOTHING;
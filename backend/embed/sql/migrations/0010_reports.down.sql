@@ -0,0 +1,10 @@
+DELETE FROM notifications WHERE type = 'moderator_warning';
+ALTER TABLE notifications DROP CONSTRAINT IF EXISTS notifications_type_check;
+ALTER TABLE notifications ADD CONSTRAINT notifications_type_check
+    CHECK (type IN ('post_reply', 'comment_reply', 'mention', 'comment_pinned', 'topic_post'));
+ALTER TABLE notifications DROP COLUMN IF EXISTS message;
+
+ALTER TABLE users DROP COLUMN IF EXISTS suspended_until;
+
+DROP TABLE IF EXISTS moderation_log;
+DROP TABLE IF EXISTS reports;
@@ -0,0 +1,44 @@
+-- Reports of posts and comments by users, each report targets exactly one of them
+CREATE TABLE IF NOT EXISTS reports (
+    id SERIAL PRIMARY KEY,
+    reporter_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    post_id INTEGER REFERENCES posts(id) ON DELETE CASCADE,
+    comment_id INTEGER REFERENCES comments(id) ON DELETE CASCADE,
+    reason VARCHAR(30) NOT NULL CHECK (reason IN ('spam', 'harassment', 'hate', 'misinformation', 'off_topic', 'other')),
+    details TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'resolved', 'dismissed')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMP,
+    resolved_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    CHECK ((post_id IS NULL) <> (comment_id IS NULL))
+);
+
+-- A user can only have one open report per post or comment
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_open_post ON reports (post_id, reporter_id) WHERE status = 'open' AND post_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_open_comment ON reports (comment_id, reporter_id) WHERE status = 'open' AND comment_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_reports_open ON reports (created_at) WHERE status = 'open';
+
+-- Audit log of moderation actions, kept when the moderator, author or content is gone
+CREATE TABLE IF NOT EXISTS moderation_log (
+    id SERIAL PRIMARY KEY,
+    moderator_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('dismiss', 'remove', 'warn', 'suspend')),
+    post_id INTEGER REFERENCES posts(id) ON DELETE SET NULL,
+    comment_id INTEGER REFERENCES comments(id) ON DELETE SET NULL,
+    target_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reports_resolved INTEGER NOT NULL DEFAULT 0,
+    note TEXT NOT NULL DEFAULT '',
+    suspended_until TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_log_created_at ON moderation_log (created_at DESC, id DESC);
+
+-- Suspended users can still sign in and read, but cannot post, comment, vote or report
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_until TIMESTAMP;
+
+-- Moderator warnings are delivered as notifications carrying the moderator's note
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS message TEXT NOT NULL DEFAULT '';
+ALTER TABLE notifications DROP CONSTRAINT IF EXISTS notifications_type_check;
+ALTER TABLE notifications ADD CONSTRAINT notifications_type_check
+    CHECK (type IN ('post_reply', 'comment_reply', 'mention', 'comment_pinned', 'topic_post', 'moderator_warning'));
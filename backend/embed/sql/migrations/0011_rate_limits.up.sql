@@ -0,0 +1,9 @@
+-- Token buckets of the PostgreSQL rate limit store, shared by all server replicas
+-- Keys combine the route group and the client, e.g. login:ip:203.0.113.7 or votes:user:42
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    key VARCHAR(255) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_updated_at ON rate_limit_buckets (updated_at);
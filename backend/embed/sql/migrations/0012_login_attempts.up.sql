@@ -0,0 +1,17 @@
+-- Every login attempt, successful or not
+-- email is the normalized address that was entered, user_id is set if it belongs to a user
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    ip_address TEXT NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    succeeded BOOLEAN NOT NULL,
+    failure_reason VARCHAR(30) CHECK (failure_reason IN ('invalid_credentials', 'locked_out')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Lockouts count recent failures per email and per IP, /me/security lists recent attempts per user
+CREATE INDEX IF NOT EXISTS idx_login_attempts_email_created_at ON login_attempts (email, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_ip_address_created_at ON login_attempts (ip_address, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_user_id_created_at ON login_attempts (user_id, created_at DESC);
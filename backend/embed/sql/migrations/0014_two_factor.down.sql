@@ -0,0 +1,8 @@
+DELETE FROM login_attempts WHERE failure_reason = 'invalid_two_factor_code';
+ALTER TABLE login_attempts DROP CONSTRAINT IF EXISTS login_attempts_failure_reason_check;
+ALTER TABLE login_attempts ADD CONSTRAINT login_attempts_failure_reason_check
+    CHECK (failure_reason IN ('invalid_credentials', 'locked_out'));
+
+DROP TABLE IF EXISTS login_challenges;
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS user_totp;
@@ -0,0 +1,39 @@
+-- TOTP secret of each user who started enrolling, enabled_at is set once a code confirms it
+-- last_used_step is the time step of the last accepted code, so a code cannot be replayed
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    enabled_at TIMESTAMP,
+    last_used_step BIGINT
+);
+
+-- One-time recovery codes for logging in without the authenticator, only their hashes are stored
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT UNIQUE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes (user_id);
+
+-- Pending logins of users with two-factor authentication, whose password was correct
+-- The session is only started once a code is entered with the challenge token
+CREATE TABLE IF NOT EXISTS login_challenges (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT UNIQUE NOT NULL,
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_challenges_user_id ON login_challenges (user_id);
+
+-- Wrong codes are failed logins too
+ALTER TABLE login_attempts DROP CONSTRAINT IF EXISTS login_attempts_failure_reason_check;
+ALTER TABLE login_attempts ADD CONSTRAINT login_attempts_failure_reason_check
+    CHECK (failure_reason IN ('invalid_credentials', 'invalid_two_factor_code', 'locked_out'));
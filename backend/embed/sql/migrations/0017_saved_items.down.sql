@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS saved_comments;
+DROP TABLE IF EXISTS saved_posts;
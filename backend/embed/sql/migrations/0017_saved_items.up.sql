@@ -0,0 +1,18 @@
+-- Posts and comments users bookmarked to come back to, listed under /me/saved
+CREATE TABLE IF NOT EXISTS saved_posts (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, post_id)
+);
+
+CREATE TABLE IF NOT EXISTS saved_comments (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    comment_id INTEGER NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, comment_id)
+);
+
+-- Saved lists are sorted by when the item was saved by default
+CREATE INDEX IF NOT EXISTS idx_saved_posts_user_id_created_at ON saved_posts (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_saved_comments_user_id_created_at ON saved_comments (user_id, created_at DESC);
@@ -0,0 +1,362 @@
+// Package config loads the server's settings from defaults, an optional YAML or TOML file, .env files
+// and environment variables, each overriding the one before
+package config
+
+import (
+	"cvwo/internal/constants"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Server    ServerConfig    `yaml:"server" toml:"server"`
+	Database  DatabaseConfig  `yaml:"database" toml:"database"`
+	Auth      AuthConfig      `yaml:"auth" toml:"auth"`
+	OIDC      OIDCConfig      `yaml:"oidc" toml:"oidc"`
+	Mail      MailConfig      `yaml:"mail" toml:"mail"`
+	Events    EventsConfig    `yaml:"events" toml:"events"`
+	RateLimit RateLimitConfig `yaml:"rate_limit" toml:"rate_limit"`
+	Limits    Limits          `yaml:"limits" toml:"limits"`
+	Telemetry TelemetryConfig `yaml:"telemetry" toml:"telemetry"`
+}
+
+type ServerConfig struct {
+	// Address the server listens on
+	Addr string `yaml:"addr" toml:"addr"`
+	// Origins of the frontend allowed to call the API with credentials
+	AllowedOrigins []string `yaml:"allowed_origins" toml:"allowed_origins"`
+	// Base URL of the frontend, which links in emails and redirects after single sign-on point to
+	AppBaseURL string `yaml:"app_base_url" toml:"app_base_url"`
+	// Addresses or CIDR ranges of the reverse proxies in front of the server, whose X-Forwarded-For is believed
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies"`
+
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	// Time in-flight requests get to finish once the server is asked to stop
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+
+	// Time the queries of one request may take
+	StatementTimeout time.Duration `yaml:"statement_timeout" toml:"statement_timeout"`
+	// Time allowed to the routes that scan the most rows
+	LongStatementTimeout time.Duration `yaml:"long_statement_timeout" toml:"long_statement_timeout"`
+}
+
+type DatabaseConfig struct {
+	Host     string `yaml:"host" toml:"host"`
+	Port     string `yaml:"port" toml:"port"`
+	User     string `yaml:"user" toml:"user"`
+	Password string `yaml:"password" toml:"password"`
+	Name     string `yaml:"name" toml:"name"`
+	SSLMode  string `yaml:"sslmode" toml:"sslmode"`
+}
+
+type AuthConfig struct {
+	// Signs access tokens
+	JWTSecret string `yaml:"jwt_secret" toml:"jwt_secret"`
+}
+
+// Single sign-on is disabled unless the issuer URL is set
+type OIDCConfig struct {
+	// Issuer URL of the identity provider, its discovery document is read at startup
+	IssuerURL    string `yaml:"issuer_url" toml:"issuer_url"`
+	ClientID     string `yaml:"client_id" toml:"client_id"`
+	ClientSecret string `yaml:"client_secret" toml:"client_secret"`
+	// Where the identity provider sends users back to, the callback behind the app base URL if empty
+	RedirectURL string `yaml:"redirect_url" toml:"redirect_url"`
+	// Shown on the login button
+	DisplayName string `yaml:"display_name" toml:"display_name"`
+}
+
+type MailConfig struct {
+	// How emails are delivered: smtp, or for local development file (.eml files in Dir) or log (the server log)
+	// smtp is the default, so a server that cannot send emails refuses to start rather than silently not sending them
+	Mailer string `yaml:"mailer" toml:"mailer"`
+	// Directory the file mailer writes to
+	Dir string `yaml:"dir" toml:"dir"`
+
+	SMTPHost     string `yaml:"smtp_host" toml:"smtp_host"`
+	SMTPPort     string `yaml:"smtp_port" toml:"smtp_port"`
+	SMTPUsername string `yaml:"smtp_username" toml:"smtp_username"`
+	SMTPPassword string `yaml:"smtp_password" toml:"smtp_password"`
+	// Sender address of the emails
+	From string `yaml:"from" toml:"from"`
+}
+
+type EventsConfig struct {
+	// Delivers live events: memory (single server) or postgres (LISTEN/NOTIFY, for multiple replicas)
+	Broker string `yaml:"broker" toml:"broker"`
+}
+
+type RateLimitConfig struct {
+	// Keeps the buckets: memory (per server) or postgres (shared by multiple replicas)
+	Store string `yaml:"store" toml:"store"`
+}
+
+type TelemetryConfig struct {
+	// OTLP/HTTP collector spans are exported to, such as http://localhost:4318, tracing is off if empty
+	OTLPEndpoint string `yaml:"otlp_endpoint" toml:"otlp_endpoint"`
+	// Name the server's spans are reported under
+	ServiceName string `yaml:"service_name" toml:"service_name"`
+}
+
+// Limits are enforced by the API, the frontend has its own copies for its forms
+type Limits struct {
+	MaxPostTitleLength      int `yaml:"max_post_title_length" toml:"max_post_title_length"`
+	MaxPostContentLength    int `yaml:"max_post_content_length" toml:"max_post_content_length"`
+	MaxCommentContentLength int `yaml:"max_comment_content_length" toml:"max_comment_content_length"`
+	MaxPageSize             int `yaml:"max_page_size" toml:"max_page_size"`
+}
+
+// Default returns the settings used where nothing else is configured
+// There is no default JWT secret, and the database user and name must be set
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:                 ":8000",
+			AllowedOrigins:       []string{"http://localhost:3000", "http://localhost:80", "http://localhost"},
+			AppBaseURL:           "http://localhost:3000",
+			ReadHeaderTimeout:    constants.SERVER_READ_HEADER_TIMEOUT,
+			ReadTimeout:          constants.SERVER_READ_TIMEOUT,
+			WriteTimeout:         constants.SERVER_WRITE_TIMEOUT,
+			IdleTimeout:          constants.SERVER_IDLE_TIMEOUT,
+			ShutdownTimeout:      constants.SHUTDOWN_TIMEOUT,
+			StatementTimeout:     constants.STATEMENT_TIMEOUT,
+			LongStatementTimeout: constants.LONG_STATEMENT_TIMEOUT,
+		},
+		Database: DatabaseConfig{
+			Host:    "localhost",
+			Port:    "5432",
+			SSLMode: "disable",
+		},
+		OIDC: OIDCConfig{
+			DisplayName: "SSO",
+		},
+		Mail: MailConfig{
+			Mailer:   "smtp",
+			Dir:      "mail",
+			SMTPPort: "587",
+		},
+		Events: EventsConfig{
+			Broker: "memory",
+		},
+		RateLimit: RateLimitConfig{
+			Store: "memory",
+		},
+		Limits: DefaultLimits(),
+		Telemetry: TelemetryConfig{
+			ServiceName: "cvwo-backend",
+		},
+	}
+}
+
+func DefaultLimits() Limits {
+	return Limits{
+		MaxPostTitleLength:      constants.MAX_POST_TITLE_LENGTH,
+		MaxPostContentLength:    constants.MAX_POST_CONTENT_LENGTH,
+		MaxCommentContentLength: constants.MAX_COMMENT_CONTENT_LENGTH,
+		MaxPageSize:             constants.MAX_PAGE_SIZE,
+	}
+}
+
+// Load reads the settings file, if a path is given, then the .env files that exist, then the environment
+// Variables already set in the environment are not overridden by .env files
+// The settings are not validated, call Validate before using them
+func Load(path string, envFiles ...string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, fmt.Errorf("config file %s: %w", path, err)
+		}
+	}
+
+	for _, envFile := range envFiles {
+		if err := godotenv.Load(envFile); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("env file %s: %w", envFile, err)
+		}
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// The format is chosen by the extension, keys missing from the file keep their defaults
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return yaml.Unmarshal(data, c)
+	case ".toml":
+		return toml.Unmarshal(data, c)
+	}
+	return errors.New("unknown format, use .yaml, .yml or .toml")
+}
+
+// ALLOWED_ORIGINS and TRUSTED_PROXIES are comma separated, durations are written like 5s or 2m
+// The POSTGRES_* variables are shared with the postgres container, the OTEL_* ones are the standard OpenTelemetry names
+func (c *Config) loadEnv() error {
+	env := envReader{}
+
+	env.string("SERVER_ADDR", &c.Server.Addr)
+	env.list("ALLOWED_ORIGINS", &c.Server.AllowedOrigins)
+	env.string("APP_BASE_URL", &c.Server.AppBaseURL)
+	env.list("TRUSTED_PROXIES", &c.Server.TrustedProxies)
+	env.duration("SERVER_READ_HEADER_TIMEOUT", &c.Server.ReadHeaderTimeout)
+	env.duration("SERVER_READ_TIMEOUT", &c.Server.ReadTimeout)
+	env.duration("SERVER_WRITE_TIMEOUT", &c.Server.WriteTimeout)
+	env.duration("SERVER_IDLE_TIMEOUT", &c.Server.IdleTimeout)
+	env.duration("SHUTDOWN_TIMEOUT", &c.Server.ShutdownTimeout)
+	env.duration("STATEMENT_TIMEOUT", &c.Server.StatementTimeout)
+	env.duration("LONG_STATEMENT_TIMEOUT", &c.Server.LongStatementTimeout)
+
+	env.string("POSTGRES_HOST", &c.Database.Host)
+	env.string("POSTGRES_PORT", &c.Database.Port)
+	env.string("POSTGRES_USER", &c.Database.User)
+	env.string("POSTGRES_PASSWORD", &c.Database.Password)
+	env.string("POSTGRES_DB", &c.Database.Name)
+	env.string("POSTGRES_SSLMODE", &c.Database.SSLMode)
+
+	env.string("JWT_SECRET_KEY", &c.Auth.JWTSecret)
+
+	env.string("OIDC_ISSUER_URL", &c.OIDC.IssuerURL)
+	env.string("OIDC_CLIENT_ID", &c.OIDC.ClientID)
+	env.string("OIDC_CLIENT_SECRET", &c.OIDC.ClientSecret)
+	env.string("OIDC_REDIRECT_URL", &c.OIDC.RedirectURL)
+	env.string("OIDC_DISPLAY_NAME", &c.OIDC.DisplayName)
+
+	env.string("MAILER", &c.Mail.Mailer)
+	env.string("MAIL_DIR", &c.Mail.Dir)
+	env.string("SMTP_HOST", &c.Mail.SMTPHost)
+	env.string("SMTP_PORT", &c.Mail.SMTPPort)
+	env.string("SMTP_USERNAME", &c.Mail.SMTPUsername)
+	env.string("SMTP_PASSWORD", &c.Mail.SMTPPassword)
+	env.string("MAIL_FROM", &c.Mail.From)
+
+	env.string("EVENTS_BROKER", &c.Events.Broker)
+	env.string("RATE_LIMIT_STORE", &c.RateLimit.Store)
+
+	env.int("MAX_POST_TITLE_LENGTH", &c.Limits.MaxPostTitleLength)
+	env.int("MAX_POST_CONTENT_LENGTH", &c.Limits.MaxPostContentLength)
+	env.int("MAX_COMMENT_CONTENT_LENGTH", &c.Limits.MaxCommentContentLength)
+	env.int("MAX_PAGE_SIZE", &c.Limits.MaxPageSize)
+
+	env.string("OTEL_EXPORTER_OTLP_ENDPOINT", &c.Telemetry.OTLPEndpoint)
+	env.string("OTEL_SERVICE_NAME", &c.Telemetry.ServiceName)
+
+	return errors.Join(env.errs...)
+}
+
+// OIDCRedirectURL returns the redirect URL registered with the identity provider
+func (c *Config) OIDCRedirectURL() string {
+	if c.OIDC.RedirectURL != "" {
+		return c.OIDC.RedirectURL
+	}
+	return strings.TrimSuffix(c.Server.AppBaseURL, "/") + "/api/auth/oidc/callback"
+}
+
+// TrustedProxyPrefixes parses the trusted proxies, a single address is a range of one
+func (s ServerConfig) TrustedProxyPrefixes() ([]netip.Prefix, error) {
+	prefixes := []netip.Prefix{}
+	for _, proxy := range s.TrustedProxies {
+		if addr, err := netip.ParseAddr(proxy); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q must be an address or CIDR range such as 172.16.0.0/12 (TRUSTED_PROXIES)", proxy)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// Sets settings from the variables that are set and not empty, collecting the values that do not parse
+type envReader struct {
+	errs []error
+}
+
+func (e *envReader) string(name string, dest *string) {
+	if value := os.Getenv(name); value != "" {
+		*dest = value
+	}
+}
+
+func (e *envReader) list(name string, dest *[]string) {
+	value := os.Getenv(name)
+	if value == "" {
+		return
+	}
+
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*dest = items
+}
+
+func (e *envReader) int(name string, dest *int) {
+	value := os.Getenv(name)
+	if value == "" {
+		return
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s must be a whole number", name))
+		return
+	}
+	*dest = parsed
+}
+
+func (e *envReader) duration(name string, dest *time.Duration) {
+	value := os.Getenv(name)
+	if value == "" {
+		return
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s must be a duration such as 5s or 1m", name))
+		return
+	}
+	*dest = parsed
+}
+
+// ConnString returns the lib/pq connection string of the database
+func (d DatabaseConfig) ConnString() string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		quoteConnValue(d.Host), quoteConnValue(d.Port), quoteConnValue(d.User),
+		quoteConnValue(d.Password), quoteConnValue(d.Name), quoteConnValue(d.SSLMode))
+}
+
+// Quotes values with spaces or quotes, so passwords containing them still connect
+func quoteConnValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Modes accepted by lib/pq
+var sslModes = []string{"disable", "require", "verify-ca", "verify-full"}
+
+var (
+	mailers         = []string{"smtp", "file", "log"}
+	eventsBrokers   = []string{"memory", "postgres"}
+	rateLimitStores = []string{"memory", "postgres"}
+)
+
+// Validate checks every setting the server needs, returning all the problems at once
+func (c *Config) Validate() error {
+	errs := []error{}
+
+	if c.Server.Addr == "" {
+		errs = append(errs, errors.New("server address must be set (SERVER_ADDR)"))
+	}
+	for _, origin := range c.Server.AllowedOrigins {
+		if err := validateOrigin(origin); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if !isHTTPURL(c.Server.AppBaseURL) {
+		errs = append(errs, errors.New("app base URL must be a URL such as https://example.com (APP_BASE_URL)"))
+	}
+	if _, err := c.Server.TrustedProxyPrefixes(); err != nil {
+		errs = append(errs, err)
+	}
+
+	timeouts := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"SERVER_READ_HEADER_TIMEOUT", c.Server.ReadHeaderTimeout},
+		{"SERVER_READ_TIMEOUT", c.Server.ReadTimeout},
+		{"SERVER_WRITE_TIMEOUT", c.Server.WriteTimeout},
+		{"SERVER_IDLE_TIMEOUT", c.Server.IdleTimeout},
+		{"SHUTDOWN_TIMEOUT", c.Server.ShutdownTimeout},
+		{"STATEMENT_TIMEOUT", c.Server.StatementTimeout},
+		{"LONG_STATEMENT_TIMEOUT", c.Server.LongStatementTimeout},
+	}
+	for _, timeout := range timeouts {
+		if timeout.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive", timeout.name))
+		}
+	}
+
+	if err := c.Database.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	// An empty key would sign tokens anyone can forge
+	if c.Auth.JWTSecret == "" {
+		errs = append(errs, errors.New("JWT secret must be set (JWT_SECRET_KEY)"))
+	}
+
+	if c.OIDC.IssuerURL != "" {
+		if !isHTTPURL(c.OIDC.IssuerURL) {
+			errs = append(errs, errors.New("OIDC issuer must be a URL such as https://accounts.example.com (OIDC_ISSUER_URL)"))
+		}
+		if c.OIDC.ClientID == "" {
+			errs = append(errs, errors.New("OIDC client ID must be set for single sign-on (OIDC_CLIENT_ID)"))
+		}
+		if c.OIDC.RedirectURL != "" && !isHTTPURL(c.OIDC.RedirectURL) {
+			errs = append(errs, errors.New("OIDC redirect URL must be a URL such as https://example.com/api/auth/oidc/callback (OIDC_REDIRECT_URL)"))
+		}
+		if c.OIDC.DisplayName == "" {
+			errs = append(errs, errors.New("OIDC display name must be set for single sign-on (OIDC_DISPLAY_NAME)"))
+		}
+	}
+
+	if err := c.Mail.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if !slices.Contains(eventsBrokers, c.Events.Broker) {
+		errs = append(errs, fmt.Errorf("events broker must be one of %v (EVENTS_BROKER)", eventsBrokers))
+	}
+	if !slices.Contains(rateLimitStores, c.RateLimit.Store) {
+		errs = append(errs, fmt.Errorf("rate limit store must be one of %v (RATE_LIMIT_STORE)", rateLimitStores))
+	}
+
+	limits := []struct {
+		name  string
+		value int
+	}{
+		{"MAX_POST_TITLE_LENGTH", c.Limits.MaxPostTitleLength},
+		{"MAX_POST_CONTENT_LENGTH", c.Limits.MaxPostContentLength},
+		{"MAX_COMMENT_CONTENT_LENGTH", c.Limits.MaxCommentContentLength},
+		{"MAX_PAGE_SIZE", c.Limits.MaxPageSize},
+	}
+	for _, limit := range limits {
+		if limit.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive", limit.name))
+		}
+	}
+
+	if c.Telemetry.OTLPEndpoint != "" {
+		if !isHTTPURL(c.Telemetry.OTLPEndpoint) {
+			errs = append(errs, errors.New("OTLP endpoint must be a URL such as http://localhost:4318 (OTEL_EXPORTER_OTLP_ENDPOINT)"))
+		}
+		if c.Telemetry.ServiceName == "" {
+			errs = append(errs, errors.New("service name must be set when tracing (OTEL_SERVICE_NAME)"))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate checks the settings needed to connect, for commands that only use the database
+func (d DatabaseConfig) Validate() error {
+	errs := []error{}
+	if d.Host == "" || d.Port == "" {
+		errs = append(errs, errors.New("database host and port must be set (POSTGRES_HOST, POSTGRES_PORT)"))
+	}
+	if d.User == "" || d.Name == "" {
+		errs = append(errs, errors.New("database user and name must be set (POSTGRES_USER, POSTGRES_DB)"))
+	}
+	if !slices.Contains(sslModes, d.SSLMode) {
+		errs = append(errs, fmt.Errorf("database sslmode must be one of %v (POSTGRES_SSLMODE)", sslModes))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the settings of the chosen mailer
+func (m MailConfig) Validate() error {
+	errs := []error{}
+	switch m.Mailer {
+	case "smtp":
+		if m.SMTPHost == "" || m.From == "" {
+			errs = append(errs, errors.New("SMTP host and sender must be set to send emails (SMTP_HOST, MAIL_FROM)"))
+		}
+		if port, err := strconv.Atoi(m.SMTPPort); err != nil || port <= 0 || port > 65535 {
+			errs = append(errs, errors.New("SMTP port must be a port number such as 587 (SMTP_PORT)"))
+		}
+	case "file":
+		if m.Dir == "" {
+			errs = append(errs, errors.New("mail directory must be set for the file mailer (MAIL_DIR)"))
+		}
+	case "log":
+	default:
+		errs = append(errs, fmt.Errorf("mailer must be one of %v (MAILER)", mailers))
+	}
+	return errors.Join(errs...)
+}
+
+// Origins are a scheme and host, CORS compares them exactly so a path or trailing slash never matches
+func validateOrigin(origin string) error {
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" {
+		return fmt.Errorf("allowed origin %q must be a scheme and host such as https://example.com (ALLOWED_ORIGINS)", origin)
+	}
+	return nil
+}
+
+// An absolute http or https URL
+func isHTTPURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -1,10 +1,18 @@
 package constants
 
+import "time"
+
 // Field length constraints
 const MAX_POST_TITLE_LENGTH = 500
 const MAX_POST_CONTENT_LENGTH = 10_000
 const MAX_COMMENT_CONTENT_LENGTH = 10_000
 
+const MAX_TOPIC_NAME_LENGTH = 50
+const MAX_TOPIC_DESCRIPTION_LENGTH = 500
+
+const MAX_REPORT_DETAILS_LENGTH = 1_000
+const MAX_MODERATION_NOTE_LENGTH = 1_000
+
 // User fields
 const MIN_PASSWORD_LENGTH = 6
 const MAX_PASSWORD_LENGTH = 100
@@ -12,16 +20,240 @@ const MAX_PASSWORD_LENGTH = 100
 const MIN_USERNAME_LENGTH = 3
 const MAX_USERNAME_LENGTH = 20
 
+// Sessions
+const ACCESS_TOKEN_COOKIE = "jwt"
+const REFRESH_TOKEN_COOKIE = "refresh_token"
+
+const ACCESS_TOKEN_DURATION = 15 * time.Minute
+const REFRESH_TOKEN_DURATION = 30 * 24 * time.Hour
+
+// Single-use tokens sent by email
+const EMAIL_TOKEN_VERIFY_EMAIL = "verify_email"
+const EMAIL_TOKEN_RESET_PASSWORD = "reset_password"
+
+const VERIFY_EMAIL_TOKEN_DURATION = 48 * time.Hour
+const RESET_PASSWORD_TOKEN_DURATION = time.Hour
+
+// Two-factor authentication
+// Codes of the step before and after the current one are accepted too, for clock drift
+const TOTP_ISSUER = "CVWO"
+const TOTP_DIGITS = 6
+const TOTP_PERIOD = 30 * time.Second
+const TOTP_SKEW_STEPS = 1
+const RECOVERY_CODE_COUNT = 10
+
+// A login waiting for a code expires, and is abandoned after too many wrong codes
+const LOGIN_CHALLENGE_DURATION = 5 * time.Minute
+const MAX_LOGIN_CHALLENGE_ATTEMPTS = 5
+
+// Personal API tokens, the prefix makes leaked tokens easy to recognize
+const API_TOKEN_PREFIX = "cvwo_"
+const MAX_API_TOKEN_NAME_LENGTH = 100
+const MAX_API_TOKEN_EXPIRY_DAYS = 365
+
+// Scopes of API tokens, which limit the routes a token can be used on
+// Routes that do not require a scope cannot be used with a token at all
+const API_TOKEN_SCOPE_READ = "read"
+const API_TOKEN_SCOPE_POST_WRITE = "post:write"
+const API_TOKEN_SCOPE_VOTE = "vote"
+
+// Single sign-on
+// The state, nonce and PKCE verifier of a login are kept in a signed cookie until the provider redirects back
+const OIDC_LOGIN_COOKIE = "oidc_login"
+const OIDC_LOGIN_DURATION = 10 * time.Minute
+
+// Why a single sign-on login failed, sent to the login page (or the page a link started from) in its sso_error parameter
+const SSO_ERROR_FAILED = "sso_failed"
+const SSO_ERROR_EMAIL_REQUIRED = "email_required"
+const SSO_ERROR_EMAIL_TAKEN = "email_taken"
+const SSO_ERROR_IDENTITY_TAKEN = "identity_taken"
+
+// Login lockouts
+// Failures within the window count, for an account only those since its last successful login
+// Once over the threshold, each further failure doubles the lockout, up to the maximum
+const LOGIN_FAILURE_WINDOW = 24 * time.Hour
+const ACCOUNT_LOCKOUT_THRESHOLD = 5
+const IP_LOCKOUT_THRESHOLD = 20
+const LOCKOUT_BASE_DURATION = time.Minute
+const MAX_LOCKOUT_DURATION = time.Hour
+
+// Reasons a login attempt failed
+const LOGIN_FAILURE_INVALID_CREDENTIALS = "invalid_credentials"
+const LOGIN_FAILURE_INVALID_TWO_FACTOR_CODE = "invalid_two_factor_code"
+const LOGIN_FAILURE_LOCKED_OUT = "locked_out"
+
+// Login attempts listed by /me/security
+const MAX_SECURITY_LOG_ENTRIES = 50
+
+// User roles
+// Topic moderators have the member role and are listed in topic_moderators
+const ROLE_ADMIN = "admin"
+const ROLE_MODERATOR = "moderator"
+const ROLE_MEMBER = "member"
+
 // Summary lengths
 const COMMENT_SUMMARY_LENGTH = 400
 const POST_SUMMARY_LENGTH = 400
 
+// Notification types
+const NOTIFICATION_POST_REPLY = "post_reply"
+const NOTIFICATION_COMMENT_REPLY = "comment_reply"
+const NOTIFICATION_MENTION = "mention"
+const NOTIFICATION_COMMENT_PINNED = "comment_pinned"
+const NOTIFICATION_TOPIC_POST = "topic_post"
+
+// Sent to authors warned when reports are resolved, cannot be muted
+const NOTIFICATION_MODERATOR_WARNING = "moderator_warning"
+
+// At most this many users are notified for mentions in one post or comment
+const MAX_MENTIONS = 10
+
+// Live event types, see the events package
+const EVENT_COMMENT_CREATED = "comment_created"
+const EVENT_COMMENT_UPDATED = "comment_updated"
+const EVENT_COMMENT_DELETED = "comment_deleted"
+const EVENT_COMMENT_VOTED = "comment_voted"
+const EVENT_COMMENT_PINNED = "comment_pinned"
+const EVENT_POST_UPDATED = "post_updated"
+const EVENT_POST_DELETED = "post_deleted"
+const EVENT_POST_VOTED = "post_voted"
+const EVENT_NOTIFICATION = "notification"
+
+// Comment sent on idle event streams so proxies do not time them out
+const EVENT_STREAM_HEARTBEAT_INTERVAL = 25 * time.Second
+
+// Default timeouts of the HTTP server, event streams lift the write timeout as they stay open
+const SERVER_READ_HEADER_TIMEOUT = 5 * time.Second
+const SERVER_READ_TIMEOUT = 15 * time.Second
+const SERVER_WRITE_TIMEOUT = 30 * time.Second
+const SERVER_IDLE_TIMEOUT = 2 * time.Minute
+
+// Time in-flight requests get to finish once the server is asked to stop
+const SHUTDOWN_TIMEOUT = 20 * time.Second
+
+// Time the queries of one request may take before they are cancelled, unless its route allows more
+const STATEMENT_TIMEOUT = 5 * time.Second
+// Comment subtrees and full-text search scan the most rows
+const LONG_STATEMENT_TIMEOUT = 15 * time.Second
+
+// Time each readiness check may take
+const READINESS_CHECK_TIMEOUT = 2 * time.Second
+
+// Time the spans still buffered when the server stops get to reach the collector
+const TRACE_FLUSH_TIMEOUT = 5 * time.Second
+
+// Report reasons
+const REPORT_REASON_SPAM = "spam"
+const REPORT_REASON_HARASSMENT = "harassment"
+const REPORT_REASON_HATE = "hate"
+const REPORT_REASON_MISINFORMATION = "misinformation"
+const REPORT_REASON_OFF_TOPIC = "off_topic"
+const REPORT_REASON_OTHER = "other"
+
+// Report statuses
+const REPORT_STATUS_OPEN = "open"
+const REPORT_STATUS_RESOLVED = "resolved"
+const REPORT_STATUS_DISMISSED = "dismissed"
+
+// Report resolution actions, recorded in the moderation log
+const MODERATION_ACTION_DISMISS = "dismiss"
+const MODERATION_ACTION_REMOVE = "remove"
+const MODERATION_ACTION_WARN = "warn"
+const MODERATION_ACTION_SUSPEND = "suspend"
+
+const MAX_SUSPENSION_DAYS = 365
+
+// Report targets
+const REPORT_TARGET_POST = "post"
+const REPORT_TARGET_COMMENT = "comment"
+
+// Search result types
+const SEARCH_TYPE_POST = "post"
+const SEARCH_TYPE_COMMENT = "comment"
+
 // Error messages
 const NO_ROWS_AFFECTED_ERROR = "no rows affected"
 const NOT_FOUND_ERROR = "not found"
+const ALREADY_EXISTS_ERROR = "already exists"
+const TOPIC_ARCHIVED_ERROR = "topic is archived"
+const POST_LOCKED_ERROR = "post is locked"
+const INVALID_CURSOR_ERROR = "invalid cursor"
+
+// Error codes returned in the code field of error responses
+// Clients branch on these, so they must never change once released
+const ERROR_CODE_VALIDATION_FAILED = "validation_failed"
+const ERROR_CODE_INVALID_REQUEST_BODY = "invalid_request_body"
+const ERROR_CODE_INVALID_QUERY = "invalid_query"
+const ERROR_CODE_INVALID_ID = "invalid_id"
+const ERROR_CODE_INVALID_CURSOR = "invalid_cursor"
+
+const ERROR_CODE_AUTHENTICATION_REQUIRED = "authentication_required"
+const ERROR_CODE_INVALID_CREDENTIALS = "invalid_credentials"
+const ERROR_CODE_INVALID_REFRESH_TOKEN = "invalid_refresh_token"
+const ERROR_CODE_SESSION_EXPIRED = "session_expired"
+const ERROR_CODE_ACCESS_TOKEN_EXPIRED = "access_token_expired"
+const ERROR_CODE_INVALID_TOKEN = "invalid_token"
+const ERROR_CODE_INVALID_TWO_FACTOR_CODE = "invalid_two_factor_code"
+const ERROR_CODE_INVALID_API_TOKEN = "invalid_api_token"
+const ERROR_CODE_INSUFFICIENT_SCOPE = "insufficient_scope"
+const ERROR_CODE_FORBIDDEN = "forbidden"
+
+const ERROR_CODE_NOT_FOUND = "not_found"
+const ERROR_CODE_SSO_NOT_CONFIGURED = "sso_not_configured"
+const ERROR_CODE_METHOD_NOT_ALLOWED = "method_not_allowed"
+const ERROR_CODE_POST_NOT_FOUND = "post_not_found"
+const ERROR_CODE_COMMENT_NOT_FOUND = "comment_not_found"
+const ERROR_CODE_TOPIC_NOT_FOUND = "topic_not_found"
+const ERROR_CODE_USER_NOT_FOUND = "user_not_found"
+const ERROR_CODE_SESSION_NOT_FOUND = "session_not_found"
+const ERROR_CODE_API_TOKEN_NOT_FOUND = "api_token_not_found"
+const ERROR_CODE_REVISION_NOT_FOUND = "revision_not_found"
+const ERROR_CODE_NO_OPEN_REPORTS = "no_open_reports"
 
-// Pagination defaults
-const MAX_PAGE_SIZE = 10_000
+const ERROR_CODE_POST_DELETED = "post_deleted"
+const ERROR_CODE_COMMENT_DELETED = "comment_deleted"
+const ERROR_CODE_POST_LOCKED = "post_locked"
+const ERROR_CODE_TOPIC_ARCHIVED = "topic_archived"
+
+const ERROR_CODE_EMAIL_TAKEN = "email_taken"
+const ERROR_CODE_USERNAME_TAKEN = "username_taken"
+const ERROR_CODE_TOPIC_NAME_TAKEN = "topic_name_taken"
+const ERROR_CODE_ALREADY_FOLLOWING = "already_following"
+const ERROR_CODE_NOT_FOLLOWING = "not_following"
+const ERROR_CODE_ALREADY_MODERATOR = "already_moderator"
+const ERROR_CODE_NOT_MODERATOR = "not_moderator"
+const ERROR_CODE_ALREADY_REPORTED = "already_reported"
+const ERROR_CODE_EMAIL_ALREADY_VERIFIED = "email_already_verified"
+const ERROR_CODE_TWO_FACTOR_ALREADY_ENABLED = "two_factor_already_enabled"
+const ERROR_CODE_TWO_FACTOR_NOT_ENABLED = "two_factor_not_enabled"
+const ERROR_CODE_ACCOUNT_SUSPENDED = "account_suspended"
+
+const ERROR_CODE_RATE_LIMITED = "rate_limited"
+const ERROR_CODE_TOO_MANY_LOGIN_ATTEMPTS = "too_many_login_attempts"
+
+const ERROR_CODE_INTERNAL = "internal_error"
+const ERROR_CODE_TIMEOUT = "timeout"
+
+// Error codes of individual fields in the details of a validation_failed response
+const ERROR_CODE_REQUIRED = "required"
+const ERROR_CODE_TOO_SHORT = "too_short"
+const ERROR_CODE_TOO_LONG = "too_long"
+const ERROR_CODE_INVALID_FORMAT = "invalid_format"
+const ERROR_CODE_INVALID_VALUE = "invalid_value"
+
+// Pagination defaults, used when a list request has no page size and the most it may ask for
+const DEFAULT_PAGE_SIZE = 20
+const MAX_PAGE_SIZE = 100
+
+const DEFAULT_SEARCH_PAGE_SIZE = 20
+const MAX_SEARCH_PAGE_SIZE = 100
+const MAX_SEARCH_QUERY_LENGTH = 200
+
+const DEFAULT_NOTIFICATIONS_PAGE_SIZE = 20
+const MAX_NOTIFICATIONS_PAGE_SIZE = 100
+
+const DEFAULT_MODERATION_PAGE_SIZE = 20
+const MAX_MODERATION_PAGE_SIZE = 100
 
 // Sorting options
 const ORDER_BY_NEW = "created_at"
@@ -35,5 +267,21 @@ const ORDER_BY_KARMA = "karma"
 const ORDER_BY_VOTES = "score"
 const ORDER_BY_COMMENTS = "no_of_comments"
 
+const ORDER_BY_HOT = "hot"
+const ORDER_BY_BEST = "best"
+const ORDER_BY_CONTROVERSIAL = "controversial"
+const ORDER_BY_TOP = "top"
+
+const ORDER_BY_RELEVANCE = "relevance"
+
+// Saved lists only, most recently saved first by default
+const ORDER_BY_SAVED = "saved_at"
+
 const SORT_ASC = "asc"
 const SORT_DESC = "desc"
+
+// Time windows for ranked sorts
+const WINDOW_DAY = "day"
+const WINDOW_WEEK = "week"
+const WINDOW_MONTH = "month"
+const WINDOW_ALL = "all"
@@ -0,0 +1,165 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"cvwo/internal/telemetry"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// CreateAPIToken stores a new API token of the user with the hash of its secret
+// Returns the new token ID
+func (s *PostgresStore) CreateAPIToken(ctx context.Context, token models.APIToken, tokenHash string) (int, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "CreateAPIToken")
+	defer done()
+
+	query := `
+		INSERT INTO api_tokens (
+			user_id,
+			name,
+			token_prefix,
+			token_hash,
+			scopes,
+			created_at,
+			expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+
+	var tokenID int
+	err := s.db.QueryRowContext(ctx, query,
+		token.UserID,
+		token.Name,
+		token.TokenPrefix,
+		tokenHash,
+		pq.Array(token.Scopes),
+		token.CreatedAt,
+		token.ExpiresAt,
+	).Scan(&tokenID)
+
+	return tokenID, err
+}
+
+// ListAPITokens returns the user's tokens that are not revoked, newest first, including expired ones
+func (s *PostgresStore) ListAPITokens(ctx context.Context, userID int) ([]models.APIToken, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "ListAPITokens")
+	defer done()
+
+	query := `
+		SELECT id,
+		name,
+		token_prefix,
+		scopes,
+		created_at,
+		last_used_at,
+		expires_at
+		FROM api_tokens
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC, id DESC`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.APIToken{}
+	for rows.Next() {
+		token := models.APIToken{UserID: userID}
+		if err := rows.Scan(
+			&token.ID,
+			&token.Name,
+			&token.TokenPrefix,
+			pq.Array(&token.Scopes),
+			&token.CreatedAt,
+			&token.LastUsedAt,
+			&token.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// RevokeAPIToken revokes one of the user's tokens
+// Returns NO_ROWS_AFFECTED_ERROR if the user has no such token or it is already revoked
+func (s *PostgresStore) RevokeAPIToken(ctx context.Context, userID, tokenID int) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "RevokeAPIToken")
+	defer done()
+
+	query := `
+		UPDATE api_tokens SET
+			revoked_at = $3
+		WHERE id = $1 AND user_id = $2
+		AND revoked_at IS NULL`
+
+	result, err := s.db.ExecContext(ctx, query, tokenID, userID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	return nil
+}
+
+// AuthenticateAPIToken finds the active token with the hash, records its use and returns it with its user's role
+// Returns NOT_FOUND_ERROR if the token does not exist, was revoked or expired
+func (s *PostgresStore) AuthenticateAPIToken(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "AuthenticateAPIToken")
+	defer done()
+
+	query := `
+		UPDATE api_tokens t SET
+			last_used_at = $2
+		FROM users u
+		WHERE u.id = t.user_id
+		AND t.token_hash = $1
+		AND t.revoked_at IS NULL
+		AND (t.expires_at IS NULL OR t.expires_at > $2)
+		RETURNING t.id,
+		t.user_id,
+		u.role,
+		t.name,
+		t.token_prefix,
+		t.scopes,
+		t.created_at,
+		t.last_used_at,
+		t.expires_at`
+
+	token := &models.APIToken{}
+	err := s.db.QueryRowContext(ctx, query, tokenHash, time.Now()).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Role,
+		&token.Name,
+		&token.TokenPrefix,
+		pq.Array(&token.Scopes),
+		&token.CreatedAt,
+		&token.LastUsedAt,
+		&token.ExpiresAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New(constants.NOT_FOUND_ERROR)
+		}
+		return nil, err
+	}
+	return token, nil
+}
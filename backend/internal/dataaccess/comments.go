@@ -1,10 +1,11 @@
 package dataaccess
 
 import (
+	"context"
 	"cvwo/internal/constants"
 	"cvwo/internal/dataaccess/queries"
-	"cvwo/internal/database"
 	"cvwo/internal/models"
+	"cvwo/internal/telemetry"
 	"cvwo/internal/utils"
 	"database/sql"
 	"errors"
@@ -14,8 +15,11 @@ import (
 )
 
 // CreateComment creates a new comment with proper path handling for nested structure
-func CreateComment(comment models.Comment) (int, error) {
-	tx, err := database.DB.Begin()
+func (s *PostgresStore) CreateComment(ctx context.Context, comment models.Comment) (int, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "CreateComment")
+	defer done()
+
+	tx, err := s.begin(ctx)
 	if err != nil {
 		return 0, err
 	}
@@ -24,13 +28,18 @@ func CreateComment(comment models.Comment) (int, error) {
 	// Verify the post exists and is not deleted
 	checkPostQuery := `
 		SELECT EXISTS(SELECT 1 FROM posts
-			WHERE id = $1 AND is_deleted = false)`
+			WHERE id = $1 AND is_deleted = false),
+		EXISTS(SELECT 1 FROM posts
+			WHERE id = $1 AND is_locked = true)`
 
-	var postExists bool
-	err = tx.QueryRow(checkPostQuery, comment.PostID).Scan(&postExists)
+	var postExists, postLocked bool
+	err = tx.QueryRowContext(ctx, checkPostQuery, comment.PostID).Scan(&postExists, &postLocked)
 	if err != nil {
 		return 0, err
 	}
+	if postLocked {
+		return 0, errors.New(constants.POST_LOCKED_ERROR)
+	}
 
 	// If this is a reply to another comment, verify the parent exists
 	var parentPath string
@@ -43,7 +52,7 @@ func CreateComment(comment models.Comment) (int, error) {
 			post_id
 			FROM comments WHERE id = $1`
 
-		err = tx.QueryRow(checkParentQuery, *comment.ParentID).Scan(
+		err = tx.QueryRowContext(ctx, checkParentQuery, *comment.ParentID).Scan(
 			&parentExists,
 			&parentPath,
 			&parentPostID,
@@ -67,6 +76,7 @@ func CreateComment(comment models.Comment) (int, error) {
 		INSERT INTO comments (
 			post_id,
 			content,
+			content_html,
 			summary,
 			user_id,
 			created_at,
@@ -74,26 +84,32 @@ func CreateComment(comment models.Comment) (int, error) {
 			parent_id,
 			has_long_content,
 			is_deleted)
-		VALUES ($1, $2, $3, $4, $5, $5, $6, $7, false) RETURNING id`
+		VALUES ($1, $2, $3, $4, $5, $6, $6, $7, $8, false) RETURNING id`
 
 	now := time.Now()
-	summary, hasLongContent := utils.GenerateCommentSummary(comment)
+	utils.RenderComment(&comment)
 
 	var commentID int
-	err = tx.QueryRow(query,
+	err = tx.QueryRowContext(ctx, query,
 		comment.PostID,
 		comment.Content,
-		summary,
+		comment.ContentHTML,
+		comment.Summary,
 		comment.UserID,
 		now,
 		comment.ParentID,
-		hasLongContent,
+		comment.HasLongContent,
 	).Scan(&commentID)
 
 	if err != nil {
 		return 0, err
 	}
 
+	comment.ID = commentID
+	if err := insertCommentRevision(ctx, tx.Tx, &comment, comment.UserID, nil, now); err != nil {
+		return 0, err
+	}
+
 	// Update the path
 	var path string
 	if comment.ParentID != nil {
@@ -107,7 +123,7 @@ func CreateComment(comment models.Comment) (int, error) {
 			path = $1
 		WHERE id = $2`
 
-	_, err = tx.Exec(updatePathQuery, path, commentID)
+	_, err = tx.ExecContext(ctx, updatePathQuery, path, commentID)
 	if err != nil {
 		return 0, err
 	}
@@ -118,7 +134,7 @@ func CreateComment(comment models.Comment) (int, error) {
 			no_of_comments = no_of_comments + 1
 		WHERE id = $1`
 
-	_, err = tx.Exec(updatePostQuery, comment.PostID)
+	_, err = tx.ExecContext(ctx, updatePostQuery, comment.PostID)
 	if err != nil {
 		return 0, err
 	}
@@ -130,7 +146,7 @@ func CreateComment(comment models.Comment) (int, error) {
 				no_of_replies = no_of_replies + 1
 			WHERE id = $1`
 
-		_, err = tx.Exec(updateParentQuery, *comment.ParentID)
+		_, err = tx.ExecContext(ctx, updateParentQuery, *comment.ParentID)
 		if err != nil {
 			return 0, err
 		}
@@ -139,24 +155,46 @@ func CreateComment(comment models.Comment) (int, error) {
 	return commentID, tx.Commit()
 }
 
-// UpdateComment updates an existing comment's content
-func UpdateComment(comment models.Comment) error {
+// UpdateComment updates an existing comment's content, re-rendering it and regenerating its summary
+// The rendered content and summary are set on the comment, and the new version is recorded as a revision
+func (s *PostgresStore) UpdateComment(ctx context.Context, comment *models.Comment, editorID int) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "UpdateComment")
+	defer done()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updateComment(ctx, tx.Tx, comment, editorID, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Updates a comment and records the new version as its next revision
+// The update locks the comment row, so concurrent edits get consecutive revision numbers
+func updateComment(ctx context.Context, tx *sql.Tx, comment *models.Comment, editorID int, restoredFrom *int) error {
 	query := `
 		UPDATE comments SET
 			content = $1,
-			summary = $2,
-			updated_at = $3,
-			has_long_content = $4
-		WHERE id = $5`
+			content_html = $2,
+			summary = $3,
+			updated_at = $4,
+			has_long_content = $5
+		WHERE id = $6`
 
 	now := time.Now()
-	summary, hasLongContent := utils.GenerateCommentSummary(comment)
+	utils.RenderComment(comment)
 
-	result, err := database.DB.Exec(query,
+	result, err := tx.ExecContext(ctx, query,
 		comment.Content,
-		summary,
+		comment.ContentHTML,
+		comment.Summary,
 		now,
-		hasLongContent,
+		comment.HasLongContent,
 		comment.ID,
 	)
 
@@ -173,17 +211,29 @@ func UpdateComment(comment models.Comment) error {
 		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
 	}
 
-	return nil
+	return insertCommentRevision(ctx, tx, comment, editorID, restoredFrom, now)
 }
 
 // DeleteComment performs a soft delete by marking the comment as deleted (tombstone pattern)
-func DeleteComment(id int) error {
-	tx, err := database.DB.Begin()
+func (s *PostgresStore) DeleteComment(ctx context.Context, id int) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "DeleteComment")
+	defer done()
+
+	tx, err := s.begin(ctx)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	if err := deleteComment(ctx, tx.Tx, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Soft deletes the comment within the transaction, returns NO_ROWS_AFFECTED_ERROR if it is already deleted
+func deleteComment(ctx context.Context, tx *sql.Tx, id int) error {
 	// Get comment details before deletion
 	getCommentQuery := `
 		SELECT post_id,
@@ -193,7 +243,7 @@ func DeleteComment(id int) error {
 
 	var postID int
 	var exists bool
-	err = tx.QueryRow(getCommentQuery, id).Scan(&postID, &exists)
+	err := tx.QueryRowContext(ctx, getCommentQuery, id).Scan(&postID, &exists)
 	if err != nil {
 		return err
 	}
@@ -209,7 +259,7 @@ func DeleteComment(id int) error {
 		WHERE id = $2 AND is_deleted = false`
 
 	now := time.Now()
-	result, err := tx.Exec(deleteQuery, now, id)
+	result, err := tx.ExecContext(ctx, deleteQuery, now, id)
 	if err != nil {
 		return err
 	}
@@ -229,17 +279,16 @@ func DeleteComment(id int) error {
 			no_of_comments = no_of_comments - 1
 		WHERE id = $1`
 
-	_, err = tx.Exec(updatePostQuery, postID)
-	if err != nil {
-		return err
-	}
-
-	return tx.Commit()
+	_, err = tx.ExecContext(ctx, updatePostQuery, postID)
+	return err
 }
 
 // Transaction to ensure vote and score update are atomic
-func VoteComment(vote models.CommentVote) error {
-	tx, err := database.DB.Begin()
+func (s *PostgresStore) VoteComment(ctx context.Context, vote models.CommentVote) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "VoteComment")
+	defer done()
+
+	tx, err := s.begin(ctx)
 	if err != nil {
 		return err
 	}
@@ -251,7 +300,7 @@ func VoteComment(vote models.CommentVote) error {
 			WHERE id = $1 AND is_deleted = false)`
 
 	var exists bool
-	err = tx.QueryRow(checkQuery, vote.CommentID).Scan(&exists)
+	err = tx.QueryRowContext(ctx, checkQuery, vote.CommentID).Scan(&exists)
 	if err != nil {
 		return err
 	}
@@ -268,20 +317,27 @@ func VoteComment(vote models.CommentVote) error {
 		ON CONFLICT (user_id, comment_id)
 		DO UPDATE SET vote_value = EXCLUDED.vote_value`
 
-	_, err = tx.Exec(query, vote.UserID, vote.CommentID, vote.VoteValue)
+	_, err = tx.ExecContext(ctx, query, vote.UserID, vote.CommentID, vote.VoteValue)
 	if err != nil {
 		return err
 	}
 
-	// Update comment score
+	// Update comment score and vote counts, the rankings are recomputed by PostgreSQL
 	updateScoreQuery := `
 		UPDATE comments SET
-			score = COALESCE((
-				SELECT SUM(vote_value) FROM comment_votes
-				WHERE comment_id = $1), 0)
-		WHERE id = $1`
-
-	_, err = tx.Exec(updateScoreQuery, vote.CommentID)
+			score = v.score,
+			upvotes = v.upvotes,
+			downvotes = v.downvotes
+		FROM (
+			SELECT
+				COALESCE(SUM(vote_value), 0) AS score,
+				COUNT(*) FILTER (WHERE vote_value > 0) AS upvotes,
+				COUNT(*) FILTER (WHERE vote_value < 0) AS downvotes
+			FROM comment_votes
+			WHERE comment_id = $1) v
+		WHERE comments.id = $1`
+
+	_, err = tx.ExecContext(ctx, updateScoreQuery, vote.CommentID)
 	if err != nil {
 		return err
 	}
@@ -293,7 +349,7 @@ func VoteComment(vote models.CommentVote) error {
 
 	updateKarmaQuery := queries.MakeUpdateKarmaQuery(userIdQuery)
 
-	_, err = tx.Exec(updateKarmaQuery, vote.CommentID)
+	_, err = tx.ExecContext(ctx, updateKarmaQuery, vote.CommentID)
 	if err != nil {
 		return err
 	}
@@ -301,18 +357,35 @@ func VoteComment(vote models.CommentVote) error {
 	return tx.Commit()
 }
 
-func ListComments(isAuthenticated bool, currentUserID int, req models.ListCommentsRequest) ([]models.Comment, int, error) {
+func (s *PostgresStore) ListComments(ctx context.Context, isAuthenticated bool, currentUserID int, req models.ListCommentsRequest) ([]models.Comment, models.PageInfo, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "ListComments")
+	defer done()
+
 	args := []any{}
 	var queryBuilder strings.Builder
+	pageInfo := models.PageInfo{}
+
+	desc := req.OrderBy != constants.SORT_ASC
+	sortColumn := keysetColumn{expr: "c." + rankingColumn(req.Sort), kind: rankingKind(req.Sort), desc: desc}
+	if req.SavedOnly && req.Sort == constants.ORDER_BY_SAVED {
+		sortColumn = keysetColumn{expr: "s.created_at", kind: keysetTime, desc: desc}
+	}
+	ordering := []keysetColumn{
+		sortColumn,
+		{expr: "c.id", desc: desc},
+	}
 
 	selectFields := `
 		c.id,
 		c.post_id,
 		c.summary,
+		CASE WHEN c.has_long_content THEN '' ELSE c.content_html END,
 		c.created_at,
 		c.updated_at,
 		c.user_id,
 		COALESCE(c.score, 0),
+		c.upvotes,
+		c.downvotes,
 		c.parent_id,
 		COALESCE(c.path, ''),
 		COALESCE(c.no_of_replies, 0),
@@ -321,12 +394,14 @@ func ListComments(isAuthenticated bool, currentUserID int, req models.ListCommen
 		c.has_long_content,
 		p.title,
 		u.username,
+		COALESCE(p.topic_id, 0),
 		t.name`
 
 	if isAuthenticated {
 		query := fmt.Sprintf(`
 			SELECT %s,
-			COALESCE(v.vote_value, 0)
+			COALESCE(v.vote_value, 0),
+			s.user_id IS NOT NULL%s
 			FROM comments c
 
 			LEFT JOIN users u ON c.user_id = u.id
@@ -335,24 +410,36 @@ func ListComments(isAuthenticated bool, currentUserID int, req models.ListCommen
 			LEFT JOIN comment_votes v
 				ON c.id = v.comment_id
 				AND v.user_id = $1
-			WHERE 1=1`, selectFields)
+			LEFT JOIN saved_comments s
+				ON c.id = s.comment_id
+				AND s.user_id = $1
+			WHERE 1=1`, selectFields, keysetSelectFields(ordering))
 
 		queryBuilder.WriteString(query)
 		args = append(args, currentUserID)
 	} else {
 		query := fmt.Sprintf(`
 			SELECT %s,
-			0
+			0,
+			false%s
 			FROM comments c
 
 			LEFT JOIN users u ON c.user_id = u.id
 			LEFT JOIN posts p ON c.post_id = p.id
 			LEFT JOIN topics t ON p.topic_id = t.id
-			WHERE 1=1`, selectFields)
+			WHERE 1=1`, selectFields, keysetSelectFields(ordering))
 
 		queryBuilder.WriteString(query)
 	}
 
+	// Nothing is saved without a user
+	if req.SavedOnly {
+		if !isAuthenticated {
+			return []models.Comment{}, pageInfo, nil
+		}
+		queryBuilder.WriteString(" AND s.user_id IS NOT NULL")
+	}
+
 	if req.Search != "" {
 		args = append(args, "%"+req.Search+"%")
 		queryBuilder.WriteString(fmt.Sprintf(" AND c.content ILIKE $%d", len(args)))
@@ -387,55 +474,69 @@ func ListComments(isAuthenticated bool, currentUserID int, req models.ListCommen
 		}
 	}
 
-	// Get total count for pagination
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM (%s)", queryBuilder.String())
-	var totalCount int
-	err := database.DB.QueryRow(countQuery, args...).Scan(&totalCount)
-	if err != nil {
-		return nil, 0, err
+	if since := windowStart(req.Window); since != nil {
+		args = append(args, *since)
+		queryBuilder.WriteString(fmt.Sprintf(" AND c.created_at >= $%d", len(args)))
 	}
 
-	switch req.Sort {
-	case constants.ORDER_BY_VOTES:
-		queryBuilder.WriteString(" ORDER BY c.score")
-	default:
-		queryBuilder.WriteString(" ORDER BY c.created_at")
+	// Get total count for pagination
+	if shouldCount(req.IncludeCount) {
+		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM (%s)", queryBuilder.String())
+		var totalCount int
+		err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&totalCount)
+		if err != nil {
+			return nil, pageInfo, err
+		}
+		pageInfo.Count = &totalCount
 	}
 
-	switch req.OrderBy {
-	case constants.SORT_ASC:
-		queryBuilder.WriteString(" ASC")
-	default:
-		queryBuilder.WriteString(" DESC")
+	if req.Cursor != "" {
+		values, err := decodeCursor(req.Cursor, ordering)
+		if err != nil {
+			return nil, pageInfo, err
+		}
+		writeKeysetCondition(&queryBuilder, &args, ordering, values)
 	}
 
+	writeOrderBy(&queryBuilder, ordering)
+
+	// No page size returns every comment, the post page loads the whole tree this way
 	if req.PageSize > 0 {
-		args = append(args, req.PageSize)
+		// Fetch one extra row to know whether there is a next page
+		args = append(args, req.PageSize+1)
 		queryBuilder.WriteString(fmt.Sprintf(" LIMIT $%d", len(args)))
-		if req.Page > 0 {
+
+		// The cursor replaces the offset
+		if req.Page > 0 && req.Cursor == "" {
 			offset := (req.Page - 1) * req.PageSize
 			args = append(args, offset)
 			queryBuilder.WriteString(fmt.Sprintf(" OFFSET $%d", len(args)))
 		}
 	}
 
-	rows, err := database.DB.Query(queryBuilder.String(), args...)
+	rows, err := s.db.QueryContext(ctx, queryBuilder.String(), args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, pageInfo, err
 	}
 	defer rows.Close()
 
+	keysetValues, keysetDest := keysetScanDest(ordering)
+	var cursorValues []any
+
 	comments := []models.Comment{}
 	for rows.Next() {
 		var comment models.Comment
-		if err := rows.Scan(
+		dest := []any{
 			&comment.ID,
 			&comment.PostID,
 			&comment.Summary,
+			&comment.ContentHTML,
 			&comment.CreatedAt,
 			&comment.UpdatedAt,
 			&comment.UserID,
 			&comment.Score,
+			&comment.Upvotes,
+			&comment.Downvotes,
 			&comment.ParentID,
 			&comment.Path,
 			&comment.NoOfReplies,
@@ -444,10 +545,13 @@ func ListComments(isAuthenticated bool, currentUserID int, req models.ListCommen
 			&comment.HasLongContent,
 			&comment.PostTitle,
 			&comment.Username,
+			&comment.TopicID,
 			&comment.TopicName,
 			&comment.MyVote,
-		); err != nil {
-			return nil, 0, err
+			&comment.IsSaved,
+		}
+		if err := rows.Scan(append(dest, keysetDest...)...); err != nil {
+			return nil, pageInfo, err
 		}
 
 		if !req.ShowPostTitle {
@@ -455,22 +559,34 @@ func ListComments(isAuthenticated bool, currentUserID int, req models.ListCommen
 		}
 
 		// if comment is deleted, clear summary
-		// content is not selected in this query
+		// content is not selected in this query, rendered content only for short comments
 		if comment.IsDeleted {
 			comment.Summary = ""
+			comment.ContentHTML = ""
 		}
 
 		comments = append(comments, comment)
+		if len(comments) == req.PageSize {
+			cursorValues = append([]any{}, keysetValues...)
+		}
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, 0, err
+		return nil, pageInfo, err
 	}
 
-	return comments, totalCount, nil
+	if req.PageSize > 0 && len(comments) > req.PageSize {
+		comments = comments[:req.PageSize]
+		pageInfo.NextCursor = encodeCursor(ordering, cursorValues...)
+	}
+
+	return comments, pageInfo, nil
 }
 
-func GetComment(isAuthenticated bool, userID, commentID int) (*models.Comment, error) {
+func (s *PostgresStore) GetComment(ctx context.Context, isAuthenticated bool, userID, commentID int) (*models.Comment, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "GetComment")
+	defer done()
+
 	var query string
 	args := []any{commentID}
 
@@ -478,11 +594,14 @@ func GetComment(isAuthenticated bool, userID, commentID int) (*models.Comment, e
 		c.id,
 		c.post_id,
 		c.content,
+		c.content_html,
 		c.summary,
 		c.created_at,
 		c.updated_at,
 		c.user_id,
 		COALESCE(c.score, 0),
+		c.upvotes,
+		c.downvotes,
 		c.parent_id,
 		COALESCE(c.path, ''),
 		COALESCE(c.no_of_replies, 0),
@@ -491,18 +610,23 @@ func GetComment(isAuthenticated bool, userID, commentID int) (*models.Comment, e
 		c.has_long_content,
 		p.title,
 		u.username,
+		COALESCE(p.topic_id, 0),
 		t.name`
 
 	if isAuthenticated {
 		query = fmt.Sprintf(`
 			SELECT %s,
-			COALESCE(v.vote_value, 0)
+			COALESCE(v.vote_value, 0),
+			s.user_id IS NOT NULL
 			FROM comments c
 
 			LEFT JOIN users u ON c.user_id = u.id
 			LEFT JOIN comment_votes v
 				ON c.id = v.comment_id
 				AND v.user_id = $2
+			LEFT JOIN saved_comments s
+				ON c.id = s.comment_id
+				AND s.user_id = $2
 			LEFT JOIN posts p ON c.post_id = p.id
 			LEFT JOIN topics t ON p.topic_id = t.id
 			WHERE c.id = $1`, selectFields)
@@ -511,7 +635,8 @@ func GetComment(isAuthenticated bool, userID, commentID int) (*models.Comment, e
 	} else {
 		query = fmt.Sprintf(`
 			SELECT %s,
-			0
+			0,
+			false
 			FROM comments c
 
 			LEFT JOIN users u ON c.user_id = u.id
@@ -521,15 +646,18 @@ func GetComment(isAuthenticated bool, userID, commentID int) (*models.Comment, e
 	}
 
 	comment := &models.Comment{}
-	err := database.DB.QueryRow(query, args...).Scan(
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(
 		&comment.ID,
 		&comment.PostID,
 		&comment.Content,
+		&comment.ContentHTML,
 		&comment.Summary,
 		&comment.CreatedAt,
 		&comment.UpdatedAt,
 		&comment.UserID,
 		&comment.Score,
+		&comment.Upvotes,
+		&comment.Downvotes,
 		&comment.ParentID,
 		&comment.Path,
 		&comment.NoOfReplies,
@@ -538,8 +666,10 @@ func GetComment(isAuthenticated bool, userID, commentID int) (*models.Comment, e
 		&comment.HasLongContent,
 		&comment.PostTitle,
 		&comment.Username,
+		&comment.TopicID,
 		&comment.TopicName,
 		&comment.MyVote,
+		&comment.IsSaved,
 	)
 
 	if err != nil {
@@ -552,6 +682,7 @@ func GetComment(isAuthenticated bool, userID, commentID int) (*models.Comment, e
 	// if comment is deleted, clear content and summary
 	if comment.IsDeleted {
 		comment.Content = ""
+		comment.ContentHTML = ""
 		comment.Summary = ""
 	}
 
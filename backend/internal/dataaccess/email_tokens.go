@@ -0,0 +1,156 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"cvwo/internal/telemetry"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// CreateEmailToken stores the hash of a token sent to the email
+// Earlier unused tokens of the user for the same purpose are deleted, so only the latest link works
+func (s *PostgresStore) CreateEmailToken(ctx context.Context, userID int, purpose, email, tokenHash string, expiresAt time.Time) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "CreateEmailToken")
+	defer done()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	deleteQuery := `
+		DELETE FROM email_tokens
+		WHERE user_id = $1 AND purpose = $2
+		AND used_at IS NULL`
+
+	if _, err := tx.ExecContext(ctx, deleteQuery, userID, purpose); err != nil {
+		return err
+	}
+
+	insertQuery := `
+		INSERT INTO email_tokens (
+			user_id,
+			purpose,
+			token_hash,
+			email,
+			created_at,
+			expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	if _, err := tx.ExecContext(ctx, insertQuery, userID, purpose, tokenHash, email, time.Now(), expiresAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Marks the token as used, returns its user and the email it was sent to
+// Returns NOT_FOUND_ERROR if the token does not exist, is for another purpose, was used or expired
+func consumeEmailToken(ctx context.Context, tx *sql.Tx, tokenHash, purpose string, now time.Time) (int, string, error) {
+	query := `
+		UPDATE email_tokens SET
+			used_at = $3
+		WHERE token_hash = $1 AND purpose = $2
+		AND used_at IS NULL
+		AND expires_at > $3
+		RETURNING user_id, email`
+
+	var userID int
+	var email string
+	err := tx.QueryRowContext(ctx, query, tokenHash, purpose, now).Scan(&userID, &email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, "", errors.New(constants.NOT_FOUND_ERROR)
+		}
+		return 0, "", err
+	}
+
+	return userID, email, nil
+}
+
+// Marks the user's email as verified, unless it changed since the token was sent to it
+// Returns whether it was verified
+func markEmailVerified(ctx context.Context, tx *sql.Tx, userID int, email string, now time.Time) (bool, error) {
+	query := `
+		UPDATE users SET
+			email_verified_at = COALESCE(email_verified_at, $3)
+		WHERE id = $1 AND email = $2`
+
+	result, err := tx.ExecContext(ctx, query, userID, email, now)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	return rowsAffected > 0, err
+}
+
+// VerifyEmail consumes an email verification token and marks the email it was sent to as verified
+// Returns NOT_FOUND_ERROR if the token is invalid, used or expired,
+// NO_ROWS_AFFECTED_ERROR if the user changed their email since
+func (s *PostgresStore) VerifyEmail(ctx context.Context, tokenHash string) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "VerifyEmail")
+	defer done()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	userID, email, err := consumeEmailToken(ctx, tx.Tx, tokenHash, constants.EMAIL_TOKEN_VERIFY_EMAIL, now)
+	if err != nil {
+		return err
+	}
+
+	verified, err := markEmailVerified(ctx, tx.Tx, userID, email, now)
+	if err != nil {
+		return err
+	}
+
+	if !verified {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	return tx.Commit()
+}
+
+// ResetPassword consumes a password reset token, sets the new password hash and revokes every session
+// Receiving the token proves the user owns the email, so it is marked as verified too
+// Returns NOT_FOUND_ERROR if the token is invalid, used or expired, or the user changed their email since
+func (s *PostgresStore) ResetPassword(ctx context.Context, tokenHash, newPassword string) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "ResetPassword")
+	defer done()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	userID, email, err := consumeEmailToken(ctx, tx.Tx, tokenHash, constants.EMAIL_TOKEN_RESET_PASSWORD, now)
+	if err != nil {
+		return err
+	}
+
+	if err := updateUserPassword(ctx, tx.Tx, userID, newPassword, 0); err != nil {
+		return err
+	}
+
+	// A token sent to a previous email must not take over the account
+	verified, err := markEmailVerified(ctx, tx.Tx, userID, email, now)
+	if err != nil {
+		return err
+	}
+
+	if !verified {
+		return errors.New(constants.NOT_FOUND_ERROR)
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,123 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"cvwo/internal/telemetry"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// LoginWithIdentity returns the user the identity provider account is linked to and records the login
+// Returns NOT_FOUND_ERROR if the account is not linked to a user
+func (s *PostgresStore) LoginWithIdentity(ctx context.Context, issuer, subject, email string) (int, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "LoginWithIdentity")
+	defer done()
+
+	query := `
+		UPDATE user_identities SET
+			last_login_at = $3,
+			email = NULLIF($4, '')
+		WHERE issuer = $1 AND subject = $2
+		RETURNING user_id`
+
+	var userID int
+	err := s.db.QueryRowContext(ctx, query, issuer, subject, time.Now(), email).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, errors.New(constants.NOT_FOUND_ERROR)
+		}
+		return 0, err
+	}
+	return userID, nil
+}
+
+// LinkIdentity links the identity provider account to an existing user, who signed in to both
+// If the provider verified the account's email and it is the user's, the user's email is marked as verified too
+// Returns ALREADY_EXISTS_ERROR if the account is already linked
+func (s *PostgresStore) LinkIdentity(ctx context.Context, userID int, identity models.UserIdentity, emailVerified bool) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "LinkIdentity")
+	defer done()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	if err := insertIdentity(ctx, tx.Tx, userID, identity, now); err != nil {
+		return err
+	}
+
+	if emailVerified {
+		if _, err := markEmailVerified(ctx, tx.Tx, userID, identity.Email, now); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// CreateUserWithIdentity provisions a user for the identity provider account and links it
+// The user has no usable password, so they log in with single sign-on until they reset it
+// Returns the new user ID, ALREADY_EXISTS_ERROR if the username is taken
+func (s *PostgresStore) CreateUserWithIdentity(ctx context.Context, user models.User, identity models.UserIdentity, emailVerified bool) (int, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "CreateUserWithIdentity")
+	defer done()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	var emailVerifiedAt *time.Time
+	if emailVerified {
+		emailVerifiedAt = &now
+	}
+
+	// An empty hash matches no password
+	query := `
+		INSERT INTO users (email, username, password, role, email_verified_at)
+		VALUES ($1, $2, '', $3, $4)
+		RETURNING id`
+
+	var userID int
+	err = tx.QueryRowContext(ctx, query, user.Email, user.Username, constants.ROLE_MEMBER, emailVerifiedAt).Scan(&userID)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" && pqErr.Constraint == "users_username_key" {
+			return 0, errors.New(constants.ALREADY_EXISTS_ERROR)
+		}
+		return 0, err
+	}
+
+	if err := insertIdentity(ctx, tx.Tx, userID, identity, now); err != nil {
+		return 0, err
+	}
+
+	return userID, tx.Commit()
+}
+
+func insertIdentity(ctx context.Context, tx *sql.Tx, userID int, identity models.UserIdentity, now time.Time) error {
+	query := `
+		INSERT INTO user_identities (
+			user_id,
+			issuer,
+			subject,
+			email,
+			created_at,
+			last_login_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $5)`
+
+	_, err := tx.ExecContext(ctx, query, userID, identity.Issuer, identity.Subject, identity.Email, now)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return errors.New(constants.ALREADY_EXISTS_ERROR)
+	}
+	return err
+}
@@ -0,0 +1,133 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"cvwo/internal/telemetry"
+	"time"
+)
+
+// RecordLoginAttempt logs a login attempt, successful or not
+// Times are stored in UTC, since lockouts are computed from them in Go and TIMESTAMP drops the zone
+func (s *PostgresStore) RecordLoginAttempt(ctx context.Context, attempt models.LoginAttempt) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "RecordLoginAttempt")
+	defer done()
+
+	query := `
+		INSERT INTO login_attempts (
+			user_id,
+			email,
+			ip_address,
+			user_agent,
+			succeeded,
+			failure_reason,
+			created_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)`
+
+	_, err := s.db.ExecContext(ctx, query,
+		attempt.UserID,
+		attempt.Email,
+		attempt.IPAddress,
+		attempt.UserAgent,
+		attempt.Succeeded,
+		attempt.FailureReason,
+		time.Now().UTC(),
+	)
+	return err
+}
+
+// CountEmailLoginFailures counts failed logins with the email since its last successful login, within the window
+// Wrong passwords and wrong two-factor codes count
+// Attempts rejected during a lockout are not counted, so they do not extend it
+// Returns the time of the last failure, nil if there were none
+func (s *PostgresStore) CountEmailLoginFailures(ctx context.Context, email string, since time.Time) (int, *time.Time, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "CountEmailLoginFailures")
+	defer done()
+
+	query := `
+		SELECT COUNT(*), MAX(created_at)
+		FROM login_attempts
+		WHERE email = $1
+		AND failure_reason <> $2
+		AND created_at > GREATEST($3, (
+			SELECT MAX(created_at) FROM login_attempts
+			WHERE email = $1 AND succeeded))`
+
+	var count int
+	var lastFailureAt *time.Time
+	err := s.db.QueryRowContext(ctx, query, email, constants.LOGIN_FAILURE_LOCKED_OUT, since.UTC()).Scan(&count, &lastFailureAt)
+	return count, lastFailureAt, err
+}
+
+// CountIPLoginFailures counts failed logins from the IP address within the window
+// Successful logins do not reset the count, or an attacker could reset it with their own account
+// Attempts rejected during a lockout are not counted
+// Returns the time of the last failure, nil if there were none
+func (s *PostgresStore) CountIPLoginFailures(ctx context.Context, ipAddress string, since time.Time) (int, *time.Time, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "CountIPLoginFailures")
+	defer done()
+
+	query := `
+		SELECT COUNT(*), MAX(created_at)
+		FROM login_attempts
+		WHERE ip_address = $1
+		AND failure_reason <> $2
+		AND created_at > $3`
+
+	var count int
+	var lastFailureAt *time.Time
+	err := s.db.QueryRowContext(ctx, query, ipAddress, constants.LOGIN_FAILURE_LOCKED_OUT, since.UTC()).Scan(&count, &lastFailureAt)
+	return count, lastFailureAt, err
+}
+
+// ListLoginAttempts returns the user's most recent login attempts, newest first
+func (s *PostgresStore) ListLoginAttempts(ctx context.Context, userID, limit int) ([]models.LoginAttempt, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "ListLoginAttempts")
+	defer done()
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			email,
+			ip_address,
+			user_agent,
+			succeeded,
+			COALESCE(failure_reason, ''),
+			created_at
+		FROM login_attempts
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := []models.LoginAttempt{}
+	for rows.Next() {
+		var attempt models.LoginAttempt
+		if err := rows.Scan(
+			&attempt.ID,
+			&attempt.UserID,
+			&attempt.Email,
+			&attempt.IPAddress,
+			&attempt.UserAgent,
+			&attempt.Succeeded,
+			&attempt.FailureReason,
+			&attempt.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attempts, nil
+}
@@ -0,0 +1,227 @@
+package dataaccess
+
+import (
+	"cmp"
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore implements every store in memory, for exercising the handlers without PostgreSQL
+// Rankings that PostgreSQL computes (hot, best, controversial) fall back to the score,
+// and search matches the posts and comments containing every word of the query
+// Every call completes at once, so contexts are not checked
+type MemoryStore struct {
+	mu sync.Mutex
+
+	users  map[int]*models.User
+	topics map[int]*models.Topic
+	// Old slugs of renamed topics
+	topicRedirects map[string]int
+	// Followed topic IDs by user ID
+	followedTopics map[int]map[int]bool
+
+	posts    map[int]*models.Post
+	comments map[int]*models.Comment
+	// Vote values by user ID, by post or comment ID
+	postVotes    map[int]map[int]int
+	commentVotes map[int]map[int]int
+	// Times saved by post or comment ID, by user ID
+	savedPosts    map[int]map[int]time.Time
+	savedComments map[int]map[int]time.Time
+	// Revisions by post or comment ID, oldest first
+	postRevisions    map[int][]models.PostRevision
+	commentRevisions map[int][]models.CommentRevision
+
+	sessions      map[int]*memorySession
+	loginAttempts []models.LoginAttempt
+	emailTokens   []*memoryEmailToken
+	apiTokens     map[int]*memoryAPIToken
+	identities    []*models.UserIdentity
+	// Two-factor enrollments and recovery codes by user ID, recovery codes map to whether they were used
+	twoFactors      map[int]*models.TwoFactor
+	recoveryCodes   map[int]map[string]bool
+	loginChallenges map[int]*memoryLoginChallenge
+
+	reports       map[int]*models.Report
+	moderationLog []models.ModerationLogEntry
+	// Ends of suspensions by user ID
+	suspendedUntil map[int]time.Time
+	// Appointment times by user ID, by topic ID
+	topicModerators map[int]map[int]time.Time
+
+	notifications map[int]*models.Notification
+	// Muted notification types by user ID
+	notificationMutes map[int]map[string]bool
+
+	lastID int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:          map[int]*models.User{},
+		topics:         map[int]*models.Topic{},
+		topicRedirects: map[string]int{},
+		followedTopics: map[int]map[int]bool{},
+		posts:          map[int]*models.Post{},
+		postVotes:      map[int]map[int]int{},
+		savedPosts:     map[int]map[int]time.Time{},
+		comments:       map[int]*models.Comment{},
+		commentVotes:   map[int]map[int]int{},
+		savedComments:  map[int]map[int]time.Time{},
+
+		postRevisions:     map[int][]models.PostRevision{},
+		commentRevisions:  map[int][]models.CommentRevision{},
+		sessions:          map[int]*memorySession{},
+		apiTokens:         map[int]*memoryAPIToken{},
+		twoFactors:        map[int]*models.TwoFactor{},
+		recoveryCodes:     map[int]map[string]bool{},
+		loginChallenges:   map[int]*memoryLoginChallenge{},
+		reports:           map[int]*models.Report{},
+		suspendedUntil:    map[int]time.Time{},
+		topicModerators:   map[int]map[int]time.Time{},
+		notifications:     map[int]*models.Notification{},
+		notificationMutes: map[int]map[string]bool{},
+	}
+}
+
+// NewMemoryStores returns the stores backed by one empty in-memory store
+func NewMemoryStores() Stores {
+	return storesOf(NewMemoryStore())
+}
+
+// IDs are shared by all tables, which is enough to keep them unique per table
+func (m *MemoryStore) nextID() int {
+	m.lastID++
+	return m.lastID
+}
+
+// Sums the scores of the user's posts and comments, as the karma query does
+func (m *MemoryStore) updateKarma(userID int) {
+	user, ok := m.users[userID]
+	if !ok {
+		return
+	}
+
+	karma := 0
+	for _, post := range m.posts {
+		if post.UserID == userID {
+			karma += post.Score
+		}
+	}
+	for _, comment := range m.comments {
+		if comment.UserID == userID {
+			karma += comment.Score
+		}
+	}
+	user.Karma = karma
+}
+
+// Recomputes the score and vote counts from the votes
+func tallyVotes(votes map[int]int) (score, upvotes, downvotes int) {
+	for _, value := range votes {
+		score += value
+		if value > 0 {
+			upvotes++
+		} else if value < 0 {
+			downvotes++
+		}
+	}
+	return score, upvotes, downvotes
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// A sort key of an in-memory listing, the counterpart of a keysetColumn
+type memorySortKey[T any] struct {
+	compare func(a, b T) int
+	desc    bool
+}
+
+// Sorts the items by the keys, each later key breaking ties of the earlier ones
+func sortByKeys[T any](items []T, keys ...memorySortKey[T]) {
+	slices.SortStableFunc(items, func(a, b T) int {
+		for _, key := range keys {
+			if c := key.compare(a, b); c != 0 {
+				if key.desc {
+					return -c
+				}
+				return c
+			}
+		}
+		return 0
+	})
+}
+
+func byInt[T any](value func(T) int) func(a, b T) int {
+	return func(a, b T) int { return cmp.Compare(value(a), value(b)) }
+}
+
+func byString[T any](value func(T) string) func(a, b T) int {
+	return func(a, b T) int { return strings.Compare(value(a), value(b)) }
+}
+
+func byTime[T any](value func(T) time.Time) func(a, b T) int {
+	return func(a, b T) int { return value(a).Compare(value(b)) }
+}
+
+func byBool[T any](value func(T) bool) func(a, b T) int {
+	return func(a, b T) int {
+		switch {
+		case value(a) == value(b):
+			return 0
+		case value(a):
+			return 1
+		}
+		return -1
+	}
+}
+
+// In-memory cursors hold the offset of the next page, tied to the ordering like keyset cursors
+func memoryCursorOrdering(ordering string) []keysetColumn {
+	return []keysetColumn{{expr: "memory:" + ordering}}
+}
+
+// Pages the sorted items the way the keyset queries do
+// pageSize 0 returns every item, only comment listings pass it through, the other lists default it first
+func paginate[T any](items []T, ordering string, page, pageSize int, cursorToken string, includeCount *bool) ([]T, models.PageInfo, error) {
+	pageInfo := models.PageInfo{}
+	if shouldCount(includeCount) {
+		count := len(items)
+		pageInfo.Count = &count
+	}
+
+	columns := memoryCursorOrdering(ordering)
+	offset := 0
+	if cursorToken != "" {
+		values, err := decodeCursor(cursorToken, columns)
+		if err != nil {
+			return nil, pageInfo, err
+		}
+		value := values[0].(int64)
+		if value < 0 {
+			return nil, pageInfo, errors.New(constants.INVALID_CURSOR_ERROR)
+		}
+		offset = int(value)
+	} else if page > 0 && pageSize > 0 {
+		offset = (page - 1) * pageSize
+	}
+
+	if offset >= len(items) {
+		return []T{}, pageInfo, nil
+	}
+	items = items[offset:]
+
+	if pageSize > 0 && len(items) > pageSize {
+		items = items[:pageSize]
+		pageInfo.NextCursor = encodeCursor(columns, offset+pageSize)
+	}
+
+	return slices.Clone(items), pageInfo, nil
+}
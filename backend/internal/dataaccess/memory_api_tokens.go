@@ -0,0 +1,89 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"errors"
+	"slices"
+	"time"
+)
+
+// An API token with the hash of its secret
+type memoryAPIToken struct {
+	models.APIToken
+	tokenHash string
+	revoked   bool
+}
+
+func (m *MemoryStore) CreateAPIToken(ctx context.Context, token models.APIToken, tokenHash string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token.ID = m.nextID()
+	token.Role = ""
+	token.Scopes = slices.Clone(token.Scopes)
+	token.LastUsedAt = nil
+	m.apiTokens[token.ID] = &memoryAPIToken{APIToken: token, tokenHash: tokenHash}
+	return token.ID, nil
+}
+
+func (m *MemoryStore) ListAPITokens(ctx context.Context, userID int) ([]models.APIToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tokens := []models.APIToken{}
+	for _, token := range m.apiTokens {
+		if token.UserID == userID && !token.revoked {
+			tokens = append(tokens, token.view())
+		}
+	}
+
+	sortByKeys(tokens,
+		memorySortKey[models.APIToken]{byTime(func(t models.APIToken) time.Time { return t.CreatedAt }), true},
+		memorySortKey[models.APIToken]{byInt(func(t models.APIToken) int { return t.ID }), true})
+	return tokens, nil
+}
+
+func (m *MemoryStore) RevokeAPIToken(ctx context.Context, userID, tokenID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token, ok := m.apiTokens[tokenID]
+	if !ok || token.UserID != userID || token.revoked {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	token.revoked = true
+	return nil
+}
+
+func (m *MemoryStore) AuthenticateAPIToken(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for _, token := range m.apiTokens {
+		if token.tokenHash != tokenHash || token.revoked || (token.ExpiresAt != nil && !token.ExpiresAt.After(now)) {
+			continue
+		}
+
+		user, ok := m.users[token.UserID]
+		if !ok {
+			break
+		}
+
+		token.LastUsedAt = &now
+		view := token.view()
+		view.Role = user.Role
+		return &view, nil
+	}
+	return nil, errors.New(constants.NOT_FOUND_ERROR)
+}
+
+// Copies the token, so callers cannot change the stored scopes
+func (token *memoryAPIToken) view() models.APIToken {
+	view := token.APIToken
+	view.Scopes = slices.Clone(token.Scopes)
+	return view
+}
@@ -0,0 +1,256 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"cvwo/internal/utils"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+func (m *MemoryStore) CreateComment(ctx context.Context, comment models.Comment) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	post, postExists := m.posts[comment.PostID]
+	if postExists && post.IsLocked {
+		return 0, errors.New(constants.POST_LOCKED_ERROR)
+	}
+	postExists = postExists && !post.IsDeleted
+
+	var parent *models.Comment
+	if comment.ParentID != nil {
+		var ok bool
+		parent, ok = m.comments[*comment.ParentID]
+		if !ok {
+			return 0, errors.New(constants.NOT_FOUND_ERROR)
+		}
+		if parent.PostID != comment.PostID {
+			return 0, errors.New("parent comment does not belong to the same post")
+		}
+	} else if !postExists {
+		return 0, errors.New(constants.NOT_FOUND_ERROR)
+	}
+
+	now := time.Now()
+	utils.RenderComment(&comment)
+	comment.ID = m.nextID()
+	comment.CreatedAt = now
+	comment.UpdatedAt = now
+	comment.Score, comment.Upvotes, comment.Downvotes, comment.NoOfReplies = 0, 0, 0, 0
+	comment.IsDeleted, comment.DeletedAt = false, nil
+
+	if parent != nil {
+		comment.Path = fmt.Sprintf("%s.%d", parent.Path, comment.ID)
+		parent.NoOfReplies++
+	} else {
+		comment.Path = fmt.Sprintf("%d", comment.ID)
+	}
+	m.comments[comment.ID] = &comment
+	m.addCommentRevision(&comment, comment.UserID, nil, now)
+
+	if post != nil {
+		post.NoOfComments++
+	}
+	return comment.ID, nil
+}
+
+func (m *MemoryStore) UpdateComment(ctx context.Context, comment *models.Comment, editorID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.editComment(comment, editorID, nil)
+}
+
+// Saves the new content of a comment and records it as a revision
+// Returns NO_ROWS_AFFECTED_ERROR if the comment does not exist
+func (m *MemoryStore) editComment(comment *models.Comment, editorID int, restoredFrom *int) error {
+	stored, ok := m.comments[comment.ID]
+	if !ok {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	now := time.Now()
+	utils.RenderComment(comment)
+	stored.Content = comment.Content
+	stored.ContentHTML = comment.ContentHTML
+	stored.Summary = comment.Summary
+	stored.HasLongContent = comment.HasLongContent
+	stored.UpdatedAt = now
+	m.addCommentRevision(stored, editorID, restoredFrom, now)
+	return nil
+}
+
+func (m *MemoryStore) DeleteComment(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.removeComment(id)
+}
+
+// Returns NO_ROWS_AFFECTED_ERROR if the comment does not exist or is already deleted
+func (m *MemoryStore) removeComment(id int) error {
+	comment, ok := m.comments[id]
+	if !ok || comment.IsDeleted {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	now := time.Now()
+	comment.IsDeleted = true
+	comment.DeletedAt = &now
+
+	if post, ok := m.posts[comment.PostID]; ok {
+		post.NoOfComments--
+	}
+	return nil
+}
+
+func (m *MemoryStore) VoteComment(ctx context.Context, vote models.CommentVote) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	comment, ok := m.comments[vote.CommentID]
+	if !ok || comment.IsDeleted {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	if m.commentVotes[comment.ID] == nil {
+		m.commentVotes[comment.ID] = map[int]int{}
+	}
+	m.commentVotes[comment.ID][vote.UserID] = vote.VoteValue
+
+	comment.Score, comment.Upvotes, comment.Downvotes = tallyVotes(m.commentVotes[comment.ID])
+	m.updateKarma(comment.UserID)
+	return nil
+}
+
+func (m *MemoryStore) ListComments(ctx context.Context, isAuthenticated bool, currentUserID int, req models.ListCommentsRequest) ([]models.Comment, models.PageInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Nothing is saved without a user
+	if req.SavedOnly && !isAuthenticated {
+		return []models.Comment{}, models.PageInfo{}, nil
+	}
+
+	// Replies anywhere below the parent, unless only its direct replies are asked for
+	var subtree *string
+	if req.ParentID != nil && !req.OnlyTopLevel {
+		prefix := ""
+		if parent, ok := m.comments[*req.ParentID]; ok {
+			prefix = parent.Path + "."
+		}
+		subtree = &prefix
+	}
+
+	since := windowStart(req.Window)
+	comments := []models.Comment{}
+	for _, comment := range m.comments {
+		switch {
+		case req.SavedOnly && m.savedComments[currentUserID][comment.ID].IsZero(),
+			req.Search != "" && !containsFold(comment.Content, req.Search),
+			!req.ShowDeletedComments && comment.IsDeleted,
+			req.PostID != nil && comment.PostID != *req.PostID,
+			req.UserID != nil && comment.UserID != *req.UserID,
+			req.OnlyTopLevel && req.ParentID != nil && (comment.ParentID == nil || *comment.ParentID != *req.ParentID),
+			req.OnlyTopLevel && req.ParentID == nil && comment.ParentID != nil,
+			subtree != nil && (*subtree == "" || !strings.HasPrefix(comment.Path, *subtree)),
+			since != nil && comment.CreatedAt.Before(*since):
+			continue
+		}
+
+		// Content is not listed, rendered content only for short comments
+		view := m.commentView(comment, isAuthenticated, currentUserID)
+		view.Content = ""
+		if view.HasLongContent {
+			view.ContentHTML = ""
+		}
+		if !req.ShowPostTitle {
+			view.PostTitle = ""
+		}
+		comments = append(comments, view)
+	}
+
+	desc := req.OrderBy != constants.SORT_ASC
+	keys := []memorySortKey[models.Comment]{}
+	ordering := req.Sort
+	switch {
+	case req.SavedOnly && req.Sort == constants.ORDER_BY_SAVED:
+		saved := m.savedComments[currentUserID]
+		keys = append(keys, memorySortKey[models.Comment]{byTime(func(c models.Comment) time.Time { return saved[c.ID] }), desc})
+	case rankingColumn(req.Sort) == "created_at":
+		ordering = constants.ORDER_BY_NEW
+		keys = append(keys, memorySortKey[models.Comment]{byTime(func(c models.Comment) time.Time { return c.CreatedAt }), desc})
+	default:
+		keys = append(keys, memorySortKey[models.Comment]{byInt(func(c models.Comment) int { return c.Score }), desc})
+	}
+	keys = append(keys, memorySortKey[models.Comment]{byInt(func(c models.Comment) int { return c.ID }), desc})
+	sortByKeys(comments, keys...)
+
+	return paginate(comments, "comments "+ordering+" "+req.OrderBy, req.Page, req.PageSize, req.Cursor, req.IncludeCount)
+}
+
+func (m *MemoryStore) GetComment(ctx context.Context, isAuthenticated bool, userID, commentID int) (*models.Comment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	comment, ok := m.comments[commentID]
+	if !ok {
+		return nil, errors.New(constants.NOT_FOUND_ERROR)
+	}
+
+	view := m.commentView(comment, isAuthenticated, userID)
+	return &view, nil
+}
+
+func (m *MemoryStore) SaveComment(ctx context.Context, userID, commentID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.savedComments[userID] == nil {
+		m.savedComments[userID] = map[int]time.Time{}
+	}
+	if _, ok := m.savedComments[userID][commentID]; !ok {
+		m.savedComments[userID][commentID] = time.Now()
+	}
+	return nil
+}
+
+func (m *MemoryStore) UnsaveComment(ctx context.Context, userID, commentID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.savedComments[userID], commentID)
+	return nil
+}
+
+// Copies the comment with its post, topic, author and the user's vote and bookmark
+// Deleted comments have their content and summary cleared
+func (m *MemoryStore) commentView(comment *models.Comment, isAuthenticated bool, userID int) models.Comment {
+	view := *comment
+	if post, ok := m.posts[comment.PostID]; ok {
+		view.PostTitle = post.Title
+		view.TopicID = post.TopicID
+		if topic, ok := m.topics[post.TopicID]; ok {
+			view.TopicName = topic.Name
+		}
+	}
+	if user, ok := m.users[comment.UserID]; ok {
+		view.Username = user.Username
+	}
+
+	if isAuthenticated {
+		view.MyVote = m.commentVotes[comment.ID][userID]
+		_, view.IsSaved = m.savedComments[userID][comment.ID]
+	}
+
+	if view.IsDeleted {
+		view.Content = ""
+		view.ContentHTML = ""
+		view.Summary = ""
+	}
+	return view
+}
@@ -0,0 +1,99 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"errors"
+	"slices"
+	"time"
+)
+
+type memoryEmailToken struct {
+	userID    int
+	purpose   string
+	email     string
+	tokenHash string
+	expiresAt time.Time
+	used      bool
+}
+
+func (m *MemoryStore) CreateEmailToken(ctx context.Context, userID int, purpose, email, tokenHash string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.emailTokens = slices.DeleteFunc(m.emailTokens, func(token *memoryEmailToken) bool {
+		return token.userID == userID && token.purpose == purpose && !token.used
+	})
+	m.emailTokens = append(m.emailTokens, &memoryEmailToken{
+		userID:    userID,
+		purpose:   purpose,
+		email:     email,
+		tokenHash: tokenHash,
+		expiresAt: expiresAt,
+	})
+	return nil
+}
+
+// The token is only used up once the email is verified, as the transaction would be rolled back otherwise
+func (m *MemoryStore) VerifyEmail(ctx context.Context, tokenHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	token := m.emailToken(tokenHash, constants.EMAIL_TOKEN_VERIFY_EMAIL, now)
+	if token == nil {
+		return errors.New(constants.NOT_FOUND_ERROR)
+	}
+
+	if !m.markEmailVerified(token.userID, token.email, now) {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	token.used = true
+	return nil
+}
+
+func (m *MemoryStore) ResetPassword(ctx context.Context, tokenHash, newPassword string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	token := m.emailToken(tokenHash, constants.EMAIL_TOKEN_RESET_PASSWORD, now)
+	if token == nil {
+		return errors.New(constants.NOT_FOUND_ERROR)
+	}
+
+	// A token sent to a previous email must not take over the account
+	if !m.markEmailVerified(token.userID, token.email, now) {
+		return errors.New(constants.NOT_FOUND_ERROR)
+	}
+
+	m.users[token.userID].Password = newPassword
+	m.revokeSessions(token.userID, 0)
+	token.used = true
+	return nil
+}
+
+// Returns the unused and unexpired token for the purpose, nil if there is none
+func (m *MemoryStore) emailToken(tokenHash, purpose string, now time.Time) *memoryEmailToken {
+	for _, token := range m.emailTokens {
+		if token.tokenHash == tokenHash && token.purpose == purpose && !token.used && token.expiresAt.After(now) {
+			return token
+		}
+	}
+	return nil
+}
+
+// Marks the user's email as verified, unless it changed since the token was sent to it
+// Returns whether it was verified
+func (m *MemoryStore) markEmailVerified(userID int, email string, now time.Time) bool {
+	user, ok := m.users[userID]
+	if !ok || user.Email != email {
+		return false
+	}
+
+	if user.EmailVerifiedAt == nil {
+		user.EmailVerifiedAt = &now
+	}
+	return true
+}
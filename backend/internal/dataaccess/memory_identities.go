@@ -0,0 +1,96 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"errors"
+	"time"
+)
+
+func (m *MemoryStore) LoginWithIdentity(ctx context.Context, issuer, subject, email string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	identity := m.identity(issuer, subject)
+	if identity == nil {
+		return 0, errors.New(constants.NOT_FOUND_ERROR)
+	}
+
+	identity.LastLoginAt = time.Now()
+	identity.Email = email
+	return identity.UserID, nil
+}
+
+func (m *MemoryStore) LinkIdentity(ctx context.Context, userID int, identity models.UserIdentity, emailVerified bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if err := m.insertIdentity(userID, identity, now); err != nil {
+		return err
+	}
+
+	if emailVerified {
+		m.markEmailVerified(userID, identity.Email, now)
+	}
+	return nil
+}
+
+// Returns ALREADY_EXISTS_ERROR if the username is taken, as PostgreSQL would violate its unique constraint
+func (m *MemoryStore) CreateUserWithIdentity(ctx context.Context, user models.User, identity models.UserIdentity, emailVerified bool) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.userByUsername(user.Username) != nil {
+		return 0, errors.New(constants.ALREADY_EXISTS_ERROR)
+	}
+	if m.userByEmail(user.Email) != nil {
+		return 0, errors.New("email is already used")
+	}
+	if m.identity(identity.Issuer, identity.Subject) != nil {
+		return 0, errors.New(constants.ALREADY_EXISTS_ERROR)
+	}
+
+	now := time.Now()
+	var emailVerifiedAt *time.Time
+	if emailVerified {
+		emailVerifiedAt = &now
+	}
+
+	// An empty hash matches no password
+	created := &models.User{
+		ID:              m.nextID(),
+		Email:           user.Email,
+		Username:        user.Username,
+		Role:            constants.ROLE_MEMBER,
+		CreatedAt:       now,
+		EmailVerifiedAt: emailVerifiedAt,
+	}
+	m.users[created.ID] = created
+
+	return created.ID, m.insertIdentity(created.ID, identity, now)
+}
+
+// Returns ALREADY_EXISTS_ERROR if the identity provider account is already linked
+func (m *MemoryStore) insertIdentity(userID int, identity models.UserIdentity, now time.Time) error {
+	if m.identity(identity.Issuer, identity.Subject) != nil {
+		return errors.New(constants.ALREADY_EXISTS_ERROR)
+	}
+
+	identity.ID = m.nextID()
+	identity.UserID = userID
+	identity.CreatedAt = now
+	identity.LastLoginAt = now
+	m.identities = append(m.identities, &identity)
+	return nil
+}
+
+func (m *MemoryStore) identity(issuer, subject string) *models.UserIdentity {
+	for _, identity := range m.identities {
+		if identity.Issuer == issuer && identity.Subject == subject {
+			return identity
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"time"
+)
+
+func (m *MemoryStore) RecordLoginAttempt(ctx context.Context, attempt models.LoginAttempt) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempt.ID = m.nextID()
+	attempt.CreatedAt = time.Now().UTC()
+	m.loginAttempts = append(m.loginAttempts, attempt)
+	return nil
+}
+
+func (m *MemoryStore) CountEmailLoginFailures(ctx context.Context, email string, since time.Time) (int, *time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Failures before the last successful login are not counted
+	for _, attempt := range m.loginAttempts {
+		if attempt.Email == email && attempt.Succeeded && attempt.CreatedAt.After(since) {
+			since = attempt.CreatedAt
+		}
+	}
+
+	count, lastFailureAt := m.countLoginFailures(func(attempt models.LoginAttempt) bool {
+		return attempt.Email == email
+	}, since)
+	return count, lastFailureAt, nil
+}
+
+func (m *MemoryStore) CountIPLoginFailures(ctx context.Context, ipAddress string, since time.Time) (int, *time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count, lastFailureAt := m.countLoginFailures(func(attempt models.LoginAttempt) bool {
+		return attempt.IPAddress == ipAddress
+	}, since)
+	return count, lastFailureAt, nil
+}
+
+func (m *MemoryStore) ListLoginAttempts(ctx context.Context, userID, limit int) ([]models.LoginAttempt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempts := []models.LoginAttempt{}
+	for _, attempt := range m.loginAttempts {
+		if attempt.UserID != nil && *attempt.UserID == userID {
+			attempts = append(attempts, attempt)
+		}
+	}
+
+	sortByKeys(attempts,
+		memorySortKey[models.LoginAttempt]{byTime(func(a models.LoginAttempt) time.Time { return a.CreatedAt }), true},
+		memorySortKey[models.LoginAttempt]{byInt(func(a models.LoginAttempt) int { return a.ID }), true})
+	if len(attempts) > limit {
+		attempts = attempts[:limit]
+	}
+	return attempts, nil
+}
+
+// Counts the failures the filter matches after since, leaving out attempts rejected during a lockout
+// Returns the time of the last failure, nil if there were none
+func (m *MemoryStore) countLoginFailures(matches func(attempt models.LoginAttempt) bool, since time.Time) (int, *time.Time) {
+	count := 0
+	var lastFailureAt *time.Time
+	for _, attempt := range m.loginAttempts {
+		if attempt.Succeeded || attempt.FailureReason == constants.LOGIN_FAILURE_LOCKED_OUT ||
+			!attempt.CreatedAt.After(since) || !matches(attempt) {
+			continue
+		}
+
+		count++
+		if lastFailureAt == nil || attempt.CreatedAt.After(*lastFailureAt) {
+			createdAt := attempt.CreatedAt
+			lastFailureAt = &createdAt
+		}
+	}
+	return count, lastFailureAt
+}
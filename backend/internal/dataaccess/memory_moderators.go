@@ -0,0 +1,84 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"errors"
+	"slices"
+	"time"
+)
+
+func (m *MemoryStore) IsTopicModerator(ctx context.Context, userID, topicID int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.topicModerators[topicID][userID]
+	return ok, nil
+}
+
+func (m *MemoryStore) AddTopicModerator(ctx context.Context, userID, topicID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.topicModerators[topicID][userID]; ok {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	if m.topicModerators[topicID] == nil {
+		m.topicModerators[topicID] = map[int]time.Time{}
+	}
+	m.topicModerators[topicID][userID] = time.Now()
+	return nil
+}
+
+func (m *MemoryStore) RemoveTopicModerator(ctx context.Context, userID, topicID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.topicModerators[topicID][userID]; !ok {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	delete(m.topicModerators[topicID], userID)
+	return nil
+}
+
+func (m *MemoryStore) ListTopicModerators(ctx context.Context, topicID int) ([]models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	appointed := m.topicModerators[topicID]
+	users := []models.User{}
+	for userID := range appointed {
+		if user, ok := m.users[userID]; ok {
+			users = append(users, models.User{
+				ID:        user.ID,
+				Username:  user.Username,
+				Karma:     user.Karma,
+				Role:      user.Role,
+				CreatedAt: user.CreatedAt,
+			})
+		}
+	}
+
+	sortByKeys(users,
+		memorySortKey[models.User]{byTime(func(u models.User) time.Time { return appointed[u.ID] }), false},
+		memorySortKey[models.User]{byInt(func(u models.User) int { return u.ID }), false})
+	return users, nil
+}
+
+func (m *MemoryStore) ListModeratedTopicIDs(ctx context.Context, userID int) ([]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	topicIDs := []int{}
+	for topicID, moderators := range m.topicModerators {
+		if _, ok := moderators[userID]; ok {
+			topicIDs = append(topicIDs, topicID)
+		}
+	}
+
+	slices.Sort(topicIDs)
+	return topicIDs, nil
+}
@@ -0,0 +1,163 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"slices"
+	"time"
+)
+
+func (m *MemoryStore) CreateNotifications(ctx context.Context, notification models.Notification, recipientIDs []int) ([]models.Notification, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.insertNotifications(notification, recipientIDs), nil
+}
+
+func (m *MemoryStore) CreateTopicFollowerNotifications(ctx context.Context, notification models.Notification, excludeIDs []int) ([]models.Notification, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if notification.TopicID == nil {
+		return []models.Notification{}, nil
+	}
+
+	recipientIDs := []int{}
+	for userID, topicIDs := range m.followedTopics {
+		if topicIDs[*notification.TopicID] && !slices.Contains(excludeIDs, userID) {
+			recipientIDs = append(recipientIDs, userID)
+		}
+	}
+	slices.Sort(recipientIDs)
+
+	return m.insertNotifications(notification, recipientIDs), nil
+}
+
+// Notifies each recipient once, skipping the actor and recipients who muted the type
+func (m *MemoryStore) insertNotifications(notification models.Notification, recipientIDs []int) []models.Notification {
+	now := time.Now()
+	notified := map[int]bool{}
+	notifications := []models.Notification{}
+	for _, userID := range recipientIDs {
+		if notified[userID] || (notification.ActorID != nil && *notification.ActorID == userID) ||
+			m.notificationMutes[userID][notification.Type] {
+			continue
+		}
+		notified[userID] = true
+
+		created := notification
+		created.ID = m.nextID()
+		created.UserID = userID
+		created.CreatedAt = now
+		created.ReadAt = nil
+		stored := created
+		m.notifications[created.ID] = &stored
+		notifications = append(notifications, created)
+	}
+	return notifications
+}
+
+func (m *MemoryStore) ListNotifications(ctx context.Context, userID int, req models.ListNotificationsRequest) ([]models.Notification, models.PageInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	notifications := []models.Notification{}
+	for _, notification := range m.notifications {
+		if notification.UserID != userID || (req.UnreadOnly && notification.ReadAt != nil) {
+			continue
+		}
+		notifications = append(notifications, m.notificationView(notification))
+	}
+
+	sortByKeys(notifications,
+		memorySortKey[models.Notification]{byTime(func(n models.Notification) time.Time { return n.CreatedAt }), true},
+		memorySortKey[models.Notification]{byInt(func(n models.Notification) int { return n.ID }), true})
+
+	if req.PageSize <= 0 {
+		req.PageSize = constants.DEFAULT_NOTIFICATIONS_PAGE_SIZE
+	}
+	if req.PageSize > constants.MAX_NOTIFICATIONS_PAGE_SIZE {
+		req.PageSize = constants.MAX_NOTIFICATIONS_PAGE_SIZE
+	}
+
+	return paginate(notifications, "notifications", 0, req.PageSize, req.Cursor, req.IncludeCount)
+}
+
+func (m *MemoryStore) CountUnreadNotifications(ctx context.Context, userID int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	for _, notification := range m.notifications {
+		if notification.UserID == userID && notification.ReadAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *MemoryStore) MarkNotificationsRead(ctx context.Context, userID int, notificationIDs []int) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var marked int64
+	for _, notification := range m.notifications {
+		if notification.UserID != userID || notification.ReadAt != nil ||
+			(len(notificationIDs) > 0 && !slices.Contains(notificationIDs, notification.ID)) {
+			continue
+		}
+		notification.ReadAt = &now
+		marked++
+	}
+	return marked, nil
+}
+
+func (m *MemoryStore) ListNotificationMutes(ctx context.Context, userID int) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mutedTypes := []string{}
+	for notificationType := range m.notificationMutes[userID] {
+		mutedTypes = append(mutedTypes, notificationType)
+	}
+	slices.Sort(mutedTypes)
+	return mutedTypes, nil
+}
+
+func (m *MemoryStore) SetNotificationMutes(ctx context.Context, userID int, mutedTypes []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mutes := map[string]bool{}
+	for _, notificationType := range mutedTypes {
+		mutes[notificationType] = true
+	}
+	m.notificationMutes[userID] = mutes
+	return nil
+}
+
+// Copies the notification with the names of its actor, post, comment and topic
+// Titles and summaries of deleted posts and comments are blanked
+func (m *MemoryStore) notificationView(notification *models.Notification) models.Notification {
+	view := *notification
+	view.ActorUsername = m.username(notification.ActorID)
+	if notification.PostID != nil {
+		if post, ok := m.posts[*notification.PostID]; ok && !post.IsDeleted {
+			view.PostTitle = post.Title
+		}
+	}
+	if notification.CommentID != nil {
+		if comment, ok := m.comments[*notification.CommentID]; ok && !comment.IsDeleted {
+			view.CommentSummary = comment.Summary
+		}
+	}
+	if notification.TopicID != nil {
+		if topic, ok := m.topics[*notification.TopicID]; ok {
+			view.TopicName = topic.Name
+			view.TopicSlug = topic.Slug
+		}
+	}
+	return view
+}
@@ -0,0 +1,265 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"cvwo/internal/utils"
+	"errors"
+	"time"
+)
+
+func (m *MemoryStore) CreatePost(ctx context.Context, post models.Post) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	topic, ok := m.topics[post.TopicID]
+	if !ok {
+		return 0, errors.New(constants.NOT_FOUND_ERROR)
+	}
+	if topic.IsArchived {
+		return 0, errors.New(constants.TOPIC_ARCHIVED_ERROR)
+	}
+
+	now := time.Now()
+	utils.RenderPost(&post)
+	post.ID = m.nextID()
+	post.CreatedAt = now
+	post.UpdatedAt = now
+	post.PinnedCommentID = nil
+	post.Score, post.Upvotes, post.Downvotes, post.NoOfComments = 0, 0, 0, 0
+	post.IsDeleted, post.DeletedAt, post.IsLocked, post.IsPinned = false, nil, false, false
+	m.posts[post.ID] = &post
+	m.addPostRevision(&post, post.UserID, nil, now)
+
+	topic.NoOfPosts++
+	return post.ID, nil
+}
+
+func (m *MemoryStore) UpdatePost(ctx context.Context, post *models.Post, editorID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.editPost(post, editorID, nil)
+}
+
+// Saves the new title and content of a post that is not deleted and records them as a revision
+// Returns NO_ROWS_AFFECTED_ERROR if the post is deleted
+func (m *MemoryStore) editPost(post *models.Post, editorID int, restoredFrom *int) error {
+	stored, ok := m.posts[post.ID]
+	if !ok || stored.IsDeleted {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	now := time.Now()
+	utils.RenderPost(post)
+	stored.Title = post.Title
+	stored.Summary = post.Summary
+	stored.Content = post.Content
+	stored.ContentHTML = post.ContentHTML
+	stored.UpdatedAt = now
+	m.addPostRevision(stored, editorID, restoredFrom, now)
+	return nil
+}
+
+func (m *MemoryStore) DeletePost(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.removePost(id)
+}
+
+// Returns NO_ROWS_AFFECTED_ERROR if the post does not exist or is already deleted
+func (m *MemoryStore) removePost(id int) error {
+	post, ok := m.posts[id]
+	if !ok || post.IsDeleted {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	now := time.Now()
+	post.IsDeleted = true
+	post.DeletedAt = &now
+
+	if topic, ok := m.topics[post.TopicID]; ok {
+		topic.NoOfPosts--
+	}
+	return nil
+}
+
+func (m *MemoryStore) VotePost(ctx context.Context, vote models.PostVote) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	post, ok := m.posts[vote.PostID]
+	if !ok || post.IsDeleted {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	if m.postVotes[post.ID] == nil {
+		m.postVotes[post.ID] = map[int]int{}
+	}
+	m.postVotes[post.ID][vote.UserID] = vote.VoteValue
+
+	post.Score, post.Upvotes, post.Downvotes = tallyVotes(m.postVotes[post.ID])
+	m.updateKarma(post.UserID)
+	return nil
+}
+
+func (m *MemoryStore) ListPosts(ctx context.Context, isAuthenticated bool, currentUserID int, req models.ListPostsRequest) ([]models.Post, models.PageInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Nothing is saved without a user
+	if req.SavedOnly && !isAuthenticated {
+		return []models.Post{}, models.PageInfo{}, nil
+	}
+
+	since := windowStart(req.Window)
+	posts := []models.Post{}
+	for _, post := range m.posts {
+		switch {
+		case req.FilterFollowingTopics && isAuthenticated && !m.followedTopics[currentUserID][post.TopicID],
+			req.SavedOnly && m.savedPosts[currentUserID][post.ID].IsZero(),
+			req.Search != "" && !containsFold(post.Title, req.Search),
+			!req.ShowDeletedPosts && post.IsDeleted,
+			req.UserID != nil && post.UserID != *req.UserID,
+			req.TopicID != nil && post.TopicID != *req.TopicID,
+			since != nil && post.CreatedAt.Before(*since):
+			continue
+		}
+
+		// Content is not listed
+		view := m.postView(post, isAuthenticated, currentUserID)
+		view.Content = ""
+		view.ContentHTML = ""
+		view.PinnedCommentID = nil
+		posts = append(posts, view)
+	}
+
+	// Pinned posts come first within a topic
+	desc := req.OrderBy != constants.SORT_ASC
+	keys := []memorySortKey[models.Post]{}
+	if req.TopicID != nil {
+		keys = append(keys, memorySortKey[models.Post]{byBool(func(p models.Post) bool { return p.IsPinned }), true})
+	}
+
+	ordering := req.Sort
+	switch {
+	case req.SavedOnly && req.Sort == constants.ORDER_BY_SAVED:
+		saved := m.savedPosts[currentUserID]
+		keys = append(keys, memorySortKey[models.Post]{byTime(func(p models.Post) time.Time { return saved[p.ID] }), desc})
+	case req.Sort == constants.ORDER_BY_COMMENTS:
+		keys = append(keys, memorySortKey[models.Post]{byInt(func(p models.Post) int { return p.NoOfComments }), desc})
+	case rankingColumn(req.Sort) == "created_at":
+		ordering = constants.ORDER_BY_NEW
+		keys = append(keys, memorySortKey[models.Post]{byTime(func(p models.Post) time.Time { return p.CreatedAt }), desc})
+	default:
+		keys = append(keys, memorySortKey[models.Post]{byInt(func(p models.Post) int { return p.Score }), desc})
+	}
+	keys = append(keys, memorySortKey[models.Post]{byInt(func(p models.Post) int { return p.ID }), desc})
+	sortByKeys(posts, keys...)
+
+	// The handlers cap the page size at the configured maximum
+	if req.PageSize <= 0 {
+		req.PageSize = constants.DEFAULT_PAGE_SIZE
+	}
+
+	return paginate(posts, "posts "+ordering+" "+req.OrderBy, req.Page, req.PageSize, req.Cursor, req.IncludeCount)
+}
+
+func (m *MemoryStore) GetPost(ctx context.Context, isAuthenticated bool, userID, postID int) (*models.Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	post, ok := m.posts[postID]
+	if !ok {
+		return nil, errors.New(constants.NOT_FOUND_ERROR)
+	}
+
+	view := m.postView(post, isAuthenticated, userID)
+	return &view, nil
+}
+
+func (m *MemoryStore) PinComment(ctx context.Context, postID, commentID int) error {
+	return m.updatePost(postID, func(post *models.Post) {
+		post.PinnedCommentID = &commentID
+	})
+}
+
+func (m *MemoryStore) UnpinComment(ctx context.Context, postID int) error {
+	return m.updatePost(postID, func(post *models.Post) {
+		post.PinnedCommentID = nil
+	})
+}
+
+func (m *MemoryStore) SetPostLocked(ctx context.Context, postID int, isLocked bool) error {
+	return m.updatePost(postID, func(post *models.Post) {
+		post.IsLocked = isLocked
+	})
+}
+
+func (m *MemoryStore) SetPostPinned(ctx context.Context, postID int, isPinned bool) error {
+	return m.updatePost(postID, func(post *models.Post) {
+		post.IsPinned = isPinned
+	})
+}
+
+func (m *MemoryStore) SavePost(ctx context.Context, userID, postID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.savedPosts[userID] == nil {
+		m.savedPosts[userID] = map[int]time.Time{}
+	}
+	if _, ok := m.savedPosts[userID][postID]; !ok {
+		m.savedPosts[userID][postID] = time.Now()
+	}
+	return nil
+}
+
+func (m *MemoryStore) UnsavePost(ctx context.Context, userID, postID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.savedPosts[userID], postID)
+	return nil
+}
+
+// Applies the change to a post that is not deleted, returns NO_ROWS_AFFECTED_ERROR otherwise
+func (m *MemoryStore) updatePost(postID int, update func(post *models.Post)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	post, ok := m.posts[postID]
+	if !ok || post.IsDeleted {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	update(post)
+	return nil
+}
+
+// Copies the post with its topic name, author and the user's vote and bookmark
+// Deleted posts have their title, summary and content cleared
+func (m *MemoryStore) postView(post *models.Post, isAuthenticated bool, userID int) models.Post {
+	view := *post
+	if topic, ok := m.topics[post.TopicID]; ok {
+		view.TopicName = topic.Name
+	}
+	if user, ok := m.users[post.UserID]; ok {
+		view.Username = user.Username
+	}
+
+	if isAuthenticated {
+		view.MyVote = m.postVotes[post.ID][userID]
+		_, view.IsSaved = m.savedPosts[userID][post.ID]
+	}
+
+	if view.IsDeleted {
+		view.Title = ""
+		view.Summary = ""
+		view.Content = ""
+		view.ContentHTML = ""
+	}
+	return view
+}
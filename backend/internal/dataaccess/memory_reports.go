@@ -0,0 +1,290 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"errors"
+	"slices"
+	"time"
+)
+
+func (m *MemoryStore) CreateReport(ctx context.Context, report models.Report) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Only the comment of a comment report is stored, like reportTarget
+	if report.CommentID != nil {
+		report.PostID = nil
+	}
+
+	for _, existing := range m.openReports(report.PostID, report.CommentID) {
+		if existing.ReporterID != nil && report.ReporterID != nil && *existing.ReporterID == *report.ReporterID {
+			return 0, errors.New(constants.ALREADY_EXISTS_ERROR)
+		}
+	}
+
+	report.ID = m.nextID()
+	report.ReporterUsername = ""
+	report.Status = constants.REPORT_STATUS_OPEN
+	report.CreatedAt = time.Now()
+	report.ResolvedAt = nil
+	m.reports[report.ID] = &report
+	return report.ID, nil
+}
+
+func (m *MemoryStore) ListOpenPostReports(ctx context.Context, postID int) ([]models.Report, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.openReportViews(m.openReports(&postID, nil)), nil
+}
+
+func (m *MemoryStore) ListOpenCommentReports(ctx context.Context, commentID int) ([]models.Report, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.openReportViews(m.openReports(nil, &commentID)), nil
+}
+
+func (m *MemoryStore) ListReportedContent(ctx context.Context, req models.ListReportsRequest, topicIDs []int) ([]models.ReportedContent, models.PageInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Open reports grouped by their post or comment, keyed by the comment ID or the negated post ID
+	type group struct {
+		content       models.ReportedContent
+		firstReportID int
+	}
+	groups := map[int]*group{}
+	for _, report := range m.reports {
+		if report.Status != constants.REPORT_STATUS_OPEN {
+			continue
+		}
+
+		key := 0
+		var comment *models.Comment
+		var post *models.Post
+		if report.CommentID != nil {
+			comment = m.comments[*report.CommentID]
+			if comment == nil {
+				continue
+			}
+			key = comment.ID
+			post = m.posts[comment.PostID]
+		} else {
+			key = -*report.PostID
+			post = m.posts[*report.PostID]
+		}
+		if post == nil {
+			continue
+		}
+
+		g, ok := groups[key]
+		if !ok {
+			g = &group{content: m.reportedContent(post, comment), firstReportID: report.ID}
+			g.content.FirstReportedAt = report.CreatedAt
+			g.content.LastReportedAt = report.CreatedAt
+			groups[key] = g
+		}
+
+		g.content.ReportCount++
+		g.content.Reasons[report.Reason]++
+		if report.CreatedAt.Before(g.content.FirstReportedAt) {
+			g.content.FirstReportedAt = report.CreatedAt
+		}
+		if report.CreatedAt.After(g.content.LastReportedAt) {
+			g.content.LastReportedAt = report.CreatedAt
+		}
+		g.firstReportID = min(g.firstReportID, report.ID)
+	}
+
+	filtered := []*group{}
+	for _, g := range groups {
+		switch {
+		case topicIDs != nil && !slices.Contains(topicIDs, g.content.TopicID),
+			req.TopicID != 0 && g.content.TopicID != req.TopicID,
+			req.Type != "" && g.content.TargetType != req.Type:
+			continue
+		}
+		filtered = append(filtered, g)
+	}
+
+	sortByKeys(filtered,
+		memorySortKey[*group]{byTime(func(g *group) time.Time { return g.content.FirstReportedAt }), false},
+		memorySortKey[*group]{byInt(func(g *group) int { return g.firstReportID }), false})
+
+	contents := []models.ReportedContent{}
+	for _, g := range filtered {
+		contents = append(contents, g.content)
+	}
+
+	if req.PageSize <= 0 {
+		req.PageSize = constants.DEFAULT_MODERATION_PAGE_SIZE
+	}
+	if req.PageSize > constants.MAX_MODERATION_PAGE_SIZE {
+		req.PageSize = constants.MAX_MODERATION_PAGE_SIZE
+	}
+
+	return paginate(contents, "reports", 0, req.PageSize, req.Cursor, req.IncludeCount)
+}
+
+func (m *MemoryStore) ResolveReports(ctx context.Context, entry *models.ModerationLogEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	postID := entry.PostID
+	if entry.CommentID != nil {
+		postID = nil
+	}
+
+	reports := m.openReports(postID, entry.CommentID)
+	if len(reports) == 0 {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	now := time.Now()
+	status := constants.REPORT_STATUS_RESOLVED
+	if entry.Action == constants.MODERATION_ACTION_DISMISS {
+		status = constants.REPORT_STATUS_DISMISSED
+	}
+	for _, report := range reports {
+		report.Status = status
+		report.ResolvedAt = &now
+	}
+	entry.ReportsResolved = len(reports)
+
+	switch entry.Action {
+	case constants.MODERATION_ACTION_REMOVE:
+		// Content that is already deleted only has its reports closed
+		if entry.CommentID != nil {
+			m.removeComment(*entry.CommentID)
+		} else {
+			m.removePost(*entry.PostID)
+		}
+
+	case constants.MODERATION_ACTION_SUSPEND:
+		if entry.TargetUserID != nil && entry.SuspendedUntil != nil {
+			if until, ok := m.suspendedUntil[*entry.TargetUserID]; !ok || entry.SuspendedUntil.After(until) {
+				m.suspendedUntil[*entry.TargetUserID] = *entry.SuspendedUntil
+			}
+		}
+	}
+
+	entry.ID = m.nextID()
+	entry.CreatedAt = now
+	logged := *entry
+	logged.ModeratorUsername = ""
+	logged.TargetUsername = ""
+	m.moderationLog = append(m.moderationLog, logged)
+	return nil
+}
+
+func (m *MemoryStore) ListModerationLog(ctx context.Context, req models.ListModerationLogRequest) ([]models.ModerationLogEntry, models.PageInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries := []models.ModerationLogEntry{}
+	for _, entry := range m.moderationLog {
+		if req.Action != "" && entry.Action != req.Action {
+			continue
+		}
+		entry.ModeratorUsername = m.username(entry.ModeratorID)
+		entry.TargetUsername = m.username(entry.TargetUserID)
+		entries = append(entries, entry)
+	}
+
+	sortByKeys(entries,
+		memorySortKey[models.ModerationLogEntry]{byTime(func(e models.ModerationLogEntry) time.Time { return e.CreatedAt }), true},
+		memorySortKey[models.ModerationLogEntry]{byInt(func(e models.ModerationLogEntry) int { return e.ID }), true})
+
+	if req.PageSize <= 0 {
+		req.PageSize = constants.DEFAULT_MODERATION_PAGE_SIZE
+	}
+	if req.PageSize > constants.MAX_MODERATION_PAGE_SIZE {
+		req.PageSize = constants.MAX_MODERATION_PAGE_SIZE
+	}
+
+	return paginate(entries, "moderation_log", 0, req.PageSize, req.Cursor, req.IncludeCount)
+}
+
+func (m *MemoryStore) GetUserSuspendedUntil(ctx context.Context, userID int) (*time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	until, ok := m.suspendedUntil[userID]
+	if !ok || !until.After(time.Now()) {
+		return nil, nil
+	}
+	return &until, nil
+}
+
+// Returns the open reports of the post or, if commentID is set, the comment
+func (m *MemoryStore) openReports(postID, commentID *int) []*models.Report {
+	reports := []*models.Report{}
+	for _, report := range m.reports {
+		if report.Status != constants.REPORT_STATUS_OPEN {
+			continue
+		}
+		if commentID != nil {
+			if report.CommentID != nil && *report.CommentID == *commentID {
+				reports = append(reports, report)
+			}
+		} else if postID != nil && report.PostID != nil && *report.PostID == *postID {
+			reports = append(reports, report)
+		}
+	}
+	return reports
+}
+
+// Copies the reports with their reporters' usernames, oldest first
+func (m *MemoryStore) openReportViews(reports []*models.Report) []models.Report {
+	views := []models.Report{}
+	for _, report := range reports {
+		view := *report
+		view.ReporterUsername = m.username(report.ReporterID)
+		views = append(views, view)
+	}
+
+	sortByKeys(views,
+		memorySortKey[models.Report]{byTime(func(r models.Report) time.Time { return r.CreatedAt }), false},
+		memorySortKey[models.Report]{byInt(func(r models.Report) int { return r.ID }), false})
+	return views
+}
+
+// Describes the reported post, or its comment if one is given, without the counts of its reports
+// Deleted content has its title and summary cleared
+func (m *MemoryStore) reportedContent(post *models.Post, comment *models.Comment) models.ReportedContent {
+	content := models.ReportedContent{
+		TargetType: constants.REPORT_TARGET_POST,
+		PostID:     post.ID,
+		AuthorID:   post.UserID,
+		TopicID:    post.TopicID,
+		IsDeleted:  post.IsDeleted,
+		Reasons:    map[string]int{},
+	}
+	if !post.IsDeleted {
+		content.Title = post.Title
+		content.Summary = post.Summary
+	}
+
+	if comment != nil {
+		content.TargetType = constants.REPORT_TARGET_COMMENT
+		content.CommentID = &comment.ID
+		content.AuthorID = comment.UserID
+		content.IsDeleted = comment.IsDeleted
+		content.Summary = ""
+		if !comment.IsDeleted {
+			content.Summary = comment.Summary
+		}
+	}
+
+	if author, ok := m.users[content.AuthorID]; ok {
+		content.AuthorUsername = author.Username
+	}
+	if topic, ok := m.topics[post.TopicID]; ok {
+		content.TopicName = topic.Name
+		content.TopicSlug = topic.Slug
+	}
+	return content
+}
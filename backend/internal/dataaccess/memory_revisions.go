@@ -0,0 +1,96 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"errors"
+	"time"
+)
+
+func (m *MemoryStore) ListPostRevisions(ctx context.Context, postID int) ([]models.PostRevision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	revisions := []models.PostRevision{}
+	for _, revision := range m.postRevisions[postID] {
+		revision.EditorUsername = m.username(revision.EditorID)
+		revisions = append(revisions, revision)
+	}
+	return revisions, nil
+}
+
+func (m *MemoryStore) ListCommentRevisions(ctx context.Context, commentID int) ([]models.CommentRevision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	revisions := []models.CommentRevision{}
+	for _, revision := range m.commentRevisions[commentID] {
+		revision.EditorUsername = m.username(revision.EditorID)
+		revisions = append(revisions, revision)
+	}
+	return revisions, nil
+}
+
+func (m *MemoryStore) RestorePostRevision(ctx context.Context, post *models.Post, revision, editorID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	revisions := m.postRevisions[post.ID]
+	if revision < 1 || revision > len(revisions) {
+		return errors.New(constants.NOT_FOUND_ERROR)
+	}
+
+	post.Title = revisions[revision-1].Title
+	post.Content = revisions[revision-1].Content
+	return m.editPost(post, editorID, &revision)
+}
+
+func (m *MemoryStore) RestoreCommentRevision(ctx context.Context, comment *models.Comment, revision, editorID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	revisions := m.commentRevisions[comment.ID]
+	if revision < 1 || revision > len(revisions) {
+		return errors.New(constants.NOT_FOUND_ERROR)
+	}
+
+	comment.Content = revisions[revision-1].Content
+	return m.editComment(comment, editorID, &revision)
+}
+
+// Records the post's current title and content as its next revision
+func (m *MemoryStore) addPostRevision(post *models.Post, editorID int, restoredFrom *int, createdAt time.Time) {
+	m.postRevisions[post.ID] = append(m.postRevisions[post.ID], models.PostRevision{
+		PostID:       post.ID,
+		Revision:     len(m.postRevisions[post.ID]) + 1,
+		Title:        post.Title,
+		Content:      post.Content,
+		EditorID:     &editorID,
+		RestoredFrom: restoredFrom,
+		CreatedAt:    createdAt,
+	})
+}
+
+// Records the comment's current content as its next revision
+func (m *MemoryStore) addCommentRevision(comment *models.Comment, editorID int, restoredFrom *int, createdAt time.Time) {
+	m.commentRevisions[comment.ID] = append(m.commentRevisions[comment.ID], models.CommentRevision{
+		CommentID:    comment.ID,
+		Revision:     len(m.commentRevisions[comment.ID]) + 1,
+		Content:      comment.Content,
+		EditorID:     &editorID,
+		RestoredFrom: restoredFrom,
+		CreatedAt:    createdAt,
+	})
+}
+
+// Returns the username of the user, empty if there is no such user
+func (m *MemoryStore) username(userID *int) string {
+	if userID == nil {
+		return ""
+	}
+	if user, ok := m.users[*userID]; ok {
+		return user.Username
+	}
+	return ""
+}
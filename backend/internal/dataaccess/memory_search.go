@@ -0,0 +1,120 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"html"
+	"strings"
+	"time"
+)
+
+// Longest snippet of a search result, in runes
+const memorySnippetLength = 200
+
+func (m *MemoryStore) Search(ctx context.Context, req models.SearchRequest, from, to *time.Time) ([]models.SearchResult, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	terms := strings.Fields(req.Query)
+	matches := func(text string) bool {
+		for _, term := range terms {
+			if !containsFold(text, term) {
+				return false
+			}
+		}
+		return len(terms) > 0
+	}
+	inRange := func(createdAt time.Time) bool {
+		return (from == nil || !createdAt.Before(*from)) && (to == nil || createdAt.Before(*to))
+	}
+
+	results := []models.SearchResult{}
+	for _, post := range m.posts {
+		if req.Type != "" && req.Type != constants.SEARCH_TYPE_POST {
+			break
+		}
+		if post.IsDeleted || !inRange(post.CreatedAt) || !matches(post.Title+" "+post.Content) {
+			continue
+		}
+		if result, ok := m.searchResult(req, constants.SEARCH_TYPE_POST, post.ID, post, post.Content, post.Score, post.CreatedAt, post.UserID); ok {
+			results = append(results, result)
+		}
+	}
+	for _, comment := range m.comments {
+		if req.Type != "" && req.Type != constants.SEARCH_TYPE_COMMENT {
+			break
+		}
+		post, ok := m.posts[comment.PostID]
+		if !ok || post.IsDeleted || comment.IsDeleted || !inRange(comment.CreatedAt) || !matches(comment.Content) {
+			continue
+		}
+		if result, ok := m.searchResult(req, constants.SEARCH_TYPE_COMMENT, comment.ID, post, comment.Content, comment.Score, comment.CreatedAt, comment.UserID); ok {
+			results = append(results, result)
+		}
+	}
+
+	keys := []memorySortKey[models.SearchResult]{}
+	if req.Sort != constants.ORDER_BY_NEW {
+		keys = append(keys, memorySortKey[models.SearchResult]{byInt(func(r models.SearchResult) int { return r.Score }), true})
+	}
+	keys = append(keys,
+		memorySortKey[models.SearchResult]{byTime(func(r models.SearchResult) time.Time { return r.CreatedAt }), true},
+		memorySortKey[models.SearchResult]{byInt(func(r models.SearchResult) int { return r.ID }), true})
+	sortByKeys(results, keys...)
+
+	if req.PageSize <= 0 {
+		req.PageSize = constants.DEFAULT_SEARCH_PAGE_SIZE
+	}
+	if req.PageSize > constants.MAX_SEARCH_PAGE_SIZE {
+		req.PageSize = constants.MAX_SEARCH_PAGE_SIZE
+	}
+
+	totalCount := len(results)
+	offset := 0
+	if req.Page > 0 {
+		offset = (req.Page - 1) * req.PageSize
+	}
+	if offset >= len(results) {
+		return []models.SearchResult{}, totalCount, nil
+	}
+	results = results[offset:min(offset+req.PageSize, len(results))]
+
+	return results, totalCount, nil
+}
+
+// Builds the result of a matching post or comment, unless the topic or author filters exclude it
+// Titles and snippets are HTML-escaped like the highlighted ones, without highlights
+func (m *MemoryStore) searchResult(req models.SearchRequest, resultType string, id int, post *models.Post, body string, score int, createdAt time.Time, userID int) (models.SearchResult, bool) {
+	if req.TopicID != nil && post.TopicID != *req.TopicID {
+		return models.SearchResult{}, false
+	}
+
+	result := models.SearchResult{
+		Type:      resultType,
+		ID:        id,
+		PostID:    post.ID,
+		PostTitle: html.EscapeString(post.Title),
+		Rank:      1,
+		Score:     score,
+		CreatedAt: createdAt,
+		UserID:    userID,
+		TopicID:   post.TopicID,
+	}
+	if user, ok := m.users[userID]; ok {
+		result.Username = user.Username
+	}
+	if req.Author != "" && result.Username != req.Author {
+		return models.SearchResult{}, false
+	}
+	if topic, ok := m.topics[post.TopicID]; ok {
+		result.TopicName = topic.Name
+	}
+
+	snippet := []rune(body)
+	if len(snippet) > memorySnippetLength {
+		snippet = snippet[:memorySnippetLength]
+	}
+	result.Snippet = html.EscapeString(string(snippet))
+	return result, true
+}
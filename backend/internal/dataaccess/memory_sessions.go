@@ -0,0 +1,151 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"errors"
+	"time"
+)
+
+// A session with the refresh tokens the sessions table keeps
+type memorySession struct {
+	models.Session
+	refreshTokenHash         string
+	previousRefreshTokenHash string
+}
+
+func (m *MemoryStore) CreateSession(ctx context.Context, session models.Session, refreshTokenHash string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	session.ID = m.nextID()
+	session.CreatedAt = now
+	session.LastUsedAt = now
+	session.RevokedAt = nil
+	session.IsCurrent = false
+	m.sessions[session.ID] = &memorySession{Session: session, refreshTokenHash: refreshTokenHash}
+	return session.ID, nil
+}
+
+func (m *MemoryStore) GetSessionByRefreshTokenHash(ctx context.Context, refreshTokenHash string) (*models.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, session := range m.sessions {
+		if session.refreshTokenHash == refreshTokenHash {
+			view := session.Session
+			return &view, nil
+		}
+	}
+	return nil, errors.New(constants.NOT_FOUND_ERROR)
+}
+
+func (m *MemoryStore) RevokeSessionByPreviousRefreshTokenHash(ctx context.Context, refreshTokenHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	revoked := false
+	for _, session := range m.sessions {
+		if session.previousRefreshTokenHash == refreshTokenHash && session.RevokedAt == nil {
+			session.RevokedAt = &now
+			revoked = true
+		}
+	}
+
+	if !revoked {
+		return errors.New(constants.NOT_FOUND_ERROR)
+	}
+	return nil
+}
+
+func (m *MemoryStore) RotateRefreshToken(ctx context.Context, sessionID int, oldHash, newHash string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	session, ok := m.sessions[sessionID]
+	if !ok || session.refreshTokenHash != oldHash || !session.isActive(now) {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	session.previousRefreshTokenHash = oldHash
+	session.refreshTokenHash = newHash
+	session.LastUsedAt = now
+	session.ExpiresAt = expiresAt
+	return nil
+}
+
+func (m *MemoryStore) GetActiveSessionRole(ctx context.Context, sessionID, userID int) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[sessionID]
+	if !ok || session.UserID != userID || !session.isActive(time.Now()) {
+		return "", errors.New(constants.NOT_FOUND_ERROR)
+	}
+
+	user, ok := m.users[userID]
+	if !ok {
+		return "", errors.New(constants.NOT_FOUND_ERROR)
+	}
+	return user.Role, nil
+}
+
+func (m *MemoryStore) ListActiveSessions(ctx context.Context, userID int) ([]models.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	sessions := []models.Session{}
+	for _, session := range m.sessions {
+		if session.UserID == userID && session.isActive(now) {
+			sessions = append(sessions, session.Session)
+		}
+	}
+
+	sortByKeys(sessions,
+		memorySortKey[models.Session]{byTime(func(s models.Session) time.Time { return s.LastUsedAt }), true},
+		memorySortKey[models.Session]{byInt(func(s models.Session) int { return s.ID }), true})
+	return sessions, nil
+}
+
+func (m *MemoryStore) RevokeSession(ctx context.Context, userID, sessionID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[sessionID]
+	if !ok || session.UserID != userID || session.RevokedAt != nil {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	now := time.Now()
+	session.RevokedAt = &now
+	return nil
+}
+
+func (m *MemoryStore) RevokeAllSessions(ctx context.Context, userID int) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.revokeSessions(userID, 0), nil
+}
+
+// Revokes the user's sessions other than keepSessionID, returns how many were revoked
+func (m *MemoryStore) revokeSessions(userID, keepSessionID int) int64 {
+	now := time.Now()
+	var revoked int64
+	for _, session := range m.sessions {
+		if session.UserID == userID && session.ID != keepSessionID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+			revoked++
+		}
+	}
+	return revoked
+}
+
+func (session *memorySession) isActive(now time.Time) bool {
+	return session.RevokedAt == nil && session.ExpiresAt.After(now)
+}
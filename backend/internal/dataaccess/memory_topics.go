@@ -0,0 +1,241 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"cvwo/internal/utils"
+	"errors"
+	"strings"
+	"time"
+)
+
+func (m *MemoryStore) ListTopicsSummary(ctx context.Context) ([]models.Topic, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	topics := []models.Topic{}
+	for _, topic := range m.topics {
+		if !topic.IsArchived {
+			topics = append(topics, models.Topic{ID: topic.ID, Name: topic.Name, Slug: topic.Slug})
+		}
+	}
+
+	sortByKeys(topics, memorySortKey[models.Topic]{compare: byString(func(t models.Topic) string { return t.Name })})
+	return topics, nil
+}
+
+func (m *MemoryStore) ListTopics(ctx context.Context, isAuthenticated bool, currentUserID int, req models.ListTopicsRequest) ([]models.Topic, models.PageInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if req.FilterFollowing && !isAuthenticated {
+		return nil, models.PageInfo{}, errors.New("user must be authenticated when filtering by following status")
+	}
+
+	topics := []models.Topic{}
+	for _, topic := range m.topics {
+		view := m.topicView(topic, isAuthenticated, currentUserID)
+		switch {
+		case req.Search != "" && !containsFold(topic.Name, req.Search),
+			!req.ShowArchived && topic.IsArchived,
+			req.FilterFollowing && !view.IsFollowing:
+			continue
+		}
+		topics = append(topics, view)
+	}
+
+	desc := req.OrderBy != constants.SORT_ASC
+	var key memorySortKey[models.Topic]
+	switch req.Sort {
+	case constants.ORDER_BY_POSTS:
+		key = memorySortKey[models.Topic]{byInt(func(t models.Topic) int { return t.NoOfPosts }), desc}
+	case constants.ORDER_BY_FOLLOWERS:
+		key = memorySortKey[models.Topic]{byInt(func(t models.Topic) int { return t.NoOfFollowers }), desc}
+	default:
+		req.Sort = constants.ORDER_BY_NAME
+		key = memorySortKey[models.Topic]{byString(func(t models.Topic) string { return t.Name }), desc}
+	}
+	sortByKeys(topics, key, memorySortKey[models.Topic]{byInt(func(t models.Topic) int { return t.ID }), desc})
+
+	// The handlers cap the page size at the configured maximum
+	if req.PageSize <= 0 {
+		req.PageSize = constants.DEFAULT_PAGE_SIZE
+	}
+
+	return paginate(topics, "topics "+req.Sort+" "+req.OrderBy, req.Page, req.PageSize, req.Cursor, req.IncludeCount)
+}
+
+func (m *MemoryStore) GetTopicBySlug(ctx context.Context, isAuthenticated bool, userID int, slug string) (*models.Topic, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	topic := m.topicBySlug(slug)
+	if topic == nil {
+		return nil, errors.New(constants.NOT_FOUND_ERROR)
+	}
+
+	view := m.topicView(topic, isAuthenticated, userID)
+	return &view, nil
+}
+
+func (m *MemoryStore) FollowTopic(ctx context.Context, userID int, topicName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	topic := m.topicByName(topicName)
+	if topic == nil {
+		return errors.New(constants.NOT_FOUND_ERROR)
+	}
+	if m.followedTopics[userID][topic.ID] {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	if m.followedTopics[userID] == nil {
+		m.followedTopics[userID] = map[int]bool{}
+	}
+	m.followedTopics[userID][topic.ID] = true
+	topic.NoOfFollowers++
+	return nil
+}
+
+func (m *MemoryStore) UnfollowTopic(ctx context.Context, userID int, topicName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	topic := m.topicByName(topicName)
+	if topic == nil {
+		return errors.New(constants.NOT_FOUND_ERROR)
+	}
+	if !m.followedTopics[userID][topic.ID] {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	delete(m.followedTopics[userID], topic.ID)
+	topic.NoOfFollowers--
+	return nil
+}
+
+func (m *MemoryStore) CreateTopic(ctx context.Context, topic models.Topic) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	slug := utils.SlugifyTopicName(topic.Name)
+	if m.isTopicNameOrSlugTaken(topic.Name, slug, 0) {
+		return 0, errors.New(constants.ALREADY_EXISTS_ERROR)
+	}
+
+	topicID := m.nextID()
+	m.topics[topicID] = &models.Topic{
+		ID:          topicID,
+		Name:        topic.Name,
+		Slug:        slug,
+		Description: topic.Description,
+	}
+	return topicID, nil
+}
+
+func (m *MemoryStore) UpdateTopic(ctx context.Context, topic models.Topic) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.topics[topic.ID]
+	if !ok {
+		return errors.New(constants.NOT_FOUND_ERROR)
+	}
+
+	newSlug := utils.SlugifyTopicName(topic.Name)
+	if m.isTopicNameOrSlugTaken(topic.Name, newSlug, topic.ID) {
+		return errors.New(constants.ALREADY_EXISTS_ERROR)
+	}
+
+	var archivedAt *time.Time
+	if topic.IsArchived {
+		archivedAt = topic.ArchivedAt
+		if !stored.IsArchived || archivedAt == nil {
+			now := time.Now()
+			archivedAt = &now
+		}
+	}
+
+	// Renaming back to an old slug makes it current again
+	if newSlug != stored.Slug {
+		delete(m.topicRedirects, newSlug)
+		m.topicRedirects[stored.Slug] = stored.ID
+	}
+
+	stored.Name = topic.Name
+	stored.Slug = newSlug
+	stored.Description = topic.Description
+	stored.IsArchived = topic.IsArchived
+	stored.ArchivedAt = archivedAt
+
+	// Counts are recomputed from their sources, as the update query does
+	stored.NoOfPosts = 0
+	for _, post := range m.posts {
+		if post.TopicID == stored.ID && !post.IsDeleted {
+			stored.NoOfPosts++
+		}
+	}
+	stored.NoOfFollowers = 0
+	for _, followed := range m.followedTopics {
+		if followed[stored.ID] {
+			stored.NoOfFollowers++
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) ArchiveTopic(ctx context.Context, topicID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	topic, ok := m.topics[topicID]
+	if !ok || topic.IsArchived {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	now := time.Now()
+	topic.IsArchived = true
+	topic.ArchivedAt = &now
+	return nil
+}
+
+func (m *MemoryStore) topicBySlug(slug string) *models.Topic {
+	for _, topic := range m.topics {
+		if topic.Slug == slug {
+			return topic
+		}
+	}
+	if topicID, ok := m.topicRedirects[slug]; ok {
+		return m.topics[topicID]
+	}
+	return nil
+}
+
+func (m *MemoryStore) topicByName(name string) *models.Topic {
+	for _, topic := range m.topics {
+		if topic.Name == name {
+			return topic
+		}
+	}
+	return nil
+}
+
+// Copies the topic with whether the user follows it
+func (m *MemoryStore) topicView(topic *models.Topic, isAuthenticated bool, userID int) models.Topic {
+	view := *topic
+	view.IsFollowing = isAuthenticated && m.followedTopics[userID][topic.ID]
+	return view
+}
+
+// A name or slug is taken if another topic uses it, or another topic used the slug before a rename
+func (m *MemoryStore) isTopicNameOrSlugTaken(name, slug string, excludeTopicID int) bool {
+	for _, topic := range m.topics {
+		if topic.ID != excludeTopicID && (strings.EqualFold(topic.Name, name) || topic.Slug == slug) {
+			return true
+		}
+	}
+	topicID, ok := m.topicRedirects[slug]
+	return ok && topicID != excludeTopicID
+}
@@ -0,0 +1,184 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"errors"
+	"time"
+)
+
+// A pending login with the hash of its token
+type memoryLoginChallenge struct {
+	models.LoginChallenge
+	tokenHash string
+	used      bool
+}
+
+func (m *MemoryStore) GetTwoFactor(ctx context.Context, userID int) (*models.TwoFactor, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	twoFactor, ok := m.twoFactors[userID]
+	if !ok {
+		return nil, errors.New(constants.NOT_FOUND_ERROR)
+	}
+
+	view := *twoFactor
+	return &view, nil
+}
+
+func (m *MemoryStore) IsTwoFactorEnabled(ctx context.Context, userID int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	twoFactor, ok := m.twoFactors[userID]
+	return ok && twoFactor.EnabledAt != nil, nil
+}
+
+func (m *MemoryStore) StartTwoFactorEnrollment(ctx context.Context, userID int, secret string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if twoFactor, ok := m.twoFactors[userID]; ok && twoFactor.EnabledAt != nil {
+		return errors.New(constants.ALREADY_EXISTS_ERROR)
+	}
+
+	m.twoFactors[userID] = &models.TwoFactor{UserID: userID, Secret: secret, CreatedAt: time.Now()}
+	return nil
+}
+
+func (m *MemoryStore) EnableTwoFactor(ctx context.Context, userID int, step int64, recoveryCodeHashes []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	twoFactor, ok := m.twoFactors[userID]
+	if !ok || twoFactor.EnabledAt != nil {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	now := time.Now()
+	twoFactor.EnabledAt = &now
+	twoFactor.LastUsedStep = &step
+	m.replaceRecoveryCodes(userID, recoveryCodeHashes)
+	return nil
+}
+
+func (m *MemoryStore) DisableTwoFactor(ctx context.Context, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.twoFactors, userID)
+	delete(m.recoveryCodes, userID)
+	for id, challenge := range m.loginChallenges {
+		if challenge.UserID == userID {
+			delete(m.loginChallenges, id)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) UseTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	twoFactor, ok := m.twoFactors[userID]
+	if !ok || twoFactor.EnabledAt == nil || (twoFactor.LastUsedStep != nil && *twoFactor.LastUsedStep >= step) {
+		return false, nil
+	}
+
+	twoFactor.LastUsedStep = &step
+	return true, nil
+}
+
+func (m *MemoryStore) ReplaceRecoveryCodes(ctx context.Context, userID int, recoveryCodeHashes []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.replaceRecoveryCodes(userID, recoveryCodeHashes)
+	return nil
+}
+
+func (m *MemoryStore) replaceRecoveryCodes(userID int, recoveryCodeHashes []string) {
+	codes := map[string]bool{}
+	for _, codeHash := range recoveryCodeHashes {
+		codes[codeHash] = false
+	}
+	m.recoveryCodes[userID] = codes
+}
+
+func (m *MemoryStore) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	used, ok := m.recoveryCodes[userID][codeHash]
+	if !ok || used {
+		return false, nil
+	}
+
+	m.recoveryCodes[userID][codeHash] = true
+	return true, nil
+}
+
+func (m *MemoryStore) CountUnusedRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	for _, used := range m.recoveryCodes[userID] {
+		if !used {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *MemoryStore) CreateLoginChallenge(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := m.nextID()
+	m.loginChallenges[id] = &memoryLoginChallenge{
+		LoginChallenge: models.LoginChallenge{ID: id, UserID: userID, ExpiresAt: expiresAt},
+		tokenHash:      tokenHash,
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetLoginChallenge(ctx context.Context, tokenHash string) (*models.LoginChallenge, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for _, challenge := range m.loginChallenges {
+		if challenge.tokenHash == tokenHash && !challenge.used && challenge.ExpiresAt.After(now) &&
+			challenge.FailedAttempts < constants.MAX_LOGIN_CHALLENGE_ATTEMPTS {
+			view := challenge.LoginChallenge
+			return &view, nil
+		}
+	}
+	return nil, errors.New(constants.NOT_FOUND_ERROR)
+}
+
+func (m *MemoryStore) RecordLoginChallengeFailure(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if challenge, ok := m.loginChallenges[id]; ok {
+		challenge.FailedAttempts++
+	}
+	return nil
+}
+
+func (m *MemoryStore) CompleteLoginChallenge(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	challenge, ok := m.loginChallenges[id]
+	if !ok || challenge.used {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	challenge.used = true
+	return nil
+}
@@ -0,0 +1,219 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Returns ALREADY_EXISTS_ERROR where PostgreSQL would violate the unique email or username
+func (m *MemoryStore) CreateUser(ctx context.Context, user models.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.userByEmail(user.Email) != nil || m.userByUsername(user.Username) != nil {
+		return errors.New(constants.ALREADY_EXISTS_ERROR)
+	}
+
+	if user.Role == "" {
+		user.Role = constants.ROLE_MEMBER
+	}
+	user.ID = m.nextID()
+	user.Karma = 0
+	user.CreatedAt = time.Now()
+	user.EmailVerifiedAt = nil
+	m.users[user.ID] = &user
+	return nil
+}
+
+func (m *MemoryStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user := m.userByEmail(email)
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	return &models.User{
+		ID:       user.ID,
+		Username: user.Username,
+		Email:    user.Email,
+		Password: user.Password,
+		Role:     user.Role,
+	}, nil
+}
+
+func (m *MemoryStore) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	view := *user
+	view.Password = ""
+	return &view, nil
+}
+
+func (m *MemoryStore) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user := m.userByUsername(username)
+	if user == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	view := *user
+	view.Password = ""
+	view.EmailVerifiedAt = nil
+	return &view, nil
+}
+
+func (m *MemoryStore) GetUserPasswordHash(ctx context.Context, id int) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return user.Password, nil
+}
+
+func (m *MemoryStore) UpdateUserPassword(ctx context.Context, id int, newPassword string, keepSessionID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if user, ok := m.users[id]; ok {
+		user.Password = newPassword
+	}
+	m.revokeSessions(id, keepSessionID)
+	return nil
+}
+
+func (m *MemoryStore) UpdateUserRole(ctx context.Context, id int, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	user.Role = role
+	return nil
+}
+
+// Changing the email clears its verification
+func (m *MemoryStore) UpdateUserData(ctx context.Context, user *models.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.users[user.ID]
+	if !ok {
+		return nil
+	}
+
+	if existing := m.userByEmail(user.Email); existing != nil && existing.ID != user.ID {
+		return errors.New(constants.ALREADY_EXISTS_ERROR)
+	}
+	if existing := m.userByUsername(user.Username); existing != nil && existing.ID != user.ID {
+		return errors.New(constants.ALREADY_EXISTS_ERROR)
+	}
+
+	if stored.Email != user.Email {
+		stored.EmailVerifiedAt = nil
+	}
+	stored.Email = user.Email
+	stored.Username = user.Username
+	return nil
+}
+
+func (m *MemoryStore) CheckUserExistsByEmail(ctx context.Context, email string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.userByEmail(email) != nil, nil
+}
+
+func (m *MemoryStore) CheckUserExistsByUsername(ctx context.Context, username string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.userByUsername(username) != nil, nil
+}
+
+func (m *MemoryStore) ListUsers(ctx context.Context, req models.ListUsersRequest) ([]models.User, models.PageInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	users := []models.User{}
+	for _, user := range m.users {
+		if req.Search != "" && !containsFold(user.Username, req.Search) {
+			continue
+		}
+		users = append(users, models.User{
+			ID:        user.ID,
+			Username:  user.Username,
+			Karma:     user.Karma,
+			CreatedAt: user.CreatedAt,
+		})
+	}
+
+	// Oldest first unless descending order is requested
+	desc := req.OrderBy != "" && req.OrderBy != constants.SORT_ASC
+	var key memorySortKey[models.User]
+	switch req.Sort {
+	case constants.ORDER_BY_KARMA:
+		key = memorySortKey[models.User]{byInt(func(u models.User) int { return u.Karma }), desc}
+	default:
+		req.Sort = constants.ORDER_BY_NEW
+		key = memorySortKey[models.User]{byTime(func(u models.User) time.Time { return u.CreatedAt }), desc}
+	}
+	sortByKeys(users, key, memorySortKey[models.User]{byInt(func(u models.User) int { return u.ID }), desc})
+
+	// The handlers cap the page size at the configured maximum
+	if req.PageSize <= 0 {
+		req.PageSize = constants.DEFAULT_PAGE_SIZE
+	}
+
+	return paginate(users, "users "+req.Sort+" "+req.OrderBy, req.Page, req.PageSize, req.Cursor, req.IncludeCount)
+}
+
+func (m *MemoryStore) GetUserIDsByUsernames(ctx context.Context, usernames []string) ([]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	userIDs := []int{}
+	for _, username := range usernames {
+		if user := m.userByUsername(username); user != nil {
+			userIDs = append(userIDs, user.ID)
+		}
+	}
+	return userIDs, nil
+}
+
+func (m *MemoryStore) userByEmail(email string) *models.User {
+	for _, user := range m.users {
+		if user.Email == email {
+			return user
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) userByUsername(username string) *models.User {
+	for _, user := range m.users {
+		if user.Username == username {
+			return user
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"cvwo/internal/telemetry"
+	"errors"
+	"time"
+)
+
+func (s *PostgresStore) IsTopicModerator(ctx context.Context, userID, topicID int) (bool, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "IsTopicModerator")
+	defer done()
+
+	query := `
+		SELECT EXISTS(SELECT 1 FROM topic_moderators
+			WHERE user_id = $1 AND topic_id = $2)`
+
+	var isModerator bool
+	err := s.db.QueryRowContext(ctx, query, userID, topicID).Scan(&isModerator)
+	return isModerator, err
+}
+
+// Returns NO_ROWS_AFFECTED_ERROR if the user already moderates the topic
+func (s *PostgresStore) AddTopicModerator(ctx context.Context, userID, topicID int) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "AddTopicModerator")
+	defer done()
+
+	query := `
+		INSERT INTO topic_moderators (user_id, topic_id, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, topic_id) DO NOTHING`
+
+	result, err := s.db.ExecContext(ctx, query, userID, topicID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	return nil
+}
+
+// Returns NO_ROWS_AFFECTED_ERROR if the user does not moderate the topic
+func (s *PostgresStore) RemoveTopicModerator(ctx context.Context, userID, topicID int) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "RemoveTopicModerator")
+	defer done()
+
+	query := `
+		DELETE FROM topic_moderators
+		WHERE user_id = $1 AND topic_id = $2`
+
+	result, err := s.db.ExecContext(ctx, query, userID, topicID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	return nil
+}
+
+// ListTopicModerators returns the moderators of a topic, oldest appointment first
+func (s *PostgresStore) ListTopicModerators(ctx context.Context, topicID int) ([]models.User, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "ListTopicModerators")
+	defer done()
+
+	query := `
+		SELECT u.id,
+		u.username,
+		u.karma,
+		u.role,
+		u.created_at
+		FROM topic_moderators tm
+		INNER JOIN users u ON tm.user_id = u.id
+		WHERE tm.topic_id = $1
+		ORDER BY tm.created_at ASC`
+
+	rows, err := s.db.QueryContext(ctx, query, topicID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(
+			&user.ID,
+			&user.Username,
+			&user.Karma,
+			&user.Role,
+			&user.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// ListModeratedTopicIDs returns the IDs of the topics the user moderates
+func (s *PostgresStore) ListModeratedTopicIDs(ctx context.Context, userID int) ([]int, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "ListModeratedTopicIDs")
+	defer done()
+
+	query := `
+		SELECT topic_id
+		FROM topic_moderators
+		WHERE user_id = $1
+		ORDER BY topic_id ASC`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	topicIDs := []int{}
+	for rows.Next() {
+		var topicID int
+		if err := rows.Scan(&topicID); err != nil {
+			return nil, err
+		}
+		topicIDs = append(topicIDs, topicID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return topicIDs, nil
+}
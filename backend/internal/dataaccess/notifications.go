@@ -0,0 +1,360 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"cvwo/internal/telemetry"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Skips recipients who muted the notification type, the type is always $3
+const notMutedCondition = `
+	NOT EXISTS (
+		SELECT 1 FROM notification_mutes m
+		WHERE m.user_id = %s AND m.type = $3)`
+
+// CreateNotifications notifies each recipient once
+// The actor is never notified of their own action, and recipients who muted the type are skipped
+// Returns the notifications created
+func (s *PostgresStore) CreateNotifications(ctx context.Context, notification models.Notification, recipientIDs []int) ([]models.Notification, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "CreateNotifications")
+	defer done()
+
+	if len(recipientIDs) == 0 {
+		return []models.Notification{}, nil
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO notifications (
+			user_id,
+			actor_id,
+			type,
+			post_id,
+			comment_id,
+			topic_id,
+			message,
+			created_at)
+		SELECT r.id, $2::int, $3, $4::int, $5::int, $6::int, $8, $7::timestamp
+		FROM (SELECT DISTINCT unnest($1::int[]) AS id) r
+		WHERE r.id IS DISTINCT FROM $2
+		AND %s
+		RETURNING id, user_id, created_at`, fmt.Sprintf(notMutedCondition, "r.id"))
+
+	return s.insertNotifications(ctx, notification, query,
+		pq.Array(recipientIDs),
+		notification.ActorID,
+		notification.Type,
+		notification.PostID,
+		notification.CommentID,
+		notification.TopicID,
+		time.Now(),
+		notification.Message,
+	)
+}
+
+// CreateTopicFollowerNotifications notifies every follower of the notification's topic
+// Followers in excludeIDs are skipped, e.g. because they were already notified of a mention
+// Returns the notifications created
+func (s *PostgresStore) CreateTopicFollowerNotifications(ctx context.Context, notification models.Notification, excludeIDs []int) ([]models.Notification, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "CreateTopicFollowerNotifications")
+	defer done()
+
+	query := fmt.Sprintf(`
+		INSERT INTO notifications (
+			user_id,
+			actor_id,
+			type,
+			post_id,
+			comment_id,
+			topic_id,
+			message,
+			created_at)
+		SELECT ut.user_id, $2::int, $3, $4::int, $5::int, $6::int, $8, $7::timestamp
+		FROM user_topics ut
+		WHERE ut.topic_id = $6
+		AND ut.user_id IS DISTINCT FROM $2
+		AND NOT (ut.user_id = ANY($1::int[]))
+		AND %s
+		RETURNING id, user_id, created_at`, fmt.Sprintf(notMutedCondition, "ut.user_id"))
+
+	if excludeIDs == nil {
+		excludeIDs = []int{}
+	}
+
+	return s.insertNotifications(ctx, notification, query,
+		pq.Array(excludeIDs),
+		notification.ActorID,
+		notification.Type,
+		notification.PostID,
+		notification.CommentID,
+		notification.TopicID,
+		time.Now(),
+		notification.Message,
+	)
+}
+
+// Runs an INSERT ... RETURNING id, user_id, created_at query and fills in the created notifications
+func (s *PostgresStore) insertNotifications(ctx context.Context, notification models.Notification, query string, args ...any) ([]models.Notification, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		created := notification
+		if err := rows.Scan(&created.ID, &created.UserID, &created.CreatedAt); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, created)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+// ListNotifications returns the user's notifications, newest first
+// Titles and summaries of deleted posts and comments are blanked
+func (s *PostgresStore) ListNotifications(ctx context.Context, userID int, req models.ListNotificationsRequest) ([]models.Notification, models.PageInfo, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "ListNotifications")
+	defer done()
+
+	args := []any{userID}
+	var queryBuilder strings.Builder
+	pageInfo := models.PageInfo{}
+
+	ordering := []keysetColumn{
+		{expr: "n.created_at", kind: keysetTime, desc: true},
+		{expr: "n.id", desc: true},
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			n.id,
+			n.user_id,
+			n.type,
+			n.actor_id,
+			COALESCE(a.username, ''),
+			n.post_id,
+			CASE WHEN p.is_deleted THEN '' ELSE COALESCE(p.title, '') END,
+			n.comment_id,
+			CASE WHEN c.is_deleted THEN '' ELSE COALESCE(c.summary, '') END,
+			n.topic_id,
+			COALESCE(t.name, ''),
+			COALESCE(t.slug, ''),
+			n.message,
+			n.created_at,
+			n.read_at%s
+		FROM notifications n
+
+		LEFT JOIN users a ON n.actor_id = a.id
+		LEFT JOIN posts p ON n.post_id = p.id
+		LEFT JOIN comments c ON n.comment_id = c.id
+		LEFT JOIN topics t ON n.topic_id = t.id
+		WHERE n.user_id = $1`, keysetSelectFields(ordering))
+
+	queryBuilder.WriteString(query)
+
+	if req.UnreadOnly {
+		queryBuilder.WriteString(" AND n.read_at IS NULL")
+	}
+
+	// Get total count for pagination
+	if shouldCount(req.IncludeCount) {
+		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM (%s)", queryBuilder.String())
+		var totalCount int
+		err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&totalCount)
+		if err != nil {
+			return nil, pageInfo, err
+		}
+		pageInfo.Count = &totalCount
+	}
+
+	if req.Cursor != "" {
+		values, err := decodeCursor(req.Cursor, ordering)
+		if err != nil {
+			return nil, pageInfo, err
+		}
+		writeKeysetCondition(&queryBuilder, &args, ordering, values)
+	}
+
+	writeOrderBy(&queryBuilder, ordering)
+
+	if req.PageSize <= 0 {
+		req.PageSize = constants.DEFAULT_NOTIFICATIONS_PAGE_SIZE
+	}
+	if req.PageSize > constants.MAX_NOTIFICATIONS_PAGE_SIZE {
+		req.PageSize = constants.MAX_NOTIFICATIONS_PAGE_SIZE
+	}
+
+	// Fetch one extra row to know whether there is a next page
+	args = append(args, req.PageSize+1)
+	queryBuilder.WriteString(fmt.Sprintf(" LIMIT $%d", len(args)))
+
+	rows, err := s.db.QueryContext(ctx, queryBuilder.String(), args...)
+	if err != nil {
+		return nil, pageInfo, err
+	}
+	defer rows.Close()
+
+	keysetValues, keysetDest := keysetScanDest(ordering)
+	var cursorValues []any
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		var notification models.Notification
+		dest := []any{
+			&notification.ID,
+			&notification.UserID,
+			&notification.Type,
+			&notification.ActorID,
+			&notification.ActorUsername,
+			&notification.PostID,
+			&notification.PostTitle,
+			&notification.CommentID,
+			&notification.CommentSummary,
+			&notification.TopicID,
+			&notification.TopicName,
+			&notification.TopicSlug,
+			&notification.Message,
+			&notification.CreatedAt,
+			&notification.ReadAt,
+		}
+		if err := rows.Scan(append(dest, keysetDest...)...); err != nil {
+			return nil, pageInfo, err
+		}
+
+		notifications = append(notifications, notification)
+		if len(notifications) == req.PageSize {
+			cursorValues = append([]any{}, keysetValues...)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, pageInfo, err
+	}
+
+	if len(notifications) > req.PageSize {
+		notifications = notifications[:req.PageSize]
+		pageInfo.NextCursor = encodeCursor(ordering, cursorValues...)
+	}
+
+	return notifications, pageInfo, nil
+}
+
+// CountUnreadNotifications returns the number of the user's unread notifications
+func (s *PostgresStore) CountUnreadNotifications(ctx context.Context, userID int) (int, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "CountUnreadNotifications")
+	defer done()
+
+	query := `
+		SELECT COUNT(*)
+		FROM notifications
+		WHERE user_id = $1 AND read_at IS NULL`
+
+	var count int
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(&count)
+	return count, err
+}
+
+// MarkNotificationsRead marks the user's notifications with the given IDs as read, or all of them if none are given
+// Returns the number of notifications marked
+func (s *PostgresStore) MarkNotificationsRead(ctx context.Context, userID int, notificationIDs []int) (int64, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "MarkNotificationsRead")
+	defer done()
+
+	args := []any{userID, time.Now()}
+	query := `
+		UPDATE notifications SET
+			read_at = $2
+		WHERE user_id = $1
+		AND read_at IS NULL`
+
+	if len(notificationIDs) > 0 {
+		args = append(args, pq.Array(notificationIDs))
+		query += " AND id = ANY($3::int[])"
+	}
+
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// ListNotificationMutes returns the notification types the user muted
+func (s *PostgresStore) ListNotificationMutes(ctx context.Context, userID int) ([]string, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "ListNotificationMutes")
+	defer done()
+
+	query := `
+		SELECT type
+		FROM notification_mutes
+		WHERE user_id = $1
+		ORDER BY type`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mutedTypes := []string{}
+	for rows.Next() {
+		var notificationType string
+		if err := rows.Scan(&notificationType); err != nil {
+			return nil, err
+		}
+		mutedTypes = append(mutedTypes, notificationType)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return mutedTypes, nil
+}
+
+// SetNotificationMutes replaces the notification types the user muted
+func (s *PostgresStore) SetNotificationMutes(ctx context.Context, userID int, mutedTypes []string) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "SetNotificationMutes")
+	defer done()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	deleteQuery := `
+		DELETE FROM notification_mutes
+		WHERE user_id = $1`
+
+	if _, err := tx.ExecContext(ctx, deleteQuery, userID); err != nil {
+		return err
+	}
+
+	insertQuery := `
+		INSERT INTO notification_mutes (
+			user_id,
+			type,
+			created_at)
+		SELECT $1::int, t, $3::timestamp
+		FROM (SELECT DISTINCT unnest($2::text[]) AS t) types`
+
+	if _, err := tx.ExecContext(ctx, insertQuery, userID, pq.Array(mutedTypes), time.Now()); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
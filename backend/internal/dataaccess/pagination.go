@@ -0,0 +1,196 @@
+package dataaccess
+
+import (
+	"bytes"
+	"cvwo/internal/constants"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A column of the ordering used for keyset pagination
+// The last column must be unique (the id) so the ordering is total
+type keysetColumn struct {
+	expr string
+	kind keysetKind
+	desc bool
+}
+
+// The type of a column's values, which the values decoded from a cursor must have
+type keysetKind int
+
+const (
+	keysetInt keysetKind = iota
+	keysetFloat
+	keysetTime
+	keysetBool
+	keysetString
+)
+
+// Cursor contents: the ordering it was issued for and the sort key values of the last row
+type cursor struct {
+	Ordering string `json:"o"`
+	Values   []any  `json:"v"`
+}
+
+// Identifies an ordering, so a cursor cannot be reused with a different sort
+func orderingKey(columns []keysetColumn) string {
+	parts := []string{}
+	for _, column := range columns {
+		direction := constants.SORT_ASC
+		if column.desc {
+			direction = constants.SORT_DESC
+		}
+		parts = append(parts, column.expr+" "+direction)
+	}
+	return strings.Join(parts, ",")
+}
+
+// Encodes the sort key values of the last row of a page into an opaque token
+func encodeCursor(columns []keysetColumn, values ...any) string {
+	for i, value := range values {
+		// Microsecond precision survives the round trip through TIMESTAMP
+		if t, ok := value.(time.Time); ok {
+			values[i] = t.Format(time.RFC3339Nano)
+		}
+	}
+
+	data, _ := json.Marshal(cursor{Ordering: orderingKey(columns), Values: values})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decodes a cursor, returns INVALID_CURSOR_ERROR if it is malformed, was issued for another ordering
+// or holds a value of the wrong type for its column
+func decodeCursor(token string, columns []keysetColumn) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New(constants.INVALID_CURSOR_ERROR)
+	}
+
+	// Keep numbers as json.Number, so integers are passed to PostgreSQL exactly
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+
+	var c cursor
+	if err := decoder.Decode(&c); err != nil || decoder.More() {
+		return nil, errors.New(constants.INVALID_CURSOR_ERROR)
+	}
+
+	if c.Ordering != orderingKey(columns) || len(c.Values) != len(columns) {
+		return nil, errors.New(constants.INVALID_CURSOR_ERROR)
+	}
+
+	values := make([]any, len(columns))
+	for i, column := range columns {
+		value, ok := cursorValue(c.Values[i], column.kind)
+		if !ok {
+			return nil, errors.New(constants.INVALID_CURSOR_ERROR)
+		}
+		values[i] = value
+	}
+
+	return values, nil
+}
+
+// Converts a decoded cursor value to the column's type, ok is false if it has another type
+func cursorValue(value any, kind keysetKind) (any, bool) {
+	switch kind {
+	case keysetInt:
+		number, ok := value.(json.Number)
+		if !ok {
+			return nil, false
+		}
+		n, err := number.Int64()
+		return n, err == nil
+	case keysetFloat:
+		number, ok := value.(json.Number)
+		if !ok {
+			return nil, false
+		}
+		f, err := number.Float64()
+		return f, err == nil
+	case keysetTime:
+		text, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+		_, err := time.Parse(time.RFC3339Nano, text)
+		return text, err == nil
+	case keysetBool:
+		b, ok := value.(bool)
+		return b, ok
+	case keysetString:
+		text, ok := value.(string)
+		return text, ok
+	}
+	return nil, false
+}
+
+// Writes the condition selecting rows strictly after the cursor values in the given ordering
+// Expanded lexicographically, (a > x) OR (a = x AND b > y) OR ..., since directions can differ per column
+func writeKeysetCondition(queryBuilder *strings.Builder, args *[]any, columns []keysetColumn, values []any) {
+	placeholders := make([]string, len(values))
+	for i, value := range values {
+		*args = append(*args, value)
+		placeholders[i] = fmt.Sprintf("$%d", len(*args))
+	}
+
+	disjuncts := []string{}
+	for i, column := range columns {
+		conjuncts := []string{}
+		for j := 0; j < i; j++ {
+			conjuncts = append(conjuncts, fmt.Sprintf("%s = %s", columns[j].expr, placeholders[j]))
+		}
+
+		operator := ">"
+		if column.desc {
+			operator = "<"
+		}
+		conjuncts = append(conjuncts, fmt.Sprintf("%s %s %s", column.expr, operator, placeholders[i]))
+
+		disjuncts = append(disjuncts, "("+strings.Join(conjuncts, " AND ")+")")
+	}
+
+	queryBuilder.WriteString(" AND (" + strings.Join(disjuncts, " OR ") + ")")
+}
+
+// Writes the ORDER BY clause for the given ordering
+func writeOrderBy(queryBuilder *strings.Builder, columns []keysetColumn) {
+	parts := []string{}
+	for _, column := range columns {
+		direction := " ASC"
+		if column.desc {
+			direction = " DESC"
+		}
+		parts = append(parts, column.expr+direction)
+	}
+	queryBuilder.WriteString(" ORDER BY " + strings.Join(parts, ", "))
+}
+
+// Selects the ordering columns after a row's own fields, so the cursor can be built from the last row
+func keysetSelectFields(columns []keysetColumn) string {
+	var fields strings.Builder
+	for _, column := range columns {
+		fields.WriteString(",\n\t\t" + column.expr)
+	}
+	return fields.String()
+}
+
+// Returns the values the ordering columns are scanned into and the matching scan destinations
+func keysetScanDest(columns []keysetColumn) ([]any, []any) {
+	values := make([]any, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	return values, dest
+}
+
+// Counting scans the whole result set, so infinite scroll clients can opt out
+func shouldCount(includeCount *bool) bool {
+	return includeCount == nil || *includeCount
+}
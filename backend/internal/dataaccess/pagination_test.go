@@ -0,0 +1,83 @@
+package dataaccess
+
+import (
+	"cvwo/internal/constants"
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	ordering := []keysetColumn{
+		{expr: "p.is_pinned", kind: keysetBool, desc: true},
+		{expr: "p.hot_rank", kind: keysetFloat, desc: true},
+		{expr: "p.created_at", kind: keysetTime, desc: true},
+		{expr: "t.name", kind: keysetString, desc: true},
+		{expr: "p.id", kind: keysetInt, desc: true},
+	}
+	orderingKey := `"p.is_pinned desc,p.hot_rank desc,p.created_at desc,t.name desc,p.id desc"`
+	createdAt := time.Date(2026, 10, 18, 11, 12, 13, 456789000, time.UTC)
+
+	issued := encodeCursor(ordering, true, 1.25, createdAt, "golang", int64(42))
+	values, err := decodeCursor(issued, ordering)
+	if err != nil {
+		t.Fatalf("issued cursor rejected: %v", err)
+	}
+	want := []any{true, 1.25, createdAt.Format(time.RFC3339Nano), "golang", int64(42)}
+	for i := range want {
+		if values[i] != want[i] {
+			t.Errorf("value %d: got %#v, want %#v", i, values[i], want[i])
+		}
+	}
+
+	tests := []struct {
+		name string
+		json string
+	}{
+		{"other ordering", `{"o":"p.id asc","v":[42]}`},
+		{"missing value", `{"o":` + orderingKey + `,"v":[true,1.25,"2026-10-18T11:12:13Z","golang"]}`},
+		{"extra value", `{"o":` + orderingKey + `,"v":[true,1.25,"2026-10-18T11:12:13Z","golang",42,43]}`},
+		{"null value", `{"o":` + orderingKey + `,"v":[true,1.25,"2026-10-18T11:12:13Z","golang",null]}`},
+		{"string for a boolean", `{"o":` + orderingKey + `,"v":["true",1.25,"2026-10-18T11:12:13Z","golang",42]}`},
+		{"string for a number", `{"o":` + orderingKey + `,"v":[true,"1 OR 1=1","2026-10-18T11:12:13Z","golang",42]}`},
+		{"malformed time", `{"o":` + orderingKey + `,"v":[true,1.25,"yesterday","golang",42]}`},
+		{"number for a string", `{"o":` + orderingKey + `,"v":[true,1.25,"2026-10-18T11:12:13Z",7,42]}`},
+		{"fractional id", `{"o":` + orderingKey + `,"v":[true,1.25,"2026-10-18T11:12:13Z","golang",4.2]}`},
+		{"id out of range", `{"o":` + orderingKey + `,"v":[true,1.25,"2026-10-18T11:12:13Z","golang",99999999999999999999]}`},
+		{"unknown field", `{"o":` + orderingKey + `,"v":[true,1.25,"2026-10-18T11:12:13Z","golang",42],"x":1}`},
+		{"trailing data", `{"o":` + orderingKey + `,"v":[true,1.25,"2026-10-18T11:12:13Z","golang",42]}{}`},
+		{"not JSON", `cursor`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := base64.RawURLEncoding.EncodeToString([]byte(tt.json))
+			if _, err := decodeCursor(token, ordering); err == nil || err.Error() != constants.INVALID_CURSOR_ERROR {
+				t.Errorf("got %v, want %s", err, constants.INVALID_CURSOR_ERROR)
+			}
+		})
+	}
+
+	if _, err := decodeCursor("not base64!", ordering); err == nil || err.Error() != constants.INVALID_CURSOR_ERROR {
+		t.Errorf("malformed base64: got %v, want %s", err, constants.INVALID_CURSOR_ERROR)
+	}
+}
+
+func TestPaginateRejectsNegativeOffset(t *testing.T) {
+	items := []int{1, 2, 3}
+	ordering := "numbers"
+
+	_, pageInfo, err := paginate(items, ordering, 0, 2, "", nil)
+	if err != nil || pageInfo.NextCursor == "" {
+		t.Fatalf("first page: cursor %q, error %v", pageInfo.NextCursor, err)
+	}
+	page, _, err := paginate(items, ordering, 0, 2, pageInfo.NextCursor, nil)
+	if err != nil || len(page) != 1 || page[0] != 3 {
+		t.Fatalf("second page: got %v, error %v", page, err)
+	}
+
+	token := encodeCursor(memoryCursorOrdering(ordering), -2)
+	if _, _, err := paginate(items, ordering, 0, 2, token, nil); err == nil || err.Error() != constants.INVALID_CURSOR_ERROR {
+		t.Errorf("got %v, want %s", err, constants.INVALID_CURSOR_ERROR)
+	}
+}
@@ -1,10 +1,11 @@
 package dataaccess
 
 import (
+	"context"
 	"cvwo/internal/constants"
 	"cvwo/internal/dataaccess/queries"
-	"cvwo/internal/database"
 	"cvwo/internal/models"
+	"cvwo/internal/telemetry"
 	"cvwo/internal/utils"
 	"database/sql"
 	"errors"
@@ -13,16 +14,36 @@ import (
 	"time"
 )
 
-// CreatePost creates a new post in the database with automatic summary generation
+// CreatePost creates a new post in the database, rendering its content and generating its summary
 // Uses transaction to ensure both post creation and topic count update are atomic
 // Returns the newly created post ID or an error if creation fails
-func CreatePost(post models.Post) (int, error) {
-	tx, err := database.DB.Begin()
+func (s *PostgresStore) CreatePost(ctx context.Context, post models.Post) (int, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "CreatePost")
+	defer done()
+
+	tx, err := s.begin(ctx)
 	if err != nil {
 		return 0, err
 	}
 	defer tx.Rollback()
 
+	// Verify the topic exists and still accepts posts
+	checkTopicQuery := `
+		SELECT is_archived
+		FROM topics WHERE id = $1`
+
+	var isArchived bool
+	err = tx.QueryRowContext(ctx, checkTopicQuery, post.TopicID).Scan(&isArchived)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, errors.New(constants.NOT_FOUND_ERROR)
+		}
+		return 0, err
+	}
+	if isArchived {
+		return 0, errors.New(constants.TOPIC_ARCHIVED_ERROR)
+	}
+
 	// Insert the new post
 	query := `
 		INSERT INTO posts (
@@ -30,20 +51,22 @@ func CreatePost(post models.Post) (int, error) {
 			title,
 			summary,
 			content,
+			content_html,
 			user_id,
 			created_at,
 			updated_at,
 			is_deleted)
-		VALUES ($1, $2, $3, $4, $5, $6, $6, false) RETURNING id`
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7, false) RETURNING id`
 
 	var postID int
 	now := time.Now()
-	summary, _ := utils.GeneratePostSummary(post)
-	err = tx.QueryRow(query,
+	utils.RenderPost(&post)
+	err = tx.QueryRowContext(ctx, query,
 		post.TopicID,
 		post.Title,
-		summary,
+		post.Summary,
 		post.Content,
+		post.ContentHTML,
 		post.UserID,
 		now,
 	).Scan(&postID)
@@ -51,13 +74,18 @@ func CreatePost(post models.Post) (int, error) {
 		return 0, err
 	}
 
+	post.ID = postID
+	if err := insertPostRevision(ctx, tx.Tx, &post, post.UserID, nil, now); err != nil {
+		return 0, err
+	}
+
 	// Update topic post count
 	updateTopicQuery := `
 		UPDATE topics SET
 			no_of_posts = no_of_posts + 1
 		WHERE id = $1`
 
-	_, err = tx.Exec(updateTopicQuery, post.TopicID)
+	_, err = tx.ExecContext(ctx, updateTopicQuery, post.TopicID)
 	if err != nil {
 		return 0, err
 	}
@@ -65,24 +93,46 @@ func CreatePost(post models.Post) (int, error) {
 	return postID, tx.Commit()
 }
 
-// UpdatePost modifies an existing post's content and regenerates its summary
+// UpdatePost modifies an existing post's content, re-rendering it and regenerating its summary
+// The rendered content and summary are set on the post, and the new version is recorded as a revision
 // Only updates non-deleted posts and returns error if post is not found or deleted
-func UpdatePost(post models.Post) error {
+func (s *PostgresStore) UpdatePost(ctx context.Context, post *models.Post, editorID int) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "UpdatePost")
+	defer done()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updatePost(ctx, tx.Tx, post, editorID, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Updates a post and records the new version as its next revision
+// The update locks the post row, so concurrent edits get consecutive revision numbers
+func updatePost(ctx context.Context, tx *sql.Tx, post *models.Post, editorID int, restoredFrom *int) error {
 	query := `
 		UPDATE posts SET
 			title = $1,
 			summary = $2,
 			content = $3,
-			updated_at = $4
-		WHERE id = $5 AND is_deleted = false`
+			content_html = $4,
+			updated_at = $5
+		WHERE id = $6 AND is_deleted = false`
 
 	now := time.Now()
-	summary, _ := utils.GeneratePostSummary(post)
+	utils.RenderPost(post)
 
-	result, err := database.DB.Exec(query,
+	result, err := tx.ExecContext(ctx, query,
 		post.Title,
-		summary,
+		post.Summary,
 		post.Content,
+		post.ContentHTML,
 		now,
 		post.ID,
 	)
@@ -100,20 +150,32 @@ func UpdatePost(post models.Post) error {
 		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
 	}
 
-	return nil
+	return insertPostRevision(ctx, tx, post, editorID, restoredFrom, now)
 }
 
 // DeletePost performs a soft delete by marking the post as deleted (tombstone pattern)
 // Preserves data integrity while hiding the post from normal queries
 // Uses transaction to ensure both post deletion and topic count update are atomic
 // Using tombstone - mark as deleted instead of actually deleting
-func DeletePost(id int) error {
-	tx, err := database.DB.Begin()
+func (s *PostgresStore) DeletePost(ctx context.Context, id int) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "DeletePost")
+	defer done()
+
+	tx, err := s.begin(ctx)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
+	if err := deletePost(ctx, tx.Tx, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Soft deletes the post within the transaction, returns NO_ROWS_AFFECTED_ERROR if it is already deleted
+func deletePost(ctx context.Context, tx *sql.Tx, id int) error {
 	// First get the topic_id before marking as deleted
 	getTopicQuery := `
 		SELECT topic_id
@@ -121,7 +183,7 @@ func DeletePost(id int) error {
 		AND is_deleted = false`
 
 	var topicID int
-	err = tx.QueryRow(getTopicQuery, id).Scan(&topicID)
+	err := tx.QueryRowContext(ctx, getTopicQuery, id).Scan(&topicID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
@@ -137,7 +199,7 @@ func DeletePost(id int) error {
 			deleted_at = $1
 		WHERE id = $2 AND is_deleted = false`
 
-	result, err := tx.Exec(deleteQuery, now, id)
+	result, err := tx.ExecContext(ctx, deleteQuery, now, id)
 	if err != nil {
 		return err
 	}
@@ -157,20 +219,19 @@ func DeletePost(id int) error {
 			no_of_posts = no_of_posts - 1
 		WHERE id = $1`
 
-	_, err = tx.Exec(updateTopicQuery, topicID)
-	if err != nil {
-		return err
-	}
-
-	return tx.Commit()
+	_, err = tx.ExecContext(ctx, updateTopicQuery, topicID)
+	return err
 }
 
 // VotePost records or updates a user's vote on a post
 // Uses upsert pattern (INSERT ... ON CONFLICT) and atomic transactions
 // Automatically recalculates post score based on all votes
 // Transaction to ensure vote and score update are atomic
-func VotePost(vote models.PostVote) error {
-	tx, err := database.DB.Begin()
+func (s *PostgresStore) VotePost(ctx context.Context, vote models.PostVote) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "VotePost")
+	defer done()
+
+	tx, err := s.begin(ctx)
 	if err != nil {
 		return err
 	}
@@ -182,7 +243,7 @@ func VotePost(vote models.PostVote) error {
 			WHERE id = $1 AND is_deleted = false)`
 
 	var exists bool
-	err = tx.QueryRow(checkQuery, vote.PostID).Scan(&exists)
+	err = tx.QueryRowContext(ctx, checkQuery, vote.PostID).Scan(&exists)
 	if err != nil {
 		return err
 	}
@@ -199,20 +260,27 @@ func VotePost(vote models.PostVote) error {
 		ON CONFLICT (user_id, post_id)
 		DO UPDATE SET vote_value = EXCLUDED.vote_value`
 
-	_, err = tx.Exec(query, vote.UserID, vote.PostID, vote.VoteValue)
+	_, err = tx.ExecContext(ctx, query, vote.UserID, vote.PostID, vote.VoteValue)
 	if err != nil {
 		return err
 	}
 
-	// Update post score
+	// Update post score and vote counts, the rankings are recomputed by PostgreSQL
 	updateScoreQuery := `
 		UPDATE posts SET
-			score = COALESCE((
-				SELECT SUM(vote_value) FROM post_votes
-				WHERE post_id = $1), 0)
-		WHERE id = $1`
-
-	_, err = tx.Exec(updateScoreQuery, vote.PostID)
+			score = v.score,
+			upvotes = v.upvotes,
+			downvotes = v.downvotes
+		FROM (
+			SELECT
+				COALESCE(SUM(vote_value), 0) AS score,
+				COUNT(*) FILTER (WHERE vote_value > 0) AS upvotes,
+				COUNT(*) FILTER (WHERE vote_value < 0) AS downvotes
+			FROM post_votes
+			WHERE post_id = $1) v
+		WHERE posts.id = $1`
+
+	_, err = tx.ExecContext(ctx, updateScoreQuery, vote.PostID)
 	if err != nil {
 		return err
 	}
@@ -224,7 +292,7 @@ func VotePost(vote models.PostVote) error {
 
 	updateKarmaQuery := queries.MakeUpdateKarmaQuery(userIdQuery)
 
-	_, err = tx.Exec(updateKarmaQuery, vote.PostID)
+	_, err = tx.ExecContext(ctx, updateKarmaQuery, vote.PostID)
 	if err != nil {
 		return err
 	}
@@ -235,9 +303,31 @@ func VotePost(vote models.PostVote) error {
 // GetPosts retrieves a paginated list of posts with optional filtering by topic and user
 // Excludes deleted posts and orders by creation date (newest first)
 // Parameters: limit (max results), offset (pagination), topicID (optional filter), userID (optional filter)
-func ListPosts(isAuthenticated bool, currentUserID int, req models.ListPostsRequest) ([]models.Post, int, error) {
+// Pages by keyset when req.Cursor is set, the returned NextCursor continues after the last post
+func (s *PostgresStore) ListPosts(ctx context.Context, isAuthenticated bool, currentUserID int, req models.ListPostsRequest) ([]models.Post, models.PageInfo, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "ListPosts")
+	defer done()
+
 	args := []any{}
 	var queryBuilder strings.Builder
+	pageInfo := models.PageInfo{}
+
+	// Pinned posts come first within a topic
+	ordering := []keysetColumn{}
+	if req.TopicID != nil {
+		ordering = append(ordering, keysetColumn{expr: "p.is_pinned", kind: keysetBool, desc: true})
+	}
+
+	desc := req.OrderBy != constants.SORT_ASC
+	switch {
+	case req.SavedOnly && req.Sort == constants.ORDER_BY_SAVED:
+		ordering = append(ordering, keysetColumn{expr: "s.created_at", kind: keysetTime, desc: desc})
+	case req.Sort == constants.ORDER_BY_COMMENTS:
+		ordering = append(ordering, keysetColumn{expr: "p.no_of_comments", desc: desc})
+	default:
+		ordering = append(ordering, keysetColumn{expr: "p." + rankingColumn(req.Sort), kind: rankingKind(req.Sort), desc: desc})
+	}
+	ordering = append(ordering, keysetColumn{expr: "p.id", desc: desc})
 
 	// no content, no pinned_comment_id field
 	selectFields := `
@@ -249,9 +339,13 @@ func ListPosts(isAuthenticated bool, currentUserID int, req models.ListPostsRequ
 		p.updated_at,
 		p.user_id,
 		COALESCE(p.score, 0),
+		p.upvotes,
+		p.downvotes,
 		COALESCE(p.no_of_comments, 0),
 		p.is_deleted,
 		p.deleted_at,
+		COALESCE(p.is_locked, false),
+		COALESCE(p.is_pinned, false),
 		t.name,
 		u.username`
 
@@ -260,48 +354,65 @@ func ListPosts(isAuthenticated bool, currentUserID int, req models.ListPostsRequ
 		if req.FilterFollowingTopics {
 			query := fmt.Sprintf(`
 				SELECT %s,
-				COALESCE(v.vote_value, 0)
+				COALESCE(v.vote_value, 0),
+				s.user_id IS NOT NULL%s
 				FROM posts p
 
 				LEFT JOIN post_votes v
 					ON p.id = v.post_id
 					AND v.user_id = $1
+				LEFT JOIN saved_posts s
+					ON p.id = s.post_id
+					AND s.user_id = $1
 				LEFT JOIN topics t ON p.topic_id = t.id
 				LEFT JOIN users u ON p.user_id = u.id
 				INNER JOIN user_topics ut
 					ON p.topic_id = ut.topic_id
 					AND ut.user_id = $1
-				WHERE 1=1`, selectFields)
+				WHERE 1=1`, selectFields, keysetSelectFields(ordering))
 
 			queryBuilder.WriteString(query)
 		} else {
 			query := fmt.Sprintf(`
 				SELECT %s,
-				COALESCE(v.vote_value, 0)
+				COALESCE(v.vote_value, 0),
+				s.user_id IS NOT NULL%s
 				FROM posts p
 
 				LEFT JOIN post_votes v
 					ON p.id = v.post_id
 					AND v.user_id = $1
+				LEFT JOIN saved_posts s
+					ON p.id = s.post_id
+					AND s.user_id = $1
 				LEFT JOIN topics t ON p.topic_id = t.id
 				LEFT JOIN users u ON p.user_id = u.id
-				WHERE 1=1`, selectFields)
+				WHERE 1=1`, selectFields, keysetSelectFields(ordering))
 
 			queryBuilder.WriteString(query)
 		}
 	} else {
 		query := fmt.Sprintf(`
 			SELECT %s,
-			0
+			0,
+			false%s
 			FROM posts p
 
 			LEFT JOIN topics t ON p.topic_id = t.id
 			LEFT JOIN users u ON p.user_id = u.id
-			WHERE 1=1`, selectFields)
+			WHERE 1=1`, selectFields, keysetSelectFields(ordering))
 
 		queryBuilder.WriteString(query)
 	}
 
+	// Nothing is saved without a user
+	if req.SavedOnly {
+		if !isAuthenticated {
+			return []models.Post{}, pageInfo, nil
+		}
+		queryBuilder.WriteString(" AND s.user_id IS NOT NULL")
+	}
+
 	if req.Search != "" {
 		args = append(args, "%"+req.Search+"%")
 		queryBuilder.WriteString(fmt.Sprintf(" AND p.title ILIKE $%d", len(args)))
@@ -321,53 +432,61 @@ func ListPosts(isAuthenticated bool, currentUserID int, req models.ListPostsRequ
 		queryBuilder.WriteString(fmt.Sprintf(" AND p.topic_id = $%d", len(args)))
 	}
 
-	// Get total count for pagination
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM (%s)", queryBuilder.String())
-	var totalCount int
-	err := database.DB.QueryRow(countQuery, args...).Scan(&totalCount)
-	if err != nil {
-		return nil, 0, err
+	if since := windowStart(req.Window); since != nil {
+		args = append(args, *since)
+		queryBuilder.WriteString(fmt.Sprintf(" AND p.created_at >= $%d", len(args)))
 	}
 
-	switch req.Sort {
-	case constants.ORDER_BY_VOTES:
-		queryBuilder.WriteString(" ORDER BY p.score")
-	case constants.ORDER_BY_COMMENTS:
-		queryBuilder.WriteString(" ORDER BY p.no_of_comments")
-	default:
-		queryBuilder.WriteString(" ORDER BY p.created_at")
+	// Get total count for pagination
+	if shouldCount(req.IncludeCount) {
+		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM (%s)", queryBuilder.String())
+		var totalCount int
+		err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&totalCount)
+		if err != nil {
+			return nil, pageInfo, err
+		}
+		pageInfo.Count = &totalCount
 	}
 
-	switch req.OrderBy {
-	case constants.SORT_ASC:
-		queryBuilder.WriteString(" ASC")
-	default:
-		queryBuilder.WriteString(" DESC")
+	if req.Cursor != "" {
+		values, err := decodeCursor(req.Cursor, ordering)
+		if err != nil {
+			return nil, pageInfo, err
+		}
+		writeKeysetCondition(&queryBuilder, &args, ordering, values)
 	}
 
-	if req.PageSize <= 0 || req.PageSize > constants.MAX_PAGE_SIZE {
-		req.PageSize = constants.MAX_PAGE_SIZE
+	writeOrderBy(&queryBuilder, ordering)
+
+	// The handlers cap the page size at the configured maximum
+	if req.PageSize <= 0 {
+		req.PageSize = constants.DEFAULT_PAGE_SIZE
 	}
 
-	args = append(args, req.PageSize)
+	// Fetch one extra row to know whether there is a next page
+	args = append(args, req.PageSize+1)
 	queryBuilder.WriteString(fmt.Sprintf(" LIMIT $%d", len(args)))
 
-	if req.Page > 0 {
+	// The cursor replaces the offset
+	if req.Page > 0 && req.Cursor == "" {
 		offset := (req.Page - 1) * req.PageSize
 		args = append(args, offset)
 		queryBuilder.WriteString(fmt.Sprintf(" OFFSET $%d", len(args)))
 	}
 
-	rows, err := database.DB.Query(queryBuilder.String(), args...)
+	rows, err := s.db.QueryContext(ctx, queryBuilder.String(), args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, pageInfo, err
 	}
 	defer rows.Close()
 
+	keysetValues, keysetDest := keysetScanDest(ordering)
+	var cursorValues []any
+
 	posts := []models.Post{}
 	for rows.Next() {
 		var post models.Post
-		if err := rows.Scan(
+		dest := []any{
 			&post.ID,
 			&post.TopicID,
 			&post.Title,
@@ -376,14 +495,20 @@ func ListPosts(isAuthenticated bool, currentUserID int, req models.ListPostsRequ
 			&post.UpdatedAt,
 			&post.UserID,
 			&post.Score,
+			&post.Upvotes,
+			&post.Downvotes,
 			&post.NoOfComments,
 			&post.IsDeleted,
 			&post.DeletedAt,
+			&post.IsLocked,
+			&post.IsPinned,
 			&post.TopicName,
 			&post.Username,
 			&post.MyVote,
-		); err != nil {
-			return nil, 0, err
+			&post.IsSaved,
+		}
+		if err := rows.Scan(append(dest, keysetDest...)...); err != nil {
+			return nil, pageInfo, err
 		}
 
 		// if post is deleted, clear title and summary
@@ -394,18 +519,29 @@ func ListPosts(isAuthenticated bool, currentUserID int, req models.ListPostsRequ
 		}
 
 		posts = append(posts, post)
+		if len(posts) == req.PageSize {
+			cursorValues = append([]any{}, keysetValues...)
+		}
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, 0, err
+		return nil, pageInfo, err
 	}
 
-	return posts, totalCount, nil
+	if len(posts) > req.PageSize {
+		posts = posts[:req.PageSize]
+		pageInfo.NextCursor = encodeCursor(ordering, cursorValues...)
+	}
+
+	return posts, pageInfo, nil
 }
 
 // GetPostByID retrieves a single post by its ID, including deleted posts
 // Returns nil and error if post is not found or deleted
-func GetPost(isAutheticated bool, userID, postID int) (*models.Post, error) {
+func (s *PostgresStore) GetPost(ctx context.Context, isAutheticated bool, userID, postID int) (*models.Post, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "GetPost")
+	defer done()
+
 	var query string
 	args := []any{postID}
 	post := &models.Post{}
@@ -416,25 +552,33 @@ func GetPost(isAutheticated bool, userID, postID int) (*models.Post, error) {
 		p.title,
 		p.summary,
 		p.content,
+		p.content_html,
 		p.created_at,
 		p.updated_at,
 		p.user_id,
 		p.pinned_comment_id,
 		COALESCE(p.score, 0),
+		p.upvotes,
+		p.downvotes,
 		COALESCE(p.no_of_comments, 0),
 		p.is_deleted,
 		p.deleted_at,
+		COALESCE(p.is_locked, false),
+		COALESCE(p.is_pinned, false),
 		t.name,
 		u.username`
 
 	if isAutheticated {
 		query = fmt.Sprintf(`
 			SELECT %s,
-			COALESCE(v.vote_value, 0)
+			COALESCE(v.vote_value, 0),
+			s.user_id IS NOT NULL
 			FROM posts p
 
 			LEFT JOIN post_votes v
 				ON p.id = v.post_id AND v.user_id = $2
+			LEFT JOIN saved_posts s
+				ON p.id = s.post_id AND s.user_id = $2
 			LEFT JOIN topics t ON p.topic_id = t.id
 			LEFT JOIN users u ON p.user_id = u.id
 			WHERE p.id = $1`, selectFields)
@@ -443,30 +587,37 @@ func GetPost(isAutheticated bool, userID, postID int) (*models.Post, error) {
 	} else {
 		query = fmt.Sprintf(`
 			  SELECT %s,
-			  0
+			  0,
+			  false
 			  FROM posts p
 
 			  LEFT JOIN topics t ON p.topic_id = t.id
 			  LEFT JOIN users u ON p.user_id = u.id
 			  WHERE p.id = $1`, selectFields)
 	}
-	err := database.DB.QueryRow(query, args...).Scan(
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(
 		&post.ID,
 		&post.TopicID,
 		&post.Title,
 		&post.Summary,
 		&post.Content,
+		&post.ContentHTML,
 		&post.CreatedAt,
 		&post.UpdatedAt,
 		&post.UserID,
 		&post.PinnedCommentID,
 		&post.Score,
+		&post.Upvotes,
+		&post.Downvotes,
 		&post.NoOfComments,
 		&post.IsDeleted,
 		&post.DeletedAt,
+		&post.IsLocked,
+		&post.IsPinned,
 		&post.TopicName,
 		&post.Username,
 		&post.MyVote,
+		&post.IsSaved,
 	)
 
 	if err != nil {
@@ -481,18 +632,22 @@ func GetPost(isAutheticated bool, userID, postID int) (*models.Post, error) {
 		post.Title = ""
 		post.Summary = ""
 		post.Content = ""
+		post.ContentHTML = ""
 	}
 
 	return post, nil
 }
 
-func PinComment(postID, commentID int) error {
+func (s *PostgresStore) PinComment(ctx context.Context, postID, commentID int) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "PinComment")
+	defer done()
+
 	query := `
 		UPDATE posts SET
 			pinned_comment_id = $2
 		WHERE id = $1 AND is_deleted = false`
 
-	result, err := database.DB.Exec(query, postID, commentID)
+	result, err := s.db.ExecContext(ctx, query, postID, commentID)
 	if err != nil {
 		return err
 	}
@@ -509,13 +664,70 @@ func PinComment(postID, commentID int) error {
 	return nil
 }
 
-func UnpinComment(postID int) error {
+func (s *PostgresStore) UnpinComment(ctx context.Context, postID int) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "UnpinComment")
+	defer done()
+
 	query := `
 		UPDATE posts SET
 			pinned_comment_id = NULL
 		WHERE id = $1 AND is_deleted = false`
 
-	result, err := database.DB.Exec(query, postID)
+	result, err := s.db.ExecContext(ctx, query, postID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	return nil
+}
+
+// SetPostLocked locks or unlocks a post, locked posts do not accept new comments
+func (s *PostgresStore) SetPostLocked(ctx context.Context, postID int, isLocked bool) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "SetPostLocked")
+	defer done()
+
+	query := `
+		UPDATE posts SET
+			is_locked = $2
+		WHERE id = $1 AND is_deleted = false`
+
+	result, err := s.db.ExecContext(ctx, query, postID, isLocked)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	return nil
+}
+
+// SetPostPinned pins or unpins a post to the top of its topic
+func (s *PostgresStore) SetPostPinned(ctx context.Context, postID int, isPinned bool) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "SetPostPinned")
+	defer done()
+
+	query := `
+		UPDATE posts SET
+			is_pinned = $2
+		WHERE id = $1 AND is_deleted = false`
+
+	result, err := s.db.ExecContext(ctx, query, postID, isPinned)
 	if err != nil {
 		return err
 	}
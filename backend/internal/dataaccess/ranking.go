@@ -0,0 +1,51 @@
+package dataaccess
+
+import (
+	"cvwo/internal/constants"
+	"time"
+)
+
+// Column of posts and comments to order by for the sort option
+// The hot, best and controversial rankings are generated columns, see the ranking migration
+func rankingColumn(sort string) string {
+	switch sort {
+	case constants.ORDER_BY_HOT:
+		return "hot_rank"
+	case constants.ORDER_BY_BEST:
+		return "best_rank"
+	case constants.ORDER_BY_CONTROVERSIAL:
+		return "controversy_rank"
+	case constants.ORDER_BY_TOP, constants.ORDER_BY_VOTES:
+		return "score"
+	default:
+		return "created_at"
+	}
+}
+
+// Type of the ranking column's values, which cursors are checked against
+func rankingKind(sort string) keysetKind {
+	switch rankingColumn(sort) {
+	case "hot_rank", "best_rank", "controversy_rank":
+		return keysetFloat
+	case "score":
+		return keysetInt
+	default:
+		return keysetTime
+	}
+}
+
+// Earliest creation time included by the time window, nil for all time
+func windowStart(window string) *time.Time {
+	var since time.Time
+	switch window {
+	case constants.WINDOW_DAY:
+		since = time.Now().AddDate(0, 0, -1)
+	case constants.WINDOW_WEEK:
+		since = time.Now().AddDate(0, 0, -7)
+	case constants.WINDOW_MONTH:
+		since = time.Now().AddDate(0, -1, 0)
+	default:
+		return nil
+	}
+	return &since
+}
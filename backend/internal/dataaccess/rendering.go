@@ -0,0 +1,122 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/models"
+	"cvwo/internal/telemetry"
+	"cvwo/internal/utils"
+)
+
+// Rows re-rendered per query, so large tables are not loaded into memory at once
+const rerenderBatchSize = 500
+
+// RerenderPosts re-renders the content and summary of every post, e.g. after the Markdown pipeline changed
+// Returns the number of posts updated
+func (s *PostgresStore) RerenderPosts(ctx context.Context) (int, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "RerenderPosts")
+	defer done()
+
+	selectQuery := `
+		SELECT id, COALESCE(content, '')
+		FROM posts
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2`
+
+	updateQuery := `
+		UPDATE posts SET
+			content_html = $2,
+			summary = $3
+		WHERE id = $1`
+
+	updated := 0
+	lastID := 0
+	for {
+		posts := []models.Post{}
+		rows, err := s.db.QueryContext(ctx, selectQuery, lastID, rerenderBatchSize)
+		if err != nil {
+			return updated, err
+		}
+		for rows.Next() {
+			var post models.Post
+			if err := rows.Scan(&post.ID, &post.Content); err != nil {
+				rows.Close()
+				return updated, err
+			}
+			posts = append(posts, post)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return updated, err
+		}
+
+		if len(posts) == 0 {
+			return updated, nil
+		}
+
+		for _, post := range posts {
+			utils.RenderPost(&post)
+			if _, err := s.db.ExecContext(ctx, updateQuery, post.ID, post.ContentHTML, post.Summary); err != nil {
+				return updated, err
+			}
+			updated++
+			lastID = post.ID
+		}
+	}
+}
+
+// RerenderComments re-renders the content and summary of every comment, e.g. after the Markdown pipeline changed
+// Returns the number of comments updated
+func (s *PostgresStore) RerenderComments(ctx context.Context) (int, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "RerenderComments")
+	defer done()
+
+	selectQuery := `
+		SELECT id, content
+		FROM comments
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2`
+
+	updateQuery := `
+		UPDATE comments SET
+			content_html = $2,
+			summary = $3,
+			has_long_content = $4
+		WHERE id = $1`
+
+	updated := 0
+	lastID := 0
+	for {
+		comments := []models.Comment{}
+		rows, err := s.db.QueryContext(ctx, selectQuery, lastID, rerenderBatchSize)
+		if err != nil {
+			return updated, err
+		}
+		for rows.Next() {
+			var comment models.Comment
+			if err := rows.Scan(&comment.ID, &comment.Content); err != nil {
+				rows.Close()
+				return updated, err
+			}
+			comments = append(comments, comment)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return updated, err
+		}
+
+		if len(comments) == 0 {
+			return updated, nil
+		}
+
+		for _, comment := range comments {
+			utils.RenderComment(&comment)
+			if _, err := s.db.ExecContext(ctx, updateQuery, comment.ID, comment.ContentHTML, comment.Summary, comment.HasLongContent); err != nil {
+				return updated, err
+			}
+			updated++
+			lastID = comment.ID
+		}
+	}
+}
@@ -0,0 +1,546 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"cvwo/internal/telemetry"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// CreateReport records a user's report of a post or comment
+// Returns ALREADY_EXISTS_ERROR if the user already has an open report of it
+func (s *PostgresStore) CreateReport(ctx context.Context, report models.Report) (int, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "CreateReport")
+	defer done()
+
+	targetColumn, targetID := reportTarget(report.PostID, report.CommentID)
+
+	query := fmt.Sprintf(`
+		INSERT INTO reports (
+			reporter_id,
+			%[1]s,
+			reason,
+			details,
+			status,
+			created_at)
+		SELECT $1::int, $2::int, $3, $4, $5, $6::timestamp
+		WHERE NOT EXISTS (
+			SELECT 1 FROM reports
+			WHERE %[1]s = $2 AND reporter_id = $1 AND status = $5)
+		RETURNING id`, targetColumn)
+
+	var id int
+	err := s.db.QueryRowContext(ctx, query,
+		report.ReporterID,
+		targetID,
+		report.Reason,
+		report.Details,
+		constants.REPORT_STATUS_OPEN,
+		time.Now(),
+	).Scan(&id)
+	if err != nil {
+		// A concurrent report by the same user is caught by the unique index
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return 0, errors.New(constants.ALREADY_EXISTS_ERROR)
+		}
+		if err == sql.ErrNoRows {
+			return 0, errors.New(constants.ALREADY_EXISTS_ERROR)
+		}
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// Returns the reports column and ID of a report target, the comment if one is given
+func reportTarget(postID, commentID *int) (string, *int) {
+	if commentID != nil {
+		return "comment_id", commentID
+	}
+	return "post_id", postID
+}
+
+// ListOpenPostReports returns the open reports of a post, oldest first
+func (s *PostgresStore) ListOpenPostReports(ctx context.Context, postID int) ([]models.Report, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "ListOpenPostReports")
+	defer done()
+
+	return s.listOpenReports(ctx, "post_id", postID)
+}
+
+// ListOpenCommentReports returns the open reports of a comment, oldest first
+func (s *PostgresStore) ListOpenCommentReports(ctx context.Context, commentID int) ([]models.Report, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "ListOpenCommentReports")
+	defer done()
+
+	return s.listOpenReports(ctx, "comment_id", commentID)
+}
+
+func (s *PostgresStore) listOpenReports(ctx context.Context, targetColumn string, targetID int) ([]models.Report, error) {
+	query := fmt.Sprintf(`
+		SELECT
+			r.id,
+			r.reporter_id,
+			COALESCE(u.username, ''),
+			r.post_id,
+			r.comment_id,
+			r.reason,
+			r.details,
+			r.status,
+			r.created_at,
+			r.resolved_at
+		FROM reports r
+		LEFT JOIN users u ON r.reporter_id = u.id
+		WHERE r.%s = $1 AND r.status = $2
+		ORDER BY r.created_at, r.id`, targetColumn)
+
+	rows, err := s.db.QueryContext(ctx, query, targetID, constants.REPORT_STATUS_OPEN)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []models.Report{}
+	for rows.Next() {
+		var report models.Report
+		err := rows.Scan(
+			&report.ID,
+			&report.ReporterID,
+			&report.ReporterUsername,
+			&report.PostID,
+			&report.CommentID,
+			&report.Reason,
+			&report.Details,
+			&report.Status,
+			&report.CreatedAt,
+			&report.ResolvedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reports, nil
+}
+
+// ListReportedContent returns the moderation queue: posts and comments with open reports,
+// one entry per post or comment, the longest waiting first
+// topicIDs limits the queue to the given topics, nil means every topic
+func (s *PostgresStore) ListReportedContent(ctx context.Context, req models.ListReportsRequest, topicIDs []int) ([]models.ReportedContent, models.PageInfo, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "ListReportedContent")
+	defer done()
+
+	args := []any{constants.REPORT_STATUS_OPEN}
+	var queryBuilder strings.Builder
+	pageInfo := models.PageInfo{}
+
+	// The oldest report of a group belongs to no other group, so it breaks ties
+	ordering := []keysetColumn{
+		{expr: "q.first_reported_at", kind: keysetTime},
+		{expr: "q.first_report_id"},
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			CASE WHEN q.comment_id IS NULL THEN '%s' ELSE '%s' END,
+			p.id,
+			q.comment_id,
+			CASE WHEN p.is_deleted THEN '' ELSE COALESCE(p.title, '') END,
+			CASE
+				WHEN q.comment_id IS NULL THEN CASE WHEN p.is_deleted THEN '' ELSE COALESCE(p.summary, '') END
+				ELSE CASE WHEN c.is_deleted THEN '' ELSE COALESCE(c.summary, '') END
+			END,
+			a.id,
+			a.username,
+			t.id,
+			t.name,
+			t.slug,
+			CASE WHEN q.comment_id IS NULL THEN p.is_deleted ELSE c.is_deleted END,
+			q.report_count,
+			q.reasons,
+			q.first_reported_at,
+			q.last_reported_at%s
+		FROM (
+			SELECT
+				COALESCE(r.post_id, rc.post_id) AS post_id,
+				r.comment_id,
+				COUNT(*) AS report_count,
+				array_agg(r.reason) AS reasons,
+				MIN(r.created_at) AS first_reported_at,
+				MAX(r.created_at) AS last_reported_at,
+				MIN(r.id) AS first_report_id
+			FROM reports r
+			LEFT JOIN comments rc ON r.comment_id = rc.id
+			WHERE r.status = $1
+			GROUP BY COALESCE(r.post_id, rc.post_id), r.comment_id
+		) q
+		INNER JOIN posts p ON q.post_id = p.id
+		LEFT JOIN comments c ON q.comment_id = c.id
+		INNER JOIN users a ON a.id = COALESCE(c.user_id, p.user_id)
+		INNER JOIN topics t ON p.topic_id = t.id
+		WHERE true`,
+		constants.REPORT_TARGET_POST,
+		constants.REPORT_TARGET_COMMENT,
+		keysetSelectFields(ordering))
+
+	queryBuilder.WriteString(query)
+
+	if topicIDs != nil {
+		args = append(args, pq.Array(topicIDs))
+		queryBuilder.WriteString(fmt.Sprintf(" AND t.id = ANY($%d::int[])", len(args)))
+	}
+
+	if req.TopicID != 0 {
+		args = append(args, req.TopicID)
+		queryBuilder.WriteString(fmt.Sprintf(" AND t.id = $%d", len(args)))
+	}
+
+	switch req.Type {
+	case constants.REPORT_TARGET_POST:
+		queryBuilder.WriteString(" AND q.comment_id IS NULL")
+	case constants.REPORT_TARGET_COMMENT:
+		queryBuilder.WriteString(" AND q.comment_id IS NOT NULL")
+	}
+
+	// Get total count for pagination
+	if shouldCount(req.IncludeCount) {
+		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM (%s)", queryBuilder.String())
+		var totalCount int
+		err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&totalCount)
+		if err != nil {
+			return nil, pageInfo, err
+		}
+		pageInfo.Count = &totalCount
+	}
+
+	if req.Cursor != "" {
+		values, err := decodeCursor(req.Cursor, ordering)
+		if err != nil {
+			return nil, pageInfo, err
+		}
+		writeKeysetCondition(&queryBuilder, &args, ordering, values)
+	}
+
+	writeOrderBy(&queryBuilder, ordering)
+
+	if req.PageSize <= 0 {
+		req.PageSize = constants.DEFAULT_MODERATION_PAGE_SIZE
+	}
+	if req.PageSize > constants.MAX_MODERATION_PAGE_SIZE {
+		req.PageSize = constants.MAX_MODERATION_PAGE_SIZE
+	}
+
+	// Fetch one extra row to know whether there is a next page
+	args = append(args, req.PageSize+1)
+	queryBuilder.WriteString(fmt.Sprintf(" LIMIT $%d", len(args)))
+
+	rows, err := s.db.QueryContext(ctx, queryBuilder.String(), args...)
+	if err != nil {
+		return nil, pageInfo, err
+	}
+	defer rows.Close()
+
+	keysetValues, keysetDest := keysetScanDest(ordering)
+	var cursorValues []any
+
+	contents := []models.ReportedContent{}
+	for rows.Next() {
+		var content models.ReportedContent
+		var reasons []string
+		dest := []any{
+			&content.TargetType,
+			&content.PostID,
+			&content.CommentID,
+			&content.Title,
+			&content.Summary,
+			&content.AuthorID,
+			&content.AuthorUsername,
+			&content.TopicID,
+			&content.TopicName,
+			&content.TopicSlug,
+			&content.IsDeleted,
+			&content.ReportCount,
+			pq.Array(&reasons),
+			&content.FirstReportedAt,
+			&content.LastReportedAt,
+		}
+		if err := rows.Scan(append(dest, keysetDest...)...); err != nil {
+			return nil, pageInfo, err
+		}
+
+		content.Reasons = map[string]int{}
+		for _, reason := range reasons {
+			content.Reasons[reason]++
+		}
+
+		contents = append(contents, content)
+		if len(contents) == req.PageSize {
+			cursorValues = append([]any{}, keysetValues...)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, pageInfo, err
+	}
+
+	if len(contents) > req.PageSize {
+		contents = contents[:req.PageSize]
+		pageInfo.NextCursor = encodeCursor(ordering, cursorValues...)
+	}
+
+	return contents, pageInfo, nil
+}
+
+// ResolveReports closes every open report of the entry's post or comment, applies the action
+// and writes the entry to the moderation log, all in one transaction
+// Removing content that is already deleted only closes the reports
+// Suspensions never shorten an existing longer suspension
+// Returns NO_ROWS_AFFECTED_ERROR if there are no open reports
+func (s *PostgresStore) ResolveReports(ctx context.Context, entry *models.ModerationLogEntry) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "ResolveReports")
+	defer done()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	targetColumn, targetID := reportTarget(entry.PostID, entry.CommentID)
+
+	status := constants.REPORT_STATUS_RESOLVED
+	if entry.Action == constants.MODERATION_ACTION_DISMISS {
+		status = constants.REPORT_STATUS_DISMISSED
+	}
+
+	resolveQuery := fmt.Sprintf(`
+		UPDATE reports SET
+			status = $1,
+			resolved_at = $2,
+			resolved_by = $3
+		WHERE %s = $4 AND status = $5`, targetColumn)
+
+	result, err := tx.ExecContext(ctx, resolveQuery, status, now, entry.ModeratorID, targetID, constants.REPORT_STATUS_OPEN)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+	entry.ReportsResolved = int(rowsAffected)
+
+	switch entry.Action {
+	case constants.MODERATION_ACTION_REMOVE:
+		if entry.CommentID != nil {
+			err = deleteComment(ctx, tx.Tx, *entry.CommentID)
+		} else {
+			err = deletePost(ctx, tx.Tx, *entry.PostID)
+		}
+		if err != nil && err.Error() != constants.NO_ROWS_AFFECTED_ERROR {
+			return err
+		}
+
+	case constants.MODERATION_ACTION_SUSPEND:
+		suspendQuery := `
+			UPDATE users SET
+				suspended_until = GREATEST(suspended_until, $2)
+			WHERE id = $1`
+
+		if _, err := tx.ExecContext(ctx, suspendQuery, entry.TargetUserID, entry.SuspendedUntil); err != nil {
+			return err
+		}
+	}
+
+	logQuery := `
+		INSERT INTO moderation_log (
+			moderator_id,
+			action,
+			post_id,
+			comment_id,
+			target_user_id,
+			reports_resolved,
+			note,
+			suspended_until,
+			created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id`
+
+	err = tx.QueryRowContext(ctx, logQuery,
+		entry.ModeratorID,
+		entry.Action,
+		entry.PostID,
+		entry.CommentID,
+		entry.TargetUserID,
+		entry.ReportsResolved,
+		entry.Note,
+		entry.SuspendedUntil,
+		now,
+	).Scan(&entry.ID)
+	if err != nil {
+		return err
+	}
+	entry.CreatedAt = now
+
+	return tx.Commit()
+}
+
+// ListModerationLog returns the moderation audit log, newest first
+func (s *PostgresStore) ListModerationLog(ctx context.Context, req models.ListModerationLogRequest) ([]models.ModerationLogEntry, models.PageInfo, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "ListModerationLog")
+	defer done()
+
+	args := []any{}
+	var queryBuilder strings.Builder
+	pageInfo := models.PageInfo{}
+
+	ordering := []keysetColumn{
+		{expr: "l.created_at", kind: keysetTime, desc: true},
+		{expr: "l.id", desc: true},
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			l.id,
+			l.moderator_id,
+			COALESCE(m.username, ''),
+			l.action,
+			l.post_id,
+			l.comment_id,
+			l.target_user_id,
+			COALESCE(u.username, ''),
+			l.reports_resolved,
+			l.note,
+			l.suspended_until,
+			l.created_at%s
+		FROM moderation_log l
+
+		LEFT JOIN users m ON l.moderator_id = m.id
+		LEFT JOIN users u ON l.target_user_id = u.id
+		WHERE true`, keysetSelectFields(ordering))
+
+	queryBuilder.WriteString(query)
+
+	if req.Action != "" {
+		args = append(args, req.Action)
+		queryBuilder.WriteString(fmt.Sprintf(" AND l.action = $%d", len(args)))
+	}
+
+	// Get total count for pagination
+	if shouldCount(req.IncludeCount) {
+		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM (%s)", queryBuilder.String())
+		var totalCount int
+		err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&totalCount)
+		if err != nil {
+			return nil, pageInfo, err
+		}
+		pageInfo.Count = &totalCount
+	}
+
+	if req.Cursor != "" {
+		values, err := decodeCursor(req.Cursor, ordering)
+		if err != nil {
+			return nil, pageInfo, err
+		}
+		writeKeysetCondition(&queryBuilder, &args, ordering, values)
+	}
+
+	writeOrderBy(&queryBuilder, ordering)
+
+	if req.PageSize <= 0 {
+		req.PageSize = constants.DEFAULT_MODERATION_PAGE_SIZE
+	}
+	if req.PageSize > constants.MAX_MODERATION_PAGE_SIZE {
+		req.PageSize = constants.MAX_MODERATION_PAGE_SIZE
+	}
+
+	// Fetch one extra row to know whether there is a next page
+	args = append(args, req.PageSize+1)
+	queryBuilder.WriteString(fmt.Sprintf(" LIMIT $%d", len(args)))
+
+	rows, err := s.db.QueryContext(ctx, queryBuilder.String(), args...)
+	if err != nil {
+		return nil, pageInfo, err
+	}
+	defer rows.Close()
+
+	keysetValues, keysetDest := keysetScanDest(ordering)
+	var cursorValues []any
+
+	entries := []models.ModerationLogEntry{}
+	for rows.Next() {
+		var entry models.ModerationLogEntry
+		dest := []any{
+			&entry.ID,
+			&entry.ModeratorID,
+			&entry.ModeratorUsername,
+			&entry.Action,
+			&entry.PostID,
+			&entry.CommentID,
+			&entry.TargetUserID,
+			&entry.TargetUsername,
+			&entry.ReportsResolved,
+			&entry.Note,
+			&entry.SuspendedUntil,
+			&entry.CreatedAt,
+		}
+		if err := rows.Scan(append(dest, keysetDest...)...); err != nil {
+			return nil, pageInfo, err
+		}
+
+		entries = append(entries, entry)
+		if len(entries) == req.PageSize {
+			cursorValues = append([]any{}, keysetValues...)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, pageInfo, err
+	}
+
+	if len(entries) > req.PageSize {
+		entries = entries[:req.PageSize]
+		pageInfo.NextCursor = encodeCursor(ordering, cursorValues...)
+	}
+
+	return entries, pageInfo, nil
+}
+
+// GetUserSuspendedUntil returns the end of the user's suspension, nil if the user is not suspended
+func (s *PostgresStore) GetUserSuspendedUntil(ctx context.Context, userID int) (*time.Time, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "GetUserSuspendedUntil")
+	defer done()
+
+	query := `
+		SELECT suspended_until
+		FROM users
+		WHERE id = $1 AND suspended_until > $2`
+
+	var suspendedUntil time.Time
+	err := s.db.QueryRowContext(ctx, query, userID, time.Now()).Scan(&suspendedUntil)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &suspendedUntil, nil
+}
@@ -0,0 +1,231 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"cvwo/internal/telemetry"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Records the post's current title and content as its next revision
+func insertPostRevision(ctx context.Context, tx *sql.Tx, post *models.Post, editorID int, restoredFrom *int, createdAt time.Time) error {
+	query := `
+		INSERT INTO post_revisions (
+			post_id,
+			revision,
+			title,
+			content,
+			editor_id,
+			restored_from,
+			created_at)
+		SELECT $1::int, COALESCE(MAX(revision), 0) + 1, $2, $3, $4::int, $5::int, $6::timestamp
+		FROM post_revisions
+		WHERE post_id = $1`
+
+	_, err := tx.ExecContext(ctx, query,
+		post.ID,
+		post.Title,
+		post.Content,
+		editorID,
+		restoredFrom,
+		createdAt,
+	)
+	return err
+}
+
+// Records the comment's current content as its next revision
+func insertCommentRevision(ctx context.Context, tx *sql.Tx, comment *models.Comment, editorID int, restoredFrom *int, createdAt time.Time) error {
+	query := `
+		INSERT INTO comment_revisions (
+			comment_id,
+			revision,
+			content,
+			editor_id,
+			restored_from,
+			created_at)
+		SELECT $1::int, COALESCE(MAX(revision), 0) + 1, $2, $3::int, $4::int, $5::timestamp
+		FROM comment_revisions
+		WHERE comment_id = $1`
+
+	_, err := tx.ExecContext(ctx, query,
+		comment.ID,
+		comment.Content,
+		editorID,
+		restoredFrom,
+		createdAt,
+	)
+	return err
+}
+
+// ListPostRevisions returns every revision of the post, oldest first
+// Diffs are left to the caller
+func (s *PostgresStore) ListPostRevisions(ctx context.Context, postID int) ([]models.PostRevision, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "ListPostRevisions")
+	defer done()
+
+	query := `
+		SELECT
+			r.post_id,
+			r.revision,
+			r.title,
+			r.content,
+			r.editor_id,
+			COALESCE(u.username, ''),
+			r.restored_from,
+			r.created_at
+		FROM post_revisions r
+		LEFT JOIN users u ON r.editor_id = u.id
+		WHERE r.post_id = $1
+		ORDER BY r.revision`
+
+	rows, err := s.db.QueryContext(ctx, query, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []models.PostRevision{}
+	for rows.Next() {
+		var revision models.PostRevision
+		err := rows.Scan(
+			&revision.PostID,
+			&revision.Revision,
+			&revision.Title,
+			&revision.Content,
+			&revision.EditorID,
+			&revision.EditorUsername,
+			&revision.RestoredFrom,
+			&revision.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// ListCommentRevisions returns every revision of the comment, oldest first
+// Diffs are left to the caller
+func (s *PostgresStore) ListCommentRevisions(ctx context.Context, commentID int) ([]models.CommentRevision, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "ListCommentRevisions")
+	defer done()
+
+	query := `
+		SELECT
+			r.comment_id,
+			r.revision,
+			r.content,
+			r.editor_id,
+			COALESCE(u.username, ''),
+			r.restored_from,
+			r.created_at
+		FROM comment_revisions r
+		LEFT JOIN users u ON r.editor_id = u.id
+		WHERE r.comment_id = $1
+		ORDER BY r.revision`
+
+	rows, err := s.db.QueryContext(ctx, query, commentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []models.CommentRevision{}
+	for rows.Next() {
+		var revision models.CommentRevision
+		err := rows.Scan(
+			&revision.CommentID,
+			&revision.Revision,
+			&revision.Content,
+			&revision.EditorID,
+			&revision.EditorUsername,
+			&revision.RestoredFrom,
+			&revision.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// RestorePostRevision makes an earlier revision the post's current version
+// The restore is itself recorded as a new revision, so it can be undone
+// Returns NOT_FOUND_ERROR if the revision does not exist, NO_ROWS_AFFECTED_ERROR if the post is deleted
+func (s *PostgresStore) RestorePostRevision(ctx context.Context, post *models.Post, revision, editorID int) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "RestorePostRevision")
+	defer done()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT title, content
+		FROM post_revisions
+		WHERE post_id = $1 AND revision = $2`
+
+	err = tx.QueryRowContext(ctx, query, post.ID, revision).Scan(&post.Title, &post.Content)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New(constants.NOT_FOUND_ERROR)
+		}
+		return err
+	}
+
+	if err := updatePost(ctx, tx.Tx, post, editorID, &revision); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RestoreCommentRevision makes an earlier revision the comment's current version
+// The restore is itself recorded as a new revision, so it can be undone
+// Returns NOT_FOUND_ERROR if the revision does not exist
+func (s *PostgresStore) RestoreCommentRevision(ctx context.Context, comment *models.Comment, revision, editorID int) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "RestoreCommentRevision")
+	defer done()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT content
+		FROM comment_revisions
+		WHERE comment_id = $1 AND revision = $2`
+
+	err = tx.QueryRowContext(ctx, query, comment.ID, revision).Scan(&comment.Content)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New(constants.NOT_FOUND_ERROR)
+		}
+		return err
+	}
+
+	if err := updateComment(ctx, tx.Tx, comment, editorID, &revision); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
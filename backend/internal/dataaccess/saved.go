@@ -0,0 +1,53 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/telemetry"
+	"time"
+)
+
+// SavePost bookmarks the post for the user, saving it again keeps the original save time
+func (s *PostgresStore) SavePost(ctx context.Context, userID, postID int) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "SavePost")
+	defer done()
+
+	query := `
+		INSERT INTO saved_posts (user_id, post_id, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, post_id) DO NOTHING`
+
+	_, err := s.db.ExecContext(ctx, query, userID, postID, time.Now())
+	return err
+}
+
+// UnsavePost removes the post from the user's bookmarks, if it was saved
+func (s *PostgresStore) UnsavePost(ctx context.Context, userID, postID int) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "UnsavePost")
+	defer done()
+
+	_, err := s.db.ExecContext(ctx, `DELETE FROM saved_posts WHERE user_id = $1 AND post_id = $2`, userID, postID)
+	return err
+}
+
+// SaveComment bookmarks the comment for the user, saving it again keeps the original save time
+func (s *PostgresStore) SaveComment(ctx context.Context, userID, commentID int) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "SaveComment")
+	defer done()
+
+	query := `
+		INSERT INTO saved_comments (user_id, comment_id, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, comment_id) DO NOTHING`
+
+	_, err := s.db.ExecContext(ctx, query, userID, commentID, time.Now())
+	return err
+}
+
+// UnsaveComment removes the comment from the user's bookmarks, if it was saved
+func (s *PostgresStore) UnsaveComment(ctx context.Context, userID, commentID int) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "UnsaveComment")
+	defer done()
+
+	_, err := s.db.ExecContext(ctx, `DELETE FROM saved_comments WHERE user_id = $1 AND comment_id = $2`, userID, commentID)
+	return err
+}
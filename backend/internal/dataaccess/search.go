@@ -0,0 +1,206 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"cvwo/internal/telemetry"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+)
+
+// ts_headline wraps matches in these, they are replaced by <mark> tags after HTML-escaping the snippet
+const highlightStart = "\x02"
+const highlightStop = "\x03"
+
+const headlineOptions = "StartSel=" + highlightStart + ", StopSel=" + highlightStop + ", MaxFragments=2, MaxWords=30, MinWords=10, FragmentDelimiter=\" ... \""
+const titleHeadlineOptions = "StartSel=" + highlightStart + ", StopSel=" + highlightStop + ", HighlightAll=true"
+
+// Search runs a full-text search over posts and comments, ranked by ts_rank unless sorting by new
+// The query uses websearch syntax: "quoted phrases", -negated terms and OR
+// Snippets are only generated for the returned page, since ts_headline is expensive
+func (s *PostgresStore) Search(ctx context.Context, req models.SearchRequest, from, to *time.Time) ([]models.SearchResult, int, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "Search")
+	defer done()
+
+	args := []any{req.Query}
+
+	// Both branches share the filter parameters, so append them once
+	var filters []string
+	if req.TopicID != nil {
+		args = append(args, *req.TopicID)
+		filters = append(filters, fmt.Sprintf(" AND p.topic_id = $%d", len(args)))
+	}
+	if req.Author != "" {
+		args = append(args, req.Author)
+		filters = append(filters, fmt.Sprintf(" AND u.username = $%d", len(args)))
+	}
+	if from != nil {
+		args = append(args, *from)
+		filters = append(filters, fmt.Sprintf(" AND {alias}.created_at >= $%d", len(args)))
+	}
+	if to != nil {
+		args = append(args, *to)
+		filters = append(filters, fmt.Sprintf(" AND {alias}.created_at < $%d", len(args)))
+	}
+
+	// alias is the table whose created_at is filtered
+	buildFilters := func(alias string) string {
+		return strings.ReplaceAll(strings.Join(filters, ""), "{alias}", alias)
+	}
+
+	branches := []string{}
+
+	if req.Type == "" || req.Type == constants.SEARCH_TYPE_POST {
+		branches = append(branches, fmt.Sprintf(`
+			SELECT 'post' AS type,
+			p.id,
+			p.id AS post_id,
+			p.title,
+			COALESCE(p.content, '') AS body,
+			ts_rank(p.search_vector, q.query) AS rank,
+			COALESCE(p.score, 0) AS score,
+			p.created_at,
+			p.user_id,
+			u.username,
+			p.topic_id,
+			t.name AS topic_name
+			FROM posts p
+			CROSS JOIN q
+
+			LEFT JOIN users u ON p.user_id = u.id
+			LEFT JOIN topics t ON p.topic_id = t.id
+			WHERE p.search_vector @@ q.query
+			AND p.is_deleted = false%s`, buildFilters("p")))
+	}
+
+	if req.Type == "" || req.Type == constants.SEARCH_TYPE_COMMENT {
+		branches = append(branches, fmt.Sprintf(`
+			SELECT 'comment' AS type,
+			c.id,
+			c.post_id,
+			p.title,
+			c.content AS body,
+			ts_rank(c.search_vector, q.query) AS rank,
+			COALESCE(c.score, 0) AS score,
+			c.created_at,
+			c.user_id,
+			u.username,
+			p.topic_id,
+			t.name AS topic_name
+			FROM comments c
+			CROSS JOIN q
+
+			INNER JOIN posts p ON c.post_id = p.id
+			LEFT JOIN users u ON c.user_id = u.id
+			LEFT JOIN topics t ON p.topic_id = t.id
+			WHERE c.search_vector @@ q.query
+			AND c.is_deleted = false
+			AND p.is_deleted = false%s`, buildFilters("c")))
+	}
+
+	withQuery := "WITH q AS (SELECT websearch_to_tsquery('english', $1) AS query)"
+	resultsQuery := strings.Join(branches, "\n\t\t\tUNION ALL")
+
+	// Get total count for pagination
+	countQuery := fmt.Sprintf("%s SELECT COUNT(*) FROM (%s) AS results", withQuery, resultsQuery)
+	var totalCount int
+	err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&totalCount)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	orderBy := "rank DESC, created_at DESC"
+	if req.Sort == constants.ORDER_BY_NEW {
+		orderBy = "created_at DESC"
+	}
+
+	if req.PageSize <= 0 {
+		req.PageSize = constants.DEFAULT_SEARCH_PAGE_SIZE
+	}
+	if req.PageSize > constants.MAX_SEARCH_PAGE_SIZE {
+		req.PageSize = constants.MAX_SEARCH_PAGE_SIZE
+	}
+
+	offset := 0
+	if req.Page > 0 {
+		offset = (req.Page - 1) * req.PageSize
+	}
+
+	args = append(args, req.PageSize, offset, headlineOptions, titleHeadlineOptions)
+	n := len(args)
+
+	// Highlight only the page of results, post titles are highlighted for post matches
+	query := fmt.Sprintf(`
+		%s,
+		page AS (
+			SELECT * FROM (%s) AS results
+			ORDER BY %s
+			LIMIT $%d OFFSET $%d
+		)
+		SELECT page.type,
+		page.id,
+		page.post_id,
+		CASE WHEN page.type = 'post'
+			THEN ts_headline('english', page.title, q.query, $%d)
+			ELSE page.title END,
+		ts_headline('english', page.body, q.query, $%d),
+		page.rank,
+		page.score,
+		page.created_at,
+		page.user_id,
+		page.username,
+		page.topic_id,
+		page.topic_name
+		FROM page
+		CROSS JOIN q
+		ORDER BY %s`,
+		withQuery, resultsQuery, orderBy, n-3, n-2, n, n-1, orderBy)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	results := []models.SearchResult{}
+	for rows.Next() {
+		var result models.SearchResult
+		if err := rows.Scan(
+			&result.Type,
+			&result.ID,
+			&result.PostID,
+			&result.PostTitle,
+			&result.Snippet,
+			&result.Rank,
+			&result.Score,
+			&result.CreatedAt,
+			&result.UserID,
+			&result.Username,
+			&result.TopicID,
+			&result.TopicName,
+		); err != nil {
+			return nil, 0, err
+		}
+
+		result.PostTitle = highlightToHTML(result.PostTitle)
+		result.Snippet = highlightToHTML(result.Snippet)
+
+		results = append(results, result)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return results, totalCount, nil
+}
+
+// Escapes the user content, then turns the ts_headline markers into <mark> tags
+func highlightToHTML(headline string) string {
+	escaped := html.EscapeString(headline)
+	escaped = strings.ReplaceAll(escaped, highlightStart, "<mark>")
+	return strings.ReplaceAll(escaped, highlightStop, "</mark>")
+}
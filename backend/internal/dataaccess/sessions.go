@@ -0,0 +1,262 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"cvwo/internal/telemetry"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const sessionSelectFields = `
+	id,
+	user_id,
+	COALESCE(user_agent, ''),
+	COALESCE(ip_address, ''),
+	created_at,
+	last_used_at,
+	expires_at,
+	revoked_at`
+
+func scanSession(row interface{ Scan(...any) error }, session *models.Session) error {
+	return row.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.UserAgent,
+		&session.IPAddress,
+		&session.CreatedAt,
+		&session.LastUsedAt,
+		&session.ExpiresAt,
+		&session.RevokedAt,
+	)
+}
+
+// CreateSession stores a new login session with the hash of its refresh token
+// Returns the new session ID
+func (s *PostgresStore) CreateSession(ctx context.Context, session models.Session, refreshTokenHash string) (int, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "CreateSession")
+	defer done()
+
+	query := `
+		INSERT INTO sessions (
+			user_id,
+			refresh_token_hash,
+			user_agent,
+			ip_address,
+			created_at,
+			last_used_at,
+			expires_at)
+		VALUES ($1, $2, $3, $4, $5, $5, $6) RETURNING id`
+
+	var sessionID int
+	err := s.db.QueryRowContext(ctx, query,
+		session.UserID,
+		refreshTokenHash,
+		session.UserAgent,
+		session.IPAddress,
+		time.Now(),
+		session.ExpiresAt,
+	).Scan(&sessionID)
+
+	return sessionID, err
+}
+
+// GetSessionByRefreshTokenHash finds the session whose current refresh token matches
+// Includes revoked and expired sessions, callers must check them
+func (s *PostgresStore) GetSessionByRefreshTokenHash(ctx context.Context, refreshTokenHash string) (*models.Session, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "GetSessionByRefreshTokenHash")
+	defer done()
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM sessions
+		WHERE refresh_token_hash = $1`, sessionSelectFields)
+
+	session := &models.Session{}
+	err := scanSession(s.db.QueryRowContext(ctx, query, refreshTokenHash), session)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New(constants.NOT_FOUND_ERROR)
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+// RevokeSessionByPreviousRefreshTokenHash handles reuse of an already rotated refresh token
+// The token was either replayed by an attacker or by the legitimate client after theft,
+// so the whole session is revoked. Returns NOT_FOUND_ERROR if no session used that token
+func (s *PostgresStore) RevokeSessionByPreviousRefreshTokenHash(ctx context.Context, refreshTokenHash string) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "RevokeSessionByPreviousRefreshTokenHash")
+	defer done()
+
+	query := `
+		UPDATE sessions SET
+			revoked_at = $2
+		WHERE previous_refresh_token_hash = $1
+		AND revoked_at IS NULL`
+
+	result, err := s.db.ExecContext(ctx, query, refreshTokenHash, time.Now())
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New(constants.NOT_FOUND_ERROR)
+	}
+
+	return nil
+}
+
+// RotateRefreshToken swaps the session's refresh token and extends its expiry
+// Only succeeds if oldHash is still current and the session is active,
+// so two concurrent refreshes with the same token cannot both succeed
+func (s *PostgresStore) RotateRefreshToken(ctx context.Context, sessionID int, oldHash, newHash string, expiresAt time.Time) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "RotateRefreshToken")
+	defer done()
+
+	query := `
+		UPDATE sessions SET
+			refresh_token_hash = $3,
+			previous_refresh_token_hash = $2,
+			last_used_at = $4,
+			expires_at = $5
+		WHERE id = $1
+		AND refresh_token_hash = $2
+		AND revoked_at IS NULL
+		AND expires_at > $4`
+
+	result, err := s.db.ExecContext(ctx, query, sessionID, oldHash, newHash, time.Now(), expiresAt)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	return nil
+}
+
+// GetActiveSessionRole checks the session belongs to the user and is neither revoked nor expired
+// Returns the user's current role, so role changes apply without waiting for a new token
+// Returns NOT_FOUND_ERROR if the session is not active
+func (s *PostgresStore) GetActiveSessionRole(ctx context.Context, sessionID, userID int) (string, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "GetActiveSessionRole")
+	defer done()
+
+	query := `
+		SELECT u.role
+		FROM sessions s
+		INNER JOIN users u ON s.user_id = u.id
+		WHERE s.id = $1 AND s.user_id = $2
+		AND s.revoked_at IS NULL
+		AND s.expires_at > $3`
+
+	var role string
+	err := s.db.QueryRowContext(ctx, query, sessionID, userID, time.Now()).Scan(&role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", errors.New(constants.NOT_FOUND_ERROR)
+		}
+		return "", err
+	}
+	return role, nil
+}
+
+// ListActiveSessions returns the user's sessions that are neither revoked nor expired, most recently used first
+func (s *PostgresStore) ListActiveSessions(ctx context.Context, userID int) ([]models.Session, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "ListActiveSessions")
+	defer done()
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM sessions
+		WHERE user_id = $1
+		AND revoked_at IS NULL
+		AND expires_at > $2
+		ORDER BY last_used_at DESC`, sessionSelectFields)
+
+	rows, err := s.db.QueryContext(ctx, query, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var session models.Session
+		if err := scanSession(rows, &session); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession revokes one of the user's sessions
+// Returns NO_ROWS_AFFECTED_ERROR if the session does not exist, belongs to someone else or is already revoked
+func (s *PostgresStore) RevokeSession(ctx context.Context, userID, sessionID int) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "RevokeSession")
+	defer done()
+
+	query := `
+		UPDATE sessions SET
+			revoked_at = $3
+		WHERE id = $1 AND user_id = $2
+		AND revoked_at IS NULL`
+
+	result, err := s.db.ExecContext(ctx, query, sessionID, userID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	return nil
+}
+
+// RevokeAllSessions revokes every active session of the user ("log out all devices")
+// Returns the number of sessions revoked
+func (s *PostgresStore) RevokeAllSessions(ctx context.Context, userID int) (int64, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "RevokeAllSessions")
+	defer done()
+
+	query := `
+		UPDATE sessions SET
+			revoked_at = $2
+		WHERE user_id = $1
+		AND revoked_at IS NULL`
+
+	result, err := s.db.ExecContext(ctx, query, userID, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,285 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/models"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// PostStore reads and writes posts, their votes and bookmarks
+type PostStore interface {
+	CreatePost(ctx context.Context, post models.Post) (int, error)
+	UpdatePost(ctx context.Context, post *models.Post, editorID int) error
+	DeletePost(ctx context.Context, id int) error
+	VotePost(ctx context.Context, vote models.PostVote) error
+	ListPosts(ctx context.Context, isAuthenticated bool, currentUserID int, req models.ListPostsRequest) ([]models.Post, models.PageInfo, error)
+	GetPost(ctx context.Context, isAuthenticated bool, userID, postID int) (*models.Post, error)
+	PinComment(ctx context.Context, postID, commentID int) error
+	UnpinComment(ctx context.Context, postID int) error
+	SetPostLocked(ctx context.Context, postID int, isLocked bool) error
+	SetPostPinned(ctx context.Context, postID int, isPinned bool) error
+	SavePost(ctx context.Context, userID, postID int) error
+	UnsavePost(ctx context.Context, userID, postID int) error
+}
+
+// CommentStore reads and writes comments, their votes and bookmarks
+type CommentStore interface {
+	CreateComment(ctx context.Context, comment models.Comment) (int, error)
+	UpdateComment(ctx context.Context, comment *models.Comment, editorID int) error
+	DeleteComment(ctx context.Context, id int) error
+	VoteComment(ctx context.Context, vote models.CommentVote) error
+	ListComments(ctx context.Context, isAuthenticated bool, currentUserID int, req models.ListCommentsRequest) ([]models.Comment, models.PageInfo, error)
+	GetComment(ctx context.Context, isAuthenticated bool, userID, commentID int) (*models.Comment, error)
+	SaveComment(ctx context.Context, userID, commentID int) error
+	UnsaveComment(ctx context.Context, userID, commentID int) error
+}
+
+// TopicStore reads and writes topics and who follows them
+type TopicStore interface {
+	ListTopicsSummary(ctx context.Context) ([]models.Topic, error)
+	ListTopics(ctx context.Context, isAuthenticated bool, currentUserID int, req models.ListTopicsRequest) ([]models.Topic, models.PageInfo, error)
+	GetTopicBySlug(ctx context.Context, isAuthenticated bool, userID int, slug string) (*models.Topic, error)
+	FollowTopic(ctx context.Context, userID int, topicName string) error
+	UnfollowTopic(ctx context.Context, userID int, topicName string) error
+	CreateTopic(ctx context.Context, topic models.Topic) (int, error)
+	UpdateTopic(ctx context.Context, topic models.Topic) error
+	ArchiveTopic(ctx context.Context, topicID int) error
+}
+
+// UserStore reads and writes user accounts
+type UserStore interface {
+	CreateUser(ctx context.Context, user models.User) error
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetUserByID(ctx context.Context, id int) (*models.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	GetUserPasswordHash(ctx context.Context, id int) (string, error)
+	UpdateUserPassword(ctx context.Context, id int, newPassword string, keepSessionID int) error
+	UpdateUserRole(ctx context.Context, id int, role string) error
+	UpdateUserData(ctx context.Context, user *models.User) error
+	CheckUserExistsByEmail(ctx context.Context, email string) (bool, error)
+	CheckUserExistsByUsername(ctx context.Context, username string) (bool, error)
+	ListUsers(ctx context.Context, req models.ListUsersRequest) ([]models.User, models.PageInfo, error)
+	GetUserIDsByUsernames(ctx context.Context, usernames []string) ([]int, error)
+}
+
+// SessionStore reads and writes login sessions and their refresh tokens
+type SessionStore interface {
+	CreateSession(ctx context.Context, session models.Session, refreshTokenHash string) (int, error)
+	GetSessionByRefreshTokenHash(ctx context.Context, refreshTokenHash string) (*models.Session, error)
+	RevokeSessionByPreviousRefreshTokenHash(ctx context.Context, refreshTokenHash string) error
+	RotateRefreshToken(ctx context.Context, sessionID int, oldHash, newHash string, expiresAt time.Time) error
+	GetActiveSessionRole(ctx context.Context, sessionID, userID int) (string, error)
+	ListActiveSessions(ctx context.Context, userID int) ([]models.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID int) error
+	RevokeAllSessions(ctx context.Context, userID int) (int64, error)
+}
+
+// LoginAttemptStore records login attempts, which lockouts and the security log are built from
+type LoginAttemptStore interface {
+	RecordLoginAttempt(ctx context.Context, attempt models.LoginAttempt) error
+	CountEmailLoginFailures(ctx context.Context, email string, since time.Time) (int, *time.Time, error)
+	CountIPLoginFailures(ctx context.Context, ipAddress string, since time.Time) (int, *time.Time, error)
+	ListLoginAttempts(ctx context.Context, userID, limit int) ([]models.LoginAttempt, error)
+}
+
+// EmailTokenStore reads and writes the single-use tokens sent by email
+type EmailTokenStore interface {
+	CreateEmailToken(ctx context.Context, userID int, purpose, email, tokenHash string, expiresAt time.Time) error
+	VerifyEmail(ctx context.Context, tokenHash string) error
+	ResetPassword(ctx context.Context, tokenHash, newPassword string) error
+}
+
+// TwoFactorStore reads and writes TOTP enrollments, recovery codes and logins waiting for a code
+type TwoFactorStore interface {
+	GetTwoFactor(ctx context.Context, userID int) (*models.TwoFactor, error)
+	IsTwoFactorEnabled(ctx context.Context, userID int) (bool, error)
+	StartTwoFactorEnrollment(ctx context.Context, userID int, secret string) error
+	EnableTwoFactor(ctx context.Context, userID int, step int64, recoveryCodeHashes []string) error
+	DisableTwoFactor(ctx context.Context, userID int) error
+	UseTOTPStep(ctx context.Context, userID int, step int64) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID int, recoveryCodeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error)
+	CountUnusedRecoveryCodes(ctx context.Context, userID int) (int, error)
+	CreateLoginChallenge(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error
+	GetLoginChallenge(ctx context.Context, tokenHash string) (*models.LoginChallenge, error)
+	RecordLoginChallengeFailure(ctx context.Context, id int) error
+	CompleteLoginChallenge(ctx context.Context, id int) error
+}
+
+// APITokenStore reads and writes personal API tokens
+type APITokenStore interface {
+	CreateAPIToken(ctx context.Context, token models.APIToken, tokenHash string) (int, error)
+	ListAPITokens(ctx context.Context, userID int) ([]models.APIToken, error)
+	RevokeAPIToken(ctx context.Context, userID, tokenID int) error
+	AuthenticateAPIToken(ctx context.Context, tokenHash string) (*models.APIToken, error)
+}
+
+// IdentityStore links identity provider accounts to users
+type IdentityStore interface {
+	LoginWithIdentity(ctx context.Context, issuer, subject, email string) (int, error)
+	LinkIdentity(ctx context.Context, userID int, identity models.UserIdentity, emailVerified bool) error
+	CreateUserWithIdentity(ctx context.Context, user models.User, identity models.UserIdentity, emailVerified bool) (int, error)
+}
+
+// ReportStore reads and writes reports, the moderation log and the suspensions it records
+type ReportStore interface {
+	CreateReport(ctx context.Context, report models.Report) (int, error)
+	ListOpenPostReports(ctx context.Context, postID int) ([]models.Report, error)
+	ListOpenCommentReports(ctx context.Context, commentID int) ([]models.Report, error)
+	ListReportedContent(ctx context.Context, req models.ListReportsRequest, topicIDs []int) ([]models.ReportedContent, models.PageInfo, error)
+	ResolveReports(ctx context.Context, entry *models.ModerationLogEntry) error
+	ListModerationLog(ctx context.Context, req models.ListModerationLogRequest) ([]models.ModerationLogEntry, models.PageInfo, error)
+	GetUserSuspendedUntil(ctx context.Context, userID int) (*time.Time, error)
+}
+
+// RevisionStore reads the edit history of posts and comments and restores earlier versions
+type RevisionStore interface {
+	ListPostRevisions(ctx context.Context, postID int) ([]models.PostRevision, error)
+	ListCommentRevisions(ctx context.Context, commentID int) ([]models.CommentRevision, error)
+	RestorePostRevision(ctx context.Context, post *models.Post, revision, editorID int) error
+	RestoreCommentRevision(ctx context.Context, comment *models.Comment, revision, editorID int) error
+}
+
+// NotificationStore reads and writes notifications and the types users muted
+type NotificationStore interface {
+	CreateNotifications(ctx context.Context, notification models.Notification, recipientIDs []int) ([]models.Notification, error)
+	CreateTopicFollowerNotifications(ctx context.Context, notification models.Notification, excludeIDs []int) ([]models.Notification, error)
+	ListNotifications(ctx context.Context, userID int, req models.ListNotificationsRequest) ([]models.Notification, models.PageInfo, error)
+	CountUnreadNotifications(ctx context.Context, userID int) (int, error)
+	MarkNotificationsRead(ctx context.Context, userID int, notificationIDs []int) (int64, error)
+	ListNotificationMutes(ctx context.Context, userID int) ([]string, error)
+	SetNotificationMutes(ctx context.Context, userID int, mutedTypes []string) error
+}
+
+// ModeratorStore reads and writes who moderates which topics
+type ModeratorStore interface {
+	IsTopicModerator(ctx context.Context, userID, topicID int) (bool, error)
+	AddTopicModerator(ctx context.Context, userID, topicID int) error
+	RemoveTopicModerator(ctx context.Context, userID, topicID int) error
+	ListTopicModerators(ctx context.Context, topicID int) ([]models.User, error)
+	ListModeratedTopicIDs(ctx context.Context, userID int) ([]int, error)
+}
+
+// SearchStore searches posts and comments
+type SearchStore interface {
+	Search(ctx context.Context, req models.SearchRequest, from, to *time.Time) ([]models.SearchResult, int, error)
+}
+
+// Stores bundles one implementation of each store, as handed to the handlers
+// Saved posts and comments are kept by the post and comment stores, which list them too
+type Stores struct {
+	Posts         PostStore
+	Comments      CommentStore
+	Topics        TopicStore
+	Users         UserStore
+	Sessions      SessionStore
+	LoginAttempts LoginAttemptStore
+	EmailTokens   EmailTokenStore
+	TwoFactor     TwoFactorStore
+	APITokens     APITokenStore
+	Identities    IdentityStore
+	Reports       ReportStore
+	Revisions     RevisionStore
+	Notifications NotificationStore
+	Moderators    ModeratorStore
+	// Not named Search, so it does not clash with the handler of the same name on the server
+	SearchIndex SearchStore
+}
+
+// Fills every store of the bundle from one implementation of them all
+func storesOf(store interface {
+	PostStore
+	CommentStore
+	TopicStore
+	UserStore
+	SessionStore
+	LoginAttemptStore
+	EmailTokenStore
+	TwoFactorStore
+	APITokenStore
+	IdentityStore
+	ReportStore
+	RevisionStore
+	NotificationStore
+	ModeratorStore
+	SearchStore
+}) Stores {
+	return Stores{
+		Posts:         store,
+		Comments:      store,
+		Topics:        store,
+		Users:         store,
+		Sessions:      store,
+		LoginAttempts: store,
+		EmailTokens:   store,
+		TwoFactor:     store,
+		APITokens:     store,
+		Identities:    store,
+		Reports:       store,
+		Revisions:     store,
+		Notifications: store,
+		Moderators:    store,
+		SearchIndex:   store,
+	}
+}
+
+// Querier runs queries, it is either a *sql.DB or a *sql.Tx
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// PostgresStore implements every store on PostgreSQL
+// Given a *sql.Tx, all its writes join that transaction and are committed by whoever began it
+type PostgresStore struct {
+	db Querier
+}
+
+func NewPostgresStore(db Querier) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// NewPostgresStores returns the stores backed by the database or transaction
+func NewPostgresStores(db Querier) Stores {
+	return storesOf(NewPostgresStore(db))
+}
+
+// A transaction begun by a store, or the transaction the store was given
+type storeTx struct {
+	*sql.Tx
+	owned bool
+}
+
+// Begins a transaction on the store's database, or joins the store's transaction
+func (s *PostgresStore) begin(ctx context.Context) (*storeTx, error) {
+	switch db := s.db.(type) {
+	case *sql.Tx:
+		return &storeTx{Tx: db}, nil
+	case *sql.DB:
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &storeTx{Tx: tx, owned: true}, nil
+	}
+	return nil, errors.New("store cannot begin a transaction")
+}
+
+// Commits the transaction if the store began it
+func (tx *storeTx) Commit() error {
+	if !tx.owned {
+		return nil
+	}
+	return tx.Tx.Commit()
+}
+
+// Rolls back the transaction if the store began it, the owner of a joined transaction decides its fate
+func (tx *storeTx) Rollback() error {
+	if !tx.owned {
+		return nil
+	}
+	return tx.Tx.Rollback()
+}
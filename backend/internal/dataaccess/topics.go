@@ -1,24 +1,32 @@
 package dataaccess
 
 import (
+	"context"
 	"cvwo/internal/constants"
-	"cvwo/internal/database"
 	"cvwo/internal/models"
+	"cvwo/internal/telemetry"
+	"cvwo/internal/utils"
 	"database/sql"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 )
 
 // ListTopics retrieves all topics with their post and follower counts
-func ListTopicsSummary() ([]models.Topic, error) {
+func (s *PostgresStore) ListTopicsSummary(ctx context.Context) ([]models.Topic, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "ListTopicsSummary")
+	defer done()
+
 	query := `
 		SELECT id,
-		name
+		name,
+		slug
 		FROM topics
+		WHERE is_archived = false
 		ORDER BY name ASC`
 
-	rows, err := database.DB.Query(query)
+	rows, err := s.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -27,7 +35,7 @@ func ListTopicsSummary() ([]models.Topic, error) {
 	topics := []models.Topic{}
 	for rows.Next() {
 		var topic models.Topic
-		if err := rows.Scan(&topic.ID, &topic.Name); err != nil {
+		if err := rows.Scan(&topic.ID, &topic.Name, &topic.Slug); err != nil {
 			return nil, err
 		}
 		topics = append(topics, topic)
@@ -41,36 +49,55 @@ func ListTopicsSummary() ([]models.Topic, error) {
 }
 
 // ListTopics retrieves all topics with their post and follower counts
-func ListTopics(isAuthenticated bool, currentUserID int, req models.ListTopicsRequest) ([]models.Topic, int, error) {
+func (s *PostgresStore) ListTopics(ctx context.Context, isAuthenticated bool, currentUserID int, req models.ListTopicsRequest) ([]models.Topic, models.PageInfo, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "ListTopics")
+	defer done()
+
 	args := []any{}
 	var queryBuilder strings.Builder
+	pageInfo := models.PageInfo{}
+
+	desc := req.OrderBy != constants.SORT_ASC
+	ordering := []keysetColumn{}
+	switch req.Sort {
+	case constants.ORDER_BY_POSTS:
+		ordering = append(ordering, keysetColumn{expr: "t.no_of_posts", desc: desc})
+	case constants.ORDER_BY_FOLLOWERS:
+		ordering = append(ordering, keysetColumn{expr: "t.no_of_followers", desc: desc})
+	default:
+		ordering = append(ordering, keysetColumn{expr: "t.name", kind: keysetString, desc: desc})
+	}
+	ordering = append(ordering, keysetColumn{expr: "t.id", desc: desc})
 
 	selectFields := `
 		t.id,
 		t.name,
+		t.slug,
 		t.no_of_posts,
 		t.no_of_followers,
-		t.description`
+		COALESCE(t.description, ''),
+		t.is_archived,
+		t.archived_at`
 
 	if isAuthenticated {
 		query := fmt.Sprintf(`
 			SELECT %s,
-			CASE WHEN ut.user_id IS NOT NULL THEN true ELSE false END
+			CASE WHEN ut.user_id IS NOT NULL THEN true ELSE false END%s
 			FROM topics t
 
 			LEFT JOIN user_topics ut
 				ON t.id = ut.topic_id
 				AND ut.user_id = $1
-			WHERE 1=1`, selectFields)
+			WHERE 1=1`, selectFields, keysetSelectFields(ordering))
 
 		queryBuilder.WriteString(query)
 		args = append(args, currentUserID)
 	} else {
 		query := fmt.Sprintf(`
 			SELECT %s,
-			0
+			0%s
 			FROM topics t
-			WHERE 1=1`, selectFields)
+			WHERE 1=1`, selectFields, keysetSelectFields(ordering))
 
 		queryBuilder.WriteString(query)
 	}
@@ -80,89 +107,117 @@ func ListTopics(isAuthenticated bool, currentUserID int, req models.ListTopicsRe
 		queryBuilder.WriteString(fmt.Sprintf(" AND t.name ILIKE $%d", len(args)))
 	}
 
+	if !req.ShowArchived {
+		queryBuilder.WriteString(" AND t.is_archived = false")
+	}
+
 	if req.FilterFollowing {
 		if !isAuthenticated {
-			return nil, 0, errors.New("user must be authenticated when filtering by following status")
+			return nil, pageInfo, errors.New("user must be authenticated when filtering by following status")
 		}
 		queryBuilder.WriteString(" AND ut.user_id IS NOT NULL")
 	}
 
 	// Get total count for pagination
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM (%s)", queryBuilder.String())
-	var totalCount int
-	err := database.DB.QueryRow(countQuery, args...).Scan(&totalCount)
-	if err != nil {
-		return nil, 0, err
+	if shouldCount(req.IncludeCount) {
+		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM (%s)", queryBuilder.String())
+		var totalCount int
+		err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&totalCount)
+		if err != nil {
+			return nil, pageInfo, err
+		}
+		pageInfo.Count = &totalCount
 	}
 
-	switch req.Sort {
-	case constants.ORDER_BY_POSTS:
-		queryBuilder.WriteString(" ORDER BY t.no_of_posts")
-	case constants.ORDER_BY_FOLLOWERS:
-		queryBuilder.WriteString(" ORDER BY t.no_of_followers")
-	default:
-		queryBuilder.WriteString(" ORDER BY t.name")
+	if req.Cursor != "" {
+		values, err := decodeCursor(req.Cursor, ordering)
+		if err != nil {
+			return nil, pageInfo, err
+		}
+		writeKeysetCondition(&queryBuilder, &args, ordering, values)
 	}
 
-	switch req.OrderBy {
-	case constants.SORT_ASC:
-		queryBuilder.WriteString(" ASC")
-	default:
-		queryBuilder.WriteString(" DESC")
-	}
+	writeOrderBy(&queryBuilder, ordering)
 
-	if req.PageSize <= 0 || req.PageSize > constants.MAX_PAGE_SIZE {
-		req.PageSize = constants.MAX_PAGE_SIZE
+	// The handlers cap the page size at the configured maximum
+	if req.PageSize <= 0 {
+		req.PageSize = constants.DEFAULT_PAGE_SIZE
 	}
 
-	args = append(args, req.PageSize)
+	// Fetch one extra row to know whether there is a next page
+	args = append(args, req.PageSize+1)
 	queryBuilder.WriteString(fmt.Sprintf(" LIMIT $%d", len(args)))
-	if req.Page > 0 {
+
+	// The cursor replaces the offset
+	if req.Page > 0 && req.Cursor == "" {
 		offset := (req.Page - 1) * req.PageSize
 		args = append(args, offset)
 		queryBuilder.WriteString(fmt.Sprintf(" OFFSET $%d", len(args)))
 	}
 
-	rows, err := database.DB.Query(queryBuilder.String(), args...)
+	rows, err := s.db.QueryContext(ctx, queryBuilder.String(), args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, pageInfo, err
 	}
 	defer rows.Close()
 
+	keysetValues, keysetDest := keysetScanDest(ordering)
+	var cursorValues []any
+
 	topics := []models.Topic{}
 	for rows.Next() {
 		var topic models.Topic
-		if err := rows.Scan(
+		dest := []any{
 			&topic.ID,
 			&topic.Name,
+			&topic.Slug,
 			&topic.NoOfPosts,
 			&topic.NoOfFollowers,
 			&topic.Description,
+			&topic.IsArchived,
+			&topic.ArchivedAt,
 			&topic.IsFollowing,
-		); err != nil {
-			return nil, 0, err
 		}
+		if err := rows.Scan(append(dest, keysetDest...)...); err != nil {
+			return nil, pageInfo, err
+		}
+
 		topics = append(topics, topic)
+		if len(topics) == req.PageSize {
+			cursorValues = append([]any{}, keysetValues...)
+		}
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, 0, err
+		return nil, pageInfo, err
+	}
+
+	if len(topics) > req.PageSize {
+		topics = topics[:req.PageSize]
+		pageInfo.NextCursor = encodeCursor(ordering, cursorValues...)
 	}
 
-	return topics, totalCount, nil
+	return topics, pageInfo, nil
 }
 
-func GetTopicByName(isAuthenticated bool, userID int, topicName string) (*models.Topic, error) {
+// GetTopicBySlug retrieves a topic by its current slug, or by an old slug if it was renamed
+func (s *PostgresStore) GetTopicBySlug(ctx context.Context, isAuthenticated bool, userID int, slug string) (*models.Topic, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "GetTopicBySlug")
+	defer done()
+
 	var query string
-	args := []any{topicName}
+	args := []any{slug}
 	topic := &models.Topic{}
 
 	selectFields := `
 		t.id,
 		t.name,
+		t.slug,
 		t.no_of_posts,
 		t.no_of_followers,
-		t.description`
+		COALESCE(t.description, ''),
+		t.is_archived,
+		t.archived_at`
 
 	if isAuthenticated {
 		query = fmt.Sprintf(`
@@ -173,7 +228,8 @@ func GetTopicByName(isAuthenticated bool, userID int, topicName string) (*models
 			LEFT JOIN user_topics ut
 				ON t.id = ut.topic_id
 				AND ut.user_id = $2
-			WHERE t.name = $1`, selectFields)
+			WHERE t.slug = $1
+			OR t.id = (SELECT topic_id FROM topic_slug_redirects WHERE slug = $1)`, selectFields)
 
 		args = append(args, userID)
 	} else {
@@ -181,15 +237,19 @@ func GetTopicByName(isAuthenticated bool, userID int, topicName string) (*models
 					SELECT %s,
 					0
 					FROM topics t
-					WHERE t.name = $1`, selectFields)
+					WHERE t.slug = $1
+					OR t.id = (SELECT topic_id FROM topic_slug_redirects WHERE slug = $1)`, selectFields)
 	}
 
-	err := database.DB.QueryRow(query, args...).Scan(
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(
 		&topic.ID,
 		&topic.Name,
+		&topic.Slug,
 		&topic.NoOfPosts,
 		&topic.NoOfFollowers,
 		&topic.Description,
+		&topic.IsArchived,
+		&topic.ArchivedAt,
 		&topic.IsFollowing,
 	)
 
@@ -203,8 +263,11 @@ func GetTopicByName(isAuthenticated bool, userID int, topicName string) (*models
 }
 
 // Transaction to ensure both insert and update are atomic
-func FollowTopic(userID int, topicName string) error {
-	tx, err := database.DB.Begin()
+func (s *PostgresStore) FollowTopic(ctx context.Context, userID int, topicName string) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "FollowTopic")
+	defer done()
+
+	tx, err := s.begin(ctx)
 	if err != nil {
 		return err
 	}
@@ -217,7 +280,7 @@ func FollowTopic(userID int, topicName string) error {
 		WHERE name = $1`
 
 	var topicID int
-	err = tx.QueryRow(selectQuery, topicName).Scan(&topicID)
+	err = tx.QueryRowContext(ctx, selectQuery, topicName).Scan(&topicID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return errors.New(constants.NOT_FOUND_ERROR)
@@ -230,7 +293,7 @@ func FollowTopic(userID int, topicName string) error {
 		VALUES ($1, $2)
 		ON CONFLICT (user_id, topic_id) DO NOTHING`
 
-	res, err := tx.Exec(query, userID, topicID)
+	res, err := tx.ExecContext(ctx, query, userID, topicID)
 	if err != nil {
 		return fmt.Errorf("failed to follow topic: %w", err)
 	}
@@ -249,7 +312,7 @@ func FollowTopic(userID int, topicName string) error {
 			no_of_followers = no_of_followers + 1
 		WHERE id = $1`
 
-	_, err = tx.Exec(updateTopicQuery, topicID)
+	_, err = tx.ExecContext(ctx, updateTopicQuery, topicID)
 	if err != nil {
 		return err
 	}
@@ -258,8 +321,11 @@ func FollowTopic(userID int, topicName string) error {
 }
 
 // Transaction to ensure both delete and update are atomic
-func UnfollowTopic(userID int, topicName string) error {
-	tx, err := database.DB.Begin()
+func (s *PostgresStore) UnfollowTopic(ctx context.Context, userID int, topicName string) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "UnfollowTopic")
+	defer done()
+
+	tx, err := s.begin(ctx)
 	if err != nil {
 		return err
 	}
@@ -272,7 +338,7 @@ func UnfollowTopic(userID int, topicName string) error {
 		WHERE name = $1`
 
 	var topicID int
-	err = tx.QueryRow(selectQuery, topicName).Scan(&topicID)
+	err = tx.QueryRowContext(ctx, selectQuery, topicName).Scan(&topicID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return errors.New(constants.NOT_FOUND_ERROR)
@@ -284,7 +350,7 @@ func UnfollowTopic(userID int, topicName string) error {
 		DELETE FROM user_topics
 	 	WHERE user_id = $1 AND topic_id = $2`
 
-	res, err := tx.Exec(query, userID, topicID)
+	res, err := tx.ExecContext(ctx, query, userID, topicID)
 	if err != nil {
 		return err
 	}
@@ -303,10 +369,186 @@ func UnfollowTopic(userID int, topicName string) error {
 			no_of_followers = no_of_followers - 1
 		WHERE id = $1`
 
-	_, err = tx.Exec(updateTopicQuery, topicID)
+	_, err = tx.ExecContext(ctx, updateTopicQuery, topicID)
 	if err != nil {
 		return err
 	}
 
 	return tx.Commit()
 }
+
+// CreateTopic inserts a new topic with a slug derived from its name
+// Returns ALREADY_EXISTS_ERROR if the name or slug is taken, including old slugs of renamed topics
+func (s *PostgresStore) CreateTopic(ctx context.Context, topic models.Topic) (int, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "CreateTopic")
+	defer done()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	slug := utils.SlugifyTopicName(topic.Name)
+
+	taken, err := isTopicNameOrSlugTaken(ctx, tx.Tx, topic.Name, slug, 0)
+	if err != nil {
+		return 0, err
+	}
+	if taken {
+		return 0, errors.New(constants.ALREADY_EXISTS_ERROR)
+	}
+
+	query := `
+		INSERT INTO topics (
+			name,
+			slug,
+			description,
+			no_of_posts,
+			no_of_followers,
+			created_at,
+			updated_at)
+		VALUES ($1, $2, $3, 0, 0, $4, $4) RETURNING id`
+
+	var topicID int
+	err = tx.QueryRowContext(ctx, query, topic.Name, slug, topic.Description, time.Now()).Scan(&topicID)
+	if err != nil {
+		return 0, err
+	}
+
+	return topicID, tx.Commit()
+}
+
+// UpdateTopic renames, redescribes and archives/unarchives a topic
+// On rename the old slug is kept in topic_slug_redirects so existing links keep resolving
+// Post and follower counts are recomputed from their source tables at the same time
+func (s *PostgresStore) UpdateTopic(ctx context.Context, topic models.Topic) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "UpdateTopic")
+	defer done()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var oldSlug string
+	var wasArchived bool
+	err = tx.QueryRowContext(ctx, "SELECT slug, is_archived FROM topics WHERE id = $1 FOR UPDATE", topic.ID).Scan(&oldSlug, &wasArchived)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New(constants.NOT_FOUND_ERROR)
+		}
+		return err
+	}
+
+	newSlug := utils.SlugifyTopicName(topic.Name)
+
+	taken, err := isTopicNameOrSlugTaken(ctx, tx.Tx, topic.Name, newSlug, topic.ID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return errors.New(constants.ALREADY_EXISTS_ERROR)
+	}
+
+	now := time.Now()
+	var archivedAt *time.Time
+	if topic.IsArchived {
+		archivedAt = topic.ArchivedAt
+		if !wasArchived || archivedAt == nil {
+			archivedAt = &now
+		}
+	}
+
+	updateQuery := `
+		UPDATE topics SET
+			name = $1,
+			slug = $2,
+			description = $3,
+			is_archived = $4,
+			archived_at = $5,
+			updated_at = $6,
+			no_of_posts = (
+				SELECT COUNT(*) FROM posts
+				WHERE topic_id = $7 AND is_deleted = false),
+			no_of_followers = (
+				SELECT COUNT(*) FROM user_topics
+				WHERE topic_id = $7)
+		WHERE id = $7`
+
+	_, err = tx.ExecContext(ctx, updateQuery,
+		topic.Name,
+		newSlug,
+		topic.Description,
+		topic.IsArchived,
+		archivedAt,
+		now,
+		topic.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if newSlug != oldSlug {
+		// Renaming back to an old slug makes it current again
+		_, err = tx.ExecContext(ctx, "DELETE FROM topic_slug_redirects WHERE slug = $1", newSlug)
+		if err != nil {
+			return err
+		}
+
+		redirectQuery := `
+			INSERT INTO topic_slug_redirects (slug, topic_id, created_at)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (slug) DO UPDATE SET topic_id = EXCLUDED.topic_id`
+
+		_, err = tx.ExecContext(ctx, redirectQuery, oldSlug, topic.ID, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ArchiveTopic hides a topic from listings and stops new posts, existing posts stay readable
+func (s *PostgresStore) ArchiveTopic(ctx context.Context, topicID int) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "ArchiveTopic")
+	defer done()
+
+	query := `
+		UPDATE topics SET
+			is_archived = true,
+			archived_at = $2,
+			updated_at = $2
+		WHERE id = $1 AND is_archived = false`
+
+	result, err := s.db.ExecContext(ctx, query, topicID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	return nil
+}
+
+// A name or slug is taken if another topic uses it, or another topic used the slug before a rename
+func isTopicNameOrSlugTaken(ctx context.Context, tx *sql.Tx, name, slug string, excludeTopicID int) (bool, error) {
+	query := `
+		SELECT EXISTS(SELECT 1 FROM topics
+			WHERE (LOWER(name) = LOWER($1) OR slug = $2) AND id != $3)
+		OR EXISTS(SELECT 1 FROM topic_slug_redirects
+			WHERE slug = $2 AND topic_id != $3)`
+
+	var taken bool
+	err := tx.QueryRowContext(ctx, query, name, slug, excludeTopicID).Scan(&taken)
+	return taken, err
+}
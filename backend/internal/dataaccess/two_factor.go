@@ -0,0 +1,339 @@
+package dataaccess
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"cvwo/internal/telemetry"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// GetTwoFactor returns the user's TOTP enrollment, confirmed or not
+// Returns NOT_FOUND_ERROR if the user never started enrolling or disabled it
+func (s *PostgresStore) GetTwoFactor(ctx context.Context, userID int) (*models.TwoFactor, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "GetTwoFactor")
+	defer done()
+
+	query := `
+		SELECT user_id,
+		secret,
+		created_at,
+		enabled_at,
+		last_used_step
+		FROM user_totp
+		WHERE user_id = $1`
+
+	twoFactor := &models.TwoFactor{}
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(
+		&twoFactor.UserID,
+		&twoFactor.Secret,
+		&twoFactor.CreatedAt,
+		&twoFactor.EnabledAt,
+		&twoFactor.LastUsedStep,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New(constants.NOT_FOUND_ERROR)
+		}
+		return nil, err
+	}
+	return twoFactor, nil
+}
+
+// IsTwoFactorEnabled returns whether logins of the user need a two-factor code
+func (s *PostgresStore) IsTwoFactorEnabled(ctx context.Context, userID int) (bool, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "IsTwoFactorEnabled")
+	defer done()
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM user_totp
+			WHERE user_id = $1 AND enabled_at IS NOT NULL)`
+
+	var enabled bool
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(&enabled)
+	return enabled, err
+}
+
+// StartTwoFactorEnrollment stores a new unconfirmed TOTP secret, replacing an earlier unconfirmed one
+// Returns ALREADY_EXISTS_ERROR if two-factor authentication is already enabled
+func (s *PostgresStore) StartTwoFactorEnrollment(ctx context.Context, userID int, secret string) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "StartTwoFactorEnrollment")
+	defer done()
+
+	query := `
+		INSERT INTO user_totp (user_id, secret, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET
+			secret = EXCLUDED.secret,
+			created_at = EXCLUDED.created_at,
+			last_used_step = NULL
+		WHERE user_totp.enabled_at IS NULL`
+
+	result, err := s.db.ExecContext(ctx, query, userID, secret, time.Now())
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New(constants.ALREADY_EXISTS_ERROR)
+	}
+
+	return nil
+}
+
+// EnableTwoFactor confirms the user's enrollment with the step of the code they entered
+// and stores the hashes of their first recovery codes
+// Returns NO_ROWS_AFFECTED_ERROR if there is no unconfirmed enrollment
+func (s *PostgresStore) EnableTwoFactor(ctx context.Context, userID int, step int64, recoveryCodeHashes []string) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "EnableTwoFactor")
+	defer done()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE user_totp SET
+			enabled_at = $2,
+			last_used_step = $3
+		WHERE user_id = $1 AND enabled_at IS NULL`
+
+	result, err := tx.ExecContext(ctx, query, userID, time.Now(), step)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx.Tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DisableTwoFactor removes the user's TOTP secret, recovery codes and pending logins
+func (s *PostgresStore) DisableTwoFactor(ctx context.Context, userID int) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "DisableTwoFactor")
+	defer done()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	queries := []string{
+		`DELETE FROM user_totp WHERE user_id = $1`,
+		`DELETE FROM recovery_codes WHERE user_id = $1`,
+		`DELETE FROM login_challenges WHERE user_id = $1`,
+	}
+
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UseTOTPStep records the step of an accepted code
+// Returns false if a code of the same or a later step was already accepted, so the code is a replay
+func (s *PostgresStore) UseTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "UseTOTPStep")
+	defer done()
+
+	query := `
+		UPDATE user_totp SET
+			last_used_step = $2
+		WHERE user_id = $1
+		AND enabled_at IS NOT NULL
+		AND (last_used_step IS NULL OR last_used_step < $2)`
+
+	result, err := s.db.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	return rowsAffected > 0, err
+}
+
+// ReplaceRecoveryCodes invalidates the user's recovery codes and stores the hashes of new ones
+func (s *PostgresStore) ReplaceRecoveryCodes(ctx context.Context, userID int, recoveryCodeHashes []string) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "ReplaceRecoveryCodes")
+	defer done()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx.Tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID int, recoveryCodeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO recovery_codes (user_id, code_hash, created_at)
+		VALUES ($1, $2, $3)`
+
+	now := time.Now()
+	for _, codeHash := range recoveryCodeHashes {
+		if _, err := tx.ExecContext(ctx, query, userID, codeHash, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks the user's recovery code with the hash as used
+// Returns false if the user has no such unused code
+func (s *PostgresStore) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "UseRecoveryCode")
+	defer done()
+
+	query := `
+		UPDATE recovery_codes SET
+			used_at = $3
+		WHERE user_id = $1 AND code_hash = $2
+		AND used_at IS NULL`
+
+	result, err := s.db.ExecContext(ctx, query, userID, codeHash, time.Now())
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	return rowsAffected > 0, err
+}
+
+// CountUnusedRecoveryCodes returns how many recovery codes the user has left
+func (s *PostgresStore) CountUnusedRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "CountUnusedRecoveryCodes")
+	defer done()
+
+	query := `
+		SELECT COUNT(*)
+		FROM recovery_codes
+		WHERE user_id = $1 AND used_at IS NULL`
+
+	var count int
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(&count)
+	return count, err
+}
+
+// CreateLoginChallenge stores the hash of the token a pending login is completed with
+func (s *PostgresStore) CreateLoginChallenge(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "CreateLoginChallenge")
+	defer done()
+
+	query := `
+		INSERT INTO login_challenges (user_id, token_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4)`
+
+	_, err := s.db.ExecContext(ctx, query, userID, tokenHash, time.Now(), expiresAt)
+	return err
+}
+
+// GetLoginChallenge finds the pending login with the token hash
+// Returns NOT_FOUND_ERROR if it does not exist, was completed, expired or had too many wrong codes
+func (s *PostgresStore) GetLoginChallenge(ctx context.Context, tokenHash string) (*models.LoginChallenge, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "GetLoginChallenge")
+	defer done()
+
+	query := `
+		SELECT id,
+		user_id,
+		failed_attempts,
+		expires_at
+		FROM login_challenges
+		WHERE token_hash = $1
+		AND used_at IS NULL
+		AND expires_at > $2
+		AND failed_attempts < $3`
+
+	challenge := &models.LoginChallenge{}
+	err := s.db.QueryRowContext(ctx, query, tokenHash, time.Now(), constants.MAX_LOGIN_CHALLENGE_ATTEMPTS).Scan(
+		&challenge.ID,
+		&challenge.UserID,
+		&challenge.FailedAttempts,
+		&challenge.ExpiresAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New(constants.NOT_FOUND_ERROR)
+		}
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// RecordLoginChallengeFailure counts a wrong code against the pending login
+func (s *PostgresStore) RecordLoginChallengeFailure(ctx context.Context, id int) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "RecordLoginChallengeFailure")
+	defer done()
+
+	query := `
+		UPDATE login_challenges SET
+			failed_attempts = failed_attempts + 1
+		WHERE id = $1`
+
+	_, err := s.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// CompleteLoginChallenge marks the pending login as completed, so its token cannot start another session
+// Returns NO_ROWS_AFFECTED_ERROR if it was already completed
+func (s *PostgresStore) CompleteLoginChallenge(ctx context.Context, id int) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "CompleteLoginChallenge")
+	defer done()
+
+	query := `
+		UPDATE login_challenges SET
+			used_at = $2
+		WHERE id = $1 AND used_at IS NULL`
+
+	result, err := s.db.ExecContext(ctx, query, id, time.Now())
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	return nil
+}
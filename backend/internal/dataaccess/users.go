@@ -1,40 +1,56 @@
 package dataaccess
 
 import (
+	"context"
 	"cvwo/internal/constants"
-	"cvwo/internal/database"
 	"cvwo/internal/models"
+	"cvwo/internal/telemetry"
 	"database/sql"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/lib/pq"
 )
 
-func CreateUser(user models.User) error {
+func (s *PostgresStore) CreateUser(ctx context.Context, user models.User) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "CreateUser")
+	defer done()
+
+	if user.Role == "" {
+		user.Role = constants.ROLE_MEMBER
+	}
+
 	query := `
-		INSERT INTO users (email, username, password)
-		VALUES ($1, $2, $3)`
+		INSERT INTO users (email, username, password, role)
+		VALUES ($1, $2, $3, $4)`
 
-	_, err := database.DB.Exec(query, user.Email, user.Username, user.Password)
+	_, err := s.db.ExecContext(ctx, query, user.Email, user.Username, user.Password, user.Role)
 	return err
 }
 
-// Retrieves username, email, password and id (for login)
-func GetUserByEmail(email string) (*models.User, error) {
+// Retrieves username, email, password, role and id (for login)
+func (s *PostgresStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "GetUserByEmail")
+	defer done()
+
 	user := &models.User{}
 	query := `
 		SELECT id,
 		username,
 		email,
-		password
+		password,
+		role
 		FROM users
 		WHERE email = $1`
 
-	err := database.DB.QueryRow(query, email).Scan(
+	err := s.db.QueryRowContext(ctx, query, email).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
 		&user.Password,
+		&user.Role,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -45,24 +61,31 @@ func GetUserByEmail(email string) (*models.User, error) {
 	return user, nil
 }
 
-// Retrieves username, email, id, and created_at (for GetUserData)
-func GetUserByID(id int) (*models.User, error) {
+// Retrieves username, email, id, created_at and email verification (for GetUserData)
+func (s *PostgresStore) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "GetUserByID")
+	defer done()
+
 	user := &models.User{}
 	query := `
 		SELECT id,
 		username,
 		email,
 		karma,
-		created_at
+		role,
+		created_at,
+		email_verified_at
 		FROM users
 		WHERE id = $1`
 
-	err := database.DB.QueryRow(query, id).Scan(
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
 		&user.Karma,
+		&user.Role,
 		&user.CreatedAt,
+		&user.EmailVerifiedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -71,22 +94,27 @@ func GetUserByID(id int) (*models.User, error) {
 }
 
 // Retrieves username, email, id, and created_at (for GetUserData)
-func GetUserByUsername(username string) (*models.User, error) {
+func (s *PostgresStore) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "GetUserByUsername")
+	defer done()
+
 	user := &models.User{}
 	query := `
 		SELECT id,
 		username,
 		email,
 		karma,
+		role,
 		created_at
 		FROM users
 		WHERE username = $1`
 
-	err := database.DB.QueryRow(query, username).Scan(
+	err := s.db.QueryRowContext(ctx, query, username).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
 		&user.Karma,
+		&user.Role,
 		&user.CreatedAt,
 	)
 	if err != nil {
@@ -95,24 +123,99 @@ func GetUserByUsername(username string) (*models.User, error) {
 	return user, nil
 }
 
-func UpdateUserPassword(id int, newPassword string) error {
+// Retrieves the password hash of the user (for ChangePassword)
+func (s *PostgresStore) GetUserPasswordHash(ctx context.Context, id int) (string, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "GetUserPasswordHash")
+	defer done()
+
+	query := `
+		SELECT password
+		FROM users
+		WHERE id = $1`
+
+	var passwordHash string
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&passwordHash)
+	return passwordHash, err
+}
+
+// UpdateUserPassword sets the user's password hash and revokes every other session of the user,
+// so whoever knew the old password is logged out, keepSessionID 0 revokes them all
+func (s *PostgresStore) UpdateUserPassword(ctx context.Context, id int, newPassword string, keepSessionID int) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "UpdateUserPassword")
+	defer done()
+
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updateUserPassword(ctx, tx.Tx, id, newPassword, keepSessionID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func updateUserPassword(ctx context.Context, tx *sql.Tx, id int, newPassword string, keepSessionID int) error {
 	query := `
 		UPDATE users SET
 			password = $1
 		WHERE id = $2`
 
-	_, err := database.DB.Exec(query, newPassword, id)
+	if _, err := tx.ExecContext(ctx, query, newPassword, id); err != nil {
+		return err
+	}
+
+	revokeQuery := `
+		UPDATE sessions SET
+			revoked_at = $3
+		WHERE user_id = $1 AND id <> $2
+		AND revoked_at IS NULL`
+
+	_, err := tx.ExecContext(ctx, revokeQuery, id, keepSessionID, time.Now())
 	return err
 }
 
-func UpdateUserData(user *models.User) error {
+func (s *PostgresStore) UpdateUserRole(ctx context.Context, id int, role string) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "UpdateUserRole")
+	defer done()
+
 	query := `
 		UPDATE users SET
+			role = $1
+		WHERE id = $2`
+
+	result, err := s.db.ExecContext(ctx, query, role, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New(constants.NO_ROWS_AFFECTED_ERROR)
+	}
+
+	return nil
+}
+
+// Changing the email clears its verification
+func (s *PostgresStore) UpdateUserData(ctx context.Context, user *models.User) error {
+	ctx, done := telemetry.ObserveQuery(ctx, "UpdateUserData")
+	defer done()
+
+	query := `
+		UPDATE users SET
+			email_verified_at = CASE WHEN email = $1 THEN email_verified_at END,
 			email = $1,
 			username = $2
 		WHERE id = $3`
 
-	_, err := database.DB.Exec(query,
+	_, err := s.db.ExecContext(ctx, query,
 		user.Email,
 		user.Username,
 		user.ID,
@@ -120,14 +223,17 @@ func UpdateUserData(user *models.User) error {
 	return err
 }
 
-func CheckUserExistsByEmail(email string) (bool, error) {
+func (s *PostgresStore) CheckUserExistsByEmail(ctx context.Context, email string) (bool, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "CheckUserExistsByEmail")
+	defer done()
+
 	query := `
 		SELECT id
 		FROM users
 		WHERE email = $1`
 
 	var id int
-	err := database.DB.QueryRow(query, email).Scan(&id)
+	err := s.db.QueryRowContext(ctx, query, email).Scan(&id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return false, nil
@@ -137,14 +243,17 @@ func CheckUserExistsByEmail(email string) (bool, error) {
 	return true, nil
 }
 
-func CheckUserExistsByUsername(username string) (bool, error) {
+func (s *PostgresStore) CheckUserExistsByUsername(ctx context.Context, username string) (bool, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "CheckUserExistsByUsername")
+	defer done()
+
 	query := `
 		SELECT id
 		FROM users
 		WHERE username = $1`
 
 	var id int
-	err := database.DB.QueryRow(query, username).Scan(&id)
+	err := s.db.QueryRowContext(ctx, query, username).Scan(&id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return false, nil
@@ -155,14 +264,30 @@ func CheckUserExistsByUsername(username string) (bool, error) {
 }
 
 // Retrieves users with pagination, sorting, and search functionality
-func ListUsers(req models.ListUsersRequest) ([]models.User, int, error) {
-	baseQuery := `
+func (s *PostgresStore) ListUsers(ctx context.Context, req models.ListUsersRequest) ([]models.User, models.PageInfo, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "ListUsers")
+	defer done()
+
+	pageInfo := models.PageInfo{}
+
+	// Oldest first unless descending order is requested
+	desc := req.OrderBy != "" && req.OrderBy != constants.SORT_ASC
+	ordering := []keysetColumn{}
+	switch req.Sort {
+	case constants.ORDER_BY_KARMA:
+		ordering = append(ordering, keysetColumn{expr: "karma", desc: desc})
+	default:
+		ordering = append(ordering, keysetColumn{expr: "created_at", kind: keysetTime, desc: desc})
+	}
+	ordering = append(ordering, keysetColumn{expr: "id", desc: desc})
+
+	baseQuery := fmt.Sprintf(`
 			SELECT id,
 			username,
 			karma,
-			created_at
+			created_at%s
 			FROM users
-			WHERE 1=1`
+			WHERE 1=1`, keysetSelectFields(ordering))
 
 	args := []any{}
 
@@ -175,67 +300,114 @@ func ListUsers(req models.ListUsersRequest) ([]models.User, int, error) {
 	}
 
 	// Get total count for pagination
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM (%s) AS count_query", queryBuilder.String())
-	var totalCount int
-	err := database.DB.QueryRow(countQuery, args...).Scan(&totalCount)
-	if err != nil {
-		return nil, 0, err
-	}
-
-	if req.Sort != "" {
-		switch req.Sort {
-		case constants.ORDER_BY_KARMA:
-			queryBuilder.WriteString(" ORDER BY karma")
-		default:
-			queryBuilder.WriteString(" ORDER BY created_at")
+	if shouldCount(req.IncludeCount) {
+		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM (%s) AS count_query", queryBuilder.String())
+		var totalCount int
+		err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&totalCount)
+		if err != nil {
+			return nil, pageInfo, err
 		}
+		pageInfo.Count = &totalCount
 	}
 
-	if req.OrderBy != "" {
-		switch req.OrderBy {
-		case constants.SORT_ASC:
-			queryBuilder.WriteString(" ASC")
-		default:
-			queryBuilder.WriteString(" DESC")
+	if req.Cursor != "" {
+		values, err := decodeCursor(req.Cursor, ordering)
+		if err != nil {
+			return nil, pageInfo, err
 		}
+		writeKeysetCondition(&queryBuilder, &args, ordering, values)
 	}
 
-	if req.PageSize <= 0 || req.PageSize > constants.MAX_PAGE_SIZE {
-		req.PageSize = constants.MAX_PAGE_SIZE
+	writeOrderBy(&queryBuilder, ordering)
+
+	// The handlers cap the page size at the configured maximum
+	if req.PageSize <= 0 {
+		req.PageSize = constants.DEFAULT_PAGE_SIZE
 	}
 
-	args = append(args, req.PageSize)
+	// Fetch one extra row to know whether there is a next page
+	args = append(args, req.PageSize+1)
 	queryBuilder.WriteString(fmt.Sprintf(" LIMIT $%d", len(args)))
 
-	if req.Page > 0 {
+	// The cursor replaces the offset
+	if req.Page > 0 && req.Cursor == "" {
 		offset := (req.Page - 1) * req.PageSize
 		args = append(args, offset)
 		queryBuilder.WriteString(fmt.Sprintf(" OFFSET $%d", len(args)))
 	}
 
-	rows, err := database.DB.Query(queryBuilder.String(), args...)
+	rows, err := s.db.QueryContext(ctx, queryBuilder.String(), args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, pageInfo, err
 	}
 	defer rows.Close()
 
+	keysetValues, keysetDest := keysetScanDest(ordering)
+	var cursorValues []any
+
 	users := []models.User{}
 	for rows.Next() {
 		var user models.User
-		if err := rows.Scan(
+		dest := []any{
 			&user.ID,
 			&user.Username,
 			&user.Karma,
 			&user.CreatedAt,
-		); err != nil {
-			return nil, 0, err
 		}
+		if err := rows.Scan(append(dest, keysetDest...)...); err != nil {
+			return nil, pageInfo, err
+		}
+
 		users = append(users, user)
+		if len(users) == req.PageSize {
+			cursorValues = append([]any{}, keysetValues...)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, pageInfo, err
+	}
+
+	if len(users) > req.PageSize {
+		users = users[:req.PageSize]
+		pageInfo.NextCursor = encodeCursor(ordering, cursorValues...)
+	}
+
+	return users, pageInfo, nil
+}
+
+// GetUserIDsByUsernames returns the IDs of the users with the given usernames, unknown usernames are skipped
+func (s *PostgresStore) GetUserIDsByUsernames(ctx context.Context, usernames []string) ([]int, error) {
+	ctx, done := telemetry.ObserveQuery(ctx, "GetUserIDsByUsernames")
+	defer done()
+
+	if len(usernames) == 0 {
+		return []int{}, nil
+	}
+
+	query := `
+		SELECT id
+		FROM users
+		WHERE username = ANY($1::text[])`
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(usernames))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIDs := []int{}
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 
-	return users, totalCount, nil
+	return userIDs, nil
 }
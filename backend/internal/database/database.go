@@ -1,43 +1,30 @@
 package database
 
 import (
+	"cvwo/internal/config"
 	"database/sql"
 	"fmt"
-	"log"
-	"os"
 
 	_ "github.com/lib/pq"
 )
 
 var DB *sql.DB
 
-func Connect() string {
-	user := os.Getenv("POSTGRES_USER")
-	password := os.Getenv("POSTGRES_PASSWORD")
-	port := os.Getenv("POSTGRES_PORT")
-	dbname := os.Getenv("POSTGRES_DB")
-	host := os.Getenv("POSTGRES_HOST")
-
-	if host == "" {
-		host = "localhost"
-	}
-
-	if port == "" {
-		port = "5432"
-	}
-
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", host, port, user, password, dbname)
+// Connect opens the database and checks that it can be reached, returning the connection string
+func Connect(cfg config.DatabaseConfig) (string, error) {
+	connStr := cfg.ConnString()
 
 	var err error
 	DB, err = sql.Open("postgres", connStr)
 	if err != nil {
-		log.Fatal(err)
+		return "", err
 	}
 
 	if err = DB.Ping(); err != nil {
-		log.Fatal("Could not connect to the database:", err)
+		DB.Close()
+		return "", fmt.Errorf("could not connect to the database: %w", err)
 	}
 
 	fmt.Println("Connected to the database!")
-	return connStr
+	return connStr, nil
 }
@@ -0,0 +1,39 @@
+// Package events publishes changes to posts, comments and notifications to live subscribers
+// Events are published after the change is committed, subscribers are the SSE streams
+package events
+
+import (
+	"fmt"
+	"log"
+)
+
+type Event struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+// Broker delivers published events to the subscribers of a topic
+// Events published while no one is subscribed are dropped
+type Broker interface {
+	Publish(topic string, event Event) error
+	// Subscribe returns the events of the topic and a function that ends the subscription
+	Subscribe(topic string) (<-chan Event, func())
+}
+
+// Publish sends the event to the topic's subscribers on the broker
+// Delivery is best effort: failures are logged and never fail the change that caused them
+func Publish(broker Broker, topic string, eventType string, data any) {
+	if err := broker.Publish(topic, Event{Type: eventType, Data: data}); err != nil {
+		log.Printf("events: failed to publish %s to %s: %v", eventType, topic, err)
+	}
+}
+
+// Topic of changes to a post and its comments
+func PostTopic(postID int) string {
+	return fmt.Sprintf("post:%d", postID)
+}
+
+// Topic of a user's own events, e.g. new notifications
+func UserTopic(userID int) string {
+	return fmt.Sprintf("user:%d", userID)
+}
@@ -0,0 +1,58 @@
+package events
+
+import "sync"
+
+// Events buffered per subscriber, further events are dropped until the subscriber catches up
+const subscriberBufferSize = 64
+
+// MemoryBroker is an in-process pub/sub hub, it only reaches subscribers of the same server
+type MemoryBroker struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[chan Event]struct{}
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		subscribers: map[string]map[chan Event]struct{}{},
+	}
+}
+
+// Publish never blocks, slow subscribers miss events instead of holding up the publisher
+func (b *MemoryBroker) Publish(topic string, event Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers[topic] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(topic string) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBufferSize)
+
+	b.mu.Lock()
+	if b.subscribers[topic] == nil {
+		b.subscribers[topic] = map[chan Event]struct{}{}
+	}
+	b.subscribers[topic][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers[topic], ch)
+			if len(b.subscribers[topic]) == 0 {
+				delete(b.subscribers, topic)
+			}
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+
+	return ch, unsubscribe
+}
@@ -0,0 +1,92 @@
+package events
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PostgreSQL channel shared by all server replicas
+const notifyChannel = "cvwo_events"
+
+// Envelope sent through NOTIFY, payloads are limited to 8000 bytes by PostgreSQL
+type notifyPayload struct {
+	Topic string          `json:"topic"`
+	Type  string          `json:"type"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// PostgresBroker publishes through PostgreSQL NOTIFY so every replica listening on the channel
+// delivers the event to its own subscribers
+type PostgresBroker struct {
+	db       *sql.DB
+	listener *pq.Listener
+	local    *MemoryBroker
+}
+
+// NewPostgresBroker starts listening on the events channel with a dedicated connection
+func NewPostgresBroker(db *sql.DB, connStr string) (*PostgresBroker, error) {
+	listener := pq.NewListener(connStr, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("events: listener error: %v", err)
+		}
+	})
+
+	if err := listener.Listen(notifyChannel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	b := &PostgresBroker{
+		db:       db,
+		listener: listener,
+		local:    NewMemoryBroker(),
+	}
+	go b.run()
+
+	return b, nil
+}
+
+// Delivers notifications from every replica, including this one, to local subscribers
+func (b *PostgresBroker) run() {
+	for notification := range b.listener.Notify {
+		// nil after the connection was re-established, events in between are lost
+		if notification == nil {
+			continue
+		}
+
+		var payload notifyPayload
+		if err := json.Unmarshal([]byte(notification.Extra), &payload); err != nil {
+			log.Printf("events: invalid notification payload: %v", err)
+			continue
+		}
+
+		b.local.Publish(payload.Topic, Event{Type: payload.Type, Data: payload.Data})
+	}
+}
+
+func (b *PostgresBroker) Publish(topic string, event Event) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(notifyPayload{Topic: topic, Type: event.Type, Data: data})
+	if err != nil {
+		return err
+	}
+
+	_, err = b.db.Exec("SELECT pg_notify($1, $2)", notifyChannel, string(payload))
+	return err
+}
+
+func (b *PostgresBroker) Subscribe(topic string) (<-chan Event, func()) {
+	return b.local.Subscribe(topic)
+}
+
+func (b *PostgresBroker) Close() error {
+	return b.listener.Close()
+}
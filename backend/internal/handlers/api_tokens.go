@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"cvwo/internal/utils"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ListAPITokens returns the current user's API tokens, without their secrets
+func (s *Server) ListAPITokens(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+	if !isAuthenticated {
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
+		return
+	}
+
+	tokens, err := s.APITokens.ListAPITokens(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch API tokens")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"tokens": tokens,
+		"count":  len(tokens),
+	})
+}
+
+// CreateAPIToken creates an API token for the current user
+// The token is only returned in this response, only its hash is stored
+func (s *Server) CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+	if !isAuthenticated {
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
+		return
+	}
+
+	var req models.CreateAPITokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	var errs utils.ValidationErrors
+	errs.Check("name", utils.ValidateAPITokenName(req.Name))
+	errs.Check("scopes", utils.ValidateAPITokenScopes(req.Scopes))
+	errs.Check("expires_in_days", utils.ValidateAPITokenExpiry(req.ExpiresInDays))
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	secret, err := utils.GenerateRandomToken()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not create API token")
+		return
+	}
+	rawToken := constants.API_TOKEN_PREFIX + secret
+
+	slices.Sort(req.Scopes)
+	token := models.APIToken{
+		UserID:      userID,
+		Name:        req.Name,
+		TokenPrefix: rawToken[:len(constants.API_TOKEN_PREFIX)+6],
+		Scopes:      slices.Compact(req.Scopes),
+		CreatedAt:   time.Now(),
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := token.CreatedAt.AddDate(0, 0, req.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+
+	token.ID, err = s.APITokens.CreateAPIToken(r.Context(), token, utils.HashToken(rawToken))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not create API token")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"token":     rawToken,
+		"api_token": token,
+	})
+}
+
+// RevokeAPIToken revokes one of the current user's API tokens, it stops working immediately
+func (s *Server) RevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+	if !isAuthenticated {
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
+		return
+	}
+
+	tokenID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid API token ID")
+		return
+	}
+
+	if err := s.APITokens.RevokeAPIToken(r.Context(), userID, tokenID); err != nil {
+		if err.Error() == constants.NO_ROWS_AFFECTED_ERROR {
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_API_TOKEN_NOT_FOUND, "API token not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not revoke API token")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "API token revoked successfully",
+	})
+}
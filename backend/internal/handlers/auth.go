@@ -1,45 +1,41 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"math"
 	"net/http"
-	"os"
+	"strconv"
 	"time"
 
-	"cvwo/internal/dataaccess"
+	"cvwo/internal/constants"
 	"cvwo/internal/models"
+	"cvwo/internal/telemetry"
 	"cvwo/internal/utils"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var secretKey = []byte(os.Getenv("JWT_SECRET_KEY"))
-
-func Register(w http.ResponseWriter, r *http.Request) {
+func (s *Server) Register(w http.ResponseWriter, r *http.Request) {
 	var req models.RegisterRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	if emailErr := utils.ValidateEmail(req.Email); emailErr != "" {
-		http.Error(w, emailErr, http.StatusBadRequest)
+		writeDecodeError(w, err)
 		return
 	}
 
-	if usernameErr := utils.ValidateUsername(req.Username); usernameErr != "" {
-		http.Error(w, usernameErr, http.StatusBadRequest)
-		return
-	}
-
-	if passwordErr := utils.ValidatePassword(req.Password); passwordErr != "" {
-		http.Error(w, passwordErr, http.StatusBadRequest)
+	var errs utils.ValidationErrors
+	errs.Check("email", utils.ValidateEmail(req.Email))
+	errs.Check("username", utils.ValidateUsername(req.Username))
+	errs.Check("password", utils.ValidatePassword(req.Password))
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
 		return
 	}
 
 	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
-		http.Error(w, "Could not hash password", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not hash password")
 		return
 	}
 
@@ -49,72 +45,139 @@ func Register(w http.ResponseWriter, r *http.Request) {
 		Password: string(hashedPassword),
 	}
 
-	if exists, _ := dataaccess.CheckUserExistsByEmail(req.Email); exists {
-		http.Error(w, "Email already exists", http.StatusConflict)
+	if exists, _ := s.Users.CheckUserExistsByEmail(r.Context(), req.Email); exists {
+		writeError(w, http.StatusConflict, constants.ERROR_CODE_EMAIL_TAKEN, "Email already exists")
 		return
 	}
 
-	if exists, _ := dataaccess.CheckUserExistsByUsername(req.Username); exists {
-		http.Error(w, "Username already exists", http.StatusConflict)
+	if exists, _ := s.Users.CheckUserExistsByUsername(r.Context(), req.Username); exists {
+		writeError(w, http.StatusConflict, constants.ERROR_CODE_USERNAME_TAKEN, "Username already exists")
 		return
 	}
 
-	if err := dataaccess.CreateUser(user); err != nil {
-		http.Error(w, "Could not create user", http.StatusInternalServerError)
+	if err := s.Users.CreateUser(r.Context(), user); err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not create user")
 		return
 	}
+	telemetry.UserRegistered("password")
+
+	// The account works without verifying, so a failed email only means the user has to ask for another
+	if created, err := s.Users.GetUserByEmail(r.Context(), req.Email); err != nil {
+		logf(r.Context(), "Register: failed to fetch new user: %v", err)
+	} else if err := s.sendVerificationEmail(r.Context(), created); err != nil {
+		logf(r.Context(), "Register: failed to send verification email to user %d: %v", created.ID, err)
+	}
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]string{"message": "User registered successfully"})
 }
 
-func Login(w http.ResponseWriter, r *http.Request) {
+// Login starts a session for the user with the given email and password
+// Unknown emails and wrong passwords get the same response, so registered emails cannot be enumerated
+// Repeated failures for an email or from an IP address lock logins progressively longer
+// Users with two-factor authentication get a challenge token to send with a code to LoginTwoFactor instead
+func (s *Server) Login(w http.ResponseWriter, r *http.Request) {
 	var req models.LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeDecodeError(w, err)
 		return
 	}
 
-	if emailErr := utils.ValidateEmail(req.Email); emailErr != "" {
-		http.Error(w, emailErr, http.StatusBadRequest)
+	var errs utils.ValidationErrors
+	errs.Check("email", utils.ValidateEmail(req.Email))
+	errs.Check("password", utils.ValidatePassword(req.Password))
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
 		return
 	}
 
-	if passwordErr := utils.ValidatePassword(req.Password); passwordErr != "" {
-		http.Error(w, passwordErr, http.StatusBadRequest)
-		return
+	attempt := models.LoginAttempt{
+		Email:     utils.NormalizeEmail(req.Email),
+		IPAddress: s.clientIP(r),
+		UserAgent: r.UserAgent(),
 	}
 
-	user, err := dataaccess.GetUserByEmail(req.Email)
+	// Unknown emails are locked out like known ones, so lockouts do not reveal which emails exist
+	lockout, err := s.loginLockoutRemaining(r.Context(), attempt.Email, attempt.IPAddress)
 	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not login")
 		return
 	}
 
-	if err := utils.CompareHashAndPassword(user.Password, req.Password); err != nil {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+	user, err := s.Users.GetUserByEmail(r.Context(), req.Email)
+	if err == nil {
+		attempt.UserID = &user.ID
+	}
+
+	if lockout > 0 {
+		attempt.FailureReason = constants.LOGIN_FAILURE_LOCKED_OUT
+		s.recordLoginAttempt(r.Context(), attempt)
+
+		seconds := int(math.Ceil(lockout.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		writeError(w, http.StatusTooManyRequests, constants.ERROR_CODE_TOO_MANY_LOGIN_ATTEMPTS,
+			fmt.Sprintf("Too many failed login attempts, try again in %d seconds", seconds))
 		return
 	}
 
-	claims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"issuer": user.ID,
-		"exp":    time.Now().Add(time.Hour * 24).Unix(), // 1 day expiration
-	})
+	if user == nil {
+		utils.CompareDummyPassword(req.Password)
+	}
+	if user == nil || utils.CompareHashAndPassword(user.Password, req.Password) != nil {
+		attempt.FailureReason = constants.LOGIN_FAILURE_INVALID_CREDENTIALS
+		s.recordLoginAttempt(r.Context(), attempt)
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_INVALID_CREDENTIALS, "Invalid email or password")
+		return
+	}
 
-	token, err := claims.SignedString(secretKey)
+	twoFactorEnabled, err := s.TwoFactor.IsTwoFactorEnabled(r.Context(), user.ID)
 	if err != nil {
-		http.Error(w, "Could not login", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not login")
 		return
 	}
 
-	cookie := &http.Cookie{
-		Name:     "jwt",
-		Value:    token,
-		Expires:  time.Now().Add(time.Hour * 24),
-		HttpOnly: true,
-		Path:     "/",
+	// The session starts once a code is sent to /login/2fa, the attempt is recorded then
+	if twoFactorEnabled {
+		challengeToken, expiresAt, err := s.createLoginChallenge(r.Context(), user.ID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not login")
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"two_factor_required": true,
+			"challenge_token":     challengeToken,
+			"expires_at":          expiresAt,
+		})
+		return
+	}
+
+	s.completeLogin(w, r, user, attempt)
+}
+
+// Starts the second step of a login, returns the token to send with the code to LoginTwoFactor
+func (s *Server) createLoginChallenge(ctx context.Context, userID int) (string, time.Time, error) {
+	challengeToken, err := utils.GenerateRandomToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiresAt := time.Now().Add(constants.LOGIN_CHALLENGE_DURATION)
+	if err := s.TwoFactor.CreateLoginChallenge(ctx, userID, utils.HashToken(challengeToken), expiresAt); err != nil {
+		return "", time.Time{}, err
+	}
+	return challengeToken, expiresAt, nil
+}
+
+// Records the successful attempt, starts the session and responds with the user
+func (s *Server) completeLogin(w http.ResponseWriter, r *http.Request, user *models.User, attempt models.LoginAttempt) {
+	attempt.Succeeded = true
+	s.recordLoginAttempt(r.Context(), attempt)
+
+	if err := s.startSession(w, r, user.ID, user.Role); err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not login")
+		return
 	}
-	http.SetCookie(w, cookie)
 
 	json.NewEncoder(w).Encode(map[string]any{
 		"username": user.Username,
@@ -123,33 +186,216 @@ func Login(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func Logout(w http.ResponseWriter, r *http.Request) {
-	cookie := &http.Cookie{
-		Name:     "jwt",
+// Returns how much longer logins with the email or from the IP address are locked, the longer of the two
+func (s *Server) loginLockoutRemaining(ctx context.Context, email, ipAddress string) (time.Duration, error) {
+	since := time.Now().Add(-constants.LOGIN_FAILURE_WINDOW)
+
+	emailFailures, lastEmailFailureAt, err := s.LoginAttempts.CountEmailLoginFailures(ctx, email, since)
+	if err != nil {
+		return 0, err
+	}
+
+	ipFailures, lastIPFailureAt, err := s.LoginAttempts.CountIPLoginFailures(ctx, ipAddress, since)
+	if err != nil {
+		return 0, err
+	}
+
+	return max(
+		utils.LockoutRemaining(emailFailures, constants.ACCOUNT_LOCKOUT_THRESHOLD, lastEmailFailureAt),
+		utils.LockoutRemaining(ipFailures, constants.IP_LOCKOUT_THRESHOLD, lastIPFailureAt),
+	), nil
+}
+
+// Login attempts are logged best effort, a failure to log does not fail the login
+// Failures are logged even if the client hangs up, so aborted guesses still count towards a lockout
+func (s *Server) recordLoginAttempt(ctx context.Context, attempt models.LoginAttempt) {
+	if err := s.LoginAttempts.RecordLoginAttempt(context.WithoutCancel(ctx), attempt); err != nil {
+		logf(ctx, "Login: failed to record login attempt: %v", err)
+	}
+}
+
+// Refresh exchanges a valid refresh token for a new access token
+// The refresh token is rotated, reusing an old one revokes the whole session
+func (s *Server) Refresh(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(constants.REFRESH_TOKEN_COOKIE)
+	if err != nil || cookie.Value == "" {
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_INVALID_REFRESH_TOKEN, "Refresh token required")
+		return
+	}
+	oldHash := utils.HashToken(cookie.Value)
+
+	session, err := s.Sessions.GetSessionByRefreshTokenHash(r.Context(), oldHash)
+	if err != nil {
+		if err.Error() == constants.NOT_FOUND_ERROR {
+			// Token was already rotated, someone is replaying it
+			if err := s.Sessions.RevokeSessionByPreviousRefreshTokenHash(r.Context(), oldHash); err == nil {
+				logf(r.Context(), "Refresh: reuse of rotated refresh token detected, session revoked")
+			}
+			clearSessionCookies(w)
+			writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_INVALID_REFRESH_TOKEN, "Invalid refresh token")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not refresh session")
+		return
+	}
+
+	refreshToken, err := utils.GenerateRandomToken()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not refresh session")
+		return
+	}
+
+	refreshExpiresAt := time.Now().Add(constants.REFRESH_TOKEN_DURATION)
+	err = s.Sessions.RotateRefreshToken(r.Context(), session.ID, oldHash, utils.HashToken(refreshToken), refreshExpiresAt)
+	if err != nil {
+		if err.Error() == constants.NO_ROWS_AFFECTED_ERROR {
+			// Revoked, expired, or rotated concurrently
+			clearSessionCookies(w)
+			writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_SESSION_EXPIRED, "Session expired or revoked")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not refresh session")
+		return
+	}
+
+	user, err := s.Users.GetUserByID(r.Context(), session.UserID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not refresh session")
+		return
+	}
+
+	if err := s.setSessionCookies(w, user.ID, user.Role, session.ID, refreshToken, refreshExpiresAt); err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not refresh session")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Session refreshed"})
+}
+
+// Logout revokes the current session server-side and clears the cookies
+// Works with an expired access token, as long as the refresh token is sent
+func (s *Server) Logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(constants.REFRESH_TOKEN_COOKIE); err == nil && cookie.Value != "" {
+		session, err := s.Sessions.GetSessionByRefreshTokenHash(r.Context(), utils.HashToken(cookie.Value))
+		if err == nil && session.RevokedAt == nil {
+			if err := s.Sessions.RevokeSession(r.Context(), session.UserID, session.ID); err != nil && err.Error() != constants.NO_ROWS_AFFECTED_ERROR {
+				writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not logout")
+				return
+			}
+		}
+	}
+
+	clearSessionCookies(w)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Logged out"})
+}
+
+// Creates a session row for the user and sets the access and refresh token cookies
+func (s *Server) startSession(w http.ResponseWriter, r *http.Request, userID int, role string) error {
+	refreshToken, err := utils.GenerateRandomToken()
+	if err != nil {
+		return err
+	}
+
+	session := models.Session{
+		UserID:    userID,
+		UserAgent: r.UserAgent(),
+		IPAddress: s.clientIP(r),
+		ExpiresAt: time.Now().Add(constants.REFRESH_TOKEN_DURATION),
+	}
+
+	sessionID, err := s.Sessions.CreateSession(r.Context(), session, utils.HashToken(refreshToken))
+	if err != nil {
+		return err
+	}
+
+	return s.setSessionCookies(w, userID, role, sessionID, refreshToken, session.ExpiresAt)
+}
+
+// Signs a short-lived access token for the session and sets both cookies
+// The role claim is informational for clients, the server re-reads it on every request
+func (s *Server) setSessionCookies(w http.ResponseWriter, userID int, role string, sessionID int, refreshToken string, refreshExpiresAt time.Time) error {
+	accessExpiresAt := time.Now().Add(constants.ACCESS_TOKEN_DURATION)
+
+	claims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"issuer":     userID,
+		"session_id": sessionID,
+		"role":       role,
+		"exp":        accessExpiresAt.Unix(),
+	})
+
+	token, err := claims.SignedString(s.jwtSecret)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     constants.ACCESS_TOKEN_COOKIE,
+		Value:    token,
+		Expires:  accessExpiresAt,
+		HttpOnly: true,
+		Path:     "/",
+	})
+
+	// Only sent to the API, where /refresh and /logout read it
+	http.SetCookie(w, &http.Cookie{
+		Name:     constants.REFRESH_TOKEN_COOKIE,
+		Value:    refreshToken,
+		Expires:  refreshExpiresAt,
+		HttpOnly: true,
+		Path:     "/api",
+	})
+
+	return nil
+}
+
+func clearSessionCookies(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     constants.ACCESS_TOKEN_COOKIE,
 		Value:    "",
 		Expires:  time.Now().Add(-time.Hour), // Expire immediately
 		HttpOnly: true,
 		Path:     "/",
-	}
-	http.SetCookie(w, cookie)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Logged out"})
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     constants.REFRESH_TOKEN_COOKIE,
+		Value:    "",
+		Expires:  time.Now().Add(-time.Hour),
+		HttpOnly: true,
+		Path:     "/api",
+	})
 }
 
-func GetUserAuthData(w http.ResponseWriter, r *http.Request) {
+func (s *Server) GetUserAuthData(w http.ResponseWriter, r *http.Request) {
 	userID, isAuthenticated := GetUserFromContext(r)
 	if !isAuthenticated {
-		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
+		return
+	}
+
+	user, err := s.Users.GetUserByID(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, constants.ERROR_CODE_USER_NOT_FOUND, "User not found")
 		return
 	}
 
-	user, err := dataaccess.GetUserByID(userID)
+	moderatedTopicIDs, err := s.Moderators.ListModeratedTopicIDs(r.Context(), userID)
 	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch moderated topics")
+		return
+	}
+
+	twoFactorEnabled, err := s.TwoFactor.IsTwoFactorEnabled(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch two-factor status")
 		return
 	}
 
 	json.NewEncoder(w).Encode(map[string]any{
-		"username": user.Username,
-		"id":       user.ID,
+		"username":            user.Username,
+		"id":                  user.ID,
+		"role":                user.Role,
+		"email_verified":      user.EmailVerifiedAt != nil,
+		"two_factor_enabled":  twoFactorEnabled,
+		"moderated_topic_ids": moderatedTopicIDs,
 	})
 }
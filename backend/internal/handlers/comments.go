@@ -2,8 +2,9 @@ package handlers
 
 import (
 	"cvwo/internal/constants"
-	"cvwo/internal/dataaccess"
+	"cvwo/internal/events"
 	"cvwo/internal/models"
+	"cvwo/internal/telemetry"
 	"cvwo/internal/utils"
 	"encoding/json"
 	"net/http"
@@ -14,26 +15,26 @@ import (
 )
 
 // CreateComment handles creating a new comment
-func CreateComment(w http.ResponseWriter, r *http.Request) {
+func (s *Server) CreateComment(w http.ResponseWriter, r *http.Request) {
 	userID, isAuthenticated := GetUserFromContext(r)
 	if !isAuthenticated {
-		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
 		return
 	}
 
 	var req models.CreateCommentRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeDecodeError(w, err)
 		return
 	}
 
-	// Validate content
-	if contentErr := utils.ValidateCommentContent(strings.TrimSpace(req.Content)); contentErr != "" {
-		http.Error(w, contentErr, http.StatusBadRequest)
-		return
-	}
+	var errs utils.ValidationErrors
+	errs.Check("content", utils.ValidateCommentContent(strings.TrimSpace(req.Content), s.limits.MaxCommentContentLength))
 	if req.PostID <= 0 {
-		http.Error(w, "Valid post ID is required", http.StatusBadRequest)
+		errs.Add("post_id", constants.ERROR_CODE_REQUIRED, "Valid post ID is required")
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
 		return
 	}
 
@@ -44,15 +45,31 @@ func CreateComment(w http.ResponseWriter, r *http.Request) {
 		ParentID: req.ParentID,
 	}
 
-	commentID, err := dataaccess.CreateComment(comment)
+	commentID, err := s.Comments.CreateComment(r.Context(), comment)
 	if err != nil {
 		if err.Error() == constants.NOT_FOUND_ERROR {
-			http.Error(w, err.Error(), http.StatusNotFound)
+			if req.ParentID != nil {
+				writeError(w, http.StatusNotFound, constants.ERROR_CODE_COMMENT_NOT_FOUND, "Parent comment not found")
+				return
+			}
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_POST_NOT_FOUND, "Post not found")
+			return
+		}
+		if err.Error() == constants.POST_LOCKED_ERROR {
+			writeError(w, http.StatusForbidden, constants.ERROR_CODE_POST_LOCKED, "Post is locked")
 			return
 		}
-		http.Error(w, "Could not create comment", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not create comment")
 		return
 	}
+	telemetry.CommentCreated()
+
+	s.notify.CommentCreated(r.Context(), commentID)
+	events.Publish(s.broker, events.PostTopic(comment.PostID), constants.EVENT_COMMENT_CREATED, map[string]any{
+		"comment_id": commentID,
+		"post_id":    comment.PostID,
+		"parent_id":  comment.ParentID,
+	})
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]any{
@@ -62,145 +79,177 @@ func CreateComment(w http.ResponseWriter, r *http.Request) {
 }
 
 // UpdateComment handles updating an existing comment
-func UpdateComment(w http.ResponseWriter, r *http.Request) {
+func (s *Server) UpdateComment(w http.ResponseWriter, r *http.Request) {
 	userID, isAuthenticated := GetUserFromContext(r)
 	if !isAuthenticated {
-		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
 		return
 	}
 
 	commentID, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid comment ID")
 		return
 	}
 
 	var req models.UpdateCommentRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeDecodeError(w, err)
 		return
 	}
 
 	// Get the existing comment to check ownership
-	comment, err := dataaccess.GetComment(isAuthenticated, userID, commentID)
+	comment, err := s.Comments.GetComment(r.Context(), isAuthenticated, userID, commentID)
 	if err != nil {
 		if err.Error() == constants.NOT_FOUND_ERROR {
-			http.Error(w, "Comment not found", http.StatusNotFound)
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_COMMENT_NOT_FOUND, "Comment not found")
 			return
 		}
-		http.Error(w, "Failed to fetch comment", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch comment")
 		return
 	}
 
 	if comment.IsDeleted {
-		http.Error(w, "Cannot edit deleted comment", http.StatusGone)
+		writeError(w, http.StatusGone, constants.ERROR_CODE_COMMENT_DELETED, "Cannot edit deleted comment")
 		return
 	}
 
 	if comment.UserID != userID {
-		http.Error(w, "You can only edit your own comments", http.StatusForbidden)
-		return
+		canModerate, err := s.CanModerateTopic(r, comment.TopicID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to check permissions")
+			return
+		}
+		if !canModerate {
+			writeError(w, http.StatusForbidden, constants.ERROR_CODE_FORBIDDEN, "You can only edit your own comments")
+			return
+		}
 	}
 
 	// Validate updated content
-	if contentErr := utils.ValidateCommentContent(strings.TrimSpace(req.Content)); contentErr != "" {
-		http.Error(w, contentErr, http.StatusBadRequest)
+	var errs utils.ValidationErrors
+	errs.Check("content", utils.ValidateCommentContent(strings.TrimSpace(req.Content), s.limits.MaxCommentContentLength))
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
 		return
 	}
 
 	// Update the comment
 	comment.Content = strings.TrimSpace(req.Content)
-	if err := dataaccess.UpdateComment(*comment); err != nil {
-		http.Error(w, "Could not update comment", http.StatusInternalServerError)
+	if err := s.Comments.UpdateComment(r.Context(), comment, userID); err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not update comment")
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Comment updated successfully",
+	events.Publish(s.broker, events.PostTopic(comment.PostID), constants.EVENT_COMMENT_UPDATED, map[string]any{
+		"comment_id": commentID,
+		"post_id":    comment.PostID,
+	})
+
+	// Clients show the server's rendering rather than render the Markdown themselves
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":          "Comment updated successfully",
+		"content_html":     comment.ContentHTML,
+		"summary":          comment.Summary,
+		"has_long_content": comment.HasLongContent,
 	})
 }
 
 // DeleteComment handles deleting a comment
-func DeleteComment(w http.ResponseWriter, r *http.Request) {
+func (s *Server) DeleteComment(w http.ResponseWriter, r *http.Request) {
 	userID, isAuthenticated := GetUserFromContext(r)
 	if !isAuthenticated {
-		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
 		return
 	}
 
 	commentID, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid comment ID")
 		return
 	}
 
-	comment, err := dataaccess.GetComment(isAuthenticated, userID, commentID)
+	comment, err := s.Comments.GetComment(r.Context(), isAuthenticated, userID, commentID)
 	if err != nil {
 		if err.Error() == constants.NOT_FOUND_ERROR {
-			http.Error(w, "Comment not found", http.StatusNotFound)
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_COMMENT_NOT_FOUND, "Comment not found")
 			return
 		}
-		http.Error(w, "Failed to fetch comment", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch comment")
 		return
 	}
 
 	if comment.IsDeleted {
-		http.Error(w, "Comment already deleted", http.StatusGone)
+		writeError(w, http.StatusGone, constants.ERROR_CODE_COMMENT_DELETED, "Comment already deleted")
 		return
 	}
 
 	if comment.UserID != userID {
-		http.Error(w, "You can only delete your own comments", http.StatusForbidden)
-		return
+		canModerate, err := s.CanModerateTopic(r, comment.TopicID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to check permissions")
+			return
+		}
+		if !canModerate {
+			writeError(w, http.StatusForbidden, constants.ERROR_CODE_FORBIDDEN, "You can only delete your own comments")
+			return
+		}
 	}
 
-	if err := dataaccess.DeleteComment(commentID); err != nil {
-		http.Error(w, "Could not delete comment", http.StatusInternalServerError)
+	if err := s.Comments.DeleteComment(r.Context(), commentID); err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not delete comment")
 		return
 	}
 
+	events.Publish(s.broker, events.PostTopic(comment.PostID), constants.EVENT_COMMENT_DELETED, map[string]any{
+		"comment_id": commentID,
+		"post_id":    comment.PostID,
+	})
+
 	json.NewEncoder(w).Encode(map[string]string{
 		"message": "Comment deleted successfully",
 	})
 }
 
 // VoteComment handles voting on a comment
-func VoteComment(w http.ResponseWriter, r *http.Request) {
+func (s *Server) VoteComment(w http.ResponseWriter, r *http.Request) {
 	userID, isAuthenticated := GetUserFromContext(r)
 	if !isAuthenticated {
-		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
 		return
 	}
 
 	commentID, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid comment ID")
 		return
 	}
 
 	var req models.VoteCommentRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeDecodeError(w, err)
 		return
 	}
 
-	if req.VoteValue != -1 && req.VoteValue != 0 && req.VoteValue != 1 {
-		http.Error(w, "Vote value must be -1, 0, or 1", http.StatusBadRequest)
+	var errs utils.ValidationErrors
+	errs.Check("vote_value", utils.ValidateVoteValue(req.VoteValue))
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
 		return
 	}
 
-	comment, err := dataaccess.GetComment(isAuthenticated, userID, commentID)
+	comment, err := s.Comments.GetComment(r.Context(), isAuthenticated, userID, commentID)
 	if err != nil {
 		if err.Error() == constants.NOT_FOUND_ERROR {
-			http.Error(w, "Comment not found", http.StatusNotFound)
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_COMMENT_NOT_FOUND, "Comment not found")
 			return
 		}
-		http.Error(w, "Failed to fetch comment", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch comment")
 		return
 	}
 
 	if comment.IsDeleted {
-		http.Error(w, "Cannot vote on deleted comment", http.StatusGone)
+		writeError(w, http.StatusGone, constants.ERROR_CODE_COMMENT_DELETED, "Cannot vote on deleted comment")
 		return
 	}
 
@@ -210,67 +259,88 @@ func VoteComment(w http.ResponseWriter, r *http.Request) {
 		VoteValue: req.VoteValue,
 	}
 
-	if err := dataaccess.VoteComment(vote); err != nil {
-		http.Error(w, "Could not record vote", http.StatusInternalServerError)
+	if err := s.Comments.VoteComment(r.Context(), vote); err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not record vote")
 		return
 	}
+	telemetry.Voted("comment")
 
 	// Get updated comment to return new score
-	updatedComment, err := dataaccess.GetComment(isAuthenticated, userID, commentID)
+	updatedComment, err := s.Comments.GetComment(r.Context(), isAuthenticated, userID, commentID)
 	if err != nil {
 		if err.Error() == constants.NOT_FOUND_ERROR {
-			http.Error(w, "Comment not found", http.StatusNotFound)
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_COMMENT_NOT_FOUND, "Comment not found")
 			return
 		}
-		http.Error(w, "Failed to fetch comment", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch comment")
 		return
 	}
 
+	events.Publish(s.broker, events.PostTopic(updatedComment.PostID), constants.EVENT_COMMENT_VOTED, map[string]any{
+		"comment_id": commentID,
+		"post_id":    updatedComment.PostID,
+		"score":      updatedComment.Score,
+		"upvotes":    updatedComment.Upvotes,
+		"downvotes":  updatedComment.Downvotes,
+	})
+
 	json.NewEncoder(w).Encode(map[string]any{
 		"message": "Vote recorded successfully",
 		"score":   updatedComment.Score,
 	})
 }
 
-func ListComments(w http.ResponseWriter, r *http.Request) {
+func (s *Server) ListComments(w http.ResponseWriter, r *http.Request) {
 	userID, isAuthenticated := GetUserFromContext(r)
 
 	var req models.ListCommentsRequest
 	if err := utils.Decoder.Decode(&req, r.URL.Query()); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeQueryError(w, err)
+		return
+	}
+
+	var errs utils.ValidationErrors
+	errs.Check("window", utils.ValidateTimeWindow(req.Window))
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
 		return
 	}
 
-	comments, count, err := dataaccess.ListComments(isAuthenticated, userID, req)
+	comments, pageInfo, err := s.Comments.ListComments(r.Context(), isAuthenticated, userID, req)
 	if err != nil {
-		http.Error(w, "Failed to fetch comments", http.StatusInternalServerError)
+		if err.Error() == constants.INVALID_CURSOR_ERROR {
+			writeFieldError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_CURSOR, "cursor", "Invalid cursor")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch comments")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{
-		"comments": comments,
-		"count":    count,
+		"comments":    comments,
+		"count":       pageInfo.Count,
+		"next_cursor": pageInfo.NextCursor,
 	})
 }
 
-func GetComment(w http.ResponseWriter, r *http.Request) {
+func (s *Server) GetComment(w http.ResponseWriter, r *http.Request) {
 	userID, isAuthenticated := GetUserFromContext(r)
 
 	commentID, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid post ID")
 		return
 	}
 
-	comment, err := dataaccess.GetComment(isAuthenticated, userID, commentID)
+	comment, err := s.Comments.GetComment(r.Context(), isAuthenticated, userID, commentID)
 
 	if err != nil {
 		if err.Error() == constants.NOT_FOUND_ERROR {
-			http.Error(w, "Comment not found", http.StatusNotFound)
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_COMMENT_NOT_FOUND, "Comment not found")
 			return
 		}
-		http.Error(w, "Failed to fetch comment", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch comment")
 		return
 	}
 
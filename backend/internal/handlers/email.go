@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"cvwo/internal/constants"
+	"cvwo/internal/mail"
+	"cvwo/internal/models"
+	"cvwo/internal/telemetry"
+	"cvwo/internal/utils"
+)
+
+// VerifyEmail marks the email a verification link was sent to as verified
+// Does not require authentication, so the link also works on another device
+func (s *Server) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req models.VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	if err := s.EmailTokens.VerifyEmail(r.Context(), utils.HashToken(req.Token)); err != nil {
+		if err.Error() == constants.NOT_FOUND_ERROR {
+			writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_TOKEN, "Verification link is invalid or has expired")
+			return
+		}
+		if err.Error() == constants.NO_ROWS_AFFECTED_ERROR {
+			writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_TOKEN, "Your email has changed since this link was sent")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not verify email")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Email verified successfully"})
+}
+
+// ResendVerificationEmail sends a new verification link to the current user's email
+func (s *Server) ResendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+	if !isAuthenticated {
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
+		return
+	}
+
+	user, err := s.Users.GetUserByID(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, constants.ERROR_CODE_USER_NOT_FOUND, "User not found")
+		return
+	}
+
+	if user.EmailVerifiedAt != nil {
+		writeError(w, http.StatusConflict, constants.ERROR_CODE_EMAIL_ALREADY_VERIFIED, "Email is already verified")
+		return
+	}
+
+	if err := s.sendVerificationEmail(r.Context(), user); err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not send verification email")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Verification email sent"})
+}
+
+// ForgotPassword emails a password reset link if an account uses the email
+// Responds the same whether it does or not, so registered emails cannot be enumerated
+func (s *Server) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	var errs utils.ValidationErrors
+	errs.Check("email", utils.ValidateEmail(req.Email))
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	if user, err := s.Users.GetUserByEmail(r.Context(), req.Email); err == nil {
+		if err := s.sendPasswordResetEmail(r.Context(), user); err != nil {
+			logf(r.Context(), "ForgotPassword: failed to send reset link to user %d: %v", user.ID, err)
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "If an account uses this email, a password reset link has been sent to it",
+	})
+}
+
+// ResetPassword sets a new password with the token from a password reset link
+// Every session of the user is logged out
+func (s *Server) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	var errs utils.ValidationErrors
+	errs.Check("password", utils.ValidatePassword(req.Password))
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.Password)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not hash password")
+		return
+	}
+
+	if err := s.EmailTokens.ResetPassword(r.Context(), utils.HashToken(req.Token), string(hashedPassword)); err != nil {
+		if err.Error() == constants.NOT_FOUND_ERROR {
+			writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_TOKEN, "Password reset link is invalid or has expired")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not reset password")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Password reset successfully"})
+}
+
+func (s *Server) sendVerificationEmail(ctx context.Context, user *models.User) error {
+	return s.sendEmailToken(ctx, user, constants.EMAIL_TOKEN_VERIFY_EMAIL, constants.VERIFY_EMAIL_TOKEN_DURATION,
+		"Verify your email address", "/verify-email",
+		"Confirm your email address by opening this link:\n\n%s\n\n"+
+			"The link expires in %s. If you did not sign up, you can ignore this email.\n")
+}
+
+func (s *Server) sendPasswordResetEmail(ctx context.Context, user *models.User) error {
+	return s.sendEmailToken(ctx, user, constants.EMAIL_TOKEN_RESET_PASSWORD, constants.RESET_PASSWORD_TOKEN_DURATION,
+		"Reset your password", "/reset-password",
+		"Choose a new password by opening this link:\n\n%s\n\n"+
+			"The link expires in %s and logs you out everywhere. "+
+			"If you did not ask to reset your password, you can ignore this email.\n")
+}
+
+// Stores a new single-use token for the user's email and mails a link to the frontend page that uses it
+// The body is formatted with the link and how long it is valid
+func (s *Server) sendEmailToken(ctx context.Context, user *models.User, purpose string, duration time.Duration, subject, path, body string) error {
+	token, err := utils.GenerateRandomToken()
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(duration)
+	if err := s.EmailTokens.CreateEmailToken(ctx, user.ID, purpose, user.Email, utils.HashToken(token), expiresAt); err != nil {
+		return err
+	}
+
+	link := s.appBaseURL + path + "?token=" + url.QueryEscape(token)
+
+	s.sendMail(ctx, mail.Message{
+		To:      user.Email,
+		Subject: subject,
+		Body:    fmt.Sprintf("Hi %s,\n\n", user.Username) + fmt.Sprintf(body, link, formatDuration(duration)),
+	})
+
+	return nil
+}
+
+// Sends in the background, so the response time does not reveal whether an email was sent
+// Delivery is best effort: failures are logged and counted, and never fail the request that caused them
+func (s *Server) sendMail(ctx context.Context, msg mail.Message) {
+	s.mailing.Add(1)
+	go func() {
+		defer s.mailing.Done()
+
+		err := s.mailer.Send(msg)
+		telemetry.EmailSent(err)
+		if err != nil {
+			logf(ctx, "mail: failed to send %q to %s: %v", msg.Subject, msg.To, err)
+		}
+	}()
+}
+
+// WaitForMail waits for the emails still being sent, or until the context is done
+// Call it once http.Server.Shutdown has returned, so no request starts another
+func (s *Server) WaitForMail(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.mailing.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Formats whole hours for email bodies, e.g. "1 hour" or "48 hours"
+func formatDuration(duration time.Duration) string {
+	hours := int(duration.Hours())
+	if hours == 1 {
+		return "1 hour"
+	}
+	return fmt.Sprintf("%d hours", hours)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"cvwo/internal/utils"
+
+	"github.com/gorilla/schema"
+)
+
+// Writes an error response in the shared JSON format
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeErrorResponse(w, status, models.ErrorResponse{Code: code, Message: message})
+}
+
+// Writes an error response about a single request field
+func writeFieldError(w http.ResponseWriter, status int, code, field, message string) {
+	writeErrorResponse(w, status, models.ErrorResponse{Code: code, Message: message, Field: field})
+}
+
+// Writes a validation_failed response listing every invalid field
+// The top-level message and field are those of the first error, for clients that only show one
+func writeValidationErrors(w http.ResponseWriter, errs utils.ValidationErrors) {
+	writeErrorResponse(w, http.StatusBadRequest, models.ErrorResponse{
+		Code:    constants.ERROR_CODE_VALIDATION_FAILED,
+		Message: errs[0].Message,
+		Field:   errs[0].Field,
+		Details: errs,
+	})
+}
+
+// Responds to a request body that could not be decoded
+// The decoder's own message describes Go types, so it is not passed on
+func writeDecodeError(w http.ResponseWriter, err error) {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		writeFieldError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_REQUEST_BODY, typeErr.Field,
+			fmt.Sprintf("Invalid value for %s", typeErr.Field))
+		return
+	}
+
+	writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_REQUEST_BODY, "Request body must be valid JSON")
+}
+
+// Responds to query parameters that could not be decoded, with one detail per offending parameter
+func writeQueryError(w http.ResponseWriter, err error) {
+	var multiErr schema.MultiError
+	if !errors.As(err, &multiErr) || len(multiErr) == 0 {
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_QUERY, "Invalid query parameters")
+		return
+	}
+
+	keys := []string{}
+	for key := range multiErr {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	details := []models.FieldError{}
+	for _, key := range keys {
+		detail := models.FieldError{
+			Field:   key,
+			Code:    constants.ERROR_CODE_INVALID_VALUE,
+			Message: fmt.Sprintf("Invalid value for %s", key),
+		}
+		var unknownErr schema.UnknownKeyError
+		if errors.As(multiErr[key], &unknownErr) {
+			detail.Message = fmt.Sprintf("Unknown query parameter %s", key)
+		}
+		details = append(details, detail)
+	}
+
+	writeErrorResponse(w, http.StatusBadRequest, models.ErrorResponse{
+		Code:    constants.ERROR_CODE_INVALID_QUERY,
+		Message: details[0].Message,
+		Field:   details[0].Field,
+		Details: details,
+	})
+}
+
+func writeErrorResponse(w http.ResponseWriter, status int, response models.ErrorResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// NotFound handles requests to unknown routes
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, constants.ERROR_CODE_NOT_FOUND, "Not found")
+}
+
+// MethodNotAllowed handles requests to known routes with an unsupported method
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusMethodNotAllowed, constants.ERROR_CODE_METHOD_NOT_ALLOWED, "Method not allowed")
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"cvwo/internal/constants"
+	"cvwo/internal/events"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// PostEvents streams new comments, edits, deletions and vote tallies of a post as Server-Sent Events
+func (s *Server) PostEvents(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+
+	postID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid post ID")
+		return
+	}
+
+	if _, err := s.Posts.GetPost(r.Context(), isAuthenticated, userID, postID); err != nil {
+		if err.Error() == constants.NOT_FOUND_ERROR {
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_POST_NOT_FOUND, "Post not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch post")
+		return
+	}
+
+	s.streamEvents(w, r, events.PostTopic(postID))
+}
+
+// MyEvents streams the current user's new notifications as Server-Sent Events
+func (s *Server) MyEvents(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+	if !isAuthenticated {
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
+		return
+	}
+
+	s.streamEvents(w, r, events.UserTopic(userID))
+}
+
+// Writes the topic's events until the client disconnects or the server shuts down
+// Events published while the client is reconnecting are missed, clients should refetch after reconnecting
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request, topic string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Streaming is not supported")
+		return
+	}
+
+	// The stream stays open past the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	stream, unsubscribe := s.broker.Subscribe(topic)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Disable response buffering in nginx
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(constants.EVENT_STREAM_HEARTBEAT_INTERVAL)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		// Clients reconnect to another replica, or to this one once it is back
+		case <-s.shutdown:
+			return
+
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case event, ok := <-stream:
+			if !ok {
+				return
+			}
+
+			data, err := json.Marshal(event.Data)
+			if err != nil {
+				continue
+			}
+
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
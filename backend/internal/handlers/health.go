@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"encoding/json"
+	"net/http"
+)
+
+type readinessCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// AddReadinessCheck adds a dependency that must be available for the server to take requests
+func (s *Server) AddReadinessCheck(name string, check func(ctx context.Context) error) {
+	s.readinessChecks = append(s.readinessChecks, readinessCheck{name: name, check: check})
+}
+
+// Health responds 200 while the process is running, for liveness probes
+func Health(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// Ready responds 200 if every readiness check passes, 503 with the failed checks otherwise
+// A server that is shutting down is not ready, so load balancers stop sending it requests
+func (s *Server) Ready(w http.ResponseWriter, r *http.Request) {
+	status, statusText := http.StatusOK, "ready"
+	checks := map[string]string{}
+	for _, c := range s.readinessChecks {
+		ctx, cancel := context.WithTimeout(r.Context(), constants.READINESS_CHECK_TIMEOUT)
+		err := c.check(ctx)
+		cancel()
+
+		checks[c.name] = "ok"
+		if err != nil {
+			checks[c.name] = err.Error()
+			status, statusText = http.StatusServiceUnavailable, "not_ready"
+		}
+	}
+
+	if s.isShuttingDown() {
+		status, statusText = http.StatusServiceUnavailable, "shutting_down"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"status": statusText,
+		"checks": checks,
+	})
+}
@@ -2,7 +2,14 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"slices"
+	"strings"
+
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"cvwo/internal/utils"
 
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -11,67 +18,158 @@ import (
 type contextKey string
 const UserIDKey contextKey = "userID"
 const IsAuthenticatedKey contextKey = "isAuthenticated"
+const SessionIDKey contextKey = "sessionID"
+const RoleKey contextKey = "role"
+const APITokenKey contextKey = "apiToken"
 
 // Extracts user info if available, doesn't return 401 if not authenticated
-func OptionalAuthMiddleware(next http.Handler) http.Handler {
+// Unless the access token expired while the refresh token is still there, so clients know to refresh
+// API tokens sent as Authorization: Bearer only authenticate once RequireScopeMiddleware accepts them,
+// so routes that do not ask for a scope cannot be used with a token
+func (s *Server) OptionalAuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
-		// No cookie
-		cookie, err := r.Cookie("jwt")
-		if err != nil {
-			ctx = context.WithValue(ctx, IsAuthenticatedKey, false)
-			next.ServeHTTP(w, r.WithContext(ctx))
-			return
-		}
+		// API token, takes precedence over the cookie
+		if authorization := r.Header.Get("Authorization"); authorization != "" {
+			rawToken, found := strings.CutPrefix(authorization, "Bearer ")
+			if !found || rawToken == "" {
+				writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_INVALID_API_TOKEN, "Authorization header must be Bearer <token>")
+				return
+			}
 
-		token, err := jwt.Parse(cookie.Value, func(token *jwt.Token) (any, error) {
-			return secretKey, nil // secretKey is defined in auth.go
-		})
+			token, err := s.APITokens.AuthenticateAPIToken(r.Context(), utils.HashToken(rawToken))
+			if err != nil {
+				if err.Error() == constants.NOT_FOUND_ERROR {
+					writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_INVALID_API_TOKEN, "Invalid, expired or revoked API token")
+					return
+				}
+				writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not check API token")
+				return
+			}
 
-		// Invalid token
-		if err != nil || !token.Valid {
+			ctx = context.WithValue(ctx, APITokenKey, token)
 			ctx = context.WithValue(ctx, IsAuthenticatedKey, false)
 			next.ServeHTTP(w, r.WithContext(ctx))
 			return
 		}
 
-		// Invalid claims
-		claims, ok := token.Claims.(jwt.MapClaims)
+		userID, sessionID, role, ok := s.authenticateAccessCookie(r)
 		if !ok {
-			ctx = context.WithValue(ctx, IsAuthenticatedKey, false)
-			next.ServeHTTP(w, r.WithContext(ctx))
-			return
-		}
+			// A refresh token means the session may still be active, the access token only expired
+			// Rejecting the request lets the client refresh and retry, rather than carry on logged out
+			if _, err := r.Cookie(constants.REFRESH_TOKEN_COOKIE); err == nil {
+				writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_ACCESS_TOKEN_EXPIRED, "Access token expired, refresh the session")
+				return
+			}
 
-		// Invalid user ID
-		userIDFloat, ok := claims["issuer"].(float64)
-		if !ok {
 			ctx = context.WithValue(ctx, IsAuthenticatedKey, false)
 			next.ServeHTTP(w, r.WithContext(ctx))
 			return
 		}
-		userID := int(userIDFloat)
 
 		// Authenticated
 		ctx = context.WithValue(ctx, UserIDKey, userID)
+		ctx = context.WithValue(ctx, SessionIDKey, sessionID)
+		ctx = context.WithValue(ctx, RoleKey, role)
 		ctx = context.WithValue(ctx, IsAuthenticatedKey, true)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// Returns the user, session and role of the access token cookie, if it is valid and its session is active
+func (s *Server) authenticateAccessCookie(r *http.Request) (userID, sessionID int, role string, ok bool) {
+	cookie, err := r.Cookie(constants.ACCESS_TOKEN_COOKIE)
+	if err != nil {
+		return 0, 0, "", false
+	}
+
+	token, err := jwt.Parse(cookie.Value, func(token *jwt.Token) (any, error) {
+		return s.jwtSecret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid {
+		return 0, 0, "", false
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, 0, "", false
+	}
+
+	userIDFloat, ok := claims["issuer"].(float64)
+	if !ok {
+		return 0, 0, "", false
+	}
+
+	// Tokens issued before sessions existed have no session ID
+	sessionIDFloat, ok := claims["session_id"].(float64)
+	if !ok {
+		return 0, 0, "", false
+	}
+
+	// Session revoked (logout) or expired
+	// The role is read from the database rather than the token, so demotions apply immediately
+	role, err = s.Sessions.GetActiveSessionRole(r.Context(), int(sessionIDFloat), int(userIDFloat))
+	if err != nil {
+		return 0, 0, "", false
+	}
+
+	return int(userIDFloat), int(sessionIDFloat), role, true
+}
+
 // Enforces authentication, returns 401 if not authenticated
+// Requests with an API token pass, RequireScopeMiddleware decides whether the route accepts it
 func RequireAuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		isAuthenticated, ok := r.Context().Value(IsAuthenticatedKey).(bool)
-		if !ok || !isAuthenticated {
-			http.Error(w, "Authentication required", http.StatusUnauthorized)
+		if _, isAuthenticated := getRequestUserID(r); !isAuthenticated {
+			writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
 			return
 		}
 		next.ServeHTTP(w, r)
 	})
 }
 
+// Lets API tokens with the scope act as their user on the route, must run after OptionalAuthMiddleware
+// Requests authenticated with the session cookie are not restricted
+func RequireScopeMiddleware(scope string) func(http.Handler) http.Handler {
+	return scopeMiddleware(scope, true)
+}
+
+// Like RequireScopeMiddleware, for public routes: a token without the scope reads them anonymously
+// rather than being rejected, as if it had not been sent
+func OptionalScopeMiddleware(scope string) func(http.Handler) http.Handler {
+	return scopeMiddleware(scope, false)
+}
+
+func scopeMiddleware(scope string, required bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := r.Context().Value(APITokenKey).(*models.APIToken)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !slices.Contains(token.Scopes, scope) {
+				if !required {
+					// OptionalAuthMiddleware left the request unauthenticated
+					next.ServeHTTP(w, r)
+					return
+				}
+				writeError(w, http.StatusForbidden, constants.ERROR_CODE_INSUFFICIENT_SCOPE,
+					fmt.Sprintf("This API token does not have the %s scope", scope))
+				return
+			}
+
+			ctx := r.Context()
+			ctx = context.WithValue(ctx, UserIDKey, token.UserID)
+			ctx = context.WithValue(ctx, RoleKey, token.Role)
+			ctx = context.WithValue(ctx, IsAuthenticatedKey, true)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
 // Extracts user information from request context
 func GetUserFromContext(r *http.Request) (userID int, isAuthenticated bool) {
 	isAuth, ok := r.Context().Value(IsAuthenticatedKey).(bool)
@@ -90,3 +188,18 @@ func GetUserFromContext(r *http.Request) (userID int, isAuthenticated bool) {
 
 	return userIDVal, true
 }
+
+// Like GetUserFromContext, but also returns the user of an API token whose scope is not checked yet
+// Only for restrictions such as suspensions and rate limits, never to grant access
+func getRequestUserID(r *http.Request) (userID int, isAuthenticated bool) {
+	if token, ok := r.Context().Value(APITokenKey).(*models.APIToken); ok {
+		return token.UserID, true
+	}
+	return GetUserFromContext(r)
+}
+
+// Extracts the session ID of the current login from request context
+func GetSessionFromContext(r *http.Request) (sessionID int, ok bool) {
+	sessionID, ok = r.Context().Value(SessionIDKey).(int)
+	return sessionID, ok
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"cvwo/internal/utils"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// UpdateUserRole handles admin requests to change a user's global role
+func (s *Server) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	currentUserID, _ := GetUserFromContext(r)
+
+	var req models.UpdateUserRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	switch req.Role {
+	case constants.ROLE_ADMIN, constants.ROLE_MODERATOR, constants.ROLE_MEMBER:
+	default:
+		writeValidationErrors(w, utils.ValidationErrors{{
+			Field:   "role",
+			Code:    constants.ERROR_CODE_INVALID_VALUE,
+			Message: "Role must be admin, moderator, or member",
+		}})
+		return
+	}
+
+	user, err := s.Users.GetUserByUsername(r.Context(), chi.URLParam(r, "username"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, constants.ERROR_CODE_USER_NOT_FOUND, "User not found")
+		return
+	}
+
+	// Prevents the last admin from locking everyone out
+	if user.ID == currentUserID {
+		writeError(w, http.StatusForbidden, constants.ERROR_CODE_FORBIDDEN, "You cannot change your own role")
+		return
+	}
+
+	if err := s.Users.UpdateUserRole(r.Context(), user.ID, req.Role); err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not update role")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Role updated successfully",
+	})
+}
+
+// ListTopicModerators returns the moderators appointed to a topic
+func (s *Server) ListTopicModerators(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+
+	topic, err := s.Topics.GetTopicBySlug(r.Context(), isAuthenticated, userID, chi.URLParam(r, "topic_slug"))
+	if err != nil {
+		if err.Error() == constants.NOT_FOUND_ERROR {
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_TOPIC_NOT_FOUND, "Topic not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch topic")
+		return
+	}
+
+	moderators, err := s.Moderators.ListTopicModerators(r.Context(), topic.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch moderators")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"moderators": moderators,
+		"count":      len(moderators),
+	})
+}
+
+// AddTopicModerator handles admin requests to appoint a topic moderator
+func (s *Server) AddTopicModerator(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+
+	var req models.TopicModeratorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	topic, err := s.Topics.GetTopicBySlug(r.Context(), isAuthenticated, userID, chi.URLParam(r, "topic_slug"))
+	if err != nil {
+		if err.Error() == constants.NOT_FOUND_ERROR {
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_TOPIC_NOT_FOUND, "Topic not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch topic")
+		return
+	}
+
+	user, err := s.Users.GetUserByUsername(r.Context(), req.Username)
+	if err != nil {
+		writeError(w, http.StatusNotFound, constants.ERROR_CODE_USER_NOT_FOUND, "User not found")
+		return
+	}
+
+	if err := s.Moderators.AddTopicModerator(r.Context(), user.ID, topic.ID); err != nil {
+		if err.Error() == constants.NO_ROWS_AFFECTED_ERROR {
+			writeError(w, http.StatusConflict, constants.ERROR_CODE_ALREADY_MODERATOR, "User already moderates this topic")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not add moderator")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Moderator added successfully",
+	})
+}
+
+// RemoveTopicModerator handles admin requests to remove a topic moderator
+func (s *Server) RemoveTopicModerator(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+
+	topic, err := s.Topics.GetTopicBySlug(r.Context(), isAuthenticated, userID, chi.URLParam(r, "topic_slug"))
+	if err != nil {
+		if err.Error() == constants.NOT_FOUND_ERROR {
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_TOPIC_NOT_FOUND, "Topic not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch topic")
+		return
+	}
+
+	user, err := s.Users.GetUserByUsername(r.Context(), chi.URLParam(r, "username"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, constants.ERROR_CODE_USER_NOT_FOUND, "User not found")
+		return
+	}
+
+	if err := s.Moderators.RemoveTopicModerator(r.Context(), user.ID, topic.ID); err != nil {
+		if err.Error() == constants.NO_ROWS_AFFECTED_ERROR {
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_NOT_MODERATOR, "User does not moderate this topic")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not remove moderator")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Moderator removed successfully",
+	})
+}
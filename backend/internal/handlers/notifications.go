@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"cvwo/internal/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// ListNotifications returns the current user's notifications, newest first
+func (s *Server) ListNotifications(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+	if !isAuthenticated {
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
+		return
+	}
+
+	var req models.ListNotificationsRequest
+	if err := utils.Decoder.Decode(&req, r.URL.Query()); err != nil {
+		writeQueryError(w, err)
+		return
+	}
+
+	notifications, pageInfo, err := s.Notifications.ListNotifications(r.Context(), userID, req)
+	if err != nil {
+		if err.Error() == constants.INVALID_CURSOR_ERROR {
+			writeFieldError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_CURSOR, "cursor", "Invalid cursor")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch notifications")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"notifications": notifications,
+		"count":         pageInfo.Count,
+		"next_cursor":   pageInfo.NextCursor,
+	})
+}
+
+// GetUnreadNotificationCount returns the number of the current user's unread notifications
+func (s *Server) GetUnreadNotificationCount(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+	if !isAuthenticated {
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
+		return
+	}
+
+	count, err := s.Notifications.CountUnreadNotifications(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to count notifications")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"unread_count": count,
+	})
+}
+
+// MarkNotificationsRead marks the given notifications as read, or all of them if no IDs are given
+func (s *Server) MarkNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+	if !isAuthenticated {
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
+		return
+	}
+
+	var req models.MarkNotificationsReadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	marked, err := s.Notifications.MarkNotificationsRead(r.Context(), userID, req.IDs)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not mark notifications as read")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"message": "Notifications marked as read",
+		"marked":  marked,
+	})
+}
+
+// GetNotificationPreferences returns the notification types the current user muted
+func (s *Server) GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+	if !isAuthenticated {
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
+		return
+	}
+
+	mutedTypes, err := s.Notifications.ListNotificationMutes(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch notification preferences")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.NotificationPreferences{MutedTypes: mutedTypes})
+}
+
+// UpdateNotificationPreferences replaces the notification types the current user muted
+func (s *Server) UpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+	if !isAuthenticated {
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
+		return
+	}
+
+	var req models.NotificationPreferences
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	var errs utils.ValidationErrors
+	for i, notificationType := range req.MutedTypes {
+		errs.Check(fmt.Sprintf("muted_types.%d", i), utils.ValidateNotificationType(notificationType))
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	if err := s.Notifications.SetNotificationMutes(r.Context(), userID, req.MutedTypes); err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not update notification preferences")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"message": "Notification preferences updated successfully",
+	})
+}
@@ -0,0 +1,347 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"cvwo/internal/oidc"
+	"cvwo/internal/telemetry"
+	"cvwo/internal/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Contents of the login cookie, signed so it cannot be forged
+// Purpose keeps it from being mistaken for another token signed with the same key
+type oidcLoginClaims struct {
+	jwt.RegisteredClaims
+	Purpose      string `json:"purpose"`
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	ReturnTo     string `json:"return_to"`
+	// Set when a signed in user is linking the provider account to theirs rather than logging in
+	LinkUserID    int `json:"link_user_id,omitempty"`
+	LinkSessionID int `json:"link_session_id,omitempty"`
+}
+
+const oidcLoginPurpose = "oidc_login"
+
+// GetSSOConfig tells the login page whether to offer single sign-on, and under which name
+func (s *Server) GetSSOConfig(w http.ResponseWriter, r *http.Request) {
+	provider := s.sso
+	if provider == nil {
+		json.NewEncoder(w).Encode(map[string]any{"enabled": false})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"enabled":      true,
+		"display_name": provider.DisplayName(),
+	})
+}
+
+// StartOIDCLogin redirects to the identity provider's login page
+// return_to is the frontend path to go back to once logged in
+func (s *Server) StartOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	s.startOIDC(w, r, 0, 0)
+}
+
+// StartOIDCLink redirects a signed in user to the identity provider, to link the account they sign in with there
+// to theirs, after which they can log in with either. return_to is the frontend path to go back to
+// Accounts are only ever linked this way, never by a matching email
+func (s *Server) StartOIDCLink(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+	sessionID, hasSession := GetSessionFromContext(r)
+	if !isAuthenticated || !hasSession {
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
+		return
+	}
+
+	s.startOIDC(w, r, userID, sessionID)
+}
+
+// Sets the signed login cookie and redirects to the provider, linking to the user if linkUserID is set
+func (s *Server) startOIDC(w http.ResponseWriter, r *http.Request, linkUserID, linkSessionID int) {
+	provider := s.sso
+	if provider == nil {
+		writeError(w, http.StatusNotFound, constants.ERROR_CODE_SSO_NOT_CONFIGURED, "Single sign-on is not configured")
+		return
+	}
+
+	var values [3]string
+	for i := range values {
+		value, err := utils.GenerateRandomToken()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not start login")
+			return
+		}
+		values[i] = value
+	}
+	state, nonce, codeVerifier := values[0], values[1], values[2]
+
+	expiresAt := time.Now().Add(constants.OIDC_LOGIN_DURATION)
+	cookie, err := jwt.NewWithClaims(jwt.SigningMethodHS256, oidcLoginClaims{
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(expiresAt)},
+		Purpose:          oidcLoginPurpose,
+		State:            state,
+		Nonce:            nonce,
+		CodeVerifier:     codeVerifier,
+		ReturnTo:         safeReturnPath(r.URL.Query().Get("return_to")),
+		LinkUserID:       linkUserID,
+		LinkSessionID:    linkSessionID,
+	}).SignedString(s.jwtSecret)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not start login")
+		return
+	}
+
+	// Lax, so it is sent on the top-level redirect back from the provider
+	http.SetCookie(w, &http.Cookie{
+		Name:     constants.OIDC_LOGIN_COOKIE,
+		Value:    cookie,
+		Expires:  expiresAt,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Path:     "/api/auth/oidc",
+	})
+
+	http.Redirect(w, r, provider.AuthCodeURL(state, nonce, codeVerifier), http.StatusFound)
+}
+
+// OIDCCallback completes a single sign-on login or link when the identity provider redirects back
+// A login is as the user the account was linked to, or a new user created for it if its email is not taken
+// Users with two-factor authentication are sent to the login page to enter a code before the session starts
+func (s *Server) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	provider := s.sso
+	if provider == nil {
+		writeError(w, http.StatusNotFound, constants.ERROR_CODE_SSO_NOT_CONFIGURED, "Single sign-on is not configured")
+		return
+	}
+
+	login, ok := s.readOIDCLoginCookie(r)
+	clearOIDCLoginCookie(w)
+
+	// The state proves the redirect belongs to a login this browser started
+	query := r.URL.Query()
+	if !ok || subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(login.State)) != 1 {
+		s.redirectSSOError(w, r, constants.SSO_ERROR_FAILED)
+		return
+	}
+
+	if errorCode := query.Get("error"); errorCode != "" {
+		logf(r.Context(), "OIDCCallback: identity provider returned %s: %s", errorCode, query.Get("error_description"))
+		s.redirectSSOError(w, r, constants.SSO_ERROR_FAILED)
+		return
+	}
+
+	rawIDToken, err := provider.Exchange(r.Context(), query.Get("code"), login.CodeVerifier)
+	if err != nil {
+		logf(r.Context(), "OIDCCallback: failed to exchange code: %v", err)
+		s.redirectSSOError(w, r, constants.SSO_ERROR_FAILED)
+		return
+	}
+
+	claims, err := provider.VerifyIDToken(r.Context(), rawIDToken, login.Nonce)
+	if err != nil {
+		logf(r.Context(), "OIDCCallback: invalid ID token: %v", err)
+		s.redirectSSOError(w, r, constants.SSO_ERROR_FAILED)
+		return
+	}
+
+	if login.LinkUserID != 0 {
+		s.linkSSOIdentity(w, r, provider.Issuer(), claims, login)
+		return
+	}
+
+	user, ssoError := s.findOrCreateSSOUser(r.Context(), provider.Issuer(), claims)
+	if ssoError != "" {
+		s.redirectSSOError(w, r, ssoError)
+		return
+	}
+
+	twoFactorEnabled, err := s.TwoFactor.IsTwoFactorEnabled(r.Context(), user.ID)
+	if err != nil {
+		logf(r.Context(), "OIDCCallback: failed to check two-factor authentication of user %d: %v", user.ID, err)
+		s.redirectSSOError(w, r, constants.SSO_ERROR_FAILED)
+		return
+	}
+
+	// The login page continues with the code step, as after a password, and the attempt is recorded then
+	// The token is passed in the fragment, so it is not sent on to any server
+	if twoFactorEnabled {
+		challengeToken, _, err := s.createLoginChallenge(r.Context(), user.ID)
+		if err != nil {
+			logf(r.Context(), "OIDCCallback: failed to create login challenge for user %d: %v", user.ID, err)
+			s.redirectSSOError(w, r, constants.SSO_ERROR_FAILED)
+			return
+		}
+		http.Redirect(w, r, s.appBaseURL+"/login#challenge_token="+url.QueryEscape(challengeToken), http.StatusFound)
+		return
+	}
+
+	s.recordLoginAttempt(r.Context(), models.LoginAttempt{
+		UserID:    &user.ID,
+		Email:     utils.NormalizeEmail(user.Email),
+		IPAddress: s.clientIP(r),
+		UserAgent: r.UserAgent(),
+		Succeeded: true,
+	})
+
+	if err := s.startSession(w, r, user.ID, user.Role); err != nil {
+		s.redirectSSOError(w, r, constants.SSO_ERROR_FAILED)
+		return
+	}
+
+	http.Redirect(w, r, s.appBaseURL+login.ReturnTo, http.StatusFound)
+}
+
+// Returns the user linked to the provider account, creating one on the first login
+// Returns an SSO_ERROR_* code if there is no user the account may log in as
+// An existing user with the same email is never logged in as, they link the account from their settings instead
+func (s *Server) findOrCreateSSOUser(ctx context.Context, issuer string, claims *oidc.Claims) (*models.User, string) {
+	userID, err := s.Identities.LoginWithIdentity(ctx, issuer, claims.Subject, claims.Email)
+	if err != nil && err.Error() != constants.NOT_FOUND_ERROR {
+		logf(ctx, "OIDCCallback: failed to look up identity: %v", err)
+		return nil, constants.SSO_ERROR_FAILED
+	}
+
+	if err != nil {
+		if claims.Email == "" {
+			return nil, constants.SSO_ERROR_EMAIL_REQUIRED
+		}
+
+		_, err := s.Users.GetUserByEmail(ctx, claims.Email)
+		if err == nil {
+			return nil, constants.SSO_ERROR_EMAIL_TAKEN
+		}
+
+		identity := models.UserIdentity{
+			Issuer:  issuer,
+			Subject: claims.Subject,
+			Email:   claims.Email,
+		}
+		userID, err = s.createSSOUser(ctx, identity, claims)
+		if err != nil {
+			logf(ctx, "OIDCCallback: failed to create user: %v", err)
+			return nil, constants.SSO_ERROR_FAILED
+		}
+	}
+
+	user, err := s.Users.GetUserByID(ctx, userID)
+	if err != nil {
+		logf(ctx, "OIDCCallback: failed to fetch user %d: %v", userID, err)
+		return nil, constants.SSO_ERROR_FAILED
+	}
+	return user, ""
+}
+
+// Links the provider account to the user who started linking it, then returns to the page they started from
+// The session they started from must still be active, so a link cannot outlive a logout
+func (s *Server) linkSSOIdentity(w http.ResponseWriter, r *http.Request, issuer string, claims *oidc.Claims, login *oidcLoginClaims) {
+	if _, err := s.Sessions.GetActiveSessionRole(r.Context(), login.LinkSessionID, login.LinkUserID); err != nil {
+		s.redirectSSOError(w, r, constants.SSO_ERROR_FAILED)
+		return
+	}
+
+	identity := models.UserIdentity{
+		Issuer:  issuer,
+		Subject: claims.Subject,
+		Email:   claims.Email,
+	}
+	if err := s.Identities.LinkIdentity(r.Context(), login.LinkUserID, identity, claims.EmailVerified); err != nil {
+		if err.Error() == constants.ALREADY_EXISTS_ERROR {
+			s.redirectToApp(w, r, login.ReturnTo, "sso_error", constants.SSO_ERROR_IDENTITY_TAKEN)
+			return
+		}
+		logf(r.Context(), "OIDCCallback: failed to link identity to user %d: %v", login.LinkUserID, err)
+		s.redirectToApp(w, r, login.ReturnTo, "sso_error", constants.SSO_ERROR_FAILED)
+		return
+	}
+
+	s.redirectToApp(w, r, login.ReturnTo, "sso_linked", "true")
+}
+
+// Creates a user for the provider account, named after its preferred username or email
+// A number is appended if the name is taken
+func (s *Server) createSSOUser(ctx context.Context, identity models.UserIdentity, claims *oidc.Claims) (int, error) {
+	base := utils.SuggestUsername(claims.PreferredUsername, claims.Email, claims.Name)
+	username := base
+
+	for range 5 {
+		user := models.User{Email: claims.Email, Username: username}
+		userID, err := s.Identities.CreateUserWithIdentity(ctx, user, identity, claims.EmailVerified)
+		if err == nil {
+			telemetry.UserRegistered("sso")
+			return userID, nil
+		}
+		if err.Error() != constants.ALREADY_EXISTS_ERROR {
+			return 0, err
+		}
+
+		suffix := fmt.Sprintf("_%d", rand.IntN(10_000))
+		username = base[:min(len(base), constants.MAX_USERNAME_LENGTH-len(suffix))] + suffix
+	}
+
+	return 0, fmt.Errorf("no free username like %q", base)
+}
+
+func (s *Server) readOIDCLoginCookie(r *http.Request) (*oidcLoginClaims, bool) {
+	cookie, err := r.Cookie(constants.OIDC_LOGIN_COOKIE)
+	if err != nil {
+		return nil, false
+	}
+
+	var claims oidcLoginClaims
+	_, err = jwt.ParseWithClaims(cookie.Value, &claims, func(token *jwt.Token) (any, error) {
+		return s.jwtSecret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || claims.Purpose != oidcLoginPurpose {
+		return nil, false
+	}
+
+	return &claims, true
+}
+
+// The login cookie is single use
+func clearOIDCLoginCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     constants.OIDC_LOGIN_COOKIE,
+		Value:    "",
+		Expires:  time.Now().Add(-time.Hour),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Path:     "/api/auth/oidc",
+	})
+}
+
+// Failed logins go back to the login page, which shows why
+func (s *Server) redirectSSOError(w http.ResponseWriter, r *http.Request, ssoError string) {
+	s.redirectToApp(w, r, "/login", "sso_error", ssoError)
+}
+
+// Redirects to the frontend path with the search parameter added
+func (s *Server) redirectToApp(w http.ResponseWriter, r *http.Request, path, name, value string) {
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	http.Redirect(w, r, s.appBaseURL+path+separator+url.QueryEscape(name)+"="+url.QueryEscape(value), http.StatusFound)
+}
+
+// Only paths on the frontend are returned to, so the login cannot be used as an open redirect
+func safeReturnPath(returnTo string) string {
+	if !strings.HasPrefix(returnTo, "/") || strings.HasPrefix(returnTo, "//") || strings.HasPrefix(returnTo, "/\\") {
+		return "/"
+	}
+	return returnTo
+}
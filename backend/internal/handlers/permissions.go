@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"cvwo/internal/constants"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Resolves the topic a request acts on, for topic-scoped permission checks
+type TopicResolver func(r *http.Request) (topicID int, err error)
+
+// Enforces one of the given roles, must run after RequireAuthMiddleware
+func RequireRoleMiddleware(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !slices.Contains(roles, GetRoleFromContext(r)) {
+				writeError(w, http.StatusForbidden, constants.ERROR_CODE_FORBIDDEN, "You do not have permission to do this")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Rejects suspended users, must run after RequireAuthMiddleware
+// Suspended users can still sign in and read, so this only guards routes that create or change content
+func (s *Server) RequireActiveAccountMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := getRequestUserID(r)
+
+		suspendedUntil, err := s.Reports.GetUserSuspendedUntil(r.Context(), userID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to check permissions")
+			return
+		}
+		if suspendedUntil != nil {
+			writeError(w, http.StatusForbidden, constants.ERROR_CODE_ACCOUNT_SUSPENDED,
+				"Your account is suspended until "+suspendedUntil.Format(time.RFC3339))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Enforces moderation rights over the topic the request acts on, must run after RequireAuthMiddleware
+// Admins and moderators can moderate every topic, topic moderators only their own
+func (s *Server) RequireTopicModeratorMiddleware(resolve TopicResolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			topicID, err := resolve(r)
+			if err != nil {
+				if err.Error() == constants.NOT_FOUND_ERROR {
+					writeError(w, http.StatusNotFound, constants.ERROR_CODE_NOT_FOUND, "Not found")
+					return
+				}
+				writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to check permissions")
+				return
+			}
+
+			canModerate, err := s.CanModerateTopic(r, topicID)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to check permissions")
+				return
+			}
+			if !canModerate {
+				writeError(w, http.StatusForbidden, constants.ERROR_CODE_FORBIDDEN, "You do not moderate this topic")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CanModerateTopic reports whether the current user may edit, delete, lock or pin content in the topic
+func (s *Server) CanModerateTopic(r *http.Request, topicID int) (bool, error) {
+	userID, isAuthenticated := GetUserFromContext(r)
+	if !isAuthenticated {
+		return false, nil
+	}
+
+	switch GetRoleFromContext(r) {
+	case constants.ROLE_ADMIN, constants.ROLE_MODERATOR:
+		return true, nil
+	}
+
+	return s.Moderators.IsTopicModerator(r.Context(), userID, topicID)
+}
+
+// Resolves the topic of the post in the {id} URL parameter
+func (s *Server) TopicFromPostParam(r *http.Request) (int, error) {
+	userID, isAuthenticated := GetUserFromContext(r)
+
+	postID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		return 0, errors.New(constants.NOT_FOUND_ERROR)
+	}
+
+	post, err := s.Posts.GetPost(r.Context(), isAuthenticated, userID, postID)
+	if err != nil {
+		return 0, err
+	}
+	return post.TopicID, nil
+}
+
+// Resolves the topic of the comment in the {id} URL parameter
+func (s *Server) TopicFromCommentParam(r *http.Request) (int, error) {
+	userID, isAuthenticated := GetUserFromContext(r)
+
+	commentID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		return 0, errors.New(constants.NOT_FOUND_ERROR)
+	}
+
+	comment, err := s.Comments.GetComment(r.Context(), isAuthenticated, userID, commentID)
+	if err != nil {
+		return 0, err
+	}
+	return comment.TopicID, nil
+}
+
+// Resolves the topic in the {topic_slug} URL parameter
+func (s *Server) TopicFromSlugParam(r *http.Request) (int, error) {
+	userID, isAuthenticated := GetUserFromContext(r)
+
+	topic, err := s.Topics.GetTopicBySlug(r.Context(), isAuthenticated, userID, chi.URLParam(r, "topic_slug"))
+	if err != nil {
+		return 0, err
+	}
+	return topic.ID, nil
+}
+
+// Orders the global roles, a higher rank outranks a lower one
+func roleRank(role string) int {
+	switch role {
+	case constants.ROLE_ADMIN:
+		return 2
+	case constants.ROLE_MODERATOR:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Extracts the current user's role from request context, empty if not authenticated
+func GetRoleFromContext(r *http.Request) string {
+	role, _ := r.Context().Value(RoleKey).(string)
+	return role
+}
@@ -2,8 +2,9 @@ package handlers
 
 import (
 	"cvwo/internal/constants"
-	"cvwo/internal/dataaccess"
+	"cvwo/internal/events"
 	"cvwo/internal/models"
+	"cvwo/internal/telemetry"
 	"cvwo/internal/utils"
 	"encoding/json"
 	"net/http"
@@ -15,32 +16,27 @@ import (
 
 // CreatePost handles HTTP requests to create a new post
 // Requires authentication and validates input data before creation
-func CreatePost(w http.ResponseWriter, r *http.Request) {
+func (s *Server) CreatePost(w http.ResponseWriter, r *http.Request) {
 	userID, isAuthenticated := GetUserFromContext(r)
 	if !isAuthenticated {
-		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
 		return
 	}
 
 	var req models.CreatePostRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeDecodeError(w, err)
 		return
 	}
 
-	// Validate title
-	if titleErr := utils.ValidatePostTitle(strings.TrimSpace(req.Title)); titleErr != "" {
-		http.Error(w, titleErr, http.StatusBadRequest)
-		return
-	}
-
-	// Validate content
-	if contentErr := utils.ValidatePostContent(strings.TrimSpace(req.Content)); contentErr != "" {
-		http.Error(w, contentErr, http.StatusBadRequest)
-		return
-	}
+	var errs utils.ValidationErrors
+	errs.Check("title", utils.ValidatePostTitle(strings.TrimSpace(req.Title), s.limits.MaxPostTitleLength))
+	errs.Check("content", utils.ValidatePostContent(strings.TrimSpace(req.Content), s.limits.MaxPostContentLength))
 	if req.TopicID <= 0 {
-		http.Error(w, "Valid topic ID is required", http.StatusBadRequest)
+		errs.Add("topic_id", constants.ERROR_CODE_REQUIRED, "Valid topic ID is required")
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
 		return
 	}
 
@@ -51,11 +47,22 @@ func CreatePost(w http.ResponseWriter, r *http.Request) {
 		UserID:  userID,
 	}
 
-	postID, err := dataaccess.CreatePost(post)
+	postID, err := s.Posts.CreatePost(r.Context(), post)
 	if err != nil {
-		http.Error(w, "Could not create post", http.StatusInternalServerError)
+		if err.Error() == constants.NOT_FOUND_ERROR {
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_TOPIC_NOT_FOUND, "Topic not found")
+			return
+		}
+		if err.Error() == constants.TOPIC_ARCHIVED_ERROR {
+			writeError(w, http.StatusForbidden, constants.ERROR_CODE_TOPIC_ARCHIVED, "Cannot post in an archived topic")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not create post")
 		return
 	}
+	telemetry.PostCreated()
+
+	s.notify.PostCreated(r.Context(), postID)
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]any{
@@ -66,44 +73,51 @@ func CreatePost(w http.ResponseWriter, r *http.Request) {
 
 // UpdatePost handles HTTP requests to update an existing post
 // Only allows the post author to make changes and validates ownership
-func UpdatePost(w http.ResponseWriter, r *http.Request) {
+func (s *Server) UpdatePost(w http.ResponseWriter, r *http.Request) {
 	userID, isAuthenticated := GetUserFromContext(r)
 	if !isAuthenticated {
-		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
 		return
 	}
 
 	postID, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid post ID")
 		return
 	}
 
 	var req models.UpdatePostRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeDecodeError(w, err)
 		return
 	}
 
-	post, err := dataaccess.GetPost(isAuthenticated, userID, postID)
+	post, err := s.Posts.GetPost(r.Context(), isAuthenticated, userID, postID)
 
 	if err != nil {
 		if err.Error() == constants.NOT_FOUND_ERROR {
-			http.Error(w, "Post not found", http.StatusNotFound)
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_POST_NOT_FOUND, "Post not found")
 			return
 		}
-		http.Error(w, "Failed to fetch post", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch post")
 		return
 	}
 
 	if post.IsDeleted {
-		http.Error(w, "Cannot edit deleted post", http.StatusGone)
+		writeError(w, http.StatusGone, constants.ERROR_CODE_POST_DELETED, "Cannot edit deleted post")
 		return
 	}
 
 	if post.UserID != userID {
-		http.Error(w, "You can only edit your own posts", http.StatusForbidden)
-		return
+		canModerate, err := s.CanModerateTopic(r, post.TopicID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to check permissions")
+			return
+		}
+		if !canModerate {
+			writeError(w, http.StatusForbidden, constants.ERROR_CODE_FORBIDDEN, "You can only edit your own posts")
+			return
+		}
 	}
 
 	if req.Title != "" {
@@ -113,68 +127,83 @@ func UpdatePost(w http.ResponseWriter, r *http.Request) {
 		post.Content = strings.TrimSpace(req.Content)
 	}
 
-	// Validate updated title
-	if titleErr := utils.ValidatePostTitle(post.Title); titleErr != "" {
-		http.Error(w, titleErr, http.StatusBadRequest)
+	// Validate updated fields
+	var errs utils.ValidationErrors
+	errs.Check("title", utils.ValidatePostTitle(post.Title, s.limits.MaxPostTitleLength))
+	errs.Check("content", utils.ValidatePostContent(post.Content, s.limits.MaxPostContentLength))
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
 		return
 	}
 
-	// Validate updated content
-	if contentErr := utils.ValidatePostContent(post.Content); contentErr != "" {
-		http.Error(w, contentErr, http.StatusBadRequest)
+	if err := s.Posts.UpdatePost(r.Context(), post, userID); err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not update post")
 		return
 	}
 
-	if err := dataaccess.UpdatePost(*post); err != nil {
-		http.Error(w, "Could not update post", http.StatusInternalServerError)
-		return
-	}
+	events.Publish(s.broker, events.PostTopic(postID), constants.EVENT_POST_UPDATED, map[string]any{
+		"post_id": postID,
+	})
 
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Post updated successfully",
+	// Clients show the server's rendering rather than render the Markdown themselves
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":      "Post updated successfully",
+		"content_html": post.ContentHTML,
+		"summary":      post.Summary,
 	})
 }
 
 // DeletePost handles soft deletion of posts by marking them as deleted
 // Only allows the post author to delete their own posts
-func DeletePost(w http.ResponseWriter, r *http.Request) {
+func (s *Server) DeletePost(w http.ResponseWriter, r *http.Request) {
 	userID, isAuthenticated := GetUserFromContext(r)
 	if !isAuthenticated {
-		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
 		return
 	}
 
 	postID, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid post ID")
 		return
 	}
 
-	post, err := dataaccess.GetPost(isAuthenticated, userID, postID)
+	post, err := s.Posts.GetPost(r.Context(), isAuthenticated, userID, postID)
 	if err != nil {
 		if err.Error() == constants.NOT_FOUND_ERROR {
-			http.Error(w, "Post not found", http.StatusNotFound)
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_POST_NOT_FOUND, "Post not found")
 			return
 		}
-		http.Error(w, "Failed to fetch post", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch post")
 		return
 	}
 
 	if post.IsDeleted {
-		http.Error(w, "Post already deleted", http.StatusGone)
+		writeError(w, http.StatusGone, constants.ERROR_CODE_POST_DELETED, "Post already deleted")
 		return
 	}
 
 	if post.UserID != userID {
-		http.Error(w, "You can only delete your own posts", http.StatusForbidden)
-		return
+		canModerate, err := s.CanModerateTopic(r, post.TopicID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to check permissions")
+			return
+		}
+		if !canModerate {
+			writeError(w, http.StatusForbidden, constants.ERROR_CODE_FORBIDDEN, "You can only delete your own posts")
+			return
+		}
 	}
 
-	if err := dataaccess.DeletePost(postID); err != nil {
-		http.Error(w, "Could not delete post", http.StatusInternalServerError)
+	if err := s.Posts.DeletePost(r.Context(), postID); err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not delete post")
 		return
 	}
 
+	events.Publish(s.broker, events.PostTopic(postID), constants.EVENT_POST_DELETED, map[string]any{
+		"post_id": postID,
+	})
+
 	json.NewEncoder(w).Encode(map[string]string{
 		"message": "Post deleted successfully",
 	})
@@ -182,38 +211,40 @@ func DeletePost(w http.ResponseWriter, r *http.Request) {
 
 // VotePost handles voting on posts (upvote/downvote/remove vote)
 // Validates vote values and prevents voting on deleted posts
-func VotePost(w http.ResponseWriter, r *http.Request) {
+func (s *Server) VotePost(w http.ResponseWriter, r *http.Request) {
 	userID, isAuthenticated := GetUserFromContext(r)
 	if !isAuthenticated {
-		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
 		return
 	}
 
 	postID, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid post ID")
 		return
 	}
 
 	var req models.VotePostRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeDecodeError(w, err)
 		return
 	}
 
-	if req.VoteValue != -1 && req.VoteValue != 0 && req.VoteValue != 1 {
-		http.Error(w, "Vote value must be -1, 0, or 1", http.StatusBadRequest)
+	var errs utils.ValidationErrors
+	errs.Check("vote_value", utils.ValidateVoteValue(req.VoteValue))
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
 		return
 	}
 
-	post, err := dataaccess.GetPost(isAuthenticated, userID, postID)
+	post, err := s.Posts.GetPost(r.Context(), isAuthenticated, userID, postID)
 	if err != nil {
-		http.Error(w, "Post not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, constants.ERROR_CODE_POST_NOT_FOUND, "Post not found")
 		return
 	}
 
 	if post.IsDeleted {
-		http.Error(w, "Cannot vote on deleted post", http.StatusGone)
+		writeError(w, http.StatusGone, constants.ERROR_CODE_POST_DELETED, "Cannot vote on deleted post")
 		return
 	}
 
@@ -223,18 +254,26 @@ func VotePost(w http.ResponseWriter, r *http.Request) {
 		VoteValue: req.VoteValue,
 	}
 
-	if err := dataaccess.VotePost(vote); err != nil {
-		http.Error(w, "Could not record vote", http.StatusInternalServerError)
+	if err := s.Posts.VotePost(r.Context(), vote); err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not record vote")
 		return
 	}
+	telemetry.Voted("post")
 
 	// maybe don't return updated score? get new store in seperate request?
-	updatedPost, err := dataaccess.GetPost(isAuthenticated, userID, postID)
+	updatedPost, err := s.Posts.GetPost(r.Context(), isAuthenticated, userID, postID)
 	if err != nil {
-		http.Error(w, "Could not fetch updated post", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not fetch updated post")
 		return
 	}
 
+	events.Publish(s.broker, events.PostTopic(postID), constants.EVENT_POST_VOTED, map[string]any{
+		"post_id":   postID,
+		"score":     updatedPost.Score,
+		"upvotes":   updatedPost.Upvotes,
+		"downvotes": updatedPost.Downvotes,
+	})
+
 	json.NewEncoder(w).Encode(map[string]any{
 		"message": "Vote recorded successfully",
 		"score":   updatedPost.Score,
@@ -243,23 +282,23 @@ func VotePost(w http.ResponseWriter, r *http.Request) {
 
 // GetPost retrieves a single post by ID from URL parameters
 // Returns the post data as JSON if found, otherwise returns 404
-func GetPost(w http.ResponseWriter, r *http.Request) {
+func (s *Server) GetPost(w http.ResponseWriter, r *http.Request) {
 	userID, isAuthenticated := GetUserFromContext(r)
 
 	postID, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid post ID")
 		return
 	}
 
-	post, err := dataaccess.GetPost(isAuthenticated, userID, postID)
+	post, err := s.Posts.GetPost(r.Context(), isAuthenticated, userID, postID)
 
 	if err != nil {
 		if err.Error() == constants.NOT_FOUND_ERROR {
-			http.Error(w, "Post not found", http.StatusNotFound)
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_POST_NOT_FOUND, "Post not found")
 			return
 		}
-		http.Error(w, "Failed to fetch post", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch post")
 		return
 	}
 
@@ -267,102 +306,197 @@ func GetPost(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(post)
 }
 
-func ListPosts(w http.ResponseWriter, r *http.Request) {
+func (s *Server) ListPosts(w http.ResponseWriter, r *http.Request) {
 	userID, isAuthenticated := GetUserFromContext(r)
 
 	var req models.ListPostsRequest
 	if err := utils.Decoder.Decode(&req, r.URL.Query()); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeQueryError(w, err)
 		return
 	}
 
-	posts, count, err := dataaccess.ListPosts(isAuthenticated, userID, req)
+	var errs utils.ValidationErrors
+	errs.Check("window", utils.ValidateTimeWindow(req.Window))
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	req.PageSize = s.pageSize(req.PageSize)
+	posts, pageInfo, err := s.Posts.ListPosts(r.Context(), isAuthenticated, userID, req)
 	if err != nil {
-		http.Error(w, "Failed to fetch posts", http.StatusInternalServerError)
+		if err.Error() == constants.INVALID_CURSOR_ERROR {
+			writeFieldError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_CURSOR, "cursor", "Invalid cursor")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch posts")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 
 	json.NewEncoder(w).Encode(map[string]any{
-		"posts": posts,
-		"count": count,
+		"posts":       posts,
+		"count":       pageInfo.Count,
+		"next_cursor": pageInfo.NextCursor,
 	})
 }
 
-// GetPost retrieves a single post by ID from URL parameters
-// Returns the post data as JSON if found, otherwise returns 404
-func PinComment(w http.ResponseWriter, r *http.Request) {
+// PinComment pins a top-level comment to the post, or unpins it if no comment ID is given
+// Allowed for the post author and moderators of the post's topic
+func (s *Server) PinComment(w http.ResponseWriter, r *http.Request) {
 	userID, isAuthenticated := GetUserFromContext(r)
 	if !isAuthenticated {
-		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
 		return
 	}
 
 	postID, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid post ID")
 		return
 	}
 
 	var req models.PinCommentRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeDecodeError(w, err)
 		return
 	}
 
-	post, err := dataaccess.GetPost(isAuthenticated, userID, postID)
+	post, err := s.Posts.GetPost(r.Context(), isAuthenticated, userID, postID)
 	if err != nil {
 		if err.Error() == constants.NOT_FOUND_ERROR {
-			http.Error(w, "Post not found", http.StatusNotFound)
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_POST_NOT_FOUND, "Post not found")
 			return
 		}
-		http.Error(w, "Failed to fetch post", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch post")
 		return
 	}
 
 	if post.UserID != userID {
-		http.Error(w, "You can only pin comments on your own posts", http.StatusForbidden)
-		return
+		canModerate, err := s.CanModerateTopic(r, post.TopicID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to check permissions")
+			return
+		}
+		if !canModerate {
+			writeError(w, http.StatusForbidden, constants.ERROR_CODE_FORBIDDEN, "You can only pin comments on your own posts")
+			return
+		}
 	}
 
 	var message string
 	if req.CommentID != nil {
-		comment, err := dataaccess.GetComment(isAuthenticated, userID, *req.CommentID)
+		comment, err := s.Comments.GetComment(r.Context(), isAuthenticated, userID, *req.CommentID)
 		if err != nil {
 			if err.Error() == constants.NOT_FOUND_ERROR {
-				http.Error(w, "Comment not found", http.StatusNotFound)
+				writeError(w, http.StatusNotFound, constants.ERROR_CODE_COMMENT_NOT_FOUND, "Comment not found")
 				return
 			}
-			http.Error(w, "Failed to fetch comment", http.StatusInternalServerError)
+			writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch comment")
 			return
 		}
 
+		var errs utils.ValidationErrors
 		if comment.PostID != postID {
-			http.Error(w, "Comment does not belong to this post", http.StatusBadRequest)
-			return
+			errs.Add("comment_id", constants.ERROR_CODE_INVALID_VALUE, "Comment does not belong to this post")
+		} else if comment.ParentID != nil {
+			errs.Add("comment_id", constants.ERROR_CODE_INVALID_VALUE, "Only top-level comments can be pinned")
 		}
-
-		if comment.ParentID != nil {
-			http.Error(w, "Only top-level comments can be pinned", http.StatusBadRequest)
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
 			return
 		}
 
-		if err := dataaccess.PinComment(postID, *req.CommentID); err != nil {
-			http.Error(w, "Could not pin comment", http.StatusInternalServerError)
+		if err := s.Posts.PinComment(r.Context(), postID, *req.CommentID); err != nil {
+			writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not pin comment")
 			return
 		}
+		s.notify.CommentPinned(r.Context(), userID, *req.CommentID)
 		message = "Comment pinned successfully"
 	} else {
-		if err := dataaccess.UnpinComment(postID); err != nil {
-			http.Error(w, "Could not unpin comment", http.StatusInternalServerError)
+		if err := s.Posts.UnpinComment(r.Context(), postID); err != nil {
+			writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not unpin comment")
 			return
 		}
 		message = "Comment unpinned successfully"
 	}
 
+	events.Publish(s.broker, events.PostTopic(postID), constants.EVENT_COMMENT_PINNED, map[string]any{
+		"post_id":    postID,
+		"comment_id": req.CommentID,
+	})
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{
 		"message": message,
 	})
 }
+
+// LockPost locks or unlocks a post, locked posts do not accept new comments
+// Moderator permission is checked by RequireTopicModeratorMiddleware
+func (s *Server) LockPost(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid post ID")
+		return
+	}
+
+	var req models.LockPostRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	if err := s.Posts.SetPostLocked(r.Context(), postID, req.IsLocked); err != nil {
+		if err.Error() == constants.NO_ROWS_AFFECTED_ERROR {
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_POST_NOT_FOUND, "Post not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not update post")
+		return
+	}
+
+	message := "Post locked successfully"
+	if !req.IsLocked {
+		message = "Post unlocked successfully"
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": message,
+	})
+}
+
+// PinPost pins or unpins a post to the top of its topic
+// Moderator permission is checked by RequireTopicModeratorMiddleware
+func (s *Server) PinPost(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid post ID")
+		return
+	}
+
+	var req models.PinPostRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	if err := s.Posts.SetPostPinned(r.Context(), postID, req.IsPinned); err != nil {
+		if err.Error() == constants.NO_ROWS_AFFECTED_ERROR {
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_POST_NOT_FOUND, "Post not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not update post")
+		return
+	}
+
+	message := "Post pinned successfully"
+	if !req.IsPinned {
+		message = "Post unpinned successfully"
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": message,
+	})
+}
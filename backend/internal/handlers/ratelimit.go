@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"cvwo/internal/constants"
+	"cvwo/internal/ratelimit"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Throttles the routes of a group, returns 429 with Retry-After once the client's bucket is empty
+// Clients are the signed in user or API token user if OptionalAuthMiddleware ran before, otherwise the client IP,
+// taken from X-Forwarded-For only when the request came through a trusted proxy
+// Each group has its own buckets, so the name must be unique
+func (s *Server) RateLimitMiddleware(group string, limit ratelimit.Limit) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := fmt.Sprintf("%s:ip:%s", group, s.clientIP(r))
+			if userID, isAuthenticated := getRequestUserID(r); isAuthenticated {
+				key = fmt.Sprintf("%s:user:%d", group, userID)
+			}
+
+			allowed, retryAfter, err := s.rateLimits.Take(key, limit, time.Now())
+			if err != nil {
+				// Fail open, an unavailable store should not take the site down with it
+				logf(r.Context(), "ratelimit: failed to take token for %s: %v", key, err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !allowed {
+				seconds := int(math.Ceil(retryAfter.Seconds()))
+				w.Header().Set("Retry-After", strconv.Itoa(seconds))
+				writeError(w, http.StatusTooManyRequests, constants.ERROR_CODE_RATE_LIMITED,
+					fmt.Sprintf("Too many requests, try again in %d seconds", seconds))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,422 @@
+package handlers
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"cvwo/internal/events"
+	"cvwo/internal/models"
+	"cvwo/internal/utils"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ReportPost flags a post for moderators
+func (s *Server) ReportPost(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+
+	postID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid post ID")
+		return
+	}
+
+	req, ok := decodeReportRequest(w, r)
+	if !ok {
+		return
+	}
+
+	post, err := s.Posts.GetPost(r.Context(), isAuthenticated, userID, postID)
+	if err != nil {
+		if err.Error() == constants.NOT_FOUND_ERROR {
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_POST_NOT_FOUND, "Post not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch post")
+		return
+	}
+
+	if post.IsDeleted {
+		writeError(w, http.StatusGone, constants.ERROR_CODE_POST_DELETED, "Cannot report deleted post")
+		return
+	}
+
+	s.createReport(r.Context(), w, models.Report{
+		ReporterID: &userID,
+		PostID:     &postID,
+		Reason:     req.Reason,
+		Details:    req.Details,
+	}, "You have already reported this post")
+}
+
+// ReportComment flags a comment for moderators
+func (s *Server) ReportComment(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+
+	commentID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid comment ID")
+		return
+	}
+
+	req, ok := decodeReportRequest(w, r)
+	if !ok {
+		return
+	}
+
+	comment, err := s.Comments.GetComment(r.Context(), isAuthenticated, userID, commentID)
+	if err != nil {
+		if err.Error() == constants.NOT_FOUND_ERROR {
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_COMMENT_NOT_FOUND, "Comment not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch comment")
+		return
+	}
+
+	if comment.IsDeleted {
+		writeError(w, http.StatusGone, constants.ERROR_CODE_COMMENT_DELETED, "Cannot report deleted comment")
+		return
+	}
+
+	s.createReport(r.Context(), w, models.Report{
+		ReporterID: &userID,
+		CommentID:  &commentID,
+		Reason:     req.Reason,
+		Details:    req.Details,
+	}, "You have already reported this comment")
+}
+
+// Decodes and validates a report request, writes the error response if it is invalid
+func decodeReportRequest(w http.ResponseWriter, r *http.Request) (models.CreateReportRequest, bool) {
+	var req models.CreateReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return req, false
+	}
+
+	var errs utils.ValidationErrors
+	errs.Check("reason", utils.ValidateReportReason(req.Reason))
+	errs.Check("details", utils.ValidateReportDetails(req.Details))
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return req, false
+	}
+
+	return req, true
+}
+
+func (s *Server) createReport(ctx context.Context, w http.ResponseWriter, report models.Report, alreadyReportedMessage string) {
+	reportID, err := s.Reports.CreateReport(ctx, report)
+	if err != nil {
+		if err.Error() == constants.ALREADY_EXISTS_ERROR {
+			writeError(w, http.StatusConflict, constants.ERROR_CODE_ALREADY_REPORTED, alreadyReportedMessage)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not create report")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":   "Report submitted successfully",
+		"report_id": reportID,
+	})
+}
+
+// ListReports returns the moderation queue, posts and comments with open reports grouped per post or comment
+// Admins and moderators see every topic, topic moderators only the topics they moderate
+func (s *Server) ListReports(w http.ResponseWriter, r *http.Request) {
+	userID, _ := GetUserFromContext(r)
+
+	var req models.ListReportsRequest
+	if err := utils.Decoder.Decode(&req, r.URL.Query()); err != nil {
+		writeQueryError(w, err)
+		return
+	}
+
+	switch req.Type {
+	case "", constants.REPORT_TARGET_POST, constants.REPORT_TARGET_COMMENT:
+	default:
+		writeValidationErrors(w, utils.ValidationErrors{{
+			Field:   "type",
+			Code:    constants.ERROR_CODE_INVALID_VALUE,
+			Message: "Type must be post or comment",
+		}})
+		return
+	}
+
+	var topicIDs []int
+	switch GetRoleFromContext(r) {
+	case constants.ROLE_ADMIN, constants.ROLE_MODERATOR:
+	default:
+		moderatedTopicIDs, err := s.Moderators.ListModeratedTopicIDs(r.Context(), userID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to check permissions")
+			return
+		}
+		if len(moderatedTopicIDs) == 0 {
+			writeError(w, http.StatusForbidden, constants.ERROR_CODE_FORBIDDEN, "You do not moderate any topic")
+			return
+		}
+		topicIDs = moderatedTopicIDs
+	}
+
+	reported, pageInfo, err := s.Reports.ListReportedContent(r.Context(), req, topicIDs)
+	if err != nil {
+		if err.Error() == constants.INVALID_CURSOR_ERROR {
+			writeFieldError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_CURSOR, "cursor", "Invalid cursor")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch reports")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"reported":    reported,
+		"count":       pageInfo.Count,
+		"next_cursor": pageInfo.NextCursor,
+	})
+}
+
+// ListPostReports returns the open reports of a post
+// Moderator permission is checked by RequireTopicModeratorMiddleware
+func (s *Server) ListPostReports(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid post ID")
+		return
+	}
+
+	reports, err := s.Reports.ListOpenPostReports(r.Context(), postID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch reports")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"reports": reports,
+		"count":   len(reports),
+	})
+}
+
+// ListCommentReports returns the open reports of a comment
+// Moderator permission is checked by RequireTopicModeratorMiddleware
+func (s *Server) ListCommentReports(w http.ResponseWriter, r *http.Request) {
+	commentID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid comment ID")
+		return
+	}
+
+	reports, err := s.Reports.ListOpenCommentReports(r.Context(), commentID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch reports")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"reports": reports,
+		"count":   len(reports),
+	})
+}
+
+// ResolvePostReports closes the open reports of a post with a dismiss, remove, warn or suspend action
+// Moderator permission is checked by RequireTopicModeratorMiddleware
+func (s *Server) ResolvePostReports(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+
+	postID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid post ID")
+		return
+	}
+
+	post, err := s.Posts.GetPost(r.Context(), isAuthenticated, userID, postID)
+	if err != nil {
+		if err.Error() == constants.NOT_FOUND_ERROR {
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_POST_NOT_FOUND, "Post not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch post")
+		return
+	}
+
+	entry := models.ModerationLogEntry{
+		PostID:       &postID,
+		TargetUserID: &post.UserID,
+	}
+
+	if !s.resolveReports(w, r, &entry, post.TopicID) {
+		return
+	}
+
+	if entry.Action == constants.MODERATION_ACTION_REMOVE && !post.IsDeleted {
+		events.Publish(s.broker, events.PostTopic(postID), constants.EVENT_POST_DELETED, map[string]any{
+			"post_id": postID,
+		})
+	}
+
+	writeResolvedReports(w, entry)
+}
+
+// ResolveCommentReports closes the open reports of a comment with a dismiss, remove, warn or suspend action
+// Moderator permission is checked by RequireTopicModeratorMiddleware
+func (s *Server) ResolveCommentReports(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+
+	commentID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid comment ID")
+		return
+	}
+
+	comment, err := s.Comments.GetComment(r.Context(), isAuthenticated, userID, commentID)
+	if err != nil {
+		if err.Error() == constants.NOT_FOUND_ERROR {
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_COMMENT_NOT_FOUND, "Comment not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch comment")
+		return
+	}
+
+	entry := models.ModerationLogEntry{
+		PostID:       &comment.PostID,
+		CommentID:    &commentID,
+		TargetUserID: &comment.UserID,
+	}
+
+	if !s.resolveReports(w, r, &entry, comment.TopicID) {
+		return
+	}
+
+	if entry.Action == constants.MODERATION_ACTION_REMOVE && !comment.IsDeleted {
+		events.Publish(s.broker, events.PostTopic(comment.PostID), constants.EVENT_COMMENT_DELETED, map[string]any{
+			"comment_id": commentID,
+			"post_id":    comment.PostID,
+		})
+	}
+
+	writeResolvedReports(w, entry)
+}
+
+// Validates the resolution and applies it to the entry's post or comment
+// Writes the error response and returns false if it could not be applied
+func (s *Server) resolveReports(w http.ResponseWriter, r *http.Request, entry *models.ModerationLogEntry, topicID int) bool {
+	userID, _ := GetUserFromContext(r)
+
+	var req models.ResolveReportsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return false
+	}
+
+	var errs utils.ValidationErrors
+	errs.Check("action", utils.ValidateModerationAction(req.Action))
+	errs.Check("note", utils.ValidateModerationNote(req.Note))
+	if req.Action == constants.MODERATION_ACTION_SUSPEND {
+		errs.Check("suspend_days", utils.ValidateSuspendDays(req.SuspendDays))
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return false
+	}
+
+	// Suspensions apply site-wide, so topic moderators cannot issue them
+	if req.Action == constants.MODERATION_ACTION_SUSPEND {
+		switch GetRoleFromContext(r) {
+		case constants.ROLE_ADMIN, constants.ROLE_MODERATOR:
+		default:
+			writeError(w, http.StatusForbidden, constants.ERROR_CODE_FORBIDDEN, "Only admins and moderators can suspend users")
+			return false
+		}
+
+		if *entry.TargetUserID == userID {
+			writeError(w, http.StatusForbidden, constants.ERROR_CODE_FORBIDDEN, "You cannot suspend yourself")
+			return false
+		}
+
+		target, err := s.Users.GetUserByID(r.Context(), *entry.TargetUserID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch user")
+			return false
+		}
+		// Moderators cannot suspend each other or admins, and admins cannot suspend other admins
+		if roleRank(target.Role) >= roleRank(GetRoleFromContext(r)) {
+			writeError(w, http.StatusForbidden, constants.ERROR_CODE_FORBIDDEN, "You cannot suspend a user whose role is equal to or above yours")
+			return false
+		}
+
+		suspendedUntil := time.Now().AddDate(0, 0, req.SuspendDays)
+		entry.SuspendedUntil = &suspendedUntil
+	}
+
+	entry.ModeratorID = &userID
+	entry.Action = req.Action
+	entry.Note = req.Note
+
+	if err := s.Reports.ResolveReports(r.Context(), entry); err != nil {
+		if err.Error() == constants.NO_ROWS_AFFECTED_ERROR {
+			writeError(w, http.StatusConflict, constants.ERROR_CODE_NO_OPEN_REPORTS, "There are no open reports to resolve")
+			return false
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not resolve reports")
+		return false
+	}
+
+	if entry.Action == constants.MODERATION_ACTION_WARN {
+		s.notify.ModeratorWarning(r.Context(), *entry, topicID)
+	}
+
+	return true
+}
+
+func writeResolvedReports(w http.ResponseWriter, entry models.ModerationLogEntry) {
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":          "Reports resolved successfully",
+		"reports_resolved": entry.ReportsResolved,
+		"log_entry":        entry,
+	})
+}
+
+// ListModerationLog returns the audit log of report resolutions, newest first
+func (s *Server) ListModerationLog(w http.ResponseWriter, r *http.Request) {
+	var req models.ListModerationLogRequest
+	if err := utils.Decoder.Decode(&req, r.URL.Query()); err != nil {
+		writeQueryError(w, err)
+		return
+	}
+
+	if req.Action != "" {
+		var errs utils.ValidationErrors
+		errs.Check("action", utils.ValidateModerationAction(req.Action))
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+	}
+
+	entries, pageInfo, err := s.Reports.ListModerationLog(r.Context(), req)
+	if err != nil {
+		if err.Error() == constants.INVALID_CURSOR_ERROR {
+			writeFieldError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_CURSOR, "cursor", "Invalid cursor")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch moderation log")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"entries":     entries,
+		"count":       pageInfo.Count,
+		"next_cursor": pageInfo.NextCursor,
+	})
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"cvwo/internal/constants"
+	"cvwo/internal/events"
+	"cvwo/internal/utils"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ListPostRevisions returns the edit history of a post, oldest first
+// Each revision carries unified diffs of its title and content against the previous revision
+func (s *Server) ListPostRevisions(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+
+	postID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid post ID")
+		return
+	}
+
+	post, err := s.Posts.GetPost(r.Context(), isAuthenticated, userID, postID)
+	if err != nil {
+		if err.Error() == constants.NOT_FOUND_ERROR {
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_POST_NOT_FOUND, "Post not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch post")
+		return
+	}
+
+	// The history would reveal the content of deleted posts
+	if post.IsDeleted {
+		writeError(w, http.StatusGone, constants.ERROR_CODE_POST_DELETED, "Post has been deleted")
+		return
+	}
+
+	revisions, err := s.Revisions.ListPostRevisions(r.Context(), postID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch revisions")
+		return
+	}
+
+	previousRevision, previousTitle, previousContent := 0, "", ""
+	for i := range revisions {
+		revision := &revisions[i]
+		revision.TitleDiff = utils.UnifiedDiff(previousTitle, revision.Title, previousRevision, revision.Revision)
+		revision.ContentDiff = utils.UnifiedDiff(previousContent, revision.Content, previousRevision, revision.Revision)
+		previousRevision, previousTitle, previousContent = revision.Revision, revision.Title, revision.Content
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"revisions": revisions,
+		"count":     len(revisions),
+	})
+}
+
+// ListCommentRevisions returns the edit history of a comment, oldest first
+// Each revision carries a unified diff of its content against the previous revision
+func (s *Server) ListCommentRevisions(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+
+	commentID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid comment ID")
+		return
+	}
+
+	comment, err := s.Comments.GetComment(r.Context(), isAuthenticated, userID, commentID)
+	if err != nil {
+		if err.Error() == constants.NOT_FOUND_ERROR {
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_COMMENT_NOT_FOUND, "Comment not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch comment")
+		return
+	}
+
+	// The history would reveal the content of deleted comments
+	if comment.IsDeleted {
+		writeError(w, http.StatusGone, constants.ERROR_CODE_COMMENT_DELETED, "Comment has been deleted")
+		return
+	}
+
+	revisions, err := s.Revisions.ListCommentRevisions(r.Context(), commentID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch revisions")
+		return
+	}
+
+	previousRevision, previousContent := 0, ""
+	for i := range revisions {
+		revision := &revisions[i]
+		revision.ContentDiff = utils.UnifiedDiff(previousContent, revision.Content, previousRevision, revision.Revision)
+		previousRevision, previousContent = revision.Revision, revision.Content
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"revisions": revisions,
+		"count":     len(revisions),
+	})
+}
+
+// RestorePostRevision makes an earlier revision the current version of a post
+// Moderator permission is checked by RequireTopicModeratorMiddleware
+func (s *Server) RestorePostRevision(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+
+	postID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid post ID")
+		return
+	}
+
+	revision, err := strconv.Atoi(chi.URLParam(r, "revision"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid revision")
+		return
+	}
+
+	post, err := s.Posts.GetPost(r.Context(), isAuthenticated, userID, postID)
+	if err != nil {
+		if err.Error() == constants.NOT_FOUND_ERROR {
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_POST_NOT_FOUND, "Post not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch post")
+		return
+	}
+
+	if post.IsDeleted {
+		writeError(w, http.StatusGone, constants.ERROR_CODE_POST_DELETED, "Cannot restore deleted post")
+		return
+	}
+
+	if err := s.Revisions.RestorePostRevision(r.Context(), post, revision, userID); err != nil {
+		if err.Error() == constants.NOT_FOUND_ERROR {
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_REVISION_NOT_FOUND, "Revision not found")
+			return
+		}
+		if err.Error() == constants.NO_ROWS_AFFECTED_ERROR {
+			writeError(w, http.StatusGone, constants.ERROR_CODE_POST_DELETED, "Cannot restore deleted post")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not restore revision")
+		return
+	}
+
+	events.Publish(s.broker, events.PostTopic(postID), constants.EVENT_POST_UPDATED, map[string]any{
+		"post_id": postID,
+	})
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":      "Revision restored successfully",
+		"title":        post.Title,
+		"content":      post.Content,
+		"content_html": post.ContentHTML,
+		"summary":      post.Summary,
+	})
+}
+
+// RestoreCommentRevision makes an earlier revision the current version of a comment
+// Moderator permission is checked by RequireTopicModeratorMiddleware
+func (s *Server) RestoreCommentRevision(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+
+	commentID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid comment ID")
+		return
+	}
+
+	revision, err := strconv.Atoi(chi.URLParam(r, "revision"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid revision")
+		return
+	}
+
+	comment, err := s.Comments.GetComment(r.Context(), isAuthenticated, userID, commentID)
+	if err != nil {
+		if err.Error() == constants.NOT_FOUND_ERROR {
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_COMMENT_NOT_FOUND, "Comment not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch comment")
+		return
+	}
+
+	if comment.IsDeleted {
+		writeError(w, http.StatusGone, constants.ERROR_CODE_COMMENT_DELETED, "Cannot restore deleted comment")
+		return
+	}
+
+	if err := s.Revisions.RestoreCommentRevision(r.Context(), comment, revision, userID); err != nil {
+		if err.Error() == constants.NOT_FOUND_ERROR {
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_REVISION_NOT_FOUND, "Revision not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not restore revision")
+		return
+	}
+
+	events.Publish(s.broker, events.PostTopic(comment.PostID), constants.EVENT_COMMENT_UPDATED, map[string]any{
+		"comment_id": commentID,
+		"post_id":    comment.PostID,
+	})
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":          "Revision restored successfully",
+		"content":          comment.Content,
+		"content_html":     comment.ContentHTML,
+		"summary":          comment.Summary,
+		"has_long_content": comment.HasLongContent,
+	})
+}
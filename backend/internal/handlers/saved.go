@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"cvwo/internal/utils"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// SavePost bookmarks a post for the user, saving an already saved post does nothing
+func (s *Server) SavePost(w http.ResponseWriter, r *http.Request) {
+	s.setPostSaved(w, r, true)
+}
+
+// UnsavePost removes a post from the user's bookmarks
+func (s *Server) UnsavePost(w http.ResponseWriter, r *http.Request) {
+	s.setPostSaved(w, r, false)
+}
+
+// SaveComment bookmarks a comment for the user, saving an already saved comment does nothing
+func (s *Server) SaveComment(w http.ResponseWriter, r *http.Request) {
+	s.setCommentSaved(w, r, true)
+}
+
+// UnsaveComment removes a comment from the user's bookmarks
+func (s *Server) UnsaveComment(w http.ResponseWriter, r *http.Request) {
+	s.setCommentSaved(w, r, false)
+}
+
+// ListSavedPosts lists the posts the user saved, with the same pagination and sorting as ListPosts
+// Sorted by when they were saved unless another sort is given
+func (s *Server) ListSavedPosts(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+	if !isAuthenticated {
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
+		return
+	}
+
+	var req models.ListPostsRequest
+	if err := utils.Decoder.Decode(&req, r.URL.Query()); err != nil {
+		writeQueryError(w, err)
+		return
+	}
+
+	var errs utils.ValidationErrors
+	errs.Check("window", utils.ValidateTimeWindow(req.Window))
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	if req.Sort == "" {
+		req.Sort = constants.ORDER_BY_SAVED
+	}
+	req.PageSize = s.pageSize(req.PageSize)
+	req.SavedOnly = true
+
+	posts, pageInfo, err := s.Posts.ListPosts(r.Context(), isAuthenticated, userID, req)
+	if err != nil {
+		if err.Error() == constants.INVALID_CURSOR_ERROR {
+			writeFieldError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_CURSOR, "cursor", "Invalid cursor")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch saved posts")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"posts":       posts,
+		"count":       pageInfo.Count,
+		"next_cursor": pageInfo.NextCursor,
+	})
+}
+
+// ListSavedComments lists the comments the user saved, with the same pagination and sorting as ListComments
+// Sorted by when they were saved unless another sort is given
+func (s *Server) ListSavedComments(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+	if !isAuthenticated {
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
+		return
+	}
+
+	var req models.ListCommentsRequest
+	if err := utils.Decoder.Decode(&req, r.URL.Query()); err != nil {
+		writeQueryError(w, err)
+		return
+	}
+
+	var errs utils.ValidationErrors
+	errs.Check("window", utils.ValidateTimeWindow(req.Window))
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	if req.Sort == "" {
+		req.Sort = constants.ORDER_BY_SAVED
+	}
+	req.PageSize = s.pageSize(req.PageSize)
+	req.SavedOnly = true
+
+	comments, pageInfo, err := s.Comments.ListComments(r.Context(), isAuthenticated, userID, req)
+	if err != nil {
+		if err.Error() == constants.INVALID_CURSOR_ERROR {
+			writeFieldError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_CURSOR, "cursor", "Invalid cursor")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch saved comments")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"comments":    comments,
+		"count":       pageInfo.Count,
+		"next_cursor": pageInfo.NextCursor,
+	})
+}
+
+// Deleted posts cannot be saved, but can still be unsaved
+func (s *Server) setPostSaved(w http.ResponseWriter, r *http.Request, save bool) {
+	userID, isAuthenticated := GetUserFromContext(r)
+	if !isAuthenticated {
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
+		return
+	}
+
+	postID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid post ID")
+		return
+	}
+
+	if save {
+		post, err := s.Posts.GetPost(r.Context(), isAuthenticated, userID, postID)
+		if err != nil && err.Error() != constants.NOT_FOUND_ERROR {
+			writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch post")
+			return
+		}
+		if err != nil || post.IsDeleted {
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_POST_NOT_FOUND, "Post not found")
+			return
+		}
+	}
+
+	if save {
+		err = s.Posts.SavePost(r.Context(), userID, postID)
+	} else {
+		err = s.Posts.UnsavePost(r.Context(), userID, postID)
+	}
+	if err != nil {
+		logf(r.Context(), "setPostSaved: failed to update saved post %d for user %d: %v", postID, userID, err)
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not update saved posts")
+		return
+	}
+
+	message := "Post saved successfully"
+	if !save {
+		message = "Post unsaved successfully"
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":  message,
+		"is_saved": save,
+	})
+}
+
+// Deleted comments cannot be saved, but can still be unsaved
+func (s *Server) setCommentSaved(w http.ResponseWriter, r *http.Request, save bool) {
+	userID, isAuthenticated := GetUserFromContext(r)
+	if !isAuthenticated {
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
+		return
+	}
+
+	commentID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid comment ID")
+		return
+	}
+
+	if save {
+		comment, err := s.Comments.GetComment(r.Context(), isAuthenticated, userID, commentID)
+		if err != nil && err.Error() != constants.NOT_FOUND_ERROR {
+			writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch comment")
+			return
+		}
+		if err != nil || comment.IsDeleted {
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_COMMENT_NOT_FOUND, "Comment not found")
+			return
+		}
+	}
+
+	if save {
+		err = s.Comments.SaveComment(r.Context(), userID, commentID)
+	} else {
+		err = s.Comments.UnsaveComment(r.Context(), userID, commentID)
+	}
+	if err != nil {
+		logf(r.Context(), "setCommentSaved: failed to update saved comment %d for user %d: %v", commentID, userID, err)
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not update saved comments")
+		return
+	}
+
+	message := "Comment saved successfully"
+	if !save {
+		message = "Comment unsaved successfully"
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":  message,
+		"is_saved": save,
+	})
+}
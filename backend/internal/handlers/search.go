@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"cvwo/internal/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Search handles full-text search across posts and comments
+// Supports "quoted phrases" and -negated terms, filtered by type, topic, author and date range
+func (s *Server) Search(w http.ResponseWriter, r *http.Request) {
+	var req models.SearchRequest
+	if err := utils.Decoder.Decode(&req, r.URL.Query()); err != nil {
+		writeQueryError(w, err)
+		return
+	}
+
+	var errs utils.ValidationErrors
+
+	req.Query = strings.TrimSpace(req.Query)
+	if req.Query == "" {
+		errs.Add("q", constants.ERROR_CODE_REQUIRED, "Search query is required")
+	}
+	if len(req.Query) > constants.MAX_SEARCH_QUERY_LENGTH {
+		errs.Add("q", constants.ERROR_CODE_TOO_LONG, fmt.Sprintf("Search query must be no more than %d characters", constants.MAX_SEARCH_QUERY_LENGTH))
+	}
+
+	switch req.Type {
+	case "", constants.SEARCH_TYPE_POST, constants.SEARCH_TYPE_COMMENT:
+	default:
+		errs.Add("type", constants.ERROR_CODE_INVALID_VALUE, "Type must be post or comment")
+	}
+
+	from, err := parseSearchDate(req.From, false)
+	if err != nil {
+		errs.Add("from", constants.ERROR_CODE_INVALID_FORMAT, "Invalid from date, use YYYY-MM-DD or RFC 3339")
+	}
+
+	// A plain date as the upper bound includes the whole day
+	to, err := parseSearchDate(req.To, true)
+	if err != nil {
+		errs.Add("to", constants.ERROR_CODE_INVALID_FORMAT, "Invalid to date, use YYYY-MM-DD or RFC 3339")
+	}
+
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	results, count, err := s.SearchIndex.Search(r.Context(), req, from, to)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to search")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"results": results,
+		"count":   count,
+	})
+}
+
+// Parses YYYY-MM-DD or RFC 3339, nil if empty
+func parseSearchDate(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"cvwo/internal/config"
+	"cvwo/internal/constants"
+	"cvwo/internal/dataaccess"
+	"cvwo/internal/events"
+	"cvwo/internal/mail"
+	"cvwo/internal/notifications"
+	"cvwo/internal/oidc"
+	"cvwo/internal/ratelimit"
+	"cvwo/internal/utils"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+)
+
+// Options are what the server needs besides its stores, every field but SSO and TrustedProxies is required
+type Options struct {
+	Mailer     mail.Mailer
+	Broker     events.Broker
+	RateLimits ratelimit.Store
+	// Identity provider of single sign-on, which is disabled if nil
+	SSO *oidc.Provider
+
+	// Signs and verifies access tokens
+	JWTSecret string
+	Limits    config.Limits
+	// Base URL of the frontend, which links in emails and redirects after single sign-on point to
+	AppBaseURL string
+	// Reverse proxies whose X-Forwarded-For and X-Real-IP headers are believed
+	TrustedProxies []netip.Prefix
+}
+
+// Server serves the API from its stores, so the handlers run the same on PostgreSQL or in memory
+type Server struct {
+	dataaccess.Stores
+
+	mailer         mail.Mailer
+	broker         events.Broker
+	rateLimits     ratelimit.Store
+	sso            *oidc.Provider
+	jwtSecret      []byte
+	limits         config.Limits
+	appBaseURL     string
+	trustedProxies []netip.Prefix
+
+	notify *notifications.Notifier
+	// Emails being sent in the background
+	mailing sync.WaitGroup
+
+	readinessChecks []readinessCheck
+	// Closed once the server starts shutting down
+	shutdown     chan struct{}
+	shutdownOnce sync.Once
+}
+
+func NewServer(stores dataaccess.Stores, opts Options) *Server {
+	return &Server{
+		Stores:         stores,
+		mailer:         opts.Mailer,
+		broker:         opts.Broker,
+		rateLimits:     opts.RateLimits,
+		sso:            opts.SSO,
+		jwtSecret:      []byte(opts.JWTSecret),
+		limits:         opts.Limits,
+		appBaseURL:     strings.TrimSuffix(opts.AppBaseURL, "/"),
+		trustedProxies: opts.TrustedProxies,
+		notify:         notifications.New(stores, opts.Broker),
+		shutdown:       make(chan struct{}),
+	}
+}
+
+// BeginShutdown reports the server as not ready and ends its event streams
+// Call it before http.Server.Shutdown, which waits for every request and streams never finish on their own
+func (s *Server) BeginShutdown() {
+	s.shutdownOnce.Do(func() {
+		close(s.shutdown)
+	})
+}
+
+// Returns the client IP, from the forwarded headers if the request came through a trusted proxy
+func (s *Server) clientIP(r *http.Request) string {
+	return utils.GetClientIP(r, s.trustedProxies)
+}
+
+// Returns the page size to list with, the default if none was asked for and at most the configured maximum
+func (s *Server) pageSize(requested int) int {
+	if requested <= 0 {
+		requested = constants.DEFAULT_PAGE_SIZE
+	}
+	return min(requested, s.limits.MaxPageSize)
+}
+
+func (s *Server) isShuttingDown() bool {
+	select {
+	case <-s.shutdown:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"cvwo/internal/constants"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ListSessions returns the current user's active sessions (logged in devices)
+func (s *Server) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+	if !isAuthenticated {
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
+		return
+	}
+
+	sessions, err := s.Sessions.ListActiveSessions(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch sessions")
+		return
+	}
+
+	currentSessionID, _ := GetSessionFromContext(r)
+	for i := range sessions {
+		sessions[i].IsCurrent = sessions[i].ID == currentSessionID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"sessions": sessions,
+		"count":    len(sessions),
+	})
+}
+
+// RevokeSession logs out one of the current user's sessions
+func (s *Server) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+	if !isAuthenticated {
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
+		return
+	}
+
+	sessionID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid session ID")
+		return
+	}
+
+	if err := s.Sessions.RevokeSession(r.Context(), userID, sessionID); err != nil {
+		if err.Error() == constants.NO_ROWS_AFFECTED_ERROR {
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_SESSION_NOT_FOUND, "Session not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not revoke session")
+		return
+	}
+
+	if currentSessionID, _ := GetSessionFromContext(r); currentSessionID == sessionID {
+		clearSessionCookies(w)
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Session revoked successfully",
+	})
+}
+
+// LogoutAllSessions revokes every session of the current user, including this one
+func (s *Server) LogoutAllSessions(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+	if !isAuthenticated {
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
+		return
+	}
+
+	count, err := s.Sessions.RevokeAllSessions(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not logout all sessions")
+		return
+	}
+
+	clearSessionCookies(w)
+	json.NewEncoder(w).Encode(map[string]any{
+		"message": "Logged out of all sessions",
+		"count":   count,
+	})
+}
+
+// GetSecurityLog returns the current user's recent login attempts with their IP address and user agent,
+// including failed ones, so users can spot attempts to break into their account
+func (s *Server) GetSecurityLog(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+	if !isAuthenticated {
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
+		return
+	}
+
+	attempts, err := s.LoginAttempts.ListLoginAttempts(r.Context(), userID, constants.MAX_SECURITY_LOG_ENTRIES)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch login attempts")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"logins": attempts,
+		"count":  len(attempts),
+	})
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"cvwo/internal/telemetry"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Counts and times every request by the route pattern it matched, and wraps it in a span
+// The request ID of middleware.RequestID, which must run first, is sent back in X-Request-Id
+func TelemetryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		requestID := middleware.GetReqID(r.Context())
+		if requestID != "" {
+			w.Header().Set(middleware.RequestIDHeader, requestID)
+		}
+
+		ctx, span := telemetry.StartRequestSpan(r, requestID)
+		defer span.End()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		// The pattern is only complete once the request has been routed
+		route := chi.RouteContext(r.Context()).RoutePattern()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		// Nothing written means the handler left the status at 200
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		telemetry.ObserveRequest(r.Method, route, status, time.Since(start))
+		telemetry.EndRequestSpan(span, r.Method, route, status)
+	})
+}
+
+// Logs with the ID of the request the context belongs to, so the lines of one request can be found together
+func logf(ctx context.Context, format string, args ...any) {
+	if requestID := middleware.GetReqID(ctx); requestID != "" {
+		// Clients can choose their request ID, so it is an argument and never part of the format
+		args = append([]any{requestID}, args...)
+		format = "[%s] " + format
+	}
+	log.Printf(format, args...)
+}
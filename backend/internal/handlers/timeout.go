@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"errors"
+	"net/http"
+	"time"
+)
+
+const statementDeadlineKey contextKey = "statementDeadline"
+
+// The deadline of a request's queries, which the middleware of a route can move
+type statementDeadline struct {
+	timer  *time.Timer
+	expire func()
+}
+
+// Restarts the deadline with the timeout, a timeout of 0 removes it
+func (d *statementDeadline) reset(timeout time.Duration) {
+	d.stop()
+	d.timer = nil
+	if timeout > 0 {
+		d.timer = time.AfterFunc(timeout, d.expire)
+	}
+}
+
+func (d *statementDeadline) stop() {
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+}
+
+// Cancels the request's queries once they have run for the timeout, and responds 504 instead of the handler's error
+// Used again on a route, it replaces the deadline set for its group, so routes can allow more or less time
+// A timeout of 0 removes the deadline, for event streams that stay open
+// Queries of a request whose client hung up are cancelled as well, and nothing is written back
+func StatementTimeoutMiddleware(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if deadline, ok := r.Context().Value(statementDeadlineKey).(*statementDeadline); ok {
+				deadline.reset(timeout)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithCancelCause(r.Context())
+			defer cancel(nil)
+
+			deadline := &statementDeadline{expire: func() { cancel(context.DeadlineExceeded) }}
+			deadline.reset(timeout)
+			defer deadline.stop()
+
+			ctx = context.WithValue(ctx, statementDeadlineKey, deadline)
+			next.ServeHTTP(&cancellableResponseWriter{ResponseWriter: w, ctx: ctx}, r.WithContext(ctx))
+		})
+	}
+}
+
+// Replaces the server errors of cancelled requests, which are caused by the cancellation and not by a fault
+type cancellableResponseWriter struct {
+	http.ResponseWriter
+	ctx       context.Context
+	discarded bool
+}
+
+func (w *cancellableResponseWriter) WriteHeader(status int) {
+	if status < http.StatusInternalServerError || w.ctx.Err() == nil {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	w.discarded = true
+	if errors.Is(context.Cause(w.ctx), context.DeadlineExceeded) {
+		writeError(w.ResponseWriter, http.StatusGatewayTimeout, constants.ERROR_CODE_TIMEOUT, "The request took too long")
+	}
+	// Otherwise the client hung up, there is no one to respond to
+}
+
+func (w *cancellableResponseWriter) Write(b []byte) (int, error) {
+	if w.discarded {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *cancellableResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok && !w.discarded {
+		flusher.Flush()
+	}
+}
+
+// Lets http.ResponseController reach the underlying writer
+func (w *cancellableResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -2,63 +2,62 @@ package handlers
 
 import (
 	"cvwo/internal/constants"
-	"cvwo/internal/dataaccess"
 	"cvwo/internal/models"
 	"cvwo/internal/utils"
 	"encoding/json"
-	"log"
 	"net/http"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 )
 
-func FollowTopic(w http.ResponseWriter, r *http.Request) {
+func (s *Server) FollowTopic(w http.ResponseWriter, r *http.Request) {
 	userID, isAuthenticated := GetUserFromContext(r)
 	if !isAuthenticated {
-		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
 		return
 	}
 
-	topicName := utils.DeslugifyTopicName(chi.URLParam(r, "topic_slug"))
-	if topicName == "" {
-		http.Error(w, "Invalid topic ID", http.StatusBadRequest)
+	topicSlug := chi.URLParam(r, "topic_slug")
+	if topicSlug == "" {
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_ID, "Invalid topic ID")
 		return
 	}
 
 	var req models.FollowTopicRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("FollowTopic: Error decoding request body for user %d: %v", userID, err)
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		logf(r.Context(), "FollowTopic: Error decoding request body for user %d: %v", userID, err)
+		writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_REQUEST_BODY, "Invalid request body")
 		return
 	}
 
 	// Verify topic exists before attempting to follow/unfollow
-	_, err := dataaccess.GetTopicByName(isAuthenticated, userID, topicName)
+	topic, err := s.Topics.GetTopicBySlug(r.Context(), isAuthenticated, userID, topicSlug)
 	if err != nil {
 		if err.Error() == constants.NOT_FOUND_ERROR {
-			http.Error(w, "Topic not found", http.StatusNotFound)
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_TOPIC_NOT_FOUND, "Topic not found")
 			return
 		}
-		http.Error(w, "Failed to fetch topic", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch topic")
 		return
 	}
 
 	if req.IsFollow {
-		if err := dataaccess.FollowTopic(userID, topicName); err != nil {
+		if err := s.Topics.FollowTopic(r.Context(), userID, topic.Name); err != nil {
 			if err.Error() == constants.NO_ROWS_AFFECTED_ERROR {
-				http.Error(w, "User already following this topic", http.StatusConflict)
+				writeError(w, http.StatusConflict, constants.ERROR_CODE_ALREADY_FOLLOWING, "User already following this topic")
 				return
 			}
-			http.Error(w, "Could not follow topic", http.StatusInternalServerError)
+			writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not follow topic")
 			return
 		}
 	} else {
-		if err := dataaccess.UnfollowTopic(userID, topicName); err != nil {
+		if err := s.Topics.UnfollowTopic(r.Context(), userID, topic.Name); err != nil {
 			if err.Error() == constants.NO_ROWS_AFFECTED_ERROR {
-				http.Error(w, "User already not following this topic", http.StatusConflict)
+				writeError(w, http.StatusConflict, constants.ERROR_CODE_NOT_FOLLOWING, "User already not following this topic")
 				return
 			}
-			http.Error(w, "Could not unfollow topic", http.StatusInternalServerError)
+			writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not unfollow topic")
 			return
 		}
 	}
@@ -73,18 +72,16 @@ func FollowTopic(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func GetTopic(w http.ResponseWriter, r *http.Request) {
+func (s *Server) GetTopic(w http.ResponseWriter, r *http.Request) {
 	userID, isAuthenticated := GetUserFromContext(r)
 
-	topicName := utils.DeslugifyTopicName(chi.URLParam(r, "topic_slug"))
-
-	topic, err := dataaccess.GetTopicByName(isAuthenticated, userID, topicName)
+	topic, err := s.Topics.GetTopicBySlug(r.Context(), isAuthenticated, userID, chi.URLParam(r, "topic_slug"))
 	if err != nil {
 		if err.Error() == constants.NOT_FOUND_ERROR {
-			http.Error(w, "Topic not found", http.StatusNotFound)
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_TOPIC_NOT_FOUND, "Topic not found")
 			return
 		}
-		http.Error(w, "Failed to fetch topic", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch topic")
 		return
 	}
 
@@ -92,35 +89,167 @@ func GetTopic(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(topic)
 }
 
-func ListTopics(w http.ResponseWriter, r *http.Request) {
+func (s *Server) ListTopics(w http.ResponseWriter, r *http.Request) {
 	userID, isAuthenticated := GetUserFromContext(r)
 
 	var req models.ListTopicsRequest
 	if err := utils.Decoder.Decode(&req, r.URL.Query()); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeQueryError(w, err)
 		return
 	}
 
-	topics, count, err := dataaccess.ListTopics(isAuthenticated, userID, req)
+	req.PageSize = s.pageSize(req.PageSize)
+	topics, pageInfo, err := s.Topics.ListTopics(r.Context(), isAuthenticated, userID, req)
 	if err != nil {
-		http.Error(w, "Failed to fetch topics", http.StatusInternalServerError)
+		if err.Error() == constants.INVALID_CURSOR_ERROR {
+			writeFieldError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_CURSOR, "cursor", "Invalid cursor")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch topics")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{
-		"topics": topics,
-		"count":  count,
+		"topics":      topics,
+		"count":       pageInfo.Count,
+		"next_cursor": pageInfo.NextCursor,
 	})
 }
 
-func ListTopicsSummary(w http.ResponseWriter, r *http.Request) {
-	topics, err := dataaccess.ListTopicsSummary()
+func (s *Server) ListTopicsSummary(w http.ResponseWriter, r *http.Request) {
+	topics, err := s.Topics.ListTopicsSummary(r.Context())
 	if err != nil {
-		http.Error(w, "Failed to fetch topics summary", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch topics summary")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(topics)
 }
+
+// CreateTopic handles admin requests to create a new topic
+func (s *Server) CreateTopic(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateTopicRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	topic := models.Topic{
+		Name:        strings.TrimSpace(req.Name),
+		Description: strings.TrimSpace(req.Description),
+	}
+
+	var errs utils.ValidationErrors
+	errs.Check("name", utils.ValidateTopicName(topic.Name))
+	errs.Check("description", utils.ValidateTopicDescription(topic.Description))
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	topicID, err := s.Topics.CreateTopic(r.Context(), topic)
+	if err != nil {
+		if err.Error() == constants.ALREADY_EXISTS_ERROR {
+			writeError(w, http.StatusConflict, constants.ERROR_CODE_TOPIC_NAME_TAKEN, "Topic already exists")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not create topic")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":  "Topic created successfully",
+		"topic_id": topicID,
+		"slug":     utils.SlugifyTopicName(topic.Name),
+	})
+}
+
+// UpdateTopic handles admin requests to rename, redescribe or (un)archive a topic
+// Old slugs keep resolving to the topic after a rename
+func (s *Server) UpdateTopic(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+
+	var req models.UpdateTopicRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	topic, err := s.Topics.GetTopicBySlug(r.Context(), isAuthenticated, userID, chi.URLParam(r, "topic_slug"))
+	if err != nil {
+		if err.Error() == constants.NOT_FOUND_ERROR {
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_TOPIC_NOT_FOUND, "Topic not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch topic")
+		return
+	}
+
+	if req.Name != nil {
+		topic.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Description != nil {
+		topic.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.IsArchived != nil {
+		topic.IsArchived = *req.IsArchived
+	}
+
+	var errs utils.ValidationErrors
+	errs.Check("name", utils.ValidateTopicName(topic.Name))
+	errs.Check("description", utils.ValidateTopicDescription(topic.Description))
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	if err := s.Topics.UpdateTopic(r.Context(), *topic); err != nil {
+		if err.Error() == constants.ALREADY_EXISTS_ERROR {
+			writeError(w, http.StatusConflict, constants.ERROR_CODE_TOPIC_NAME_TAKEN, "Topic name already taken")
+			return
+		}
+		if err.Error() == constants.NOT_FOUND_ERROR {
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_TOPIC_NOT_FOUND, "Topic not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not update topic")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"message": "Topic updated successfully",
+		"slug":    utils.SlugifyTopicName(topic.Name),
+	})
+}
+
+// DeleteTopic handles admin requests to archive a topic
+// Topics are never hard deleted, so their posts stay readable
+func (s *Server) DeleteTopic(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+
+	topic, err := s.Topics.GetTopicBySlug(r.Context(), isAuthenticated, userID, chi.URLParam(r, "topic_slug"))
+	if err != nil {
+		if err.Error() == constants.NOT_FOUND_ERROR {
+			writeError(w, http.StatusNotFound, constants.ERROR_CODE_TOPIC_NOT_FOUND, "Topic not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch topic")
+		return
+	}
+
+	if err := s.Topics.ArchiveTopic(r.Context(), topic.ID); err != nil {
+		if err.Error() == constants.NO_ROWS_AFFECTED_ERROR {
+			writeError(w, http.StatusGone, constants.ERROR_CODE_TOPIC_ARCHIVED, "Topic already archived")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not archive topic")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Topic archived successfully",
+	})
+}
@@ -0,0 +1,387 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cvwo/internal/constants"
+	"cvwo/internal/models"
+	"cvwo/internal/utils"
+)
+
+// LoginTwoFactor completes a login of a user with two-factor authentication
+// The challenge token comes from the password step, the code is from the authenticator or a recovery code
+func (s *Server) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req models.LoginTwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	var errs utils.ValidationErrors
+	if req.ChallengeToken == "" {
+		errs.Add("challenge_token", constants.ERROR_CODE_REQUIRED, "Challenge token is required")
+	}
+	if req.Code == "" {
+		errs.Add("code", constants.ERROR_CODE_REQUIRED, "Code is required")
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	challenge, err := s.TwoFactor.GetLoginChallenge(r.Context(), utils.HashToken(req.ChallengeToken))
+	if err != nil {
+		if err.Error() == constants.NOT_FOUND_ERROR {
+			writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_TOKEN, "Login has expired, enter your password again")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not login")
+		return
+	}
+
+	user, err := s.Users.GetUserByID(r.Context(), challenge.UserID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not login")
+		return
+	}
+
+	attempt := models.LoginAttempt{
+		UserID:    &user.ID,
+		Email:     utils.NormalizeEmail(user.Email),
+		IPAddress: s.clientIP(r),
+		UserAgent: r.UserAgent(),
+	}
+
+	// Wrong codes count towards the same lockout as wrong passwords
+	lockout, err := s.loginLockoutRemaining(r.Context(), attempt.Email, attempt.IPAddress)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not login")
+		return
+	}
+
+	if lockout > 0 {
+		attempt.FailureReason = constants.LOGIN_FAILURE_LOCKED_OUT
+		s.recordLoginAttempt(r.Context(), attempt)
+
+		seconds := int(math.Ceil(lockout.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		writeError(w, http.StatusTooManyRequests, constants.ERROR_CODE_TOO_MANY_LOGIN_ATTEMPTS,
+			fmt.Sprintf("Too many failed login attempts, try again in %d seconds", seconds))
+		return
+	}
+
+	valid, err := s.checkTwoFactorCode(r.Context(), user.ID, req.Code)
+	if err != nil {
+		if err.Error() == constants.NOT_FOUND_ERROR {
+			// Disabled since the password step
+			writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_TOKEN, "Login has expired, enter your password again")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not login")
+		return
+	}
+
+	if !valid {
+		if err := s.TwoFactor.RecordLoginChallengeFailure(r.Context(), challenge.ID); err != nil {
+			writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not login")
+			return
+		}
+		attempt.FailureReason = constants.LOGIN_FAILURE_INVALID_TWO_FACTOR_CODE
+		s.recordLoginAttempt(r.Context(), attempt)
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_INVALID_TWO_FACTOR_CODE, "Invalid authentication code")
+		return
+	}
+
+	if err := s.TwoFactor.CompleteLoginChallenge(r.Context(), challenge.ID); err != nil {
+		if err.Error() == constants.NO_ROWS_AFFECTED_ERROR {
+			writeError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_TOKEN, "Login has expired, enter your password again")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not login")
+		return
+	}
+
+	s.completeLogin(w, r, user, attempt)
+}
+
+// GetTwoFactorStatus returns whether the current user has two-factor authentication enabled
+// and how many recovery codes they have left
+func (s *Server) GetTwoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+	if !isAuthenticated {
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
+		return
+	}
+
+	enabled, err := s.TwoFactor.IsTwoFactorEnabled(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch two-factor status")
+		return
+	}
+
+	remaining, err := s.TwoFactor.CountUnusedRecoveryCodes(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch two-factor status")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"enabled":                  enabled,
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// SetupTwoFactor generates a new TOTP secret for the current user to add to their authenticator
+// It is not used for logins until a code from the authenticator confirms it
+func (s *Server) SetupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+	if !isAuthenticated {
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
+		return
+	}
+
+	user, err := s.Users.GetUserByID(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, constants.ERROR_CODE_USER_NOT_FOUND, "User not found")
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not set up two-factor authentication")
+		return
+	}
+
+	if err := s.TwoFactor.StartTwoFactorEnrollment(r.Context(), userID, secret); err != nil {
+		if err.Error() == constants.ALREADY_EXISTS_ERROR {
+			writeError(w, http.StatusConflict, constants.ERROR_CODE_TWO_FACTOR_ALREADY_ENABLED, "Two-factor authentication is already enabled")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not set up two-factor authentication")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"secret":      secret,
+		"otpauth_uri": utils.TOTPURI(user.Username, secret),
+	})
+}
+
+// ConfirmTwoFactor enables two-factor authentication once the user enters a code for the new secret
+// Returns the recovery codes, which are only shown this once
+func (s *Server) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, isAuthenticated := GetUserFromContext(r)
+	if !isAuthenticated {
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
+		return
+	}
+
+	var req models.ConfirmTwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	var errs utils.ValidationErrors
+	if req.Code == "" {
+		errs.Add("code", constants.ERROR_CODE_REQUIRED, "Code is required")
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	twoFactor, err := s.TwoFactor.GetTwoFactor(r.Context(), userID)
+	if err != nil {
+		if err.Error() == constants.NOT_FOUND_ERROR {
+			writeError(w, http.StatusConflict, constants.ERROR_CODE_TWO_FACTOR_NOT_ENABLED, "Set up two-factor authentication first")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not enable two-factor authentication")
+		return
+	}
+
+	if twoFactor.EnabledAt != nil {
+		writeError(w, http.StatusConflict, constants.ERROR_CODE_TWO_FACTOR_ALREADY_ENABLED, "Two-factor authentication is already enabled")
+		return
+	}
+
+	step, valid := utils.ValidateTOTPCode(twoFactor.Secret, req.Code, time.Now())
+	if !valid {
+		writeInvalidTwoFactorCode(w)
+		return
+	}
+
+	recoveryCodes, recoveryCodeHashes, err := generateRecoveryCodes()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not enable two-factor authentication")
+		return
+	}
+
+	if err := s.TwoFactor.EnableTwoFactor(r.Context(), userID, step, recoveryCodeHashes); err != nil {
+		if err.Error() == constants.NO_ROWS_AFFECTED_ERROR {
+			writeError(w, http.StatusConflict, constants.ERROR_CODE_TWO_FACTOR_ALREADY_ENABLED, "Two-factor authentication is already enabled")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not enable two-factor authentication")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// DisableTwoFactor turns off two-factor authentication for the current user
+func (s *Server) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.checkTwoFactorChange(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.TwoFactor.DisableTwoFactor(r.Context(), userID); err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not disable two-factor authentication")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the current user's recovery codes, the old ones stop working
+func (s *Server) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.checkTwoFactorChange(w, r)
+	if !ok {
+		return
+	}
+
+	recoveryCodes, recoveryCodeHashes, err := generateRecoveryCodes()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not regenerate recovery codes")
+		return
+	}
+
+	if err := s.TwoFactor.ReplaceRecoveryCodes(r.Context(), userID, recoveryCodeHashes); err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not regenerate recovery codes")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// Decodes a TwoFactorChangeRequest and checks its password and code against the current user
+// Writes the error response and returns false if the change is not allowed
+func (s *Server) checkTwoFactorChange(w http.ResponseWriter, r *http.Request) (int, bool) {
+	userID, isAuthenticated := GetUserFromContext(r)
+	if !isAuthenticated {
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
+		return 0, false
+	}
+
+	var req models.TwoFactorChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return 0, false
+	}
+
+	var errs utils.ValidationErrors
+	if req.Password == "" {
+		errs.Add("password", constants.ERROR_CODE_REQUIRED, "Password is required")
+	}
+	if req.Code == "" {
+		errs.Add("code", constants.ERROR_CODE_REQUIRED, "Code is required")
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return 0, false
+	}
+
+	passwordHash, err := s.Users.GetUserPasswordHash(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not verify password")
+		return 0, false
+	}
+
+	// Not 401, which clients take to mean the session expired
+	if err := utils.CompareHashAndPassword(passwordHash, req.Password); err != nil {
+		writeValidationErrors(w, utils.ValidationErrors{{
+			Field:   "password",
+			Code:    constants.ERROR_CODE_INVALID_VALUE,
+			Message: "Password is incorrect",
+		}})
+		return 0, false
+	}
+
+	valid, err := s.checkTwoFactorCode(r.Context(), userID, req.Code)
+	if err != nil {
+		if err.Error() == constants.NOT_FOUND_ERROR {
+			writeError(w, http.StatusConflict, constants.ERROR_CODE_TWO_FACTOR_NOT_ENABLED, "Two-factor authentication is not enabled")
+			return 0, false
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not verify code")
+		return 0, false
+	}
+
+	if !valid {
+		writeInvalidTwoFactorCode(w)
+		return 0, false
+	}
+
+	return userID, true
+}
+
+// Checks a code from the user's authenticator or one of their recovery codes, using it up
+// Returns NOT_FOUND_ERROR if the user does not have two-factor authentication enabled
+func (s *Server) checkTwoFactorCode(ctx context.Context, userID int, code string) (bool, error) {
+	twoFactor, err := s.TwoFactor.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	if twoFactor.EnabledAt == nil {
+		return false, errors.New(constants.NOT_FOUND_ERROR)
+	}
+
+	if utils.IsTOTPCode(code) {
+		step, valid := utils.ValidateTOTPCode(twoFactor.Secret, code, time.Now())
+		if !valid {
+			return false, nil
+		}
+		return s.TwoFactor.UseTOTPStep(ctx, userID, step)
+	}
+
+	return s.TwoFactor.UseRecoveryCode(ctx, userID, utils.HashToken(utils.NormalizeRecoveryCode(code)))
+}
+
+// Returns new recovery codes to show the user and the hashes to store
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, constants.RECOVERY_CODE_COUNT)
+	hashes := make([]string, 0, constants.RECOVERY_CODE_COUNT)
+	for range constants.RECOVERY_CODE_COUNT {
+		code, err := utils.GenerateRecoveryCode()
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, utils.HashToken(utils.NormalizeRecoveryCode(code)))
+	}
+	return codes, hashes, nil
+}
+
+// Not 401 outside of logins, which clients take to mean the session expired
+func writeInvalidTwoFactorCode(w http.ResponseWriter) {
+	writeValidationErrors(w, utils.ValidationErrors{{
+		Field:   "code",
+		Code:    constants.ERROR_CODE_INVALID_VALUE,
+		Message: "Code is incorrect or was already used",
+	}})
+}
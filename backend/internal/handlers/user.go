@@ -4,47 +4,70 @@ import (
 	"encoding/json"
 	"net/http"
 
-	"cvwo/internal/dataaccess"
+	"cvwo/internal/constants"
 	"cvwo/internal/models"
 	"cvwo/internal/utils"
 
 	"github.com/go-chi/chi/v5"
-	"golang.org/x/crypto/bcrypt"
 )
 
-func ChangePassword(w http.ResponseWriter, r *http.Request) {
+// ChangePassword sets a new password for the current user, who must confirm their current one
+// Every other session of the user is logged out
+func (s *Server) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	userID, isAuthenticated := GetUserFromContext(r)
 	if !isAuthenticated {
-		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
 		return
 	}
 
 	var req models.ChangePasswordRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeDecodeError(w, err)
 		return
 	}
 
-	if passwordErr := utils.ValidatePassword(req.Password); passwordErr != "" {
-		http.Error(w, passwordErr, http.StatusBadRequest)
+	var errs utils.ValidationErrors
+	if req.CurrentPassword == "" {
+		errs.Add("current_password", constants.ERROR_CODE_REQUIRED, "Current password is required")
+	}
+	errs.Check("password", utils.ValidatePassword(req.Password))
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	currentHash, err := s.Users.GetUserPasswordHash(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not update password")
 		return
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), 14)
+	// Not 401, which clients take to mean the session expired
+	if err := utils.CompareHashAndPassword(currentHash, req.CurrentPassword); err != nil {
+		writeValidationErrors(w, utils.ValidationErrors{{
+			Field:   "current_password",
+			Code:    constants.ERROR_CODE_INVALID_VALUE,
+			Message: "Current password is incorrect",
+		}})
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
-		http.Error(w, "Could not hash password", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not hash password")
 		return
 	}
 
-	if err := dataaccess.UpdateUserPassword(userID, string(hashedPassword)); err != nil {
-		http.Error(w, "Could not update password", http.StatusInternalServerError)
+	sessionID, _ := GetSessionFromContext(r)
+	if err := s.Users.UpdateUserPassword(r.Context(), userID, string(hashedPassword), sessionID); err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not update password")
 		return
 	}
 
 	json.NewEncoder(w).Encode(map[string]string{"message": "Password updated successfully"})
 }
 
-func GetProfile(w http.ResponseWriter, r *http.Request) {
+func (s *Server) GetProfile(w http.ResponseWriter, r *http.Request) {
 	currentUserID, isAuthenticated := GetUserFromContext(r)
 
 	var user *models.User
@@ -55,18 +78,18 @@ func GetProfile(w http.ResponseWriter, r *http.Request) {
 	if username == "" {
 		// no username provided, get current user's profile
 		if !isAuthenticated {
-			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
 		} else {
-			user, err = dataaccess.GetUserByID(currentUserID)
+			user, err = s.Users.GetUserByID(r.Context(), currentUserID)
 		}
 	} else {
 		// username provided, get that user's profile
-		user, err = dataaccess.GetUserByUsername(username)
+		user, err = s.Users.GetUserByUsername(r.Context(), username)
 	}
 
 	// error getting user
 	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, constants.ERROR_CODE_USER_NOT_FOUND, "User not found")
 		return
 	}
 
@@ -86,34 +109,44 @@ func GetProfile(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func UpdateProfile(w http.ResponseWriter, r *http.Request) {
+func (s *Server) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	userID, isAuthenticated := GetUserFromContext(r)
 	if !isAuthenticated {
-		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		writeError(w, http.StatusUnauthorized, constants.ERROR_CODE_AUTHENTICATION_REQUIRED, "Authentication required")
 		return
 	}
 
 	var req models.UpdateUserRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeDecodeError(w, err)
 		return
 	}
 
-	user, err := dataaccess.GetUserByID(userID)
+	user, err := s.Users.GetUserByID(r.Context(), userID)
 	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, constants.ERROR_CODE_USER_NOT_FOUND, "User not found")
 		return
 	}
 
 	// Update only provided fields
+	var errs utils.ValidationErrors
+	if req.Email != "" {
+		errs.Check("email", utils.ValidateEmail(req.Email))
+	}
+	if req.Username != "" {
+		errs.Check("username", utils.ValidateUsername(req.Username))
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	emailChanged := false
 	if req.Email != "" {
-		if emailErr := utils.ValidateEmail(req.Email); emailErr != "" {
-			http.Error(w, emailErr, http.StatusBadRequest)
-			return
-		}
 		if req.Email != user.Email {
-			if exists, _ := dataaccess.CheckUserExistsByEmail(req.Email); exists {
-				http.Error(w, "Email already exists", http.StatusConflict)
+			emailChanged = true
+			if exists, _ := s.Users.CheckUserExistsByEmail(r.Context(), req.Email); exists {
+				writeError(w, http.StatusConflict, constants.ERROR_CODE_EMAIL_TAKEN, "Email already exists")
 				return
 			}
 			user.Email = req.Email
@@ -121,24 +154,27 @@ func UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if req.Username != "" {
-		if usernameErr := utils.ValidateUsername(req.Username); usernameErr != "" {
-			http.Error(w, usernameErr, http.StatusBadRequest)
-			return
-		}
 		if req.Username != user.Username {
-			if exists, _ := dataaccess.CheckUserExistsByUsername(req.Username); exists {
-				http.Error(w, "Username already exists", http.StatusConflict)
+			if exists, _ := s.Users.CheckUserExistsByUsername(r.Context(), req.Username); exists {
+				writeError(w, http.StatusConflict, constants.ERROR_CODE_USERNAME_TAKEN, "Username already exists")
 				return
 			}
 			user.Username = req.Username
 		}
 	}
 
-	if err := dataaccess.UpdateUserData(user); err != nil {
-		http.Error(w, "Could not update profile", http.StatusInternalServerError)
+	if err := s.Users.UpdateUserData(r.Context(), user); err != nil {
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Could not update profile")
 		return
 	}
 
+	// The new email is unverified until the user opens the link sent to it
+	if emailChanged {
+		if err := s.sendVerificationEmail(r.Context(), user); err != nil {
+			logf(r.Context(), "UpdateProfile: failed to send verification email to user %d: %v", user.ID, err)
+		}
+	}
+
 	json.NewEncoder(w).Encode(map[string]string{
 		"message": "Profile updated successfully",
 	})
@@ -1,29 +1,35 @@
 package handlers
 
 import (
-	"cvwo/internal/dataaccess"
+	"cvwo/internal/constants"
 	"cvwo/internal/models"
 	"cvwo/internal/utils"
 	"encoding/json"
 	"net/http"
 )
 
-func ListUsers(w http.ResponseWriter, r *http.Request) {
+func (s *Server) ListUsers(w http.ResponseWriter, r *http.Request) {
 	var req models.ListUsersRequest
 	if err := utils.Decoder.Decode(&req, r.URL.Query()); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeQueryError(w, err)
 		return
 	}
 
-	users, count, err := dataaccess.ListUsers(req)
+	req.PageSize = s.pageSize(req.PageSize)
+	users, pageInfo, err := s.Users.ListUsers(r.Context(), req)
 	if err != nil {
-		http.Error(w, "Failed to fetch users", http.StatusInternalServerError)
+		if err.Error() == constants.INVALID_CURSOR_ERROR {
+			writeFieldError(w, http.StatusBadRequest, constants.ERROR_CODE_INVALID_CURSOR, "cursor", "Invalid cursor")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, constants.ERROR_CODE_INTERNAL, "Failed to fetch users")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{
-		"users": users,
-		"count": count,
+		"users":       users,
+		"count":       pageInfo.Count,
+		"next_cursor": pageInfo.NextCursor,
 	})
 }
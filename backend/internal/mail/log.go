@@ -0,0 +1,50 @@
+package mail
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// Query values of the single-use links in emails
+var tokenParam = regexp.MustCompile(`([?&]token=)[^\s&#]+`)
+
+// LogMailer writes emails to the server log instead of sending them, for local development
+// Tokens in links are redacted, so reading the log is not enough to verify or reset someone else's account
+type LogMailer struct{}
+
+func (LogMailer) Send(msg Message) error {
+	log.Printf("mail: to %s, subject %q\n%s", msg.To, msg.Subject, RedactTokens(msg.Body))
+	return nil
+}
+
+// RedactTokens replaces the tokens of links in the text
+func RedactTokens(text string) string {
+	return tokenParam.ReplaceAllString(text, "${1}REDACTED")
+}
+
+// Characters not allowed in the file names written by FileMailer
+var unsafeFileNameChars = regexp.MustCompile(`[^a-zA-Z0-9@._-]+`)
+
+// FileMailer writes each email to its own .eml file in a directory, for local testing
+type FileMailer struct {
+	dir string
+}
+
+// NewFileMailer creates the directory if it does not exist
+func NewFileMailer(dir string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileMailer{dir: dir}, nil
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	now := time.Now()
+	name := fmt.Sprintf("%s_%s.eml", now.Format("20060102T150405.000000000"), unsafeFileNameChars.ReplaceAllString(msg.To, "_"))
+
+	return os.WriteFile(filepath.Join(m.dir, name), formatMessage("", msg, now), 0o644)
+}
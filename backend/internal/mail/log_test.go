@@ -0,0 +1,24 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactTokens(t *testing.T) {
+	body := "Choose a new password by opening this link:\n\n" +
+		"http://localhost:3000/reset-password?token=abc-DEF_123\n\n" +
+		"Or http://localhost:3000/verify-email?lang=en&token=xyz%2B9#top in another browser\n"
+
+	redacted := RedactTokens(body)
+	for _, token := range []string{"abc-DEF_123", "xyz%2B9"} {
+		if strings.Contains(redacted, token) {
+			t.Errorf("token %s is still in %q", token, redacted)
+		}
+	}
+	for _, kept := range []string{"/reset-password?token=REDACTED\n", "?lang=en&token=REDACTED#top", "Choose a new password"} {
+		if !strings.Contains(redacted, kept) {
+			t.Errorf("%q is missing from %q", kept, redacted)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Package mail sends transactional emails such as email verification and password reset links
+package mail
+
+import (
+	"bytes"
+	"cvwo/internal/config"
+	"fmt"
+	"mime"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers emails
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// New returns the configured mailer
+func New(cfg config.MailConfig) (Mailer, error) {
+	switch cfg.Mailer {
+	case "smtp":
+		return NewSMTPMailer(cfg), nil
+	case "file":
+		return NewFileMailer(cfg.Dir)
+	case "log":
+		return LogMailer{}, nil
+	}
+	return nil, fmt.Errorf("unknown mailer: %s", cfg.Mailer)
+}
+
+// Formats the email as a plain text RFC 5322 message
+func formatMessage(from string, msg Message, date time.Time) []byte {
+	var b bytes.Buffer
+	if from != "" {
+		fmt.Fprintf(&b, "From: %s\r\n", from)
+	}
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Body)
+	return b.Bytes()
+}
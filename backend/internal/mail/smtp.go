@@ -0,0 +1,34 @@
+package mail
+
+import (
+	"cvwo/internal/config"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// SMTPMailer sends emails through an SMTP server, upgrading to TLS if the server supports it
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPMailer sends through the configured server, which the configuration's Validate has checked
+func NewSMTPMailer(cfg config.MailConfig) *SMTPMailer {
+	m := &SMTPMailer{
+		addr: net.JoinHostPort(cfg.SMTPHost, cfg.SMTPPort),
+		from: cfg.From,
+	}
+
+	// PlainAuth refuses to send credentials over unencrypted connections, except to localhost
+	if cfg.SMTPUsername != "" {
+		m.auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+
+	return m
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, formatMessage(m.from, msg, time.Now()))
+}
@@ -0,0 +1,321 @@
+package migrations
+
+import (
+	"context"
+	"cvwo/embed"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Directory of the embedded migration files, relative to the embed package
+const migrationsDir = "sql/migrations"
+
+// Arbitrary key shared by every replica so only one can migrate at a time
+const advisoryLockKey = 727_001
+
+// Migration file names look like 0001_initial_schema.up.sql
+var fileNameRegex = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version   int
+	Name      string
+	UpSQL     string
+	DownSQL   string
+	AppliedAt *time.Time
+}
+
+// Load reads and pairs up the embedded migration files, ordered by version
+func Load() ([]Migration, error) {
+	entries, err := fs.ReadDir(embed.MigrationFiles, migrationsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := fileNameRegex.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("conflicting names for migration %d: %s and %s", version, migration.Name, match[2])
+		}
+
+		content, err := embed.MigrationFiles.ReadFile(migrationsDir + "/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		if match[3] == "up" {
+			migration.UpSQL = string(content)
+		} else {
+			migration.DownSQL = string(content)
+		}
+	}
+
+	migrations := []Migration{}
+	for _, migration := range byVersion {
+		if migration.UpSQL == "" {
+			return nil, fmt.Errorf("migration %d is missing its up file", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Status returns every known migration, with AppliedAt set for those already applied
+func Status(db *sql.DB) ([]Migration, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range migrations {
+		if appliedAt, ok := applied[migrations[i].Version]; ok {
+			migrations[i].AppliedAt = &appliedAt
+		}
+	}
+
+	return migrations, nil
+}
+
+// Pending returns the migrations that have not been applied yet
+func Pending(db *sql.DB) ([]Migration, error) {
+	migrations, err := Status(db)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []Migration{}
+	for _, migration := range migrations {
+		if migration.AppliedAt == nil {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// Up applies all pending migrations in order, each in its own transaction
+// Holds an advisory lock so concurrent callers (e.g. server replicas) wait instead of racing
+// Returns the migrations that were applied by this call
+func Up(db *sql.DB) ([]Migration, error) {
+	ctx := context.Background()
+	conn, unlock, err := lock(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if err := ensureTable(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	// Read the applied versions only after acquiring the lock,
+	// another replica may have just finished migrating
+	pending, err := Pending(db)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := []Migration{}
+	for _, migration := range pending {
+		insertQuery := `
+			INSERT INTO schema_migrations (version, name)
+			VALUES ($1, $2)`
+
+		err := runInTx(ctx, conn, migration.UpSQL, insertQuery, migration.Version, migration.Name)
+		if err != nil {
+			return applied, fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+		applied = append(applied, migration)
+	}
+
+	return applied, nil
+}
+
+// Down reverts the most recently applied migrations, newest first
+// Returns the migrations that were reverted by this call
+func Down(db *sql.DB, steps int) ([]Migration, error) {
+	ctx := context.Background()
+	conn, unlock, err := lock(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if err := ensureTable(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	migrations, err := Status(db)
+	if err != nil {
+		return nil, err
+	}
+
+	reverted := []Migration{}
+	for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		migration := migrations[i]
+		if migration.AppliedAt == nil {
+			continue
+		}
+		if migration.DownSQL == "" {
+			return reverted, fmt.Errorf("migration %d (%s) has no down file", migration.Version, migration.Name)
+		}
+
+		deleteQuery := `
+			DELETE FROM schema_migrations
+			WHERE version = $1`
+
+		err := runInTx(ctx, conn, migration.DownSQL, deleteQuery, migration.Version)
+		if err != nil {
+			return reverted, fmt.Errorf("reverting migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+		reverted = append(reverted, migration)
+	}
+
+	return reverted, nil
+}
+
+// Create writes an empty up/down pair to dir, numbered after the highest existing version
+// dir is the source directory (not the embedded copy), so the binary must be rebuilt to pick them up
+func Create(dir, name string) (upPath, downPath string, err error) {
+	name = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), " ", "_"))
+	if !regexp.MustCompile(`^[a-z0-9_]+$`).MatchString(name) {
+		return "", "", errors.New("migration name can only contain letters, numbers, and underscores")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", "", err
+	}
+
+	maxVersion := 0
+	for _, entry := range entries {
+		if match := fileNameRegex.FindStringSubmatch(entry.Name()); match != nil {
+			version, _ := strconv.Atoi(match[1])
+			maxVersion = max(maxVersion, version)
+		}
+	}
+
+	base := fmt.Sprintf("%04d_%s", maxVersion+1, name)
+	upPath = filepath.Join(dir, base+".up.sql")
+	downPath = filepath.Join(dir, base+".down.sql")
+
+	if err := os.WriteFile(upPath, []byte("-- "+base+" (up)\n"), 0o644); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(downPath, []byte("-- "+base+" (down)\n"), 0o644); err != nil {
+		return "", "", err
+	}
+
+	return upPath, downPath, nil
+}
+
+// Session-level advisory locks belong to a connection, so pin one from the pool
+func lock(ctx context.Context, db *sql.DB) (*sql.Conn, func(), error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", advisoryLockKey); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+
+	unlock := func() {
+		conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockKey)
+		conn.Close()
+	}
+
+	return conn, unlock, nil
+}
+
+func ensureTable(ctx context.Context, conn *sql.Conn) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`
+
+	_, err := conn.ExecContext(ctx, query)
+	return err
+}
+
+// Transaction to ensure the schema change and its bookkeeping row are atomic
+func runInTx(ctx context.Context, conn *sql.Conn, migrationSQL, bookkeepingQuery string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, migrationSQL); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, bookkeepingQuery, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Returns version -> applied_at, empty if the schema_migrations table does not exist yet
+func appliedVersions(db *sql.DB) (map[int]time.Time, error) {
+	var exists bool
+	err := db.QueryRow("SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := map[int]time.Time{}
+	if !exists {
+		return applied, nil
+	}
+
+	rows, err := db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
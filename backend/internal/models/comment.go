@@ -6,20 +6,25 @@ type Comment struct {
 	ID             int        `json:"id"`
 	PostID         int        `json:"post_id"`
 	Content        string     `json:"content"`
+	ContentHTML    string     `json:"content_html"`
 	Summary        string     `json:"summary"`
 	CreatedAt      time.Time  `json:"created_at"`
 	UpdatedAt      time.Time  `json:"updated_at"`
 	UserID         int        `json:"user_id"`
 	Score          int        `json:"score"`
+	Upvotes        int        `json:"upvotes"`
+	Downvotes      int        `json:"downvotes"`
 	ParentID       *int       `json:"parent_id"`
 	Path           string     `json:"path"`
 	NoOfReplies    int        `json:"no_of_replies"`
 	IsDeleted      bool       `json:"is_deleted"`
 	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
 	MyVote         int        `json:"my_vote"`
+	IsSaved        bool       `json:"is_saved"`
 	PostTitle      string     `json:"post_title,omitempty"`
 	HasLongContent bool       `json:"has_long_content,omitempty"`
 	Username       string     `json:"username,omitempty"`
+	TopicID        int        `json:"topic_id,omitempty"`
 	TopicName      string     `json:"topic_name,omitempty"`
 }
 
@@ -46,8 +51,11 @@ type VoteCommentRequest struct {
 type ListCommentsRequest struct {
 	Page                int    `json:"page,omitempty" schema:"page"`
 	PageSize            int    `json:"page_size,omitempty" schema:"page_size"`
+	Cursor              string `json:"cursor,omitempty" schema:"cursor"`
+	IncludeCount        *bool  `json:"include_count,omitempty" schema:"include_count"`
 	Sort                string `json:"sort,omitempty" schema:"sort"`
 	OrderBy             string `json:"order_by,omitempty" schema:"order_by"`
+	Window              string `json:"window,omitempty" schema:"window"`
 	Search              string `json:"search,omitempty" schema:"search"`
 	PostID              *int   `json:"post_id,omitempty" schema:"post_id"`
 	UserID              *int   `json:"user_id,omitempty" schema:"user_id"`
@@ -55,4 +63,6 @@ type ListCommentsRequest struct {
 	OnlyTopLevel        bool   `json:"only_top_level,omitempty" schema:"only_top_level"`
 	ShowDeletedComments bool   `json:"show_deleted_comments,omitempty" schema:"show_deleted_comments"`
 	ShowPostTitle       bool   `json:"show_post_title,omitempty" schema:"show_post_title"`
+	// Set by the saved list, only comments the user saved
+	SavedOnly bool `json:"-" schema:"-"`
 }
@@ -0,0 +1,18 @@
+package models
+
+// Body of every error response
+// Code is stable and meant for clients to branch on, Message is human-readable and may change
+// Field names the offending field, if any, Details lists every invalid field of a validation_failed error
+type ErrorResponse struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Field   string       `json:"field,omitempty"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+// Why a single request field is invalid
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
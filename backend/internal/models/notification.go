@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+type Notification struct {
+	ID             int        `json:"id"`
+	UserID         int        `json:"-"`
+	Type           string     `json:"type"`
+	ActorID        *int       `json:"actor_id,omitempty"`
+	ActorUsername  string     `json:"actor_username,omitempty"`
+	PostID         *int       `json:"post_id,omitempty"`
+	PostTitle      string     `json:"post_title,omitempty"`
+	CommentID      *int       `json:"comment_id,omitempty"`
+	CommentSummary string     `json:"comment_summary,omitempty"`
+	TopicID        *int       `json:"topic_id,omitempty"`
+	TopicName      string     `json:"topic_name,omitempty"`
+	TopicSlug      string     `json:"topic_slug,omitempty"`
+	Message        string     `json:"message,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ReadAt         *time.Time `json:"read_at,omitempty"`
+}
+
+type ListNotificationsRequest struct {
+	PageSize     int    `json:"page_size,omitempty" schema:"page_size"`
+	Cursor       string `json:"cursor,omitempty" schema:"cursor"`
+	IncludeCount *bool  `json:"include_count,omitempty" schema:"include_count"`
+	UnreadOnly   bool   `json:"unread_only,omitempty" schema:"unread_only"`
+}
+
+// Marks the given notifications as read, or all of them if no IDs are given
+type MarkNotificationsReadRequest struct {
+	IDs []int `json:"ids"`
+}
+
+type NotificationPreferences struct {
+	MutedTypes []string `json:"muted_types"`
+}
@@ -0,0 +1,9 @@
+package models
+
+// Pagination metadata returned alongside a list
+// Count is nil when the client opted out of counting with include_count=false
+// NextCursor is empty on the last page
+type PageInfo struct {
+	Count      *int   `json:"count"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
@@ -8,15 +8,21 @@ type Post struct {
 	Title           string     `json:"title"`
 	Summary         string     `json:"summary"`
 	Content         string     `json:"content"`
+	ContentHTML     string     `json:"content_html"`
 	CreatedAt       time.Time  `json:"created_at"`
 	UpdatedAt       time.Time  `json:"updated_at"`
 	UserID          int        `json:"user_id"`
 	PinnedCommentID *int       `json:"pinned_comment_id,omitempty"`
 	Score           int        `json:"score"`
+	Upvotes         int        `json:"upvotes"`
+	Downvotes       int        `json:"downvotes"`
 	NoOfComments    int        `json:"no_of_comments"`
 	IsDeleted       bool       `json:"is_deleted"`
 	DeletedAt       *time.Time `json:"deleted_at,omitempty"`
+	IsLocked        bool       `json:"is_locked"`
+	IsPinned        bool       `json:"is_pinned"`
 	MyVote          int        `json:"my_vote,omitempty"`
+	IsSaved         bool       `json:"is_saved"`
 	TopicName       string     `json:"topic_name,omitempty"`
 	Username        string     `json:"username,omitempty"`
 }
@@ -43,17 +49,30 @@ type VotePostRequest struct {
 }
 
 type ListPostsRequest struct {
-	Page                 int    `json:"page,omitempty" schema:"page"`
-	PageSize             int    `json:"page_size,omitempty" schema:"page_size"`
-	Sort                 string `json:"sort,omitempty" schema:"sort"`
-	OrderBy              string `json:"order_by,omitempty" schema:"order_by"`
-	Search               string `json:"search,omitempty" schema:"search"`
-	TopicID              *int   `json:"topic_id,omitempty" schema:"topic_id"`
-	UserID               *int   `json:"user_id,omitempty" schema:"user_id"`
+	Page                  int    `json:"page,omitempty" schema:"page"`
+	PageSize              int    `json:"page_size,omitempty" schema:"page_size"`
+	Cursor                string `json:"cursor,omitempty" schema:"cursor"`
+	IncludeCount          *bool  `json:"include_count,omitempty" schema:"include_count"`
+	Sort                  string `json:"sort,omitempty" schema:"sort"`
+	OrderBy               string `json:"order_by,omitempty" schema:"order_by"`
+	Window                string `json:"window,omitempty" schema:"window"`
+	Search                string `json:"search,omitempty" schema:"search"`
+	TopicID               *int   `json:"topic_id,omitempty" schema:"topic_id"`
+	UserID                *int   `json:"user_id,omitempty" schema:"user_id"`
 	FilterFollowingTopics bool   `json:"filter_following_topics,omitempty" schema:"filter_following_topics"`
-	ShowDeletedPosts     bool   `json:"show_deleted_posts,omitempty" schema:"show_deleted_posts"`
+	ShowDeletedPosts      bool   `json:"show_deleted_posts,omitempty" schema:"show_deleted_posts"`
+	// Set by the saved list, only posts the user saved
+	SavedOnly bool `json:"-" schema:"-"`
 }
 
 type PinCommentRequest struct {
 	CommentID *int `json:"comment_id" schema:"comment_id"`
 }
+
+type LockPostRequest struct {
+	IsLocked bool `json:"is_locked" schema:"is_locked"`
+}
+
+type PinPostRequest struct {
+	IsPinned bool `json:"is_pinned" schema:"is_pinned"`
+}
@@ -0,0 +1,80 @@
+package models
+
+import "time"
+
+// A user's report of a post or comment, exactly one of PostID and CommentID is set
+type Report struct {
+	ID               int        `json:"id"`
+	ReporterID       *int       `json:"reporter_id,omitempty"`
+	ReporterUsername string     `json:"reporter_username,omitempty"`
+	PostID           *int       `json:"post_id,omitempty"`
+	CommentID        *int       `json:"comment_id,omitempty"`
+	Reason           string     `json:"reason"`
+	Details          string     `json:"details"`
+	Status           string     `json:"status"`
+	CreatedAt        time.Time  `json:"created_at"`
+	ResolvedAt       *time.Time `json:"resolved_at,omitempty"`
+}
+
+type CreateReportRequest struct {
+	Reason  string `json:"reason" schema:"reason"`
+	Details string `json:"details" schema:"details"`
+}
+
+// A post or comment in the moderation queue, with its open reports aggregated
+type ReportedContent struct {
+	TargetType      string         `json:"target_type"`
+	PostID          int            `json:"post_id"`
+	CommentID       *int           `json:"comment_id,omitempty"`
+	Title           string         `json:"title"`
+	Summary         string         `json:"summary"`
+	AuthorID        int            `json:"author_id"`
+	AuthorUsername  string         `json:"author_username"`
+	TopicID         int            `json:"topic_id"`
+	TopicName       string         `json:"topic_name"`
+	TopicSlug       string         `json:"topic_slug"`
+	IsDeleted       bool           `json:"is_deleted"`
+	ReportCount     int            `json:"report_count"`
+	Reasons         map[string]int `json:"reasons"`
+	FirstReportedAt time.Time      `json:"first_reported_at"`
+	LastReportedAt  time.Time      `json:"last_reported_at"`
+}
+
+type ListReportsRequest struct {
+	PageSize     int    `json:"page_size,omitempty" schema:"page_size"`
+	Cursor       string `json:"cursor,omitempty" schema:"cursor"`
+	IncludeCount *bool  `json:"include_count,omitempty" schema:"include_count"`
+	Type         string `json:"type,omitempty" schema:"type"`
+	TopicID      int    `json:"topic_id,omitempty" schema:"topic_id"`
+}
+
+// Resolves every open report of a post or comment
+// SuspendDays is only used by the suspend action
+type ResolveReportsRequest struct {
+	Action      string `json:"action" schema:"action"`
+	Note        string `json:"note" schema:"note"`
+	SuspendDays int    `json:"suspend_days,omitempty" schema:"suspend_days"`
+}
+
+// An entry of the moderation audit log
+type ModerationLogEntry struct {
+	ID                int        `json:"id"`
+	ModeratorID       *int       `json:"moderator_id,omitempty"`
+	ModeratorUsername string     `json:"moderator_username,omitempty"`
+	Action            string     `json:"action"`
+	PostID            *int       `json:"post_id,omitempty"`
+	CommentID         *int       `json:"comment_id,omitempty"`
+	TargetUserID      *int       `json:"target_user_id,omitempty"`
+	TargetUsername    string     `json:"target_username,omitempty"`
+	ReportsResolved   int        `json:"reports_resolved"`
+	Note              string     `json:"note"`
+	SuspendedUntil    *time.Time `json:"suspended_until,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+type ListModerationLogRequest struct {
+	PageSize     int    `json:"page_size,omitempty" schema:"page_size"`
+	Cursor       string `json:"cursor,omitempty" schema:"cursor"`
+	IncludeCount *bool  `json:"include_count,omitempty" schema:"include_count"`
+	Action       string `json:"action,omitempty" schema:"action"`
+}
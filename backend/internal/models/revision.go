@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// A saved version of a post, the diffs are against the previous revision
+type PostRevision struct {
+	PostID         int       `json:"post_id"`
+	Revision       int       `json:"revision"`
+	Title          string    `json:"title"`
+	Content        string    `json:"content"`
+	EditorID       *int      `json:"editor_id"`
+	EditorUsername string    `json:"editor_username,omitempty"`
+	RestoredFrom   *int      `json:"restored_from,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	TitleDiff      string    `json:"title_diff"`
+	ContentDiff    string    `json:"content_diff"`
+}
+
+// A saved version of a comment, the diff is against the previous revision
+type CommentRevision struct {
+	CommentID      int       `json:"comment_id"`
+	Revision       int       `json:"revision"`
+	Content        string    `json:"content"`
+	EditorID       *int      `json:"editor_id"`
+	EditorUsername string    `json:"editor_username,omitempty"`
+	RestoredFrom   *int      `json:"restored_from,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	ContentDiff    string    `json:"content_diff"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+type SearchResult struct {
+	Type      string    `json:"type"`
+	ID        int       `json:"id"`
+	PostID    int       `json:"post_id"`
+	PostTitle string    `json:"post_title"`
+	Snippet   string    `json:"snippet"`
+	Rank      float64   `json:"rank"`
+	Score     int       `json:"score"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    int       `json:"user_id"`
+	Username  string    `json:"username"`
+	TopicID   int       `json:"topic_id"`
+	TopicName string    `json:"topic_name"`
+}
+
+// From and To are dates (2006-01-02) or RFC 3339 timestamps
+type SearchRequest struct {
+	Query    string `json:"q" schema:"q"`
+	Type     string `json:"type,omitempty" schema:"type"`
+	Sort     string `json:"sort,omitempty" schema:"sort"`
+	TopicID  *int   `json:"topic_id,omitempty" schema:"topic_id"`
+	Author   string `json:"author,omitempty" schema:"author"`
+	From     string `json:"from,omitempty" schema:"from"`
+	To       string `json:"to,omitempty" schema:"to"`
+	Page     int    `json:"page,omitempty" schema:"page"`
+	PageSize int    `json:"page_size,omitempty" schema:"page_size"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+type Session struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	IsCurrent  bool       `json:"is_current"`
+}
+
+type LoginAttempt struct {
+	ID            int       `json:"id"`
+	UserID        *int      `json:"-"`
+	Email         string    `json:"-"`
+	IPAddress     string    `json:"ip_address"`
+	UserAgent     string    `json:"user_agent"`
+	Succeeded     bool      `json:"succeeded"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// An account at an external identity provider, linked to a user for single sign-on
+type UserIdentity struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"-"`
+	Issuer      string    `json:"issuer"`
+	Subject     string    `json:"-"`
+	Email       string    `json:"email,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastLoginAt time.Time `json:"last_login_at"`
+}
+
+// A personal API token, acting as its user within its scopes
+type APIToken struct {
+	ID          int        `json:"id"`
+	UserID      int        `json:"-"`
+	Role        string     `json:"-"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"token_prefix"`
+	Scopes      []string   `json:"scopes"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+// ExpiresInDays 0 creates a token that does not expire
+type CreateAPITokenRequest struct {
+	Name          string   `json:"name" schema:"name"`
+	Scopes        []string `json:"scopes" schema:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days" schema:"expires_in_days"`
+}
@@ -1,12 +1,17 @@
 package models
 
+import "time"
+
 type Topic struct {
-	ID            int    `json:"id"`
-	Name          string `json:"name"`
-	Description   string `json:"description"`
-	NoOfPosts     int    `json:"no_of_posts"`
-	NoOfFollowers int    `json:"no_of_followers"`
-	IsFollowing   bool   `json:"is_following"`
+	ID            int        `json:"id"`
+	Name          string     `json:"name"`
+	Slug          string     `json:"slug"`
+	Description   string     `json:"description"`
+	NoOfPosts     int        `json:"no_of_posts"`
+	NoOfFollowers int        `json:"no_of_followers"`
+	IsFollowing   bool       `json:"is_following"`
+	IsArchived    bool       `json:"is_archived"`
+	ArchivedAt    *time.Time `json:"archived_at,omitempty"`
 }
 
 type UserTopic struct {
@@ -21,8 +26,23 @@ type FollowTopicRequest struct {
 type ListTopicsRequest struct {
 	Page            int    `json:"page,omitempty" schema:"page"`
 	PageSize        int    `json:"page_size,omitempty" schema:"page_size"`
+	Cursor          string `json:"cursor,omitempty" schema:"cursor"`
+	IncludeCount    *bool  `json:"include_count,omitempty" schema:"include_count"`
 	Sort            string `json:"sort,omitempty" schema:"sort"`
 	OrderBy         string `json:"order_by,omitempty" schema:"order_by"`
 	Search          string `json:"search,omitempty" schema:"search"`
 	FilterFollowing bool   `json:"filter_following,omitempty" schema:"filter_following"`
+	ShowArchived    bool   `json:"show_archived,omitempty" schema:"show_archived"`
+}
+
+type CreateTopicRequest struct {
+	Name        string `json:"name" schema:"name"`
+	Description string `json:"description" schema:"description"`
+}
+
+// Nil fields are left unchanged
+type UpdateTopicRequest struct {
+	Name        *string `json:"name,omitempty" schema:"name"`
+	Description *string `json:"description,omitempty" schema:"description"`
+	IsArchived  *bool   `json:"is_archived,omitempty" schema:"is_archived"`
 }
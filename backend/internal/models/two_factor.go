@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// A user's TOTP enrollment, not enabled until a code confirms it
+type TwoFactor struct {
+	UserID       int        `json:"-"`
+	Secret       string     `json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+	EnabledAt    *time.Time `json:"enabled_at"`
+	LastUsedStep *int64     `json:"-"`
+}
+
+// A login whose password was correct, waiting for a two-factor code
+type LoginChallenge struct {
+	ID             int       `json:"-"`
+	UserID         int       `json:"-"`
+	FailedAttempts int       `json:"-"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token" schema:"challenge_token"`
+	Code           string `json:"code" schema:"code"`
+}
+
+type ConfirmTwoFactorRequest struct {
+	Code string `json:"code" schema:"code"`
+}
+
+// Disabling two-factor authentication or regenerating recovery codes needs the password and a current code
+// The code can be a recovery code, so a lost authenticator can still be replaced
+type TwoFactorChangeRequest struct {
+	Password string `json:"password" schema:"password"`
+	Code     string `json:"code" schema:"code"`
+}
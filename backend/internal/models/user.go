@@ -8,7 +8,10 @@ type User struct {
 	Username  string    `json:"username"`
 	Password  string    `json:"password,omitempty"`
 	Karma     int       `json:"karma"`
+	Role      string    `json:"role,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
+
+	EmailVerifiedAt *time.Time `json:"-"`
 }
 
 type RegisterRequest struct {
@@ -23,18 +26,42 @@ type LoginRequest struct {
 }
 
 type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" schema:"current_password"`
+	Password        string `json:"password" schema:"password"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" schema:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" schema:"token"`
 	Password string `json:"password" schema:"password"`
 }
 
+type VerifyEmailRequest struct {
+	Token string `json:"token" schema:"token"`
+}
+
 type UpdateUserRequest struct {
 	Email    string `json:"email,omitempty" schema:"email"`
 	Username string `json:"username,omitempty" schema:"username"`
 }
 
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" schema:"role"`
+}
+
+type TopicModeratorRequest struct {
+	Username string `json:"username" schema:"username"`
+}
+
 type ListUsersRequest struct {
-	Page     int    `json:"page,omitempty" schema:"page"`
-	PageSize int    `json:"page_size,omitempty" schema:"page_size"`
-	Sort     string `json:"sort,omitempty" schema:"sort"`
-	OrderBy  string `json:"order_by,omitempty" schema:"order_by"`
-	Search   string `json:"search,omitempty" schema:"search"`
+	Page         int    `json:"page,omitempty" schema:"page"`
+	PageSize     int    `json:"page_size,omitempty" schema:"page_size"`
+	Cursor       string `json:"cursor,omitempty" schema:"cursor"`
+	IncludeCount *bool  `json:"include_count,omitempty" schema:"include_count"`
+	Sort         string `json:"sort,omitempty" schema:"sort"`
+	OrderBy      string `json:"order_by,omitempty" schema:"order_by"`
+	Search       string `json:"search,omitempty" schema:"search"`
 }
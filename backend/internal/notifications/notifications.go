@@ -0,0 +1,175 @@
+// Package notifications decides who is notified of new posts, comments, pins and moderator warnings
+// Notifying is best effort: failures are logged and never fail the action that caused them
+package notifications
+
+import (
+	"context"
+	"cvwo/internal/constants"
+	"cvwo/internal/dataaccess"
+	"cvwo/internal/events"
+	"cvwo/internal/models"
+	"cvwo/internal/utils"
+	"log"
+)
+
+// Notifier looks up the content and users it notifies about in its stores
+// The content is already saved when it is notified about, so notifying outlives the request's cancellation
+// New notifications are pushed to their recipients' live streams through the broker
+type Notifier struct {
+	stores dataaccess.Stores
+	broker events.Broker
+}
+
+func New(stores dataaccess.Stores, broker events.Broker) *Notifier {
+	return &Notifier{stores: stores, broker: broker}
+}
+
+// CommentCreated notifies the author of the parent comment (or of the post, for top-level comments)
+// and the users mentioned in the comment
+func (n *Notifier) CommentCreated(ctx context.Context, commentID int) {
+	ctx = context.WithoutCancel(ctx)
+	comment, err := n.stores.Comments.GetComment(ctx, false, 0, commentID)
+	if err != nil {
+		log.Printf("notifications: failed to fetch comment %d: %v", commentID, err)
+		return
+	}
+
+	notification := models.Notification{
+		ActorID:   &comment.UserID,
+		PostID:    &comment.PostID,
+		CommentID: &comment.ID,
+		TopicID:   &comment.TopicID,
+	}
+
+	// No one is notified of replies to deleted comments and posts
+	var recipientID int
+	if comment.ParentID != nil {
+		parent, err := n.stores.Comments.GetComment(ctx, false, 0, *comment.ParentID)
+		if err != nil {
+			log.Printf("notifications: failed to fetch comment %d: %v", *comment.ParentID, err)
+			return
+		}
+		notification.Type = constants.NOTIFICATION_COMMENT_REPLY
+		if !parent.IsDeleted {
+			recipientID = parent.UserID
+		}
+	} else {
+		post, err := n.stores.Posts.GetPost(ctx, false, 0, comment.PostID)
+		if err != nil {
+			log.Printf("notifications: failed to fetch post %d: %v", comment.PostID, err)
+			return
+		}
+		notification.Type = constants.NOTIFICATION_POST_REPLY
+		if !post.IsDeleted {
+			recipientID = post.UserID
+		}
+	}
+
+	if recipientID != 0 {
+		n.create(ctx, notification, []int{recipientID})
+	}
+
+	// The replied-to author already knows about the comment
+	notification.Type = constants.NOTIFICATION_MENTION
+	n.create(ctx, notification, withoutID(n.mentionedUserIDs(ctx, comment.Content), recipientID))
+}
+
+// PostCreated notifies the users mentioned in the post and the followers of its topic
+func (n *Notifier) PostCreated(ctx context.Context, postID int) {
+	ctx = context.WithoutCancel(ctx)
+	post, err := n.stores.Posts.GetPost(ctx, false, 0, postID)
+	if err != nil {
+		log.Printf("notifications: failed to fetch post %d: %v", postID, err)
+		return
+	}
+
+	notification := models.Notification{
+		Type:    constants.NOTIFICATION_MENTION,
+		ActorID: &post.UserID,
+		PostID:  &post.ID,
+		TopicID: &post.TopicID,
+	}
+
+	mentionedIDs := n.mentionedUserIDs(ctx, post.Title+"\n"+post.Content)
+	n.create(ctx, notification, mentionedIDs)
+
+	// Mentioned followers are only notified once
+	notification.Type = constants.NOTIFICATION_TOPIC_POST
+	created, err := n.stores.Notifications.CreateTopicFollowerNotifications(ctx, notification, mentionedIDs)
+	if err != nil {
+		log.Printf("notifications: failed to notify followers of topic %d: %v", post.TopicID, err)
+		return
+	}
+	n.publish(created)
+}
+
+// CommentPinned notifies the author of a comment that it was pinned by someone else
+func (n *Notifier) CommentPinned(ctx context.Context, actorID, commentID int) {
+	ctx = context.WithoutCancel(ctx)
+	comment, err := n.stores.Comments.GetComment(ctx, false, 0, commentID)
+	if err != nil {
+		log.Printf("notifications: failed to fetch comment %d: %v", commentID, err)
+		return
+	}
+
+	n.create(ctx, models.Notification{
+		Type:      constants.NOTIFICATION_COMMENT_PINNED,
+		ActorID:   &actorID,
+		PostID:    &comment.PostID,
+		CommentID: &comment.ID,
+		TopicID:   &comment.TopicID,
+	}, []int{comment.UserID})
+}
+
+func (n *Notifier) create(ctx context.Context, notification models.Notification, recipientIDs []int) {
+	created, err := n.stores.Notifications.CreateNotifications(ctx, notification, recipientIDs)
+	if err != nil {
+		log.Printf("notifications: failed to create %s notifications: %v", notification.Type, err)
+		return
+	}
+	n.publish(created)
+}
+
+// Pushes the notifications to their recipients' live streams
+func (n *Notifier) publish(notifications []models.Notification) {
+	for _, notification := range notifications {
+		events.Publish(n.broker, events.UserTopic(notification.UserID), constants.EVENT_NOTIFICATION, notification)
+	}
+}
+
+// Resolves the users mentioned in the text, unknown usernames are ignored
+func (n *Notifier) mentionedUserIDs(ctx context.Context, text string) []int {
+	userIDs, err := n.stores.Users.GetUserIDsByUsernames(ctx, utils.ExtractMentions(text))
+	if err != nil {
+		log.Printf("notifications: failed to resolve mentions: %v", err)
+		return []int{}
+	}
+	return userIDs
+}
+
+func withoutID(ids []int, excluded int) []int {
+	filtered := []int{}
+	for _, id := range ids {
+		if id != excluded {
+			filtered = append(filtered, id)
+		}
+	}
+	return filtered
+}
+
+// ModeratorWarning notifies the author of reported content that a moderator warned them, with the moderator's note
+func (n *Notifier) ModeratorWarning(ctx context.Context, entry models.ModerationLogEntry, topicID int) {
+	ctx = context.WithoutCancel(ctx)
+	if entry.TargetUserID == nil {
+		return
+	}
+
+	n.create(ctx, models.Notification{
+		Type:      constants.NOTIFICATION_MODERATOR_WARNING,
+		ActorID:   entry.ModeratorID,
+		PostID:    entry.PostID,
+		CommentID: entry.CommentID,
+		TopicID:   &topicID,
+		Message:   entry.Note,
+	}, []int{*entry.TargetUserID})
+}
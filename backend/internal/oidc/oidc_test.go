@@ -0,0 +1,193 @@
+package oidc_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"cvwo/internal/oidc"
+	"cvwo/internal/oidc/oidctest"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const redirectURL = "http://localhost:8000/api/auth/oidc/callback"
+
+func newProvider(t *testing.T) (*oidctest.Provider, *oidc.Provider) {
+	t.Helper()
+
+	idp := oidctest.NewServer(t, "cvwo", "secret")
+	provider, err := oidc.NewProvider(context.Background(), oidc.Config{
+		IssuerURL:    idp.Issuer,
+		ClientID:     "cvwo",
+		ClientSecret: "secret",
+		RedirectURL:  redirectURL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return idp, provider
+}
+
+// Logs in at the identity provider and returns the code it redirects back with, checking the state
+func login(t *testing.T, idp *oidctest.Provider, provider *oidc.Provider, state, nonce, codeVerifier string) string {
+	t.Helper()
+
+	callback, err := idp.Login(provider.AuthCodeURL(state, nonce, codeVerifier), url.Values{
+		"sub":            {"subject-1"},
+		"email":          {"sso@example.com"},
+		"email_verified": {"true"},
+		"name":           {"SSO User"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := callback.Scheme + "://" + callback.Host + callback.Path; got != redirectURL {
+		t.Fatalf("redirected to %s, want %s", got, redirectURL)
+	}
+	if got := callback.Query().Get("state"); got != state {
+		t.Fatalf("state %q, want %q", got, state)
+	}
+	return callback.Query().Get("code")
+}
+
+func TestNewProvider(t *testing.T) {
+	idp := oidctest.NewServer(t, "cvwo", "secret")
+	ctx := context.Background()
+
+	if _, err := oidc.NewProvider(ctx, oidc.Config{IssuerURL: idp.Issuer + "/other", ClientID: "cvwo"}); err == nil {
+		t.Error("issuer without a discovery document accepted")
+	}
+
+	// ID tokens are checked against the discovered issuer, so it must be the one configured
+	elsewhere, err := oidctest.New("https://elsewhere.example.com", "cvwo", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(elsewhere)
+	defer server.Close()
+	if _, err := oidc.NewProvider(ctx, oidc.Config{IssuerURL: server.URL, ClientID: "cvwo"}); err == nil {
+		t.Error("discovery document for another issuer accepted")
+	}
+}
+
+func TestExchangeAndVerify(t *testing.T) {
+	idp, provider := newProvider(t)
+	ctx := context.Background()
+
+	code := login(t, idp, provider, "state", "nonce", "verifier")
+	rawIDToken, err := provider.Exchange(ctx, code, "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := provider.VerifyIDToken(ctx, rawIDToken, "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := oidc.Claims{Subject: "subject-1", Email: "sso@example.com", EmailVerified: true, Name: "SSO User"}
+	if *claims != want {
+		t.Errorf("got %+v, want %+v", *claims, want)
+	}
+
+	// Codes are single use
+	if _, err := provider.Exchange(ctx, code, "verifier"); err == nil {
+		t.Error("code exchanged twice")
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	idp, provider := newProvider(t)
+
+	code := login(t, idp, provider, "state", "nonce", "verifier")
+	if _, err := provider.Exchange(context.Background(), code, "another verifier"); err == nil {
+		t.Error("code exchanged with another login's PKCE verifier")
+	}
+}
+
+func TestExchangeUsesContext(t *testing.T) {
+	idp, provider := newProvider(t)
+
+	code := login(t, idp, provider, "state", "nonce", "verifier")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := provider.Exchange(ctx, code, "verifier"); err == nil {
+		t.Error("code exchanged with a canceled context")
+	}
+}
+
+func TestVerifyIDTokenRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		nonce  string
+		tamper func(claims jwt.MapClaims, header map[string]any)
+	}{
+		{"wrong nonce", "another nonce", nil},
+		{"expired", "nonce", func(claims jwt.MapClaims, header map[string]any) {
+			claims["iat"] = time.Now().Add(-time.Hour).Unix()
+			claims["exp"] = time.Now().Add(-10 * time.Minute).Unix()
+		}},
+		{"wrong audience", "nonce", func(claims jwt.MapClaims, header map[string]any) {
+			claims["aud"] = "another-client"
+		}},
+		{"another client among several audiences", "nonce", func(claims jwt.MapClaims, header map[string]any) {
+			claims["aud"] = []string{"cvwo", "another-client"}
+			claims["azp"] = "another-client"
+		}},
+		{"wrong issuer", "nonce", func(claims jwt.MapClaims, header map[string]any) {
+			claims["iss"] = "https://elsewhere.example.com"
+		}},
+		{"wrong key ID", "nonce", func(claims jwt.MapClaims, header map[string]any) {
+			header["kid"] = "another-key"
+		}},
+		{"unsigned", "nonce", func(claims jwt.MapClaims, header map[string]any) {
+			header["alg"] = "none"
+		}},
+		{"no subject", "nonce", func(claims jwt.MapClaims, header map[string]any) {
+			delete(claims, "sub")
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp, provider := newProvider(t)
+			idp.TamperIDTokens(tt.tamper)
+			ctx := context.Background()
+
+			code := login(t, idp, provider, "state", "nonce", "verifier")
+			rawIDToken, err := provider.Exchange(ctx, code, "verifier")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if claims, err := provider.VerifyIDToken(ctx, rawIDToken, tt.nonce); err == nil {
+				t.Errorf("token accepted with claims %+v", *claims)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenRejectsForgedSignature(t *testing.T) {
+	idp, provider := newProvider(t)
+
+	// Signed by another provider's key under the same key ID
+	other := oidctest.NewServer(t, "cvwo", "secret")
+	forged, err := other.SignIDToken(jwt.MapClaims{
+		"iss":   idp.Issuer,
+		"aud":   "cvwo",
+		"sub":   "subject-1",
+		"nonce": "nonce",
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(5 * time.Minute).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := provider.VerifyIDToken(context.Background(), forged, "nonce"); err == nil {
+		t.Error("token signed with another key accepted")
+	}
+}
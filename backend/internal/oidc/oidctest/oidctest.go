@@ -0,0 +1,347 @@
+// Package oidctest is a mock OpenID Connect identity provider, for tests and for trying single sign-on locally
+// Anyone can log in as anyone: the login page asks for the claims to put in the ID token
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cvwo/internal/oidc"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeyID identifies the provider's signing key in its JWKS and ID token headers
+const KeyID = "mock-key"
+
+// A code waiting to be exchanged at the token endpoint
+type authorization struct {
+	clientID      string
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+	claims        jwt.MapClaims
+	expiresAt     time.Time
+}
+
+// Provider serves the discovery document, login page, token endpoint and JWKS of a mock identity provider
+type Provider struct {
+	// Issuer URL, must be the URL clients reach the provider at
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+
+	key *rsa.PrivateKey
+	mux *http.ServeMux
+
+	mu     sync.Mutex
+	codes  map[string]authorization
+	tamper func(claims jwt.MapClaims, header map[string]any)
+}
+
+var loginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Mock identity provider</title></head>
+<body>
+<h1>Mock identity provider</h1>
+<p>Signing in to {{.ClientID}}. Any subject is accepted.</p>
+<form method="post">
+{{range $name, $value := .Params}}<input type="hidden" name="{{$name}}" value="{{$value}}">
+{{end}}
+<p><label>Subject <input name="sub" value="mock-user-1" required></label></p>
+<p><label>Email <input name="email" value="mock.user@example.com"></label></p>
+<p><label><input type="checkbox" name="email_verified" value="true" checked> Email verified</label></p>
+<p><label>Name <input name="name" value="Mock User"></label></p>
+<p><label>Preferred username <input name="preferred_username" value="mock_user"></label></p>
+<p><button type="submit">Sign in</button></p>
+</form>
+</body>
+</html>`))
+
+// Parameters of the authorization request carried through the login page
+var authorizationParams = []string{"client_id", "redirect_uri", "state", "nonce", "code_challenge"}
+
+// New returns a provider with a new signing key, serving the client with the ID and secret
+func New(issuer, clientID, clientSecret string) (*Provider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate signing key: %w", err)
+	}
+
+	p := &Provider{
+		Issuer:       issuer,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		key:          key,
+		mux:          http.NewServeMux(),
+		codes:        map[string]authorization{},
+	}
+
+	p.mux.HandleFunc("GET /.well-known/openid-configuration", p.discovery)
+	p.mux.HandleFunc("GET /authorize", p.authorizePage)
+	p.mux.HandleFunc("POST /authorize", p.authorize)
+	p.mux.HandleFunc("POST /token", p.token)
+	p.mux.HandleFunc("GET /jwks", p.jwks)
+
+	return p, nil
+}
+
+// NewServer starts a provider on a local port for the test, it is closed when the test ends
+func NewServer(t testing.TB, clientID, clientSecret string) *Provider {
+	t.Helper()
+
+	p, err := New("", clientID, clientSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(p)
+	t.Cleanup(server.Close)
+	p.Issuer = server.URL
+	return p
+}
+
+func (p *Provider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mux.ServeHTTP(w, r)
+}
+
+// TamperIDTokens changes the claims and header of the ID tokens issued from now on, before they are signed,
+// so tests can check that invalid tokens are rejected. nil issues valid tokens again
+func (p *Provider) TamperIDTokens(tamper func(claims jwt.MapClaims, header map[string]any)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tamper = tamper
+}
+
+// Login does what the user's browser does on the login page the authorization URL leads to:
+// submits the claims to put in the ID token, and returns the redirect back to the client with the code
+func (p *Provider) Login(authCodeURL string, claims url.Values) (*url.URL, error) {
+	authURL, err := url.Parse(authCodeURL)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	for _, name := range authorizationParams {
+		form.Set(name, authURL.Query().Get(name))
+	}
+	for name, values := range claims {
+		form[name] = values
+	}
+
+	// The redirect is to the client, which the caller follows itself
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.PostForm(strings.TrimSuffix(p.Issuer, "/")+"/authorize", form)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusFound {
+		return nil, fmt.Errorf("POST /authorize: %s", resp.Status)
+	}
+	return resp.Location()
+}
+
+// SignIDToken signs the claims with the provider's key, as the token endpoint does
+func (p *Provider) SignIDToken(claims jwt.MapClaims) (string, error) {
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = KeyID
+	return idToken.SignedString(p.key)
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                p.Issuer,
+		"authorization_endpoint":                p.Issuer + "/authorize",
+		"token_endpoint":                        p.Issuer + "/token",
+		"jwks_uri":                              p.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+// Shows the login form, carrying the authorization request in hidden fields
+func (p *Provider) authorizePage(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != p.ClientID || query.Get("response_type") != "code" {
+		http.Error(w, "unknown client or unsupported response type", http.StatusBadRequest)
+		return
+	}
+	if query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256" {
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+
+	params := map[string]string{}
+	for _, name := range authorizationParams {
+		params[name] = query.Get(name)
+	}
+
+	loginPage.Execute(w, map[string]any{"ClientID": p.ClientID, "Params": params})
+}
+
+// Issues a code for the entered claims and redirects back to the client
+func (p *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	redirectURI, err := url.Parse(r.PostForm.Get("redirect_uri"))
+	if err != nil || redirectURI.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"sub":            r.PostForm.Get("sub"),
+		"email_verified": r.PostForm.Get("email_verified") == "true",
+	}
+	for _, name := range []string{"email", "name", "preferred_username"} {
+		if value := r.PostForm.Get(name); value != "" {
+			claims[name] = value
+		}
+	}
+
+	code := rand.Text()
+	p.mu.Lock()
+	p.codes[code] = authorization{
+		clientID:      r.PostForm.Get("client_id"),
+		redirectURI:   redirectURI.String(),
+		nonce:         r.PostForm.Get("nonce"),
+		codeChallenge: r.PostForm.Get("code_challenge"),
+		claims:        claims,
+		expiresAt:     time.Now().Add(time.Minute),
+	}
+	p.mu.Unlock()
+
+	query := redirectURI.Query()
+	query.Set("code", code)
+	query.Set("state", r.PostForm.Get("state"))
+	redirectURI.RawQuery = query.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+// Exchanges a code for an ID token, checking the client secret, redirect URI and PKCE verifier
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeTokenError(w, "invalid_request", err.Error())
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != p.ClientID || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(p.ClientSecret)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		writeTokenError(w, "unsupported_grant_type", "")
+		return
+	}
+
+	// Codes are single use
+	code := r.PostForm.Get("code")
+	p.mu.Lock()
+	auth, found := p.codes[code]
+	delete(p.codes, code)
+	tamper := p.tamper
+	p.mu.Unlock()
+
+	if !found || time.Now().After(auth.expiresAt) || auth.clientID != clientID {
+		writeTokenError(w, "invalid_grant", "unknown or expired code")
+		return
+	}
+	if r.PostForm.Get("redirect_uri") != auth.redirectURI {
+		writeTokenError(w, "invalid_grant", "redirect_uri does not match")
+		return
+	}
+	if oidc.CodeChallenge(r.PostForm.Get("code_verifier")) != auth.codeChallenge {
+		writeTokenError(w, "invalid_grant", "code_verifier does not match")
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss": p.Issuer,
+		"aud": p.ClientID,
+		"iat": now.Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+	}
+	if auth.nonce != "" {
+		claims["nonce"] = auth.nonce
+	}
+	for name, value := range auth.claims {
+		claims[name] = value
+	}
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = KeyID
+	if tamper != nil {
+		tamper(claims, idToken.Header)
+	}
+
+	signed, err := idToken.SignedString(p.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": rand.Text(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     signed,
+	})
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, r *http.Request) {
+	publicKey := p.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": KeyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+		}},
+	})
+}
+
+func writeTokenError(w http.ResponseWriter, code, description string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code, "error_description": description})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
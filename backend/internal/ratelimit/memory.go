@@ -0,0 +1,50 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps buckets in process, so each server replica limits clients on its own
+type MemoryStore struct {
+	mu          sync.Mutex
+	buckets     map[string]*bucket
+	lastCleanup time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: map[string]*bucket{},
+	}
+}
+
+func (s *MemoryStore) Take(key string, limit Limit, now time.Time) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cleanup(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		full := newBucket(limit, now)
+		b = &full
+		s.buckets[key] = b
+	}
+
+	allowed, retryAfter := b.take(limit, now)
+	return allowed, retryAfter, nil
+}
+
+// Drops idle buckets at most once per TTL, so memory does not grow with every client ever seen
+func (s *MemoryStore) cleanup(now time.Time) {
+	if now.Sub(s.lastCleanup) < bucketTTL {
+		return
+	}
+	s.lastCleanup = now
+
+	for key, b := range s.buckets {
+		if now.Sub(b.updatedAt) >= bucketTTL {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// PostgresStore keeps buckets in PostgreSQL, so limits are shared by every server replica
+type PostgresStore struct {
+	db   *sql.DB
+	done chan struct{}
+}
+
+// NewPostgresStore uses the rate_limit_buckets table and periodically deletes idle buckets
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	s := &PostgresStore{
+		db:   db,
+		done: make(chan struct{}),
+	}
+	go s.run()
+
+	return s
+}
+
+func (s *PostgresStore) run() {
+	ticker := time.NewTicker(bucketTTL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case now := <-ticker.C:
+			query := `
+				DELETE FROM rate_limit_buckets
+				WHERE updated_at < $1`
+
+			if _, err := s.db.Exec(query, now.Add(-bucketTTL)); err != nil {
+				log.Printf("ratelimit: failed to delete idle buckets: %v", err)
+			}
+		}
+	}
+}
+
+// The bucket row is locked for the duration of the transaction, so concurrent requests take tokens one at a time
+func (s *PostgresStore) Take(key string, limit Limit, now time.Time) (bool, time.Duration, error) {
+	// TIMESTAMP drops the zone, so times are stored and read back as UTC
+	now = now.UTC()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, 0, err
+	}
+	defer tx.Rollback()
+
+	full := newBucket(limit, now)
+
+	insertQuery := `
+		INSERT INTO rate_limit_buckets (key, tokens, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO NOTHING`
+
+	if _, err := tx.Exec(insertQuery, key, full.tokens, full.updatedAt); err != nil {
+		return false, 0, err
+	}
+
+	selectQuery := `
+		SELECT tokens, updated_at
+		FROM rate_limit_buckets
+		WHERE key = $1
+		FOR UPDATE`
+
+	var b bucket
+	if err := tx.QueryRow(selectQuery, key).Scan(&b.tokens, &b.updatedAt); err != nil {
+		return false, 0, err
+	}
+
+	allowed, retryAfter := b.take(limit, now)
+
+	updateQuery := `
+		UPDATE rate_limit_buckets SET
+			tokens = $2,
+			updated_at = $3
+		WHERE key = $1`
+
+	if _, err := tx.Exec(updateQuery, key, b.tokens, b.updatedAt); err != nil {
+		return false, 0, err
+	}
+
+	return allowed, retryAfter, tx.Commit()
+}
+
+func (s *PostgresStore) Close() error {
+	close(s.done)
+	return nil
+}
@@ -0,0 +1,68 @@
+// Package ratelimit throttles clients with token buckets, one bucket per client and route group
+// Buckets are kept in a Store, in-process or shared by replicas through PostgreSQL
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// Limit allows Burst requests at once, refilled at Requests per Per
+type Limit struct {
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+// PerMinute allows requests per minute, with bursts of up to burst requests
+func PerMinute(requests, burst int) Limit {
+	return Limit{Requests: requests, Per: time.Minute, Burst: burst}
+}
+
+// PerHour allows requests per hour, with bursts of up to burst requests
+func PerHour(requests, burst int) Limit {
+	return Limit{Requests: requests, Per: time.Hour, Burst: burst}
+}
+
+// Tokens added to a bucket per second
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// Store keeps token buckets by key
+type Store interface {
+	// Take removes a token from the key's bucket
+	// If the bucket is empty, it returns false and how long until a token is available
+	Take(key string, limit Limit, now time.Time) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// Buckets untouched for this long are full again and can be dropped
+// Holds for every limit that refills within an hour, which all of ours do
+const bucketTTL = time.Hour
+
+// A token bucket, missing buckets are full
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+func newBucket(limit Limit, now time.Time) bucket {
+	return bucket{tokens: float64(limit.Burst), updatedAt: now}
+}
+
+// Refills the bucket for the time since it was last updated and takes a token if there is one
+func (b *bucket) take(limit Limit, now time.Time) (bool, time.Duration) {
+	elapsed := now.Sub(b.updatedAt).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.rate())
+		b.updatedAt = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	missing := 1 - b.tokens
+	return false, time.Duration(math.Ceil(missing / limit.rate() * float64(time.Second)))
+}
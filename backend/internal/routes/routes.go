@@ -1,68 +1,211 @@
 package routes
 
 import (
+	"cvwo/internal/config"
+	"cvwo/internal/constants"
 	"cvwo/internal/handlers"
+	"cvwo/internal/ratelimit"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
-func GetRoutes() func(r chi.Router) {
+// Rate limits of the throttled route groups, per client IP or signed in user
+var (
+	loginRateLimit    = ratelimit.PerMinute(5, 10)
+	registerRateLimit = ratelimit.PerHour(10, 5)
+	postRateLimit     = ratelimit.PerHour(20, 5)
+	commentRateLimit  = ratelimit.PerMinute(6, 10)
+	voteRateLimit     = ratelimit.PerMinute(60, 30)
+	emailRateLimit    = ratelimit.PerHour(5, 3)
+)
+
+// Queries are cancelled after the configured statement timeouts
+func GetRoutes(s *handlers.Server, cfg config.ServerConfig) func(r chi.Router) {
 	return func(r chi.Router) {
+		// Request IDs come first, so the log lines, metrics and spans of a request all have it
+		r.Use(middleware.RequestID)
+		r.Use(handlers.TelemetryMiddleware)
+
 		// Use standard middleware
 		r.Use(middleware.Logger)
 		r.Use(middleware.Recoverer)
+		r.Use(handlers.StatementTimeoutMiddleware(cfg.StatementTimeout))
+
+		longQueries := handlers.StatementTimeoutMiddleware(cfg.LongStatementTimeout)
+		// Event streams stay open, so they have no deadline
+		noDeadline := handlers.StatementTimeoutMiddleware(0)
+
+		// Unknown routes get the same JSON errors as handlers
+		r.NotFound(handlers.NotFound)
+		r.MethodNotAllowed(handlers.MethodNotAllowed)
 
-		r.Post("/register", handlers.Register)
-		r.Post("/login", handlers.Login)
-		r.Post("/logout", handlers.Logout)
+		r.With(s.RateLimitMiddleware("register", registerRateLimit)).Post("/register", s.Register)
+		r.With(s.RateLimitMiddleware("login", loginRateLimit)).Post("/login", s.Login)
+		r.With(s.RateLimitMiddleware("login_2fa", loginRateLimit)).Post("/login/2fa", s.LoginTwoFactor)
+		r.Post("/logout", s.Logout)
+		r.Post("/refresh", s.Refresh)
 
-		r.Get("/topics-summary", handlers.ListTopicsSummary)
-		r.Get("/users", handlers.ListUsers)
+		// Single sign-on with the identity provider, if one is configured
+		r.Get("/auth/oidc", s.GetSSOConfig)
+		r.With(s.RateLimitMiddleware("oidc_login", loginRateLimit)).Get("/auth/oidc/login", s.StartOIDCLogin)
+		r.With(s.RateLimitMiddleware("oidc_callback", loginRateLimit)).Get("/auth/oidc/callback", s.OIDCCallback)
+
+		r.Post("/verify-email", s.VerifyEmail)
+		r.Post("/reset-password", s.ResetPassword)
+		r.With(s.RateLimitMiddleware("forgot-password", emailRateLimit)).Post("/forgot-password", s.ForgotPassword)
+
+		r.Get("/topics-summary", s.ListTopicsSummary)
+		r.Get("/users", s.ListUsers)
+		r.With(longQueries).Get("/search", s.Search)
 
 		// Can serve both authenticated and non-authenticated users
 		// But authenticated users might get different responses
+		// API tokens without the read scope are served the anonymous response
 		r.Group(func(r chi.Router) {
-			r.Use(handlers.OptionalAuthMiddleware)
+			r.Use(s.OptionalAuthMiddleware)
+			r.Use(handlers.OptionalScopeMiddleware(constants.API_TOKEN_SCOPE_READ))
 
 			// Will get user's email if authenticated and username matches
-			r.Get("/users/{username}", handlers.GetProfile)
+			r.Get("/users/{username}", s.GetProfile)
 
 			// Will get user's follow status if authenticated
-			r.Get("/topics", handlers.ListTopics)
-			r.Get("/topics/{topic_slug}", handlers.GetTopic)
+			r.Get("/topics", s.ListTopics)
+			r.Get("/topics/{topic_slug}", s.GetTopic)
+			r.Get("/topics/{topic_slug}/moderators", s.ListTopicModerators)
 
 			// Will get user's upvote status if authenticated
-			r.Get("/posts", handlers.ListPosts)
-			r.Get("/posts/{id}", handlers.GetPost)
+			r.Get("/posts", s.ListPosts)
+			r.Get("/posts/{id}", s.GetPost)
+			r.With(noDeadline).Get("/posts/{id}/events", s.PostEvents)
+			r.Get("/posts/{id}/revisions", s.ListPostRevisions)
 
 			// Will get user's upvote status if authenticated
-			r.Get("/comments", handlers.ListComments)
-			r.Get("/comments/{id}", handlers.GetComment)
+			r.With(longQueries).Get("/comments", s.ListComments)
+			r.Get("/comments/{id}", s.GetComment)
+			r.Get("/comments/{id}/revisions", s.ListCommentRevisions)
 		})
 
 		// Require authentication (will return 401 if not authenticated)
+		// API tokens can only be used on routes that require one of their scopes
 		r.Group(func(r chi.Router) {
-			r.Use(handlers.OptionalAuthMiddleware)
+			r.Use(s.OptionalAuthMiddleware)
 			r.Use(handlers.RequireAuthMiddleware)
 
-			r.Get("/me", handlers.GetUserAuthData)
+			readScope := handlers.RequireScopeMiddleware(constants.API_TOKEN_SCOPE_READ)
+			postWriteScope := handlers.RequireScopeMiddleware(constants.API_TOKEN_SCOPE_POST_WRITE)
+
+			r.With(readScope).Get("/me", s.GetUserAuthData)
+			r.With(readScope, noDeadline).Get("/me/events", s.MyEvents)
+			r.Get("/me/security", s.GetSecurityLog)
+			r.With(s.RateLimitMiddleware("verify-email", emailRateLimit)).Post("/me/verify-email", s.ResendVerificationEmail)
+			r.Get("/me/sessions", s.ListSessions)
+			r.Get("/me/2fa", s.GetTwoFactorStatus)
+			r.Post("/me/2fa/setup", s.SetupTwoFactor)
+			r.Post("/me/2fa/confirm", s.ConfirmTwoFactor)
+			r.Post("/me/2fa/disable", s.DisableTwoFactor)
+			r.Post("/me/2fa/recovery-codes", s.RegenerateRecoveryCodes)
+			r.Delete("/me/sessions/{id}", s.RevokeSession)
+			r.Post("/logout-all", s.LogoutAllSessions)
+
+			// Links an identity provider account to the signed in user, from a session rather than an API token
+			r.Get("/auth/oidc/link", s.StartOIDCLink)
+
+			r.Get("/me/api-tokens", s.ListAPITokens)
+			r.Post("/me/api-tokens", s.CreateAPIToken)
+			r.Delete("/me/api-tokens/{id}", s.RevokeAPIToken)
+
+			r.With(readScope).Get("/notifications", s.ListNotifications)
+			r.With(readScope).Get("/notifications/unread-count", s.GetUnreadNotificationCount)
+			r.Post("/notifications/read", s.MarkNotificationsRead)
+			r.Get("/notifications/preferences", s.GetNotificationPreferences)
+			r.Put("/notifications/preferences", s.UpdateNotificationPreferences)
+
+			r.Post("/change-password", s.ChangePassword)
+			r.Put("/profile", s.UpdateProfile)
+
+			r.Post("/topics/{topic_slug}/follow", s.FollowTopic)
+
+			// Bookmarks, listed most recently saved first
+			r.With(readScope).Get("/me/saved", s.ListSavedPosts)
+			r.With(readScope, longQueries).Get("/me/saved/comments", s.ListSavedComments)
+			r.Post("/posts/{id}/save", s.SavePost)
+			r.Delete("/posts/{id}/save", s.UnsavePost)
+			r.Post("/comments/{id}/save", s.SaveComment)
+			r.Delete("/comments/{id}/save", s.UnsaveComment)
+
+			r.With(postWriteScope).Delete("/posts/{id}", s.DeletePost)
+			r.With(postWriteScope).Delete("/comments/{id}", s.DeleteComment)
+
+			// Queue of reported content, scoped to the topics the user moderates
+			r.Get("/moderation/reports", s.ListReports)
+
+			// Reject suspended users (will return 403 if suspended)
+			r.Group(func(r chi.Router) {
+				r.Use(s.RequireActiveAccountMiddleware)
+
+				r.With(postWriteScope).Put("/posts/{id}", s.UpdatePost)
+				r.Post("/posts/{id}/pin-comment", s.PinComment)
+				r.Post("/posts/{id}/report", s.ReportPost)
+
+				r.With(postWriteScope).Put("/comments/{id}", s.UpdateComment)
+				r.Post("/comments/{id}/report", s.ReportComment)
+
+				r.With(postWriteScope, s.RateLimitMiddleware("posts", postRateLimit)).Post("/posts", s.CreatePost)
+				r.With(postWriteScope, s.RateLimitMiddleware("comments", commentRateLimit)).Post("/comments", s.CreateComment)
+
+				// Votes on posts and comments share one bucket
+				r.Group(func(r chi.Router) {
+					r.Use(handlers.RequireScopeMiddleware(constants.API_TOKEN_SCOPE_VOTE))
+					r.Use(s.RateLimitMiddleware("votes", voteRateLimit))
+
+					r.Post("/posts/{id}/vote", s.VotePost)
+					r.Post("/comments/{id}/vote", s.VoteComment)
+				})
+			})
+
+			// Require moderation rights over the post's topic (will return 403 if not a moderator)
+			r.Group(func(r chi.Router) {
+				r.Use(s.RequireTopicModeratorMiddleware(s.TopicFromPostParam))
+
+				r.Post("/posts/{id}/lock", s.LockPost)
+				r.Post("/posts/{id}/pin", s.PinPost)
+				r.Post("/posts/{id}/revisions/{revision}/restore", s.RestorePostRevision)
+				r.Get("/posts/{id}/reports", s.ListPostReports)
+				r.Post("/posts/{id}/reports/resolve", s.ResolvePostReports)
+			})
+
+			// Require moderation rights over the comment's topic (will return 403 if not a moderator)
+			r.Group(func(r chi.Router) {
+				r.Use(s.RequireTopicModeratorMiddleware(s.TopicFromCommentParam))
+
+				r.Post("/comments/{id}/revisions/{revision}/restore", s.RestoreCommentRevision)
+				r.Get("/comments/{id}/reports", s.ListCommentReports)
+				r.Post("/comments/{id}/reports/resolve", s.ResolveCommentReports)
+			})
+
+			// Require a site-wide moderation role (will return 403 if not an admin or moderator)
+			r.Group(func(r chi.Router) {
+				r.Use(handlers.RequireRoleMiddleware(constants.ROLE_ADMIN, constants.ROLE_MODERATOR))
+
+				r.Get("/moderation/log", s.ListModerationLog)
+			})
+		})
 
-			r.Post("/change-password", handlers.ChangePassword)
-			r.Put("/profile", handlers.UpdateProfile)
+		// Require admin rights (will return 403 if not an admin)
+		r.Group(func(r chi.Router) {
+			r.Use(s.OptionalAuthMiddleware)
+			r.Use(handlers.RequireAuthMiddleware)
+			r.Use(handlers.RequireRoleMiddleware(constants.ROLE_ADMIN))
 
-			r.Post("/topics/{topic_slug}/follow", handlers.FollowTopic)
+			r.Post("/topics", s.CreateTopic)
+			r.Put("/topics/{topic_slug}", s.UpdateTopic)
+			r.Delete("/topics/{topic_slug}", s.DeleteTopic)
 
-			r.Post("/posts", handlers.CreatePost)
-			r.Put("/posts/{id}", handlers.UpdatePost)
-			r.Delete("/posts/{id}", handlers.DeletePost)
-			r.Post("/posts/{id}/vote", handlers.VotePost)
-			r.Post("/posts/{id}/pin-comment", handlers.PinComment)
+			r.Post("/topics/{topic_slug}/moderators", s.AddTopicModerator)
+			r.Delete("/topics/{topic_slug}/moderators/{username}", s.RemoveTopicModerator)
 
-			r.Post("/comments", handlers.CreateComment)
-			r.Put("/comments/{id}", handlers.UpdateComment)
-			r.Delete("/comments/{id}", handlers.DeleteComment)
-			r.Post("/comments/{id}/vote", handlers.VoteComment)
+			r.Put("/users/{username}/role", s.UpdateUserRole)
 		})
 	}
 }
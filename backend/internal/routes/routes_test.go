@@ -0,0 +1,684 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"cvwo/internal/config"
+	"cvwo/internal/constants"
+	"cvwo/internal/dataaccess"
+	"cvwo/internal/events"
+	"cvwo/internal/handlers"
+	"cvwo/internal/mail"
+	"cvwo/internal/models"
+	"cvwo/internal/oidc"
+	"cvwo/internal/oidc/oidctest"
+	"cvwo/internal/ratelimit"
+	"cvwo/internal/utils"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Hands the emails the server sends to the test
+type recordingMailer struct {
+	sent chan mail.Message
+}
+
+func (m recordingMailer) Send(msg mail.Message) error {
+	m.sent <- msg
+	return nil
+}
+
+// A client of the API, served in-process on the memory stores, that keeps its session cookies
+// Requests carry the API token as Authorization: Bearer when it is set
+type testClient struct {
+	t        *testing.T
+	handler  http.Handler
+	cookies  map[string]*http.Cookie
+	apiToken string
+}
+
+func newTestAPI(t *testing.T) (dataaccess.Stores, chan mail.Message, func() *testClient) {
+	t.Helper()
+	return newTestAPIWithSSO(t, nil)
+}
+
+// Like newTestAPI, with single sign-on through the provider
+func newTestAPIWithSSO(t *testing.T, sso *oidc.Provider) (dataaccess.Stores, chan mail.Message, func() *testClient) {
+	t.Helper()
+
+	sent := make(chan mail.Message, 10)
+	stores := dataaccess.NewMemoryStores()
+	server := handlers.NewServer(stores, handlers.Options{
+		Mailer:     recordingMailer{sent: sent},
+		Broker:     events.NewMemoryBroker(),
+		RateLimits: ratelimit.NewMemoryStore(),
+		SSO:        sso,
+		JWTSecret:  "test secret",
+		Limits:     config.DefaultLimits(),
+		AppBaseURL: "http://localhost:3000",
+	})
+
+	r := chi.NewRouter()
+	r.Route("/api", GetRoutes(server, config.ServerConfig{
+		StatementTimeout:     5 * time.Second,
+		LongStatementTimeout: 15 * time.Second,
+	}))
+
+	return stores, sent, func() *testClient {
+		return &testClient{t: t, handler: r, cookies: map[string]*http.Cookie{}}
+	}
+}
+
+// Sends the request with the client's cookies, decodes the JSON response into out unless it is nil
+// Fails the test unless the response has the wanted status
+func (c *testClient) do(method, path string, body any, wantStatus int, out any) {
+	c.t.Helper()
+
+	rec := c.send(method, path, body)
+	if rec.Code != wantStatus {
+		c.t.Fatalf("%s %s: got status %d, want %d: %s", method, path, rec.Code, wantStatus, rec.Body.String())
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			c.t.Fatalf("%s %s: decoding %q: %v", method, path, rec.Body.String(), err)
+		}
+	}
+}
+
+// Follows a redirect to the API as the browser would, and returns where the API redirects to in turn
+func (c *testClient) redirect(location string) *url.URL {
+	c.t.Helper()
+
+	rec := c.send(http.MethodGet, location, nil)
+	if rec.Code != http.StatusFound {
+		c.t.Fatalf("GET %s: got status %d, want %d: %s", location, rec.Code, http.StatusFound, rec.Body.String())
+	}
+
+	next, err := rec.Result().Location()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return next
+}
+
+// Sends the request with the client's cookies, keeping the cookies the response sets
+func (c *testClient) send(method, path string, body any) *httptest.ResponseRecorder {
+	c.t.Helper()
+
+	var reader bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reader).Encode(body); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+
+	req := httptest.NewRequest(method, path, &reader)
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiToken)
+	}
+	for _, cookie := range c.cookies {
+		req.AddCookie(cookie)
+	}
+
+	rec := httptest.NewRecorder()
+	c.handler.ServeHTTP(rec, req)
+
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.MaxAge < 0 || cookie.Value == "" {
+			delete(c.cookies, cookie.Name)
+		} else {
+			c.cookies[cookie.Name] = cookie
+		}
+	}
+	return rec
+}
+
+var tokenLink = regexp.MustCompile(`\?token=(\S+)`)
+
+// Waits for the next email and returns the token in its link
+func receiveToken(t *testing.T, sent chan mail.Message, to string) string {
+	t.Helper()
+
+	select {
+	case msg := <-sent:
+		if msg.To != to {
+			t.Fatalf("email sent to %s, want %s", msg.To, to)
+		}
+		match := tokenLink.FindStringSubmatch(msg.Body)
+		if match == nil {
+			t.Fatalf("no token link in email: %q", msg.Body)
+		}
+		token, err := url.QueryUnescape(match[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no email sent to %s", to)
+	}
+	return ""
+}
+
+// Registers the user with their username at example.com and logs the client in as them
+func signUp(t *testing.T, sent chan mail.Message, c *testClient, username string) {
+	t.Helper()
+
+	register := models.RegisterRequest{Email: username + "@example.com", Username: username, Password: username + "'s password"}
+	c.do(http.MethodPost, "/api/register", register, http.StatusCreated, nil)
+	receiveToken(t, sent, register.Email)
+	c.do(http.MethodPost, "/api/login", models.LoginRequest{Email: register.Email, Password: register.Password}, http.StatusOK, nil)
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	_, sent, newClient := newTestAPI(t)
+	c := newClient()
+
+	register := models.RegisterRequest{Email: "alice@example.com", Username: "alice", Password: "correct horse"}
+	c.do(http.MethodPost, "/api/register", register, http.StatusCreated, nil)
+	c.do(http.MethodPost, "/api/register", register, http.StatusConflict, nil)
+
+	c.do(http.MethodPost, "/api/verify-email", models.VerifyEmailRequest{Token: receiveToken(t, sent, register.Email)}, http.StatusOK, nil)
+
+	c.do(http.MethodGet, "/api/me", nil, http.StatusUnauthorized, nil)
+	c.do(http.MethodPost, "/api/login", models.LoginRequest{Email: register.Email, Password: "wrong password"}, http.StatusUnauthorized, nil)
+	c.do(http.MethodPost, "/api/login", models.LoginRequest{Email: register.Email, Password: register.Password}, http.StatusOK, nil)
+
+	var me struct {
+		Username      string `json:"username"`
+		EmailVerified bool   `json:"email_verified"`
+	}
+	c.do(http.MethodGet, "/api/me", nil, http.StatusOK, &me)
+	if me.Username != register.Username || !me.EmailVerified {
+		t.Fatalf("got /me %+v, want verified %s", me, register.Username)
+	}
+
+	// The failed attempt and the login are in the security log
+	var security struct {
+		Logins []models.LoginAttempt `json:"logins"`
+	}
+	c.do(http.MethodGet, "/api/me/security", nil, http.StatusOK, &security)
+	if len(security.Logins) != 2 || !security.Logins[0].Succeeded || security.Logins[1].Succeeded {
+		t.Fatalf("got login attempts %+v, want the login after a failure", security.Logins)
+	}
+
+	c.do(http.MethodPost, "/api/refresh", nil, http.StatusOK, nil)
+	c.do(http.MethodGet, "/api/me", nil, http.StatusOK, nil)
+
+	c.do(http.MethodPost, "/api/logout", nil, http.StatusOK, nil)
+	c.do(http.MethodGet, "/api/me", nil, http.StatusUnauthorized, nil)
+}
+
+func TestResetPasswordRevokesSessions(t *testing.T) {
+	_, sent, newClient := newTestAPI(t)
+	c := newClient()
+
+	register := models.RegisterRequest{Email: "bob@example.com", Username: "bob", Password: "old password"}
+	c.do(http.MethodPost, "/api/register", register, http.StatusCreated, nil)
+	receiveToken(t, sent, register.Email)
+	c.do(http.MethodPost, "/api/login", models.LoginRequest{Email: register.Email, Password: register.Password}, http.StatusOK, nil)
+
+	other := newClient()
+	other.do(http.MethodPost, "/api/forgot-password", models.ForgotPasswordRequest{Email: register.Email}, http.StatusOK, nil)
+	token := receiveToken(t, sent, register.Email)
+	other.do(http.MethodPost, "/api/reset-password", models.ResetPasswordRequest{Token: token, Password: "new password"}, http.StatusOK, nil)
+	other.do(http.MethodPost, "/api/reset-password", models.ResetPasswordRequest{Token: token, Password: "newer password"}, http.StatusBadRequest, nil)
+
+	c.do(http.MethodGet, "/api/me", nil, http.StatusUnauthorized, nil)
+	c.do(http.MethodPost, "/api/login", models.LoginRequest{Email: register.Email, Password: register.Password}, http.StatusUnauthorized, nil)
+	c.do(http.MethodPost, "/api/login", models.LoginRequest{Email: register.Email, Password: "new password"}, http.StatusOK, nil)
+}
+
+func TestPostAndCommentCRUD(t *testing.T) {
+	stores, sent, newClient := newTestAPI(t)
+	topicID, err := stores.Topics.CreateTopic(context.Background(), models.Topic{Name: "General", Slug: "general"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	author := newClient()
+	register := models.RegisterRequest{Email: "carol@example.com", Username: "carol", Password: "carol's password"}
+	author.do(http.MethodPost, "/api/register", register, http.StatusCreated, nil)
+	receiveToken(t, sent, register.Email)
+	author.do(http.MethodPost, "/api/login", models.LoginRequest{Email: register.Email, Password: register.Password}, http.StatusOK, nil)
+
+	anonymous := newClient()
+	newPost := models.CreatePostRequest{TopicID: topicID, Title: "Hello", Content: "First post"}
+	anonymous.do(http.MethodPost, "/api/posts", newPost, http.StatusUnauthorized, nil)
+
+	var created struct {
+		PostID int `json:"post_id"`
+	}
+	author.do(http.MethodPost, "/api/posts", newPost, http.StatusCreated, &created)
+	postPath := fmt.Sprintf("/api/posts/%d", created.PostID)
+
+	author.do(http.MethodPut, postPath, models.UpdatePostRequest{Title: "Hello again", Content: "Edited post"}, http.StatusOK, nil)
+
+	var post models.Post
+	anonymous.do(http.MethodGet, postPath, nil, http.StatusOK, &post)
+	if post.Title != "Hello again" || post.Content != "Edited post" || post.Username != register.Username {
+		t.Fatalf("got post %+v, want the edited post of %s", post, register.Username)
+	}
+
+	var postRevisions struct {
+		Count int `json:"count"`
+	}
+	anonymous.do(http.MethodGet, postPath+"/revisions", nil, http.StatusOK, &postRevisions)
+	if postRevisions.Count != 2 {
+		t.Fatalf("got %d post revisions, want 2", postRevisions.Count)
+	}
+
+	var comment struct {
+		CommentID int `json:"comment_id"`
+	}
+	author.do(http.MethodPost, "/api/comments", models.CreateCommentRequest{PostID: created.PostID, Content: "First comment"}, http.StatusCreated, &comment)
+	commentPath := fmt.Sprintf("/api/comments/%d", comment.CommentID)
+	author.do(http.MethodPut, commentPath, models.UpdateCommentRequest{Content: "Edited comment"}, http.StatusOK, nil)
+
+	var comments struct {
+		Comments []models.Comment `json:"comments"`
+	}
+	anonymous.do(http.MethodGet, fmt.Sprintf("/api/comments?post_id=%d", created.PostID), nil, http.StatusOK, &comments)
+	if len(comments.Comments) != 1 || comments.Comments[0].Summary != "Edited comment" {
+		t.Fatalf("got comments %+v, want the edited comment", comments.Comments)
+	}
+
+	// Only the author may delete their content
+	other := newClient()
+	other.do(http.MethodPost, "/api/register", models.RegisterRequest{Email: "dave@example.com", Username: "dave", Password: "dave's password"}, http.StatusCreated, nil)
+	receiveToken(t, sent, "dave@example.com")
+	other.do(http.MethodPost, "/api/login", models.LoginRequest{Email: "dave@example.com", Password: "dave's password"}, http.StatusOK, nil)
+	other.do(http.MethodDelete, commentPath, nil, http.StatusForbidden, nil)
+	other.do(http.MethodDelete, postPath, nil, http.StatusForbidden, nil)
+
+	author.do(http.MethodDelete, commentPath, nil, http.StatusOK, nil)
+	author.do(http.MethodDelete, postPath, nil, http.StatusOK, nil)
+
+	anonymous.do(http.MethodGet, postPath, nil, http.StatusOK, &post)
+	if !post.IsDeleted || post.Title != "" {
+		t.Fatalf("got post %+v, want a deleted post", post)
+	}
+}
+
+func TestRateLimitBucketsAreSeparate(t *testing.T) {
+	_, _, newClient := newTestAPI(t)
+	c := newClient()
+
+	for range loginRateLimit.Burst {
+		c.do(http.MethodPost, "/api/login/2fa", models.LoginTwoFactorRequest{}, http.StatusBadRequest, nil)
+	}
+	c.do(http.MethodPost, "/api/login/2fa", models.LoginTwoFactorRequest{}, http.StatusTooManyRequests, nil)
+
+	// Logging in with a password is throttled apart from the second step
+	c.do(http.MethodPost, "/api/login", models.LoginRequest{}, http.StatusBadRequest, nil)
+}
+
+func TestExpiredAccessTokenOnPublicRoutes(t *testing.T) {
+	_, sent, newClient := newTestAPI(t)
+	c := newClient()
+	signUp(t, sent, c, "erin")
+
+	// The browser drops the access cookie once it expires, the refresh cookie outlives it
+	delete(c.cookies, constants.ACCESS_TOKEN_COOKIE)
+	var expired models.ErrorResponse
+	c.do(http.MethodGet, "/api/topics", nil, http.StatusUnauthorized, &expired)
+	if expired.Code != constants.ERROR_CODE_ACCESS_TOKEN_EXPIRED {
+		t.Fatalf("got error %q, want %q", expired.Code, constants.ERROR_CODE_ACCESS_TOKEN_EXPIRED)
+	}
+
+	c.do(http.MethodPost, "/api/refresh", nil, http.StatusOK, nil)
+	c.do(http.MethodGet, "/api/topics", nil, http.StatusOK, nil)
+
+	// Once the session is gone, the failed refresh clears the cookies and the route is public again
+	other := newClient()
+	other.cookies = maps.Clone(c.cookies)
+	c.do(http.MethodPost, "/api/logout", nil, http.StatusOK, nil)
+	other.do(http.MethodGet, "/api/topics", nil, http.StatusUnauthorized, nil)
+	other.do(http.MethodPost, "/api/refresh", nil, http.StatusUnauthorized, nil)
+	other.do(http.MethodGet, "/api/topics", nil, http.StatusOK, nil)
+
+	newClient().do(http.MethodGet, "/api/topics", nil, http.StatusOK, nil)
+}
+
+func TestSuspendRequiresHigherRole(t *testing.T) {
+	stores, sent, newClient := newTestAPI(t)
+	ctx := context.Background()
+	topicID, err := stores.Topics.CreateTopic(ctx, models.Topic{Name: "General", Slug: "general"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clients := map[string]*testClient{}
+	for username, role := range map[string]string{
+		"frank": constants.ROLE_MODERATOR,
+		"grace": constants.ROLE_MODERATOR,
+		"heidi": constants.ROLE_ADMIN,
+		"ivan":  constants.ROLE_MEMBER,
+	} {
+		clients[username] = newClient()
+		signUp(t, sent, clients[username], username)
+		user, err := stores.Users.GetUserByUsername(ctx, username)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := stores.Users.UpdateUserRole(ctx, user.ID, role); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Posts a post as the author and has the reporter report it, returns the path to resolve its reports
+	reportedPost := func(author, reporter string) string {
+		var created struct {
+			PostID int `json:"post_id"`
+		}
+		post := models.CreatePostRequest{TopicID: topicID, Title: "Post by " + author, Content: "Content"}
+		clients[author].do(http.MethodPost, "/api/posts", post, http.StatusCreated, &created)
+		report := models.CreateReportRequest{Reason: constants.REPORT_REASON_SPAM}
+		clients[reporter].do(http.MethodPost, fmt.Sprintf("/api/posts/%d/report", created.PostID), report, http.StatusCreated, nil)
+		return fmt.Sprintf("/api/posts/%d/reports/resolve", created.PostID)
+	}
+	suspend := models.ResolveReportsRequest{Action: constants.MODERATION_ACTION_SUSPEND, SuspendDays: 7}
+
+	// Moderators cannot suspend each other or an admin
+	moderatorPost := reportedPost("grace", "ivan")
+	clients["frank"].do(http.MethodPost, moderatorPost, suspend, http.StatusForbidden, nil)
+	adminPost := reportedPost("heidi", "ivan")
+	clients["frank"].do(http.MethodPost, adminPost, suspend, http.StatusForbidden, nil)
+
+	// Only a higher role can
+	clients["heidi"].do(http.MethodPost, moderatorPost, suspend, http.StatusOK, nil)
+	memberPost := reportedPost("ivan", "heidi")
+	clients["frank"].do(http.MethodPost, memberPost, suspend, http.StatusOK, nil)
+}
+
+func TestTwoFactorCodesAreSingleUse(t *testing.T) {
+	_, sent, newClient := newTestAPI(t)
+	c := newClient()
+	signUp(t, sent, c, "judy")
+
+	var setup struct {
+		Secret string `json:"secret"`
+	}
+	c.do(http.MethodPost, "/api/me/2fa/setup", nil, http.StatusOK, &setup)
+
+	step := utils.TOTPStep(time.Now())
+	code := func(step int64) string {
+		code, err := utils.TOTPCode(setup.Secret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	var enabled struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	c.do(http.MethodPost, "/api/me/2fa/confirm", models.ConfirmTwoFactorRequest{Code: code(step)}, http.StatusOK, &enabled)
+	c.do(http.MethodPost, "/api/logout", nil, http.StatusOK, nil)
+
+	// Logs in with the password and the second factor code, wanting the status for the code
+	login := func(code string, wantStatus int) {
+		t.Helper()
+		var challenge struct {
+			ChallengeToken string `json:"challenge_token"`
+		}
+		c.do(http.MethodPost, "/api/login", models.LoginRequest{Email: "judy@example.com", Password: "judy's password"}, http.StatusOK, &challenge)
+		c.do(http.MethodPost, "/api/login/2fa", models.LoginTwoFactorRequest{ChallengeToken: challenge.ChallengeToken, Code: code}, wantStatus, nil)
+		if wantStatus == http.StatusOK {
+			c.do(http.MethodPost, "/api/logout", nil, http.StatusOK, nil)
+		}
+	}
+
+	// The code that confirmed the secret was used up, the next step's code is still in the window
+	login(code(step), http.StatusUnauthorized)
+	login(code(step+1), http.StatusOK)
+	login(code(step+1), http.StatusUnauthorized)
+
+	login(enabled.RecoveryCodes[0], http.StatusOK)
+	login(enabled.RecoveryCodes[0], http.StatusUnauthorized)
+	login(strings.ToUpper(strings.ReplaceAll(enabled.RecoveryCodes[1], "-", "")), http.StatusOK)
+}
+
+func TestAPITokenScopesOnPublicRoutes(t *testing.T) {
+	_, sent, newClient := newTestAPI(t)
+	c := newClient()
+	signUp(t, sent, c, "kim")
+
+	// Returns a client that authenticates with a new API token of kim's with the scopes
+	tokenClient := func(scopes ...string) *testClient {
+		var created struct {
+			Token string `json:"token"`
+		}
+		c.do(http.MethodPost, "/api/me/api-tokens", models.CreateAPITokenRequest{Name: "bot", Scopes: scopes}, http.StatusCreated, &created)
+		client := newClient()
+		client.apiToken = created.Token
+		return client
+	}
+
+	var profile struct {
+		Email string `json:"email"`
+	}
+	reader := tokenClient(constants.API_TOKEN_SCOPE_READ)
+	reader.do(http.MethodGet, "/api/users/kim", nil, http.StatusOK, &profile)
+	if profile.Email != "kim@example.com" {
+		t.Fatalf("got email %q with the read scope, want kim's", profile.Email)
+	}
+
+	// Without the read scope, public routes answer as they would anonymously
+	voter := tokenClient(constants.API_TOKEN_SCOPE_VOTE)
+	profile.Email = ""
+	voter.do(http.MethodGet, "/api/users/kim", nil, http.StatusOK, &profile)
+	if profile.Email != "" {
+		t.Fatalf("got email %q without the read scope, want none", profile.Email)
+	}
+	voter.do(http.MethodGet, "/api/topics", nil, http.StatusOK, nil)
+
+	// Routes that require authentication still reject it
+	voter.do(http.MethodGet, "/api/me", nil, http.StatusForbidden, nil)
+}
+
+// Starts a mock identity provider and single sign-on with it
+func newSSOProvider(t *testing.T) (*oidctest.Provider, *oidc.Provider) {
+	t.Helper()
+
+	idp := oidctest.NewServer(t, "cvwo", "secret")
+	provider, err := oidc.NewProvider(context.Background(), oidc.Config{
+		IssuerURL:    idp.Issuer,
+		ClientID:     "cvwo",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:8000/api/auth/oidc/callback",
+		DisplayName:  "Mock",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return idp, provider
+}
+
+// Logs in at the identity provider with the claims, after starting from the API path, and returns where the
+// callback redirects the browser to. edit, unless nil, changes the callback URL before the browser follows it
+func ssoLogin(c *testClient, idp *oidctest.Provider, start string, claims url.Values, edit func(callback *url.URL)) *url.URL {
+	c.t.Helper()
+
+	callback, err := idp.Login(c.redirect(start).String(), claims)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if edit != nil {
+		edit(callback)
+	}
+	return c.redirect(callback.String())
+}
+
+// Claims of a provider account with a verified email
+func ssoClaims(subject, email string) url.Values {
+	return url.Values{"sub": {subject}, "email": {email}, "email_verified": {"true"}, "preferred_username": {subject}}
+}
+
+func TestSSOLoginCreatesUser(t *testing.T) {
+	idp, provider := newSSOProvider(t)
+	_, _, newClient := newTestAPIWithSSO(t, provider)
+
+	var me struct {
+		ID            int    `json:"id"`
+		Username      string `json:"username"`
+		EmailVerified bool   `json:"email_verified"`
+	}
+	c := newClient()
+	got := ssoLogin(c, idp, "/api/auth/oidc/login?return_to=/topics", ssoClaims("olivia", "olivia@example.com"), nil)
+	if got.String() != "http://localhost:3000/topics" {
+		t.Fatalf("redirected to %s, want the return_to path", got)
+	}
+	c.do(http.MethodGet, "/api/me", nil, http.StatusOK, &me)
+	if me.Username != "olivia" || !me.EmailVerified {
+		t.Fatalf("got /me %+v, want a verified olivia", me)
+	}
+
+	// Logging in again is as the same user
+	firstID := me.ID
+	other := newClient()
+	ssoLogin(other, idp, "/api/auth/oidc/login", ssoClaims("olivia", "olivia@example.com"), nil)
+	other.do(http.MethodGet, "/api/me", nil, http.StatusOK, &me)
+	if me.ID != firstID {
+		t.Fatalf("second login as user %d, want %d", me.ID, firstID)
+	}
+}
+
+func TestSSOCallbackRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		edit   func(callback *url.URL)
+		tamper func(claims jwt.MapClaims, header map[string]any)
+	}{
+		{"wrong state", func(callback *url.URL) {
+			query := callback.Query()
+			query.Set("state", "another state")
+			callback.RawQuery = query.Encode()
+		}, nil},
+		{"wrong nonce", nil, func(claims jwt.MapClaims, header map[string]any) {
+			claims["nonce"] = "another nonce"
+		}},
+		{"expired ID token", nil, func(claims jwt.MapClaims, header map[string]any) {
+			claims["iat"] = time.Now().Add(-time.Hour).Unix()
+			claims["exp"] = time.Now().Add(-10 * time.Minute).Unix()
+		}},
+		{"wrong audience", nil, func(claims jwt.MapClaims, header map[string]any) {
+			claims["aud"] = "another-client"
+		}},
+		{"wrong key ID", nil, func(claims jwt.MapClaims, header map[string]any) {
+			header["kid"] = "another-key"
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp, provider := newSSOProvider(t)
+			idp.TamperIDTokens(tt.tamper)
+			_, _, newClient := newTestAPIWithSSO(t, provider)
+
+			c := newClient()
+			got := ssoLogin(c, idp, "/api/auth/oidc/login", ssoClaims("peggy", "peggy@example.com"), tt.edit)
+			if got.String() != "http://localhost:3000/login?sso_error="+constants.SSO_ERROR_FAILED {
+				t.Fatalf("redirected to %s, want the login page with %s", got, constants.SSO_ERROR_FAILED)
+			}
+			c.do(http.MethodGet, "/api/me", nil, http.StatusUnauthorized, nil)
+		})
+	}
+}
+
+func TestSSOLinksOnlyFromASession(t *testing.T) {
+	idp, provider := newSSOProvider(t)
+	_, sent, newClient := newTestAPIWithSSO(t, provider)
+	c := newClient()
+	signUp(t, sent, c, "quinn")
+
+	// A provider account with the email of an existing user does not log in as them
+	stranger := newClient()
+	got := ssoLogin(stranger, idp, "/api/auth/oidc/login", ssoClaims("quinn-sso", "quinn@example.com"), nil)
+	if got.String() != "http://localhost:3000/login?sso_error="+constants.SSO_ERROR_EMAIL_TAKEN {
+		t.Fatalf("redirected to %s, want the login page with %s", got, constants.SSO_ERROR_EMAIL_TAKEN)
+	}
+	stranger.do(http.MethodGet, "/api/me", nil, http.StatusUnauthorized, nil)
+	stranger.do(http.MethodGet, "/api/auth/oidc/link", nil, http.StatusUnauthorized, nil)
+
+	// Signed in, quinn links it, after which it logs in as quinn
+	got = ssoLogin(c, idp, "/api/auth/oidc/link?return_to=/settings", ssoClaims("quinn-sso", "quinn@example.com"), nil)
+	if got.String() != "http://localhost:3000/settings?sso_linked=true" {
+		t.Fatalf("redirected to %s, want the settings page", got)
+	}
+
+	var me struct {
+		Username string `json:"username"`
+	}
+	ssoLogin(stranger, idp, "/api/auth/oidc/login", ssoClaims("quinn-sso", "quinn@example.com"), nil)
+	stranger.do(http.MethodGet, "/api/me", nil, http.StatusOK, &me)
+	if me.Username != "quinn" {
+		t.Fatalf("logged in as %s, want quinn", me.Username)
+	}
+
+	// The account cannot be linked to a second user
+	other := newClient()
+	signUp(t, sent, other, "rita")
+	got = ssoLogin(other, idp, "/api/auth/oidc/link?return_to=/settings", ssoClaims("quinn-sso", "quinn@example.com"), nil)
+	if got.String() != "http://localhost:3000/settings?sso_error="+constants.SSO_ERROR_IDENTITY_TAKEN {
+		t.Fatalf("redirected to %s, want the settings page with %s", got, constants.SSO_ERROR_IDENTITY_TAKEN)
+	}
+}
+
+func TestSSOLoginRequiresTwoFactor(t *testing.T) {
+	idp, provider := newSSOProvider(t)
+	_, sent, newClient := newTestAPIWithSSO(t, provider)
+	c := newClient()
+	signUp(t, sent, c, "sybil")
+
+	var setup struct {
+		Secret string `json:"secret"`
+	}
+	c.do(http.MethodPost, "/api/me/2fa/setup", nil, http.StatusOK, &setup)
+	step := utils.TOTPStep(time.Now())
+	code := func(step int64) string {
+		code, err := utils.TOTPCode(setup.Secret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+	c.do(http.MethodPost, "/api/me/2fa/confirm", models.ConfirmTwoFactorRequest{Code: code(step)}, http.StatusOK, nil)
+	ssoLogin(c, idp, "/api/auth/oidc/link", ssoClaims("sybil-sso", "sybil@example.com"), nil)
+
+	// The provider login is only the first factor, the session starts after the code
+	other := newClient()
+	got := ssoLogin(other, idp, "/api/auth/oidc/login", ssoClaims("sybil-sso", "sybil@example.com"), nil)
+	fragment, err := url.ParseQuery(got.EscapedFragment())
+	if err != nil {
+		t.Fatal(err)
+	}
+	challengeToken := fragment.Get("challenge_token")
+	if got.Path != "/login" || challengeToken == "" {
+		t.Fatalf("redirected to %s, want the login page with a challenge token", got)
+	}
+	other.do(http.MethodGet, "/api/me", nil, http.StatusUnauthorized, nil)
+
+	other.do(http.MethodPost, "/api/login/2fa", models.LoginTwoFactorRequest{ChallengeToken: challengeToken, Code: "000000"}, http.StatusUnauthorized, nil)
+	other.do(http.MethodPost, "/api/login/2fa", models.LoginTwoFactorRequest{ChallengeToken: challengeToken, Code: code(step + 1)}, http.StatusOK, nil)
+
+	var me struct {
+		Username string `json:"username"`
+	}
+	other.do(http.MethodGet, "/api/me", nil, http.StatusOK, &me)
+	if me.Username != "sybil" {
+		t.Fatalf("logged in as %s, want sybil", me.Username)
+	}
+}
@@ -0,0 +1,122 @@
+// Package telemetry records the server's Prometheus metrics, served on /metrics,
+// and the OpenTelemetry spans of requests and data-access calls, exported only once tracing is started
+package telemetry
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics are registered on their own registry, so /metrics only has what the server records
+var registry = prometheus.NewRegistry()
+
+// Buckets of data-access calls, most take a few milliseconds but the longest routes are allowed 15s
+var queryBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 15}
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, route pattern and status code",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time taken to respond to HTTP requests, by method and route pattern",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Time taken by data-access calls, by operation",
+		Buckets: queryBuckets,
+	}, []string{"operation"})
+
+	postsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "cvwo_posts_created_total",
+		Help: "Posts created",
+	})
+
+	commentsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "cvwo_comments_created_total",
+		Help: "Comments created",
+	})
+
+	votes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cvwo_votes_total",
+		Help: "Votes cast, changed or removed, by target (post or comment)",
+	}, []string{"target"})
+
+	registrations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cvwo_registrations_total",
+		Help: "Users registered, by method (password or sso)",
+	}, []string{"method"})
+
+	emails = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cvwo_emails_total",
+		Help: "Emails handed to the mailer, by result (sent or failed)",
+	}, []string{"result"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpRequestDuration,
+		queryDuration,
+		postsCreated,
+		commentsCreated,
+		votes,
+		registrations,
+		emails,
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// RegisterDB exports the connection pool statistics of the database, as reported by db.Stats()
+func RegisterDB(db *sql.DB, name string) {
+	registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// ObserveRequest records a response, route is the pattern it matched such as /api/posts/{id}
+func ObserveRequest(method, route string, status int, duration time.Duration) {
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpRequestDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+func PostCreated() {
+	postsCreated.Inc()
+}
+
+func CommentCreated() {
+	commentsCreated.Inc()
+}
+
+// Voted counts a vote on a post or comment, target is "post" or "comment"
+func Voted(target string) {
+	votes.WithLabelValues(target).Inc()
+}
+
+// UserRegistered counts a new user, method is "password" or "sso"
+func UserRegistered(method string) {
+	registrations.WithLabelValues(method).Inc()
+}
+
+// EmailSent counts an email the mailer accepted or failed to send
+func EmailSent(err error) {
+	result := "sent"
+	if err != nil {
+		result = "failed"
+	}
+	emails.WithLabelValues(result).Inc()
+}
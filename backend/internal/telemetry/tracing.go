@@ -0,0 +1,84 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "cvwo"
+
+// Spans are dropped by the global no-op provider until StartTracing replaces it
+func tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// StartTracing exports spans to the OTLP/HTTP collector at the endpoint, such as http://localhost:4318
+// Trace context sent by clients in the traceparent header is continued
+// Returns a function that flushes the remaining spans and stops the exporter
+func StartTracing(ctx context.Context, endpoint, serviceName string) (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", serviceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// ObserveQuery times a data-access call and wraps it in a span, the returned function ends both
+// The returned context carries the span, so calls made with it are nested under it
+func ObserveQuery(ctx context.Context, operation string) (context.Context, func()) {
+	start := time.Now()
+	ctx, span := tracer().Start(ctx, "dataaccess."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system", "postgresql"), attribute.String("db.operation", operation)))
+
+	return ctx, func() {
+		queryDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+		// A cancelled request is the most common reason for a call to fail
+		if err := ctx.Err(); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// StartRequestSpan starts the span of an HTTP request, continuing the trace the client sent if there is one
+// It is named after the route once known, by EndRequestSpan
+func StartRequestSpan(r *http.Request, requestID string) (context.Context, trace.Span) {
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	return tracer().Start(ctx, r.Method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("url.path", r.URL.Path),
+			attribute.String("request.id", requestID),
+		))
+}
+
+// EndRequestSpan records the route and status of the response, server errors mark the span as failed
+// The span still has to be ended
+func EndRequestSpan(span trace.Span, method, route string, status int) {
+	span.SetName(method + " " + route)
+	span.SetAttributes(attribute.String("http.route", route), attribute.Int("http.response.status_code", status))
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+}
@@ -1,6 +1,13 @@
 package utils
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"cvwo/internal/constants"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
 
 func HashPassword(password string) ([]byte, error) {
 	return bcrypt.GenerateFromPassword([]byte(password), 14)
@@ -9,3 +16,34 @@ func HashPassword(password string) ([]byte, error) {
 func CompareHashAndPassword(hash, password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
+
+// Compared against when the email is unknown, so both failures take as long as a real password check
+// Hashed on first use, since it takes as long as hashing a real password
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := HashPassword("not the password of any user")
+	return hash
+})
+
+// Hashes the password against nothing, taking as long as CompareHashAndPassword
+func CompareDummyPassword(password string) {
+	bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+}
+
+// Returns the normalized form of an email, the form login attempts are recorded under
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Returns how much longer logins are locked after the given number of recent failures, 0 if they are not
+func LockoutRemaining(failures, threshold int, lastFailureAt *time.Time) time.Duration {
+	if failures < threshold || lastFailureAt == nil {
+		return 0
+	}
+
+	lockout := constants.MAX_LOCKOUT_DURATION
+	if doublings := failures - threshold; doublings < 16 {
+		lockout = min(constants.LOCKOUT_BASE_DURATION<<doublings, constants.MAX_LOCKOUT_DURATION)
+	}
+
+	return max(time.Until(lastFailureAt.Add(lockout)), 0)
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+func isTrustedProxy(trustedProxies []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the client IP without the port
+// Only the X-Forwarded-For and X-Real-IP headers of the trusted proxies are believed
+// Behind a trusted proxy it is the last address in X-Forwarded-For that is not a trusted proxy, or X-Real-IP,
+// anything earlier in X-Forwarded-For was sent by the client and may be forged
+func GetClientIP(r *http.Request, trustedProxies []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	peer, err := netip.ParseAddr(host)
+	if err != nil || !isTrustedProxy(trustedProxies, peer.Unmap()) {
+		return host
+	}
+
+	// Each proxy appends the address it received the request from
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	client := ""
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if err != nil {
+			break
+		}
+		client = addr.Unmap().String()
+		if !isTrustedProxy(trustedProxies, addr.Unmap()) {
+			return client
+		}
+	}
+	if client != "" {
+		return client
+	}
+
+	if addr, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		return addr.Unmap().String()
+	}
+	return host
+}
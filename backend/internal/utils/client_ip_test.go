@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestGetClientIP(t *testing.T) {
+	trustedProxies := []netip.Prefix{netip.MustParsePrefix("172.16.0.0/12"), netip.MustParsePrefix("10.0.0.1/32")}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		realIP       string
+		want         string
+	}{
+		{"direct client", "203.0.113.7:1234", nil, "", "203.0.113.7"},
+		{"untrusted peer cannot forge headers", "203.0.113.7:1234", []string{"198.51.100.1"}, "198.51.100.2", "203.0.113.7"},
+		{"trusted proxy", "172.18.0.3:1234", []string{"198.51.100.1"}, "", "198.51.100.1"},
+		{"forged entries before the proxy's are ignored", "172.18.0.3:1234", []string{"1.2.3.4, 198.51.100.1"}, "", "198.51.100.1"},
+		{"chained trusted proxies are skipped", "172.18.0.3:1234", []string{"198.51.100.1, 10.0.0.1"}, "", "198.51.100.1"},
+		{"repeated headers are read in order", "172.18.0.3:1234", []string{"1.2.3.4", "198.51.100.1"}, "", "198.51.100.1"},
+		{"only trusted proxies forwarded", "172.18.0.3:1234", []string{"10.0.0.1"}, "", "10.0.0.1"},
+		{"malformed entry stops the walk", "172.18.0.3:1234", []string{"198.51.100.1, garbage, 10.0.0.1"}, "", "10.0.0.1"},
+		{"X-Real-IP without X-Forwarded-For", "172.18.0.3:1234", nil, "198.51.100.2", "198.51.100.2"},
+		{"trusted proxy without headers", "172.18.0.3:1234", nil, "", "172.18.0.3"},
+		{"IPv4-mapped IPv6 peer", "[::ffff:172.18.0.3]:1234", []string{"198.51.100.1"}, "", "198.51.100.1"},
+		{"IPv6 client", "172.18.0.3:1234", []string{"2001:db8::1"}, "", "2001:db8::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwardedFor {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			if got := GetClientIP(r, trustedProxies); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"fmt"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Lines of unchanged context around each hunk
+const diffContextLines = 3
+
+// UnifiedDiff returns the unified diff between two revisions of a text, empty if they are equal
+func UnifiedDiff(from, to string, fromRevision, toRevision int) string {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        diffLines(from),
+		B:        diffLines(to),
+		FromFile: revisionLabel(fromRevision),
+		ToFile:   revisionLabel(toRevision),
+		Context:  diffContextLines,
+	})
+	if err != nil {
+		// Only fails if writing to the string buffer fails
+		return ""
+	}
+	return diff
+}
+
+// Empty text has no lines, rather than a single empty one
+func diffLines(text string) []string {
+	if text == "" {
+		return []string{}
+	}
+	return difflib.SplitLines(text)
+}
+
+// The first revision is diffed against nothing
+func revisionLabel(revision int) string {
+	if revision == 0 {
+		return "/dev/null"
+	}
+	return fmt.Sprintf("revision %d", revision)
+}
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"bytes"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+	xhtml "golang.org/x/net/html"
+)
+
+// CommonMark with tables, strikethrough and autolinks
+// Single line breaks are kept, since content was shown as preformatted text before it was rendered
+// Raw HTML in the source is dropped by the renderer, and the output is sanitized again below
+var markdown = goldmark.New(
+	goldmark.WithExtensions(
+		extension.Table,
+		extension.Strikethrough,
+		extension.Linkify,
+	),
+	goldmark.WithRendererOptions(
+		html.WithHardWraps(),
+	),
+)
+
+// User-generated content policy, also adds rel="nofollow noopener" and opens external links in a new tab
+// The language class of code fences is kept for client-side syntax highlighting
+var sanitizer = func() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	policy.AddTargetBlankToFullyQualifiedLinks(true)
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+	return policy
+}()
+
+// Elements whose text is separated from the surrounding text when converting to plain text
+var blockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "hr": true, "pre": true, "blockquote": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "li": true,
+	"table": true, "thead": true, "tbody": true, "tr": true,
+}
+
+// RenderMarkdown renders Markdown source to sanitized HTML, safe to embed in a page as is
+func RenderMarkdown(source string) string {
+	var rendered bytes.Buffer
+	if err := markdown.Convert([]byte(source), &rendered); err != nil {
+		// Only fails if writing to the buffer fails, fall back to escaped text
+		log.Printf("RenderMarkdown: failed to render: %v", err)
+		return xhtml.EscapeString(source)
+	}
+
+	return sanitizer.Sanitize(rendered.String())
+}
+
+// PlainText extracts the text of rendered HTML, one line per block, e.g. for summaries
+func PlainText(renderedHTML string) string {
+	var text strings.Builder
+	tokenizer := xhtml.NewTokenizer(strings.NewReader(renderedHTML))
+
+	// Line breaks only matter in preformatted text, elsewhere they are whitespace like in a browser
+	preDepth := 0
+
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == xhtml.ErrorToken {
+			break
+		}
+
+		switch tokenType {
+		case xhtml.TextToken:
+			if preDepth > 0 {
+				text.Write(tokenizer.Text())
+			} else {
+				text.WriteString(strings.ReplaceAll(string(tokenizer.Text()), "\n", " "))
+			}
+		case xhtml.StartTagToken, xhtml.EndTagToken, xhtml.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			if tag == "pre" && tokenType == xhtml.StartTagToken {
+				preDepth++
+			} else if tag == "pre" && tokenType == xhtml.EndTagToken && preDepth > 0 {
+				preDepth--
+			}
+
+			if blockElements[tag] {
+				text.WriteString("\n")
+			} else if tag == "td" || tag == "th" {
+				text.WriteString(" ")
+			}
+		}
+	}
+
+	// Collapse whitespace within lines and drop empty lines
+	lines := []string{}
+	for _, line := range strings.Split(text.String(), "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
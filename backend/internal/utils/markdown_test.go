@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"raw script block", "<script>alert(1)</script>", ""},
+		{"inline script", "hi <script>alert(1)</script> there", "<p>hi alert(1) there</p>"},
+		{"onerror attribute", "<img src=x onerror=alert(1)>", ""},
+		{"onclick attribute", `<a href="https://example.com" onclick="alert(1)">x</a>`, "<p>x</p>"},
+		{"javascript link", "[click](javascript:alert(1))", "<p>click</p>"},
+		{"mixed case javascript link", "[click](JaVaScRiPt:alert(1))", "<p>click</p>"},
+		{"data link", "[click](data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==)", "<p>click</p>"},
+		{"javascript image", "![img](javascript:alert(1))", `<p><img alt="img"></p>`},
+		{"data image", "![img](data:image/svg+xml;base64,PHN2Zz48L3N2Zz4=)", `<p><img alt="img"></p>`},
+		{"javascript autolink", "<javascript:alert(1)>", "<p>javascript:alert(1)</p>"},
+		{
+			"autolink",
+			"<https://example.com>",
+			`<p><a href="https://example.com" rel="nofollow noopener" target="_blank">https://example.com</a></p>`,
+		},
+		{
+			"bare URL",
+			"visit https://example.com/a?b=1&c=2 now",
+			`<p>visit <a href="https://example.com/a?b=1&amp;c=2" rel="nofollow noopener" target="_blank">https://example.com/a?b=1&amp;c=2</a> now</p>`,
+		},
+		{
+			"bare www domain",
+			"mail www.example.com",
+			`<p>mail <a href="http://www.example.com" rel="nofollow noopener" target="_blank">www.example.com</a></p>`,
+		},
+		{"code fence language", "```go\nx := 1\n```", `<pre><code class="language-go">x := 1` + "\n</code></pre>"},
+		{"code fence unsafe class", "```go\" onmouseover=\"alert(1)\nx\n```", "<pre><code>x\n</code></pre>"},
+		{"hard wraps", "one\ntwo", "<p>one<br>\ntwo</p>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.TrimSpace(RenderMarkdown(tt.source)); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	markup := regexp.MustCompile(`<[/!a-zA-Z]|&(#\d+|#x[0-9a-fA-F]+|[a-zA-Z]+);`)
+
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"inline formatting", "**bold** _em_ ~~struck~~ `code`", "bold em struck code"},
+		{"entities are decoded", `Tom &amp; Jerry & "quotes" 'x'`, `Tom & Jerry & "quotes" 'x'`},
+		{"script dropped", "hi <script>alert(1)</script> there", "hi alert(1) there"},
+		{"onerror image dropped", "before <img src=x onerror=alert(1)> after", "before after"},
+		{"link text kept", "[click](javascript:alert(1)) and <https://example.com>", "click and https://example.com"},
+		{"blocks on separate lines", "# Title\n\nFirst paragraph\n\n- one\n- two", "Title\nFirst paragraph\none\ntwo"},
+		{"table cells separated", "| a | b |\n| - | - |\n| 1 | 2 |", "a b\n1 2"},
+		{"preformatted lines kept", "```\nline one\nline two\n```", "line one\nline two"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PlainText(RenderMarkdown(tt.source))
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if markup.MatchString(got) {
+				t.Errorf("summary %q contains markup or entities", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"cvwo/internal/constants"
+	"regexp"
+)
+
+// @username, not preceded by a username character so emails do not count as mentions
+var mentionRegex = regexp.MustCompile(`(?:^|[^a-zA-Z0-9_@])@([a-zA-Z0-9_]+)`)
+
+// ExtractMentions returns the distinct usernames mentioned in the text, at most MAX_MENTIONS
+func ExtractMentions(text string) []string {
+	usernames := []string{}
+	seen := map[string]bool{}
+
+	for _, match := range mentionRegex.FindAllStringSubmatch(text, -1) {
+		username := match[1]
+		if len(username) < constants.MIN_USERNAME_LENGTH || len(username) > constants.MAX_USERNAME_LENGTH || seen[username] {
+			continue
+		}
+
+		seen[username] = true
+		usernames = append(usernames, username)
+		if len(usernames) == constants.MAX_MENTIONS {
+			break
+		}
+	}
+
+	return usernames
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"strings"
+	"unicode/utf8"
 
 	"cvwo/internal/constants"
 	"cvwo/internal/models"
@@ -13,13 +14,23 @@ func generateSummary(content string, maxLength int) (string, bool) {
 	if len(content) <= maxLength {
 		return content, false
 	}
-	return content[:maxLength] + "...", true
+
+	// Never cut a multi-byte character in half
+	end := maxLength
+	for end > 0 && !utf8.RuneStart(content[end]) {
+		end--
+	}
+	return strings.TrimSpace(content[:end]) + "...", true
 }
 
-func GenerateCommentSummary(comment models.Comment) (string, bool) {
-	return generateSummary(comment.Content, constants.COMMENT_SUMMARY_LENGTH)
+// RenderComment renders the comment's Markdown content and derives its summary from the rendered text
+func RenderComment(comment *models.Comment) {
+	comment.ContentHTML = RenderMarkdown(comment.Content)
+	comment.Summary, comment.HasLongContent = generateSummary(PlainText(comment.ContentHTML), constants.COMMENT_SUMMARY_LENGTH)
 }
 
-func GeneratePostSummary(post models.Post) (string, bool) {
-	return generateSummary(post.Content, constants.POST_SUMMARY_LENGTH)
+// RenderPost renders the post's Markdown content and derives its summary from the rendered text
+func RenderPost(post *models.Post) {
+	post.ContentHTML = RenderMarkdown(post.Content)
+	post.Summary, _ = generateSummary(PlainText(post.ContentHTML), constants.POST_SUMMARY_LENGTH)
 }
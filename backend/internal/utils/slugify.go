@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"cvwo/internal/constants"
+	"strings"
+)
+
+/*
+ * ts implementation
+ * export function slugifyTopicName(topicName: string): string {
+ *   return topicName.toLowerCase().split(" ").join("-");
+ * }
+ */
+
+func SlugifyTopicName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, " ", "-"))
+}
+
+// Derives a valid username from the first candidate with any username characters, e.g. a preferred username or email
+// Falls back to "user", the caller must still make it unique
+func SuggestUsername(candidates ...string) string {
+	for _, candidate := range candidates {
+		candidate, _, _ = strings.Cut(candidate, "@")
+
+		username := strings.Map(func(c rune) rune {
+			switch {
+			case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '_':
+				return c
+			case c == '.' || c == '-' || c == ' ':
+				return '_'
+			}
+			return -1
+		}, candidate)
+
+		username = strings.Trim(username, "_")
+		if username == "" {
+			continue
+		}
+
+		if len(username) > constants.MAX_USERNAME_LENGTH {
+			username = username[:constants.MAX_USERNAME_LENGTH]
+		}
+		for len(username) < constants.MIN_USERNAME_LENGTH {
+			username += "_"
+		}
+		return username
+	}
+
+	return "user"
+}
@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"cvwo/internal/constants"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Returns a random 160-bit TOTP secret, base32 encoded as authenticator apps expect
+func GenerateTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// Returns the otpauth URI authenticator apps import the secret from, usually shown as a QR code
+func TOTPURI(accountName, secret string) string {
+	label := url.PathEscape(constants.TOTP_ISSUER + ":" + accountName)
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", constants.TOTP_ISSUER)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(constants.TOTP_DIGITS))
+	params.Set("period", fmt.Sprint(int(constants.TOTP_PERIOD.Seconds())))
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// Returns the time step of t, the counter codes are derived from
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / int64(constants.TOTP_PERIOD.Seconds())
+}
+
+// Returns the code of the secret for the time step (RFC 6238)
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation (RFC 4226)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for range constants.TOTP_DIGITS {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", constants.TOTP_DIGITS, value%modulo), nil
+}
+
+// Checks the code against the steps around now, returns the step it matched
+// Callers must reject steps at or before the last accepted one, so a code cannot be replayed
+func ValidateTOTPCode(secret, code string, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != constants.TOTP_DIGITS {
+		return 0, false
+	}
+
+	current := TOTPStep(now)
+	for step := current - constants.TOTP_SKEW_STEPS; step <= current+constants.TOTP_SKEW_STEPS; step++ {
+		expected, err := TOTPCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// Whether the code is shaped like a TOTP code rather than a recovery code
+func IsTOTPCode(code string) bool {
+	code = strings.TrimSpace(code)
+	if len(code) != constants.TOTP_DIGITS {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a recovery code with 80 bits of entropy, formatted as four groups of four characters
+func GenerateRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(totpEncoding.EncodeToString(b))
+	return code[0:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:16], nil
+}
+
+// Returns the form recovery codes are hashed in, so case, spaces and dashes do not matter
+func NormalizeRecoveryCode(code string) string {
+	return strings.Map(func(c rune) rune {
+		if c == '-' || c == ' ' {
+			return -1
+		}
+		return c
+	}, strings.ToLower(strings.TrimSpace(code)))
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"cvwo/internal/constants"
+	"testing"
+	"time"
+)
+
+// The SHA-1 secret of the RFC 6238 test vectors, "12345678901234567890" in base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 Appendix B gives 8 digit codes, the last 6 digits are the 6 digit codes
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		step := TOTPStep(time.Unix(tt.unix, 0))
+		got, err := TOTPCode(rfc6238Secret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("time %d: got %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+
+	// Authenticator apps may show the secret in lower case
+	if got, _ := TOTPCode("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", 1); got != "287082" {
+		t.Errorf("lower case secret: got %s, want 287082", got)
+	}
+	if _, err := TOTPCode("not base32!", 1); err == nil {
+		t.Error("malformed secret accepted")
+	}
+}
+
+func TestValidateTOTPCode(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := TOTPStep(now)
+
+	code := func(step int64) string {
+		c, err := TOTPCode(rfc6238Secret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", code(current), current, true},
+		{"surrounding spaces", " " + code(current) + " ", current, true},
+		{"previous step", code(current - constants.TOTP_SKEW_STEPS), current - constants.TOTP_SKEW_STEPS, true},
+		{"next step", code(current + constants.TOTP_SKEW_STEPS), current + constants.TOTP_SKEW_STEPS, true},
+		{"before the window", code(current - constants.TOTP_SKEW_STEPS - 1), 0, false},
+		{"after the window", code(current + constants.TOTP_SKEW_STEPS + 1), 0, false},
+		{"too short", code(current)[1:], 0, false},
+		{"too long", code(current) + "0", 0, false},
+		{"empty", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTPCode(rfc6238Secret, tt.code, now)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("got step %d, %v, want step %d, %v", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
@@ -2,88 +2,260 @@ package utils
 
 import (
 	"cvwo/internal/constants"
+	"cvwo/internal/models"
 	"fmt"
 	"regexp"
+	"strings"
 )
 
-func ValidateEmail(email string) string {
+// Collects the field errors of a request, so all of them are reported at once
+type ValidationErrors []models.FieldError
+
+// Check records the error against the field, nil errors are ignored
+func (errs *ValidationErrors) Check(field string, err *models.FieldError) {
+	if err == nil {
+		return
+	}
+	err.Field = field
+	*errs = append(*errs, *err)
+}
+
+// Add records an error against the field
+func (errs *ValidationErrors) Add(field, code, message string) {
+	errs.Check(field, fieldError(code, message))
+}
+
+// The field is filled in by ValidationErrors.Check, since the same value can be sent under different names
+func fieldError(code, message string) *models.FieldError {
+	return &models.FieldError{Code: code, Message: message}
+}
+
+func ValidateEmail(email string) *models.FieldError {
 	if email == "" {
-		return "Email is required"
+		return fieldError(constants.ERROR_CODE_REQUIRED, "Email is required")
 	}
 	emailRegex := regexp.MustCompile(`^[^\s@]+@[^\s@]+\.[^\s@]+$`)
 	if !emailRegex.MatchString(email) {
-		return "Please enter a valid email address"
+		return fieldError(constants.ERROR_CODE_INVALID_FORMAT, "Please enter a valid email address")
 	}
 
-	return ""
+	return nil
 }
 
-func ValidateUsername(username string) string {
+func ValidateUsername(username string) *models.FieldError {
 	if username == "" {
-		return "Username is required"
+		return fieldError(constants.ERROR_CODE_REQUIRED, "Username is required")
 	}
 
 	if len(username) < constants.MIN_USERNAME_LENGTH {
-		return fmt.Sprintf("Username must be at least %d characters", constants.MIN_USERNAME_LENGTH)
+		return fieldError(constants.ERROR_CODE_TOO_SHORT, fmt.Sprintf("Username must be at least %d characters", constants.MIN_USERNAME_LENGTH))
 	}
 
 	if len(username) > constants.MAX_USERNAME_LENGTH {
-		return fmt.Sprintf("Username must be no more than %d characters", constants.MAX_USERNAME_LENGTH)
+		return fieldError(constants.ERROR_CODE_TOO_LONG, fmt.Sprintf("Username must be no more than %d characters", constants.MAX_USERNAME_LENGTH))
 	}
 
 	// Letters, numbers, and underscores only
 	usernameRegex := regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
 	if !usernameRegex.MatchString(username) {
-		return "Username can only contain letters, numbers, and underscores"
+		return fieldError(constants.ERROR_CODE_INVALID_FORMAT, "Username can only contain letters, numbers, and underscores")
 	}
 
-	return ""
+	return nil
 }
 
-func ValidatePostTitle(title string) string {
+func ValidatePostTitle(title string, maxLength int) *models.FieldError {
 	if title == "" {
-		return "Title is required"
+		return fieldError(constants.ERROR_CODE_REQUIRED, "Title is required")
 	}
 
-	if len(title) > constants.MAX_POST_TITLE_LENGTH {
-		return fmt.Sprintf("Title must be less than %d characters", constants.MAX_POST_TITLE_LENGTH)
+	if len(title) > maxLength {
+		return fieldError(constants.ERROR_CODE_TOO_LONG, fmt.Sprintf("Title must be less than %d characters", maxLength))
 	}
 
-	return ""
+	return nil
 }
 
-func ValidatePostContent(content string) string {
-	if len(content) > constants.MAX_POST_CONTENT_LENGTH {
-		return fmt.Sprintf("Content must be less than %d characters", constants.MAX_POST_CONTENT_LENGTH)
+func ValidatePostContent(content string, maxLength int) *models.FieldError {
+	if len(content) > maxLength {
+		return fieldError(constants.ERROR_CODE_TOO_LONG, fmt.Sprintf("Content must be less than %d characters", maxLength))
 	}
 
-	return ""
+	return nil
 }
 
-func ValidateCommentContent(content string) string {
+func ValidateCommentContent(content string, maxLength int) *models.FieldError {
 	if content == "" {
-		return "Content is required"
+		return fieldError(constants.ERROR_CODE_REQUIRED, "Content is required")
 	}
 
-	if len(content) > constants.MAX_COMMENT_CONTENT_LENGTH {
-		return fmt.Sprintf("Content must be less than %d characters", constants.MAX_COMMENT_CONTENT_LENGTH)
+	if len(content) > maxLength {
+		return fieldError(constants.ERROR_CODE_TOO_LONG, fmt.Sprintf("Content must be less than %d characters", maxLength))
 	}
 
-	return ""
+	return nil
 }
 
-func ValidatePassword(password string) string {
+func ValidatePassword(password string) *models.FieldError {
 	if password == "" {
-		return "Password is required"
+		return fieldError(constants.ERROR_CODE_REQUIRED, "Password is required")
 	}
 
 	if len(password) < constants.MIN_PASSWORD_LENGTH {
-		return fmt.Sprintf("Password must be at least %d characters", constants.MIN_PASSWORD_LENGTH)
+		return fieldError(constants.ERROR_CODE_TOO_SHORT, fmt.Sprintf("Password must be at least %d characters", constants.MIN_PASSWORD_LENGTH))
 	}
 
 	if len(password) > constants.MAX_PASSWORD_LENGTH {
-		return fmt.Sprintf("Password must be no more than %d characters", constants.MAX_PASSWORD_LENGTH)
+		return fieldError(constants.ERROR_CODE_TOO_LONG, fmt.Sprintf("Password must be no more than %d characters", constants.MAX_PASSWORD_LENGTH))
+	}
+
+	return nil
+}
+
+func ValidateTopicName(name string) *models.FieldError {
+	if name == "" {
+		return fieldError(constants.ERROR_CODE_REQUIRED, "Topic name is required")
+	}
+
+	if len(name) > constants.MAX_TOPIC_NAME_LENGTH {
+		return fieldError(constants.ERROR_CODE_TOO_LONG, fmt.Sprintf("Topic name must be no more than %d characters", constants.MAX_TOPIC_NAME_LENGTH))
+	}
+
+	// Words of letters and numbers separated by single spaces, so the slug is URL-safe
+	topicNameRegex := regexp.MustCompile(`^[a-zA-Z0-9]+( [a-zA-Z0-9]+)*$`)
+	if !topicNameRegex.MatchString(name) {
+		return fieldError(constants.ERROR_CODE_INVALID_FORMAT, "Topic name can only contain letters, numbers, and single spaces")
+	}
+
+	return nil
+}
+
+func ValidateTopicDescription(description string) *models.FieldError {
+	if len(description) > constants.MAX_TOPIC_DESCRIPTION_LENGTH {
+		return fieldError(constants.ERROR_CODE_TOO_LONG, fmt.Sprintf("Description must be less than %d characters", constants.MAX_TOPIC_DESCRIPTION_LENGTH))
+	}
+
+	return nil
+}
+
+func ValidateTimeWindow(window string) *models.FieldError {
+	switch window {
+	case "", constants.WINDOW_DAY, constants.WINDOW_WEEK, constants.WINDOW_MONTH, constants.WINDOW_ALL:
+		return nil
+	}
+
+	return fieldError(constants.ERROR_CODE_INVALID_VALUE, "Window must be one of day, week, month or all")
+}
+
+func ValidateNotificationType(notificationType string) *models.FieldError {
+	switch notificationType {
+	case constants.NOTIFICATION_POST_REPLY,
+		constants.NOTIFICATION_COMMENT_REPLY,
+		constants.NOTIFICATION_MENTION,
+		constants.NOTIFICATION_COMMENT_PINNED,
+		constants.NOTIFICATION_TOPIC_POST:
+		return nil
+	}
+
+	return fieldError(constants.ERROR_CODE_INVALID_VALUE, fmt.Sprintf("Unknown notification type: %s", notificationType))
+}
+
+func ValidateVoteValue(voteValue int) *models.FieldError {
+	if voteValue != -1 && voteValue != 0 && voteValue != 1 {
+		return fieldError(constants.ERROR_CODE_INVALID_VALUE, "Vote value must be -1, 0, or 1")
+	}
+
+	return nil
+}
+
+func ValidateReportReason(reason string) *models.FieldError {
+	switch reason {
+	case constants.REPORT_REASON_SPAM,
+		constants.REPORT_REASON_HARASSMENT,
+		constants.REPORT_REASON_HATE,
+		constants.REPORT_REASON_MISINFORMATION,
+		constants.REPORT_REASON_OFF_TOPIC,
+		constants.REPORT_REASON_OTHER:
+		return nil
+	case "":
+		return fieldError(constants.ERROR_CODE_REQUIRED, "Reason is required")
+	}
+
+	return fieldError(constants.ERROR_CODE_INVALID_VALUE, "Reason must be one of spam, harassment, hate, misinformation, off_topic or other")
+}
+
+func ValidateReportDetails(details string) *models.FieldError {
+	if len(details) > constants.MAX_REPORT_DETAILS_LENGTH {
+		return fieldError(constants.ERROR_CODE_TOO_LONG, fmt.Sprintf("Details must be less than %d characters", constants.MAX_REPORT_DETAILS_LENGTH))
+	}
+
+	return nil
+}
+
+func ValidateModerationAction(action string) *models.FieldError {
+	switch action {
+	case constants.MODERATION_ACTION_DISMISS,
+		constants.MODERATION_ACTION_REMOVE,
+		constants.MODERATION_ACTION_WARN,
+		constants.MODERATION_ACTION_SUSPEND:
+		return nil
+	case "":
+		return fieldError(constants.ERROR_CODE_REQUIRED, "Action is required")
+	}
+
+	return fieldError(constants.ERROR_CODE_INVALID_VALUE, "Action must be one of dismiss, remove, warn or suspend")
+}
+
+func ValidateModerationNote(note string) *models.FieldError {
+	if len(note) > constants.MAX_MODERATION_NOTE_LENGTH {
+		return fieldError(constants.ERROR_CODE_TOO_LONG, fmt.Sprintf("Note must be less than %d characters", constants.MAX_MODERATION_NOTE_LENGTH))
+	}
+
+	return nil
+}
+
+func ValidateSuspendDays(days int) *models.FieldError {
+	if days < 1 || days > constants.MAX_SUSPENSION_DAYS {
+		return fieldError(constants.ERROR_CODE_INVALID_VALUE, fmt.Sprintf("Suspension must be between 1 and %d days", constants.MAX_SUSPENSION_DAYS))
+	}
+
+	return nil
+}
+
+func ValidateAPITokenName(name string) *models.FieldError {
+	if strings.TrimSpace(name) == "" {
+		return fieldError(constants.ERROR_CODE_REQUIRED, "Name is required")
+	}
+
+	if len(name) > constants.MAX_API_TOKEN_NAME_LENGTH {
+		return fieldError(constants.ERROR_CODE_TOO_LONG, fmt.Sprintf("Name must be no more than %d characters", constants.MAX_API_TOKEN_NAME_LENGTH))
+	}
+
+	return nil
+}
+
+func ValidateAPITokenScopes(scopes []string) *models.FieldError {
+	if len(scopes) == 0 {
+		return fieldError(constants.ERROR_CODE_REQUIRED, "At least one scope is required")
+	}
+
+	for _, scope := range scopes {
+		switch scope {
+		case constants.API_TOKEN_SCOPE_READ,
+			constants.API_TOKEN_SCOPE_POST_WRITE,
+			constants.API_TOKEN_SCOPE_VOTE:
+		default:
+			return fieldError(constants.ERROR_CODE_INVALID_VALUE, "Scopes must be read, post:write or vote")
+		}
+	}
+
+	return nil
+}
+
+func ValidateAPITokenExpiry(days int) *models.FieldError {
+	if days < 0 || days > constants.MAX_API_TOKEN_EXPIRY_DAYS {
+		return fieldError(constants.ERROR_CODE_INVALID_VALUE, fmt.Sprintf("Expiry must be between 1 and %d days, or 0 for none", constants.MAX_API_TOKEN_EXPIRY_DAYS))
 	}
 
-	return ""
+	return nil
 }
@@ -1,4 +1,4 @@
-import axios from "axios";
+import axios, { type AxiosError, type InternalAxiosRequestConfig } from "axios";
 
 const API_BASE_URL = "/api"
 
@@ -26,3 +26,40 @@ export const api = axios.create({
   //   return searchParams.toString();
   // },
 });
+
+// Access tokens are short-lived, so on a 401 refresh the session once and retry
+// Concurrent 401s share the same refresh request, since the refresh token is rotated
+let refreshPromise: Promise<unknown> | null = null;
+const NO_REFRESH_URLS = ["/login", "/login/2fa", "/logout", "/refresh"];
+
+api.interceptors.response.use(undefined, async (error: AxiosError) => {
+  const config = error.config as
+    | (InternalAxiosRequestConfig & { _retried?: boolean })
+    | undefined;
+
+  if (
+    error.response?.status !== 401 ||
+    !config ||
+    config._retried ||
+    NO_REFRESH_URLS.includes(config.url ?? "")
+  ) {
+    throw error;
+  }
+
+  config._retried = true;
+  refreshPromise ??= api.post("/refresh").finally(() => {
+    refreshPromise = null;
+  });
+
+  try {
+    await refreshPromise;
+  } catch {
+    // Public routes reject an expired access token so the session is refreshed, once that fails
+    // the cookies are cleared and the request can be retried logged out
+    if ((error.response?.data as { code?: string } | undefined)?.code === "access_token_expired") {
+      return api(config);
+    }
+    throw error;
+  }
+  return api(config);
+});
@@ -1,10 +1,22 @@
-import type { UserAuth } from "@/types/auth";
-import type { LoginRequest, LoginResponse } from "@/types/user";
+import type { SSOConfig, UserAuth } from "@/types/auth";
+import type {
+  LoginRequest,
+  LoginResponse,
+  LoginTwoFactorRequest,
+  TwoFactorChallengeResponse,
+} from "@/types/user";
 import { api } from "./api";
 
 export const authApi = {
   login: async (data: LoginRequest) => {
-    const response = await api.post<LoginResponse>("/login", data);
+    const response = await api.post<
+      LoginResponse | TwoFactorChallengeResponse
+    >("/login", data);
+    return response.data;
+  },
+
+  loginTwoFactor: async (data: LoginTwoFactorRequest) => {
+    const response = await api.post<LoginResponse>("/login/2fa", data);
     return response.data;
   },
 
@@ -13,6 +25,19 @@ export const authApi = {
     return response.data;
   },
 
+  getSSOConfig: async () => {
+    const response = await api.get<SSOConfig>("/auth/oidc");
+    return response.data;
+  },
+
+  // Full page navigation, the identity provider redirects back to the backend
+  ssoLoginUrl: (returnTo = "/") =>
+    `${api.defaults.baseURL}/auth/oidc/login?return_to=${encodeURIComponent(returnTo)}`,
+
+  // Links the identity provider account to the signed in user, also a full page navigation
+  ssoLinkUrl: (returnTo = "/settings") =>
+    `${api.defaults.baseURL}/auth/oidc/link?return_to=${encodeURIComponent(returnTo)}`,
+
   getMe: async () => {
     const response = await api.get<UserAuth>("/me");
     return response.data;
@@ -4,6 +4,7 @@ import type {
   ListCommentsRequest,
   PaginatedCommentsResponse,
   UpdateCommentRequest,
+  UpdateCommentResponse,
   VoteCommentRequest,
 } from "@/types/comment";
 import { api } from "./api";
@@ -18,7 +19,7 @@ export const commentApi = {
   },
 
   updateComment: async (commentId: number, data: UpdateCommentRequest) => {
-    const response = await api.put<{ message: string }>(
+    const response = await api.put<UpdateCommentResponse>(
       `/comments/${commentId}`,
       data,
     );
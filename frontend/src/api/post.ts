@@ -5,6 +5,7 @@ import type {
   PinCommentRequest,
   Post,
   UpdatePostRequest,
+  UpdatePostResponse,
   VotePostRequest,
 } from "@/types/post";
 import { api } from "./api";
@@ -19,7 +20,7 @@ export const postApi = {
   },
 
   updatePost: async (postId: number, data: UpdatePostRequest) => {
-    const response = await api.put<{ message: string }>(
+    const response = await api.put<UpdatePostResponse>(
       `/posts/${postId}`,
       data,
     );
@@ -153,6 +153,7 @@ export function CommentCard({
             hasLongContent={comment.has_long_content}
             commentId={comment.id}
             commentSummary={comment.summary}
+            commentContentHtml={comment.content_html}
           />
 
           {/* Comment Vote and Reply Buttons, and Link To Comment */}
@@ -1,7 +1,7 @@
 import { Button, Stack, Typography } from "@mui/material";
 import { useState } from "react";
 import { Loading } from "@/components/common/Loading";
-import { CLAMP_TEXT_SX } from "@/constants/style";
+import { CLAMP_TEXT_SX, RENDERED_CONTENT_SX } from "@/constants/style";
 import { useComment } from "@/hooks/comments";
 
 interface CommentContentProps {
@@ -9,6 +9,8 @@ interface CommentContentProps {
   hasLongContent?: boolean;
   commentId: number;
   commentSummary: string;
+  // Only listed for short comments, long ones are fetched in full when expanded
+  commentContentHtml?: string;
 }
 
 export function CommentContent({
@@ -16,6 +18,7 @@ export function CommentContent({
   hasLongContent,
   commentId,
   commentSummary,
+  commentContentHtml,
 }: CommentContentProps) {
   const [isExpanded, setIsExpanded] = useState(false);
 
@@ -24,6 +27,9 @@ export function CommentContent({
     isEnabled: isExpanded && hasLongContent && !isDeleted,
   });
 
+  const contentHtml =
+    isExpanded && fullComment ? fullComment.content_html : commentContentHtml;
+
   return (
     <Stack
       direction="column"
@@ -33,21 +39,31 @@ export function CommentContent({
       justifyItems="right"
     >
       {/* Comment Text */}
-      <Typography
-        variant="body2"
-        color={isDeleted ? "text.secondary" : "text.primary"}
-        fontStyle={isDeleted ? "italic" : "normal"}
-        whiteSpace="pre-wrap"
-        sx={{
-          ...(hasLongContent && !isExpanded && CLAMP_TEXT_SX(2)),
-        }}
-      >
-        {isDeleted
-          ? "[deleted]"
-          : isExpanded && fullComment
-            ? fullComment.content
-            : commentSummary}
-      </Typography>
+      {isDeleted ? (
+        <Typography variant="body2" color="text.secondary" fontStyle="italic">
+          [deleted]
+        </Typography>
+      ) : contentHtml ? (
+        // Rendered and sanitized by the backend
+        <Typography
+          variant="body2"
+          component="div"
+          color="text.primary"
+          sx={RENDERED_CONTENT_SX}
+          dangerouslySetInnerHTML={{ __html: contentHtml }}
+        />
+      ) : (
+        <Typography
+          variant="body2"
+          color="text.primary"
+          whiteSpace="pre-wrap"
+          sx={{
+            ...(hasLongContent && !isExpanded && CLAMP_TEXT_SX(2)),
+          }}
+        >
+          {commentSummary}
+        </Typography>
+      )}
 
       {/* Read More / Show Less Button */}
       {!isDeleted && hasLongContent && (
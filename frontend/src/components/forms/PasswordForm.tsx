@@ -1,7 +1,6 @@
 import { zodResolver } from "@hookform/resolvers/zod";
 import { Lock } from "@mui/icons-material";
 import { Alert, Stack } from "@mui/material";
-import type { AxiosError } from "axios";
 import { useForm } from "react-hook-form";
 import {
   FormContainer,
@@ -11,8 +10,12 @@ import {
 } from "@/components/common/form";
 import { PASSWORD_HELPER_TEXT } from "@/constants/users";
 import { useChangePassword } from "@/hooks/user";
-import { changePasswordSchema, newPasswordFormSchema } from "@/schema/users";
+import {
+  changePasswordFormSchema,
+  changePasswordSchema,
+} from "@/schema/users";
 import type { ChangePasswordForm } from "@/types/user";
+import { getErrorMessage } from "@/utils/error";
 
 export function PasswordForm() {
   const {
@@ -21,8 +24,9 @@ export function PasswordForm() {
     formState: { errors, isValid },
     reset,
   } = useForm<ChangePasswordForm>({
-    resolver: zodResolver(newPasswordFormSchema),
+    resolver: zodResolver(changePasswordFormSchema),
     defaultValues: {
+      current_password: "",
       password: "",
       confirmPassword: "",
     },
@@ -50,11 +54,23 @@ export function PasswordForm() {
       >
         {error && (
           <Alert severity="error" sx={{ mb: 2 }}>
-            {((error as AxiosError)?.response?.data as string) ||
+            {getErrorMessage(error) ||
               "Password change failed. Please try again."}
           </Alert>
         )}
 
+        <FormField
+          {...register("current_password")}
+          id="current_password"
+          label="Current Password"
+          type="password"
+          error={!!errors.current_password}
+          helperText={errors.current_password?.message}
+          autoComplete="current-password"
+          disabled={isLoading}
+          icon={<Lock />}
+        />
+
         <FormField
           {...register("password")}
           id="password"
@@ -1,7 +1,6 @@
 import { zodResolver } from "@hookform/resolvers/zod";
 import { Email, Person, Save } from "@mui/icons-material";
 import { Alert, Stack, Typography } from "@mui/material";
-import type { AxiosError } from "axios";
 import { useForm } from "react-hook-form";
 import {
   FormContainer,
@@ -13,6 +12,7 @@ import { USERNAME_HELPER_TEXT } from "@/constants/users";
 import { useUpdateProfile } from "@/hooks/user";
 import { profileSchema } from "@/schema/users";
 import type { UpdateProfileRequest } from "@/types/user";
+import { getErrorMessage } from "@/utils/error";
 
 interface ProfileFormProps {
   defaultValues: UpdateProfileRequest;
@@ -51,7 +51,7 @@ export function ProfileForm({ defaultValues }: ProfileFormProps) {
       >
         {error && (
           <Alert severity="error" sx={{ mb: 2 }}>
-            {((error as AxiosError)?.response?.data as string) ||
+            {getErrorMessage(error) ||
               "Profile update failed. Please try again."}
           </Alert>
         )}
@@ -83,7 +83,11 @@ export function PostCard({ item: post, isInDetailView }: PostCardProps) {
 
           {/* Post Content */}
           {isInDetailView ? (
-            <PostContent isDeleted={isDeleted} content={post.content} />
+            <PostContent
+              isDeleted={isDeleted}
+              content={post.content}
+              contentHtml={post.content_html}
+            />
           ) : (
             <Typography
               sx={{
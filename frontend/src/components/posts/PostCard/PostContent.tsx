@@ -1,30 +1,46 @@
 import { Button, Stack, Typography } from "@mui/material";
 import { useState } from "react";
 import { POST_TRUNCATE_LENGTH_THRESHOLD } from "@/constants/posts";
-import { CLAMP_TEXT_SX } from "@/constants/style";
+import { CLAMP_TEXT_SX, RENDERED_CONTENT_SX } from "@/constants/style";
 
 interface PostContentProps {
   isDeleted: boolean;
   content: string;
+  contentHtml: string;
 }
 
-export function PostContent({ isDeleted, content }: PostContentProps) {
+export function PostContent({
+  isDeleted,
+  content,
+  contentHtml,
+}: PostContentProps) {
   const hasLongContent = content.length > POST_TRUNCATE_LENGTH_THRESHOLD;
   const [isExpanded, setIsExpanded] = useState(false);
   return (
     <Stack direction="column" spacing={1}>
-      <Typography
-        variant="body1"
-        lineHeight={1.7}
-        color={isDeleted ? "text.disabled" : "text.primary"}
-        fontStyle={isDeleted ? "italic" : "normal"}
-        whiteSpace="pre-wrap"
-        sx={{
-          ...(hasLongContent && !isExpanded && CLAMP_TEXT_SX(5)),
-        }}
-      >
-        {isDeleted ? "This post has been deleted." : content}
-      </Typography>
+      {isDeleted ? (
+        <Typography
+          variant="body1"
+          lineHeight={1.7}
+          color="text.disabled"
+          fontStyle="italic"
+        >
+          This post has been deleted.
+        </Typography>
+      ) : (
+        // Rendered and sanitized by the backend
+        <Typography
+          variant="body1"
+          component="div"
+          lineHeight={1.7}
+          color="text.primary"
+          sx={{
+            ...RENDERED_CONTENT_SX,
+            ...(hasLongContent && !isExpanded && CLAMP_TEXT_SX(5)),
+          }}
+          dangerouslySetInnerHTML={{ __html: contentHtml }}
+        />
+      )}
 
       {/* Read More / Show Less Button */}
       {!isDeleted && hasLongContent && (
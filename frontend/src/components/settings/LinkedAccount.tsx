@@ -0,0 +1,59 @@
+import { Alert, Button, Divider, Paper, Stack, Typography } from "@mui/material";
+import { authApi } from "@/api/auth";
+import { useSSOConfig } from "@/hooks/user";
+
+const SSO_LINK_ERROR_MESSAGES: Record<string, string> = {
+  identity_taken: "That account is already linked to a user.",
+};
+
+interface LinkedAccountProps {
+  // Set by the backend when it redirects back after linking
+  linked?: boolean;
+  error?: string;
+}
+
+// Links an identity provider account, so the user can also sign in with single sign-on
+export function LinkedAccount({ linked, error }: LinkedAccountProps) {
+  const { data: ssoConfig } = useSSOConfig();
+
+  if (!ssoConfig?.enabled) {
+    return null;
+  }
+
+  // Refreshes the session first if needed, the link must start from an active one
+  const startLink = async () => {
+    await authApi.getMe();
+    window.location.assign(authApi.ssoLinkUrl("/settings"));
+  };
+
+  return (
+    <Paper elevation={3} sx={{ p: 3 }}>
+      <Stack direction="column" spacing={1.5}>
+        <Typography variant="h6">Single Sign-On</Typography>
+        <Divider />
+
+        {linked && (
+          <Alert severity="success">
+            {`Your ${ssoConfig.display_name} account is linked.`}
+          </Alert>
+        )}
+
+        {error && (
+          <Alert severity="error">
+            {SSO_LINK_ERROR_MESSAGES[error] ??
+              "Linking failed. Please try again."}
+          </Alert>
+        )}
+
+        <Typography variant="body2" color="text.secondary">
+          {`Link your ${ssoConfig.display_name} account to sign in with it as well as your password.`}
+        </Typography>
+        <Stack direction="row">
+          <Button variant="outlined" onClick={startLink}>
+            Link {ssoConfig.display_name} account
+          </Button>
+        </Stack>
+      </Stack>
+    </Paper>
+  );
+}
@@ -1,7 +1,6 @@
 import type { PostsSortValue } from "@/types/post";
 
 export const POSTS_PER_PAGE = 10;
-export const POST_SUMMARY_LENGTH = 400; // sync with backend
 
 export const POST_TRUNCATE_LENGTH_THRESHOLD = 1000; // sync with backend
 
@@ -16,6 +15,12 @@ interface PostsSortOption {
 export const POSTS_SORT_OPTIONS: PostsSortOption[] = [
   { value: { sort: "created_at", order_by: "desc" }, label: "Newest" },
   { value: { sort: "created_at", order_by: "asc" }, label: "Oldest" },
+  { value: { sort: "hot", order_by: "desc" }, label: "Hot" },
+  { value: { sort: "best", order_by: "desc" }, label: "Best" },
+  {
+    value: { sort: "controversial", order_by: "desc" },
+    label: "Controversial",
+  },
   { value: { sort: "score", order_by: "desc" }, label: "Top (Upvotes)" },
   {
     value: { sort: "no_of_comments", order_by: "desc" },
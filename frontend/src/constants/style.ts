@@ -18,3 +18,37 @@ export const CLAMP_TEXT_SX = (lines: number) => ({
   overflow: "hidden",
   textOverflow: "ellipsis",
 });
+
+// Styles for Markdown rendered by the backend, which arrives as sanitized HTML
+export const RENDERED_CONTENT_SX = {
+  wordBreak: "break-word",
+  "& > :first-of-type": { mt: 0 },
+  "& > :last-child": { mb: 0 },
+  "& p": { my: 1 },
+  "& h1, & h2, & h3, & h4, & h5, & h6": { mt: 2, mb: 1, lineHeight: 1.3 },
+  "& a": { color: "primary.main" },
+  "& blockquote": {
+    borderLeft: 4,
+    borderColor: "divider",
+    color: "text.secondary",
+    mx: 0,
+    pl: 2,
+  },
+  "& code": {
+    fontFamily: "monospace",
+    fontSize: "0.9em",
+    backgroundColor: "action.hover",
+    borderRadius: 1,
+    px: 0.5,
+  },
+  "& pre": {
+    backgroundColor: "action.hover",
+    borderRadius: 1,
+    overflowX: "auto",
+    p: 1.5,
+  },
+  "& pre code": { backgroundColor: "transparent", p: 0 },
+  "& table": { borderCollapse: "collapse", display: "block", overflowX: "auto" },
+  "& th, & td": { border: 1, borderColor: "divider", px: 1, py: 0.5 },
+  "& img": { maxWidth: "100%" },
+};
@@ -11,7 +11,6 @@ import type {
 } from "@/types/comment";
 import type { Post } from "@/types/post";
 import { mutateCache } from "@/utils/mutate";
-import { summarizeCommentContent } from "@/utils/summary";
 
 export function useCommentActions(comment: Comment) {
   const navigate = useNavigate();
@@ -50,21 +49,19 @@ export function useCommentActions(comment: Comment) {
   const editMutation = useMutation({
     mutationFn: (data: UpdateCommentRequest) =>
       commentApi.updateComment(comment.id, data),
-    onSuccess: (_, { content }) => {
+    onSuccess: ({ content_html, summary, has_long_content }, { content }) => {
       mutateCache<Comment>({
         queryClient,
         itemName: "comment",
         itemKey: comment.id,
         mutateFn: (oldComment) => {
           if (oldComment.id !== comment.id) return oldComment;
-          const { summary, isTruncated } = summarizeCommentContent(
-            content ?? oldComment.content,
-          );
           return {
             ...oldComment,
             content: content ?? oldComment.content,
+            content_html,
             summary,
-            has_long_content: isTruncated,
+            has_long_content,
             updated_at: new Date().toISOString(),
           };
         },
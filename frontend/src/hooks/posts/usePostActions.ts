@@ -5,7 +5,6 @@ import { useAuth } from "@/contexts/AuthContext";
 import { useSnackbar } from "@/contexts/SnackbarContext";
 import type { Post, UpdatePostRequest, VotePostRequest } from "@/types/post";
 import { mutateCache } from "@/utils/mutate";
-import { summarizePostContent } from "@/utils/summary";
 
 export function usePostActions(post: Post) {
   const navigate = useNavigate();
@@ -43,7 +42,7 @@ export function usePostActions(post: Post) {
 
   const editMutation = useMutation({
     mutationFn: (data: UpdatePostRequest) => postApi.updatePost(post.id, data),
-    onSuccess: (_, { title, content }) => {
+    onSuccess: ({ content_html, summary }, { title, content }) => {
       mutateCache<Post>({
         queryClient,
         itemName: "post",
@@ -54,7 +53,8 @@ export function usePostActions(post: Post) {
             ...oldPost,
             title: title ?? oldPost.title,
             content: content ?? oldPost.content,
-            summary: summarizePostContent(content ?? oldPost.content),
+            content_html,
+            summary,
             updated_at: new Date().toISOString(),
           };
         },
@@ -2,4 +2,5 @@ export * from "./useChangePassword";
 export * from "./useLogin";
 export * from "./useLogout";
 export * from "./useRegister";
+export * from "./useSSOConfig";
 export * from "./useUpdateProfile";
@@ -1,8 +1,8 @@
 import { useMutation } from "@tanstack/react-query";
-import type { AxiosError } from "axios";
 import { userApi } from "@/api/user";
 import { useSnackbar } from "@/contexts/SnackbarContext";
 import type { ChangePasswordRequest } from "@/types/user";
+import { getErrorMessage } from "@/utils/error";
 
 export function useChangePassword(onSuccessCallback?: () => void) {
   const { showSuccess, showError } = useSnackbar();
@@ -14,7 +14,7 @@ export function useChangePassword(onSuccessCallback?: () => void) {
     },
     onError: (err) => {
       showError(
-        ((err as AxiosError)?.response?.data as string) ||
+        getErrorMessage(err) ||
           "Password change failed. Please try again.",
       );
     },
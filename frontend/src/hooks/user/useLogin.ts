@@ -1,31 +1,63 @@
 import { useMutation, useQueryClient } from "@tanstack/react-query";
 import { useNavigate } from "@tanstack/react-router";
+import { useState } from "react";
 import { authApi } from "@/api/auth";
 import { useSnackbar } from "@/contexts/SnackbarContext";
-import type { LoginRequest } from "@/types/user";
+import type { LoginRequest, LoginTwoFactorRequest } from "@/types/user";
 
-export function useLogin() {
+// initialChallengeToken continues a single sign-on login that still needs a two-factor code
+export function useLogin(initialChallengeToken: string | null = null) {
   const { showSuccess, showError } = useSnackbar();
   const navigate = useNavigate();
   const queryClient = useQueryClient();
+  // Set when the password was correct but a two-factor code is still needed
+  const [challengeToken, setChallengeToken] = useState<string | null>(
+    initialChallengeToken,
+  );
+
+  const onLoggedIn = () => {
+    // Invalidate all queries to refetch user data
+    // and user specific follow / upvote states
+    queryClient.invalidateQueries();
+    showSuccess("Login successful!");
+    navigate({ to: "/" });
+  };
 
   const mutation = useMutation({
     mutationFn: (loginRequest: LoginRequest) => authApi.login(loginRequest),
-    onSuccess: () => {
-      // Invalidate all queries to refetch user data
-      // and user specific follow / upvote states
-      queryClient.invalidateQueries();
-      showSuccess("Login successful!");
-      navigate({ to: "/" });
+    onSuccess: (data) => {
+      if ("two_factor_required" in data) {
+        setChallengeToken(data.challenge_token);
+        return;
+      }
+      onLoggedIn();
     },
     onError: () => {
       showError("Login failed. Please check your credentials and try again.");
     },
   });
 
+  const twoFactorMutation = useMutation({
+    mutationFn: (request: LoginTwoFactorRequest) =>
+      authApi.loginTwoFactor(request),
+    onSuccess: onLoggedIn,
+    onError: () => {
+      showError("Invalid authentication code. Please try again.");
+    },
+  });
+
+  const verifyCode = (code: string) => {
+    if (challengeToken) {
+      twoFactorMutation.mutate({ challenge_token: challengeToken, code });
+    }
+  };
+
   return {
     login: mutation.mutate,
-    isLoading: mutation.isPending,
+    verifyCode,
+    challengeToken,
+    isLoading: mutation.isPending || twoFactorMutation.isPending,
     error: mutation.error,
+    twoFactorError: twoFactorMutation.error,
   };
 }
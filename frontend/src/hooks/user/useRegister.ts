@@ -1,9 +1,9 @@
 import { useMutation } from "@tanstack/react-query";
 import { useNavigate } from "@tanstack/react-router";
-import type { AxiosError } from "axios";
 import { userApi } from "@/api/user";
 import { useSnackbar } from "@/contexts/SnackbarContext";
 import type { RegisterRequest } from "@/types/user";
+import { getErrorMessage } from "@/utils/error";
 
 export function useRegister() {
   const { showSuccess, showError } = useSnackbar();
@@ -17,7 +17,7 @@ export function useRegister() {
     },
     onError: (err) => {
       showError(
-        ((err as AxiosError)?.response?.data as string) ||
+        getErrorMessage(err) ||
           "Registration failed. Please try again.",
       );
     },
@@ -0,0 +1,11 @@
+import { useQuery } from "@tanstack/react-query";
+import { authApi } from "@/api/auth";
+
+// Whether the backend offers single sign-on, and the name to show on its button
+export function useSSOConfig() {
+  return useQuery({
+    queryKey: ["sso-config"],
+    queryFn: () => authApi.getSSOConfig(),
+    staleTime: Number.POSITIVE_INFINITY,
+  });
+}
@@ -1,8 +1,8 @@
 import { useMutation, useQueryClient } from "@tanstack/react-query";
-import type { AxiosError } from "axios";
 import { userApi } from "@/api/user";
 import { useSnackbar } from "@/contexts/SnackbarContext";
 import type { UpdateProfileRequest } from "@/types/user";
+import { getErrorMessage } from "@/utils/error";
 
 export function useUpdateProfile(onSuccessCallback?: () => void) {
   const { showSuccess, showError } = useSnackbar();
@@ -20,7 +20,7 @@ export function useUpdateProfile(onSuccessCallback?: () => void) {
     },
     onError: (err) => {
       showError(
-        ((err as AxiosError)?.response?.data as string) ||
+        getErrorMessage(err) ||
           "Profile update failed. Please try again.",
       );
     },
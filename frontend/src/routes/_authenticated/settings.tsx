@@ -7,19 +7,38 @@ import {
   Stack,
   Typography,
 } from "@mui/material";
-import { createFileRoute, Link as RouterLink } from "@tanstack/react-router";
+import {
+  createFileRoute,
+  Link as RouterLink,
+  useSearch,
+} from "@tanstack/react-router";
+import { z } from "zod";
 import { Loading } from "@/components/common/Loading";
 import { PasswordForm } from "@/components/forms/PasswordForm";
 import { ProfileForm } from "@/components/forms/ProfileForm";
 import { AccountInfo } from "@/components/settings/AccountInfo";
+import { LinkedAccount } from "@/components/settings/LinkedAccount";
 import { useAuth } from "@/contexts/AuthContext";
 import { useUser } from "@/hooks/users";
 
+const settingsSearchParamSchema = z.object({
+  // Set by the backend when it redirects back from linking a single sign-on account
+  sso_linked: z.literal(true).optional().catch(undefined),
+  sso_error: z.string().optional().catch(undefined),
+});
+
+type SettingsSearchParams = z.infer<typeof settingsSearchParamSchema>;
+
 export const Route = createFileRoute("/_authenticated/settings")({
   component: SettingsPage,
+  validateSearch: (search): SettingsSearchParams =>
+    settingsSearchParamSchema.parse(search),
 });
 
 function SettingsPage() {
+  const { sso_linked, sso_error } = useSearch({
+    from: "/_authenticated/settings",
+  });
   const { user: authUser } = useAuth();
   const { data: user, isLoading, error } = useUser(authUser?.username || "");
 
@@ -60,6 +79,8 @@ function SettingsPage() {
         {/* Account Information */}
         <AccountInfo user={user} />
 
+        <LinkedAccount linked={!!sso_linked} error={sso_error} />
+
         <Divider />
 
         {/* Link to profile */}
@@ -1,6 +1,6 @@
 import { zodResolver } from "@hookform/resolvers/zod";
-import { Email, Lock } from "@mui/icons-material";
-import { Alert, Stack } from "@mui/material";
+import { Email, Key, Lock } from "@mui/icons-material";
+import { Alert, Button, Divider, Stack } from "@mui/material";
 import { createFileRoute, useSearch } from "@tanstack/react-router";
 import { useForm } from "react-hook-form";
 import { z } from "zod";
@@ -11,26 +11,43 @@ import {
   FormLink,
   FormSubmitButton,
 } from "@/components/common/form";
-import { useLogin } from "@/hooks/user";
-import { loginSchema } from "@/schema/users";
-import type { LoginRequest } from "@/types/user";
+import { authApi } from "@/api/auth";
+import { useLogin, useSSOConfig } from "@/hooks/user";
+import { loginSchema, twoFactorCodeSchema } from "@/schema/users";
+import type { LoginRequest, TwoFactorCodeForm } from "@/types/user";
+import { getErrorMessage } from "@/utils/error";
 
-const registerSuccessSearchParamSchema = z.object({
+const loginSearchParamSchema = z.object({
   register_success: z.literal(true).optional().catch(undefined),
+  // Set by the backend when a single sign-on login fails
+  sso_error: z.string().optional().catch(undefined),
 });
 
-type RegisterSuccessSearchParams = z.infer<
-  typeof registerSuccessSearchParamSchema
->;
+const SSO_ERROR_MESSAGES: Record<string, string> = {
+  email_required: "Your identity provider did not share an email address.",
+  email_taken:
+    "An account already uses your email. Sign in with your password, then link your account in the settings.",
+};
+
+// Set by the backend when a single sign-on login still needs a two-factor code
+// It is passed in the fragment, so it is never sent to a server
+function ssoChallengeToken() {
+  return new URLSearchParams(window.location.hash.slice(1)).get(
+    "challenge_token",
+  );
+}
+
+type LoginSearchParams = z.infer<typeof loginSearchParamSchema>;
 
 export const Route = createFileRoute("/_guest/login")({
   component: LoginPage,
-  validateSearch: (search): RegisterSuccessSearchParams =>
-    registerSuccessSearchParamSchema.parse(search),
+  validateSearch: (search): LoginSearchParams =>
+    loginSearchParamSchema.parse(search),
 });
 
 function LoginPage() {
-  const { register_success } = useSearch({ from: "/_guest/login" });
+  const { register_success, sso_error } = useSearch({ from: "/_guest/login" });
+  const { data: ssoConfig } = useSSOConfig();
   const {
     register,
     handleSubmit,
@@ -44,10 +61,27 @@ function LoginPage() {
     // mode: "onChange",
   });
 
-  const { login, isLoading, error } = useLogin();
+  const {
+    login,
+    verifyCode,
+    challengeToken,
+    isLoading,
+    error,
+    twoFactorError,
+  } = useLogin(ssoChallengeToken());
 
   const onSubmitHandler = (data: LoginRequest) => login(data);
 
+  if (challengeToken) {
+    return (
+      <TwoFactorStep
+        verifyCode={verifyCode}
+        isLoading={isLoading}
+        error={twoFactorError}
+      />
+    );
+  }
+
   return (
     <FormContainer wrapInContainer={true}>
       <FormHeader
@@ -73,6 +107,13 @@ function LoginPage() {
           </Alert>
         )}
 
+        {sso_error && (
+          <Alert severity="error" sx={{ mb: 2 }}>
+            {SSO_ERROR_MESSAGES[sso_error] ??
+              "Single sign-on failed. Please try again."}
+          </Alert>
+        )}
+
         <FormField
           {...register("email")}
           id="email"
@@ -102,6 +143,19 @@ function LoginPage() {
           Sign In
         </FormSubmitButton>
 
+        {ssoConfig?.enabled && (
+          <>
+            <Divider sx={{ my: 2 }}>or</Divider>
+            <Button
+              variant="outlined"
+              href={authApi.ssoLoginUrl()}
+              disabled={isLoading}
+            >
+              Sign in with {ssoConfig.display_name}
+            </Button>
+          </>
+        )}
+
         <FormLink
           text="Don't have an account?"
           linkText="Sign up here"
@@ -111,3 +165,64 @@ function LoginPage() {
     </FormContainer>
   );
 }
+
+interface TwoFactorStepProps {
+  verifyCode: (code: string) => void;
+  isLoading: boolean;
+  error: Error | null;
+}
+
+// Second login step for users with two-factor authentication
+function TwoFactorStep({ verifyCode, isLoading, error }: TwoFactorStepProps) {
+  const {
+    register,
+    handleSubmit,
+    formState: { errors },
+  } = useForm<TwoFactorCodeForm>({
+    resolver: zodResolver(twoFactorCodeSchema),
+    defaultValues: {
+      code: "",
+    },
+  });
+
+  const onSubmitHandler = (data: TwoFactorCodeForm) => verifyCode(data.code);
+
+  return (
+    <FormContainer wrapInContainer={true}>
+      <FormHeader
+        icon={<Key fontSize="large" color="primary" />}
+        title="Two-Factor Authentication"
+        subtitle="Enter the code from your authenticator app or a recovery code"
+      />
+
+      <Stack
+        direction="column"
+        component="form"
+        onSubmit={handleSubmit(onSubmitHandler)}
+        width="100%"
+      >
+        {error && (
+          <Alert severity="error" sx={{ mb: 2 }}>
+            {getErrorMessage(error) || "Invalid code. Please try again."}
+          </Alert>
+        )}
+
+        <FormField
+          {...register("code")}
+          id="code"
+          label="Authentication Code"
+          error={!!errors.code}
+          helperText={errors.code?.message}
+          autoComplete="one-time-code"
+          autoFocus
+          disabled={isLoading}
+          icon={<Key />}
+        />
+
+        <FormSubmitButton isSubmitting={isLoading} loadingText="Verifying...">
+          Verify
+        </FormSubmitButton>
+      </Stack>
+    </FormContainer>
+  );
+}
@@ -2,7 +2,6 @@ import { zodResolver } from "@hookform/resolvers/zod";
 import { Email, Lock, Person, PersonAdd } from "@mui/icons-material";
 import { Alert, Stack } from "@mui/material";
 import { createFileRoute } from "@tanstack/react-router";
-import type { AxiosError } from "axios";
 import { useForm } from "react-hook-form";
 import {
   FormContainer,
@@ -15,6 +14,7 @@ import { PASSWORD_HELPER_TEXT, USERNAME_HELPER_TEXT } from "@/constants/users";
 import { useRegister } from "@/hooks/user";
 import { registerFormSchema, registerSchema } from "@/schema/users";
 import type { RegisterForm } from "@/types/user";
+import { getErrorMessage } from "@/utils/error";
 
 export const Route = createFileRoute("/_guest/register")({
   component: RegisterPage,
@@ -56,7 +56,7 @@ function RegisterPage() {
       >
         {error && (
           <Alert severity="error" sx={{ mb: 2 }}>
-            {((error as AxiosError)?.response?.data as string) ||
+            {getErrorMessage(error) ||
               "Registration failed. Please try again."}
           </Alert>
         )}
@@ -3,7 +3,14 @@ import { COMMENTS_PER_PAGE, COMMENTS_SORT_OPTIONS } from "@/constants/comments";
 import { extraSearchParams } from "@/schema/searchParams";
 
 const commentsSortValueObject = {
-  sort: z.enum(["score", "created_at"]),
+  sort: z.enum([
+    "score",
+    "created_at",
+    "hot",
+    "best",
+    "controversial",
+    "top",
+  ]),
   order_by: z.enum(["asc", "desc"]),
 };
 
@@ -3,7 +3,15 @@ import { POSTS_PER_PAGE, POSTS_SORT_OPTIONS } from "@/constants/posts";
 import { extraSearchParams } from "@/schema/searchParams";
 
 const postsSortValueObject = {
-  sort: z.enum(["score", "no_of_comments", "created_at"]),
+  sort: z.enum([
+    "score",
+    "no_of_comments",
+    "created_at",
+    "hot",
+    "best",
+    "controversial",
+    "top",
+  ]),
   order_by: z.enum(["asc", "desc"]),
 };
 
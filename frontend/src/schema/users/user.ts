@@ -45,6 +45,11 @@ export const loginSchema = z.object({
   password: passwordSchema,
 });
 
+// A code from the authenticator app, or a recovery code
+export const twoFactorCodeSchema = z.object({
+  code: z.string().trim().min(1, "Code is required"),
+});
+
 export const profileSchema = z.object({
   email: emailSchema,
   username: usernameSchema,
@@ -66,7 +71,14 @@ export const registerFormSchema = z.intersection(
   newPasswordFormSchema,
 );
 
-export const changePasswordSchema = newPasswordFormSchema.transform(
+export const changePasswordFormSchema = z.intersection(
+  z.object({
+    current_password: z.string().min(1, "Current password is required"),
+  }),
+  newPasswordFormSchema,
+);
+
+export const changePasswordSchema = changePasswordFormSchema.transform(
   ({ confirmPassword, ...rest }) => rest,
 );
 
@@ -2,3 +2,8 @@ export interface UserAuth {
   id: number;
   username: string;
 }
+
+export interface SSOConfig {
+  enabled: boolean;
+  display_name?: string;
+}
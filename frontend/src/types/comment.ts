@@ -12,16 +12,20 @@ export interface Comment {
   post_id: number;
   summary: string;
   content: string;
+  content_html: string;
   created_at: string;
   updated_at: string;
   user_id: number;
   score: number;
+  upvotes: number;
+  downvotes: number;
   parent_id: number | null;
   path: string;
   no_of_replies: number;
   is_deleted: boolean;
   deleted_at: string | null;
   my_vote: number;
+  is_saved: boolean;
   username: string;
   topic_name: string;
   post_title?: string;
@@ -38,6 +42,13 @@ export type ListCommentsRequest = z.infer<typeof listCommentsRequestSchema>;
 export type CreateCommentRequest = z.infer<typeof createCommentSchema>;
 export type UpdateCommentRequest = z.infer<typeof updateCommentSchema>;
 
+export interface UpdateCommentResponse {
+  message: string;
+  content_html: string;
+  summary: string;
+  has_long_content: boolean;
+}
+
 export type VoteCommentRequest = {
   vote_value: -1 | 0 | 1;
 };
@@ -45,4 +56,5 @@ export type VoteCommentRequest = {
 export interface PaginatedCommentsResponse {
   comments: Comment[];
   count: number;
+  next_cursor?: string;
 }
@@ -0,0 +1,13 @@
+export interface FieldError {
+  field: string;
+  code: string;
+  message: string;
+}
+
+// Body of every error response from the API
+export interface ApiError {
+  code: string;
+  message: string;
+  field?: string;
+  details?: FieldError[];
+}
@@ -13,15 +13,19 @@ export interface Post {
   title: string;
   summary: string;
   content: string;
+  content_html: string;
   created_at: string;
   updated_at: string;
   user_id: number;
   pinned_comment_id: number | null;
   score: number;
+  upvotes: number;
+  downvotes: number;
   no_of_comments: number;
   is_deleted: boolean;
   deleted_at: string | null;
   my_vote: number;
+  is_saved: boolean;
   topic_name: string;
   username: string;
 }
@@ -38,6 +42,12 @@ export type VotePostRequest = {
   vote_value: -1 | 0 | 1;
 };
 
+export interface UpdatePostResponse {
+  message: string;
+  content_html: string;
+  summary: string;
+}
+
 export type PinCommentRequest = {
   comment_id: number | null;
 };
@@ -45,4 +55,5 @@ export type PinCommentRequest = {
 export interface PaginatedPostsResponse {
   posts: Post[];
   count: number;
+  next_cursor?: string;
 }
@@ -28,4 +28,5 @@ export type FollowTopicRequest = {
 export interface PaginatedTopicsResponse {
   topics: Topic[];
   count: number;
+  next_cursor?: string;
 }
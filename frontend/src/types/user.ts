@@ -1,13 +1,14 @@
 import type z from "zod";
 import type {
+  changePasswordFormSchema,
   changePasswordSchema,
   listUsersRequestSchema,
   listUsersSearchParamsSchema,
   loginSchema,
-  newPasswordFormSchema,
   profileSchema,
   registerFormSchema,
   registerSchema,
+  twoFactorCodeSchema,
   usersSortValueSchema,
 } from "@/schema/users";
 
@@ -25,16 +26,18 @@ export type ListUsersSearchParams = z.infer<typeof listUsersSearchParamsSchema>;
 
 export type ListUsersRequest = z.infer<typeof listUsersRequestSchema>;
 export type LoginRequest = z.infer<typeof loginSchema>;
+export type TwoFactorCodeForm = z.infer<typeof twoFactorCodeSchema>;
 export type RegisterRequest = z.infer<typeof registerSchema>;
 export type UpdateProfileRequest = z.infer<typeof profileSchema>;
 export type ChangePasswordRequest = z.infer<typeof changePasswordSchema>;
 
 export type RegisterForm = z.infer<typeof registerFormSchema>;
-export type ChangePasswordForm = z.infer<typeof newPasswordFormSchema>;
+export type ChangePasswordForm = z.infer<typeof changePasswordFormSchema>;
 
 export interface PaginatedUsersResponse {
   users: User[];
   count: number;
+  next_cursor?: string;
 }
 
 export interface LoginResponse {
@@ -42,3 +45,15 @@ export interface LoginResponse {
   username: string;
   email: string;
 }
+
+// Returned by /login instead when the user has two-factor authentication enabled
+export interface TwoFactorChallengeResponse {
+  two_factor_required: true;
+  challenge_token: string;
+  expires_at: string;
+}
+
+export interface LoginTwoFactorRequest {
+  challenge_token: string;
+  code: string;
+}
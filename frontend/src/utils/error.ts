@@ -0,0 +1,7 @@
+import type { AxiosError } from "axios";
+import type { ApiError } from "@/types/error";
+
+// Returns the message of an API error response, if the error is one
+export function getErrorMessage(error: unknown): string | undefined {
+  return (error as AxiosError<ApiError>)?.response?.data?.message;
+}